@@ -2,47 +2,162 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
 	"github.com/sdejongh/jsishell/internal/shell"
 )
 
 const version = "0.1.0"
 
+// options holds the parsed command line.
+type options struct {
+	command string   // Command string given with -c
+	hasCmd  bool     // True if -c was given
+	script  string   // Script file to execute
+	args    []string // Positional parameters for the command or script
+
+	login     bool // Login shell: run the profile files
+	noProfile bool // Skip the profile files of a login shell
+	noRC      bool // Skip the rc files of an interactive shell
+}
+
 func main() {
-	// Check for --version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
-		fmt.Printf("JSIShell version %s\n", version)
-		os.Exit(0)
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jsishell: %v\n", err)
+		printUsage()
+		os.Exit(2)
 	}
 
-	// Check for --help flag
-	if len(os.Args) > 1 && (os.Args[1] == "--help" || os.Args[1] == "-h") {
-		printUsage()
-		os.Exit(0)
+	// A login program starts the shell with a name beginning with "-"
+	if strings.HasPrefix(filepath.Base(os.Args[0]), "-") {
+		opts.login = true
 	}
 
-	// Create and run the shell
+	// Create the shell
 	// Configuration is loaded automatically from ~/.config/jsishell/config.yaml
 	sh := shell.New()
 
-	if err := sh.Run(); err != nil {
+	// Without a command or script, the shell reads commands from its input
+	readsInput := !opts.hasCmd && opts.script == ""
+	if readsInput {
+		sh.Env().SetPositional("jsishell", nil)
+		sh.StartInteractive()
+	}
+
+	// Startup files: profile.jsi for login shells, rc.jsi for interactive ones
+	rc := readsInput && sh.IsInteractive() && !opts.noRC
+	if !sh.LoadStartupFiles(opts.login && !opts.noProfile, rc) {
+		os.Exit(sh.ExitCode())
+	}
+
+	switch {
+	case opts.hasCmd:
+		// jsishell -c "cmd" [name [args...]]
+		name := "jsishell"
+		args := opts.args
+		if len(args) > 0 {
+			name, args = args[0], args[1:]
+		}
+		err = sh.RunCommand(opts.command, name, args)
+
+	case opts.script != "":
+		err = sh.RunFile(opts.script, opts.args)
+
+	default:
+		err = sh.Run()
+	}
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		if errors.Is(err, shellerrors.ErrFileNotFound) {
+			os.Exit(127)
+		}
 		os.Exit(1)
 	}
 
 	os.Exit(sh.ExitCode())
 }
 
+// parseArgs parses the command line arguments.
+// Options must come before the script name; everything after it is passed
+// to the script as positional parameters.
+func parseArgs(args []string) (options, error) {
+	var opts options
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-v", "--version":
+			fmt.Printf("JSIShell version %s\n", version)
+			os.Exit(0)
+
+		case "-h", "--help":
+			printUsage()
+			os.Exit(0)
+
+		case "-l", "--login":
+			opts.login = true
+
+		case "--noprofile":
+			opts.noProfile = true
+
+		case "--norc":
+			opts.noRC = true
+
+		case "-c":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("-c: option requires an argument")
+			}
+			opts.command = args[i+1]
+			opts.hasCmd = true
+			opts.args = args[i+2:]
+			return opts, nil
+
+		case "--":
+			if i+1 < len(args) {
+				opts.script = args[i+1]
+				opts.args = args[i+2:]
+			}
+			return opts, nil
+
+		default:
+			if len(arg) > 1 && arg[0] == '-' {
+				return opts, fmt.Errorf("unknown option: %s", arg)
+			}
+			opts.script = arg
+			opts.args = args[i+1:]
+			return opts, nil
+		}
+	}
+
+	return opts, nil
+}
+
 func printUsage() {
 	fmt.Println("JSIShell - Interactive Shell")
 	fmt.Println("")
-	fmt.Println("Usage: jsishell [options]")
+	fmt.Println("Usage: jsishell [options] [script [args...]]")
+	fmt.Println("       jsishell -c command [name [args...]]")
 	fmt.Println("")
 	fmt.Println("Options:")
+	fmt.Println("  -c command     Execute command and exit")
 	fmt.Println("  -h, --help     Show this help message")
+	fmt.Println("  -l, --login    Act as a login shell and run the profile files")
+	fmt.Println("  --noprofile    Do not run the profile files of a login shell")
+	fmt.Println("  --norc         Do not run the rc files of an interactive shell")
 	fmt.Println("  -v, --version  Show version information")
 	fmt.Println("")
+	fmt.Println("Startup files, from /etc/jsishell then ~/.config/jsishell:")
+	fmt.Println("  profile.jsi    Run by login shells")
+	fmt.Println("  rc.jsi         Run by interactive shells")
+	fmt.Println("")
+	fmt.Println("Scripts may start with a shebang line: #!/usr/bin/env jsishell")
+	fmt.Println("")
 	fmt.Println("Once in the shell, type 'help' to see available commands.")
 }
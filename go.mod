@@ -4,9 +4,9 @@ go 1.24.0
 
 toolchain go1.24.10
 
-require golang.org/x/term v0.37.0
-
 require (
-	golang.org/x/sys v0.38.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sys v0.38.0
+	golang.org/x/term v0.37.0
 )
+
+require gopkg.in/yaml.v3 v3.0.1
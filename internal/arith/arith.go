@@ -0,0 +1,86 @@
+// Package arith evaluates arithmetic expressions: the integer expressions
+// of $((...)) and the expressions of the calc built-in, which also handle
+// decimal numbers and sizes such as 1.5GiB.
+package arith
+
+import (
+	"math"
+	"strconv"
+)
+
+// Variables gives access to the variables an expression reads and assigns.
+type Variables interface {
+	Get(name string) string
+	Set(name, value string)
+}
+
+// Eval evaluates an integer expression, as in $((...)). Variables hold
+// integers or expressions; unset or empty variables are 0. vars may be nil.
+func Eval(expr string, vars Variables) (int64, error) {
+	e := &evaluator{vars: vars}
+	v, err := e.evalString(expr)
+	return v.i, err
+}
+
+// Value is the result of a calc expression.
+type Value struct {
+	Number float64 // Value, in bytes for a size
+	Size   bool    // The value is a size
+	SI     bool    // The size was given in decimal units (kB, MB...) rather than binary units
+}
+
+// Calc evaluates an expression with decimal numbers and sizes, as in
+// 1.5GiB / 3. Sizes can be added together, multiplied or divided by a
+// number, and divided by a size to get a ratio. vars may be nil.
+func Calc(expr string, vars Variables) (Value, error) {
+	e := &evaluator{vars: vars, float: true}
+	v, err := e.evalString(expr)
+	return Value{Number: v.f, Size: v.size, SI: v.si}, err
+}
+
+// sizeUnits are the units sizes are displayed with, largest first.
+var (
+	binaryUnits  = []string{"PiB", "TiB", "GiB", "MiB", "KiB"}
+	decimalUnits = []string{"PB", "TB", "GB", "MB", "kB"}
+)
+
+// String formats the value with up to 12 significant digits. Sizes use the
+// largest unit they reach, so that the result can be given back to calc.
+func (v Value) String() string {
+	return v.format(12)
+}
+
+// format formats the value with up to prec significant digits, or with the
+// fewest digits that read back as the same value if prec is -1.
+func (v Value) format(prec int) string {
+	if !v.Size {
+		return formatNumber(v.Number, prec)
+	}
+
+	names, base := binaryUnits, 1024.0
+	if v.SI {
+		names, base = decimalUnits, 1000.0
+	}
+	for i, name := range names {
+		scale := math.Pow(base, float64(len(names)-i))
+		if math.Abs(v.Number) >= scale {
+			return formatNumber(v.Number/scale, prec) + name
+		}
+	}
+	return formatNumber(v.Number, prec) + "B"
+}
+
+// FormatNumber formats a number as an integer if it has no fraction, or
+// with up to 12 significant digits.
+func FormatNumber(f float64) string {
+	return formatNumber(f, 12)
+}
+
+// formatNumber formats a number as an integer if it has no fraction, or
+// with up to prec significant digits, as in strconv.FormatFloat.
+func formatNumber(f float64, prec int) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', prec, 64)
+}
@@ -0,0 +1,240 @@
+package arith
+
+import (
+	"errors"
+	"testing"
+
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+// vars is a map of variables for tests.
+type vars map[string]string
+
+func (v vars) Get(name string) string { return v[name] }
+func (v vars) Set(name, value string) { v[name] = value }
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"7 / 2", 3},
+		{"-7 % 3", -1},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", 4},
+		{"0x1f + 010", 39},
+		{"1 << 4 | 1", 17},
+		{"6 & 3 ^ 1", 3},
+		{"~0", -1},
+		{"!0 + !5", 1},
+		{"3 > 2 && 2 >= 2", 1},
+		{"1 == 2 || 1 != 1", 0},
+		{"1 < 2 ? 10 : 20", 10},
+		{"0 ? 1 : 0 ? 2 : 3", 3},
+		{"x * 2", 20},
+		{"$x + ${x}", 20},
+		{"unset + 1", 1},
+		{"expr * 2", 14},
+		{"0 && 1 / 0", 0},
+		{"1 || 1 / 0", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Eval(tt.expr, vars{"x": "10", "expr": "3 + 4"})
+			if err != nil {
+				t.Fatalf("Eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %d, want %d", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalAssignment(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int64
+		x    string
+	}{
+		{"x = 5", 5, "5"},
+		{"x += 2", 12, "12"},
+		{"x <<= 1", 20, "20"},
+		{"x++", 10, "11"},
+		{"++x", 11, "11"},
+		{"x--", 10, "9"},
+		{"--x", 9, "9"},
+		{"y = x = 3", 3, "3"},
+		{"x = x * x", 100, "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			v := vars{"x": "10"}
+			got, err := Eval(tt.expr, v)
+			if err != nil {
+				t.Fatalf("Eval error: %v", err)
+			}
+			if got != tt.want || v["x"] != tt.x {
+				t.Errorf("Eval(%q) = %d with x=%s, want %d with x=%s", tt.expr, got, v["x"], tt.want, tt.x)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want error
+	}{
+		{"1 / 0", shellerrors.ErrDivisionByZero},
+		{"5 % 0", shellerrors.ErrDivisionByZero},
+		{"", shellerrors.ErrInvalidSyntax},
+		{"1 +", shellerrors.ErrInvalidSyntax},
+		{"(1 + 2", shellerrors.ErrInvalidSyntax},
+		{"1 2", shellerrors.ErrInvalidSyntax},
+		{"1.5 + 1", shellerrors.ErrInvalidSyntax},
+		{"2 ** -1", shellerrors.ErrInvalidSyntax},
+		{"1 ? 2", shellerrors.ErrInvalidSyntax},
+		{"++1", shellerrors.ErrInvalidSyntax},
+		{"1 # 2", shellerrors.ErrInvalidSyntax},
+		{"loop", shellerrors.ErrInvalidSyntax},
+		{"3x", shellerrors.ErrInvalidSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Eval(tt.expr, vars{"loop": "loop + 1"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Eval(%q) error = %v, want %v", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		expr   string
+		line   int
+		column int
+		msg    string
+	}{
+		{" 1 + ", 1, 5, "invalid syntax: missing operand"},
+		{"(1 + 2", 1, 7, "invalid syntax: missing )"},
+		{"1 2", 1, 3, `invalid syntax: unexpected "2"`},
+		{"++1", 1, 3, "invalid syntax: ++ needs a variable"},
+		{"1 # 2", 1, 3, `invalid syntax: unexpected character '#'`},
+		{"1 +\n2 * * 3", 2, 5, `invalid syntax: unexpected "*"`},
+		{"1 + 3x", 1, 5, "invalid syntax: 3x: invalid number"},
+		{"1 + é", 1, 5, `invalid syntax: unexpected character 'é'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Eval(tt.expr, nil)
+			var d *shellerrors.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("Eval(%q) error = %v, want a diagnostic", tt.expr, err)
+			}
+			if d.Line != tt.line || d.Column != tt.column || d.Error() != tt.msg {
+				t.Errorf("Eval(%q) error at %d:%d %q, want %d:%d %q", tt.expr, d.Line, d.Column, d.Error(), tt.line, tt.column, tt.msg)
+			}
+			if d.Source != tt.expr || !d.Fragment {
+				t.Errorf("Source = %q, Fragment = %v, want the expression", d.Source, d.Fragment)
+			}
+		})
+	}
+}
+
+func TestCalc(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"7 / 2", "3.5"},
+		{"0.1 + 0.2", "0.3"},
+		{".5 * 4", "2"},
+		{"1e3 + 1", "1001"},
+		{"2 ** 0.5", "1.41421356237"},
+		{"1.5GiB / 3", "512MiB"},
+		{"1.5gib / 3", "512MiB"},
+		{"1GB + 500MB", "1.5GB"},
+		{"10MiB / 1KiB", "10240"},
+		{"2K * 512", "1MiB"},
+		{"1KiB + 24", "1.0234375KiB"},
+		{"1GB + 1MB", "1.001GB"},
+		{"100B", "100B"},
+		{"1KiB / 3", "341.333333333B"},
+		{"-2MiB", "-2MiB"},
+		{"2GiB > 2GB", "1"},
+		{"7 % 2.5", "2"},
+		{"size * 2", "3GiB"},
+		{"6 & 3", "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Calc(tt.expr, vars{"size": "1.5GiB"})
+			if err != nil {
+				t.Fatalf("Calc error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Calc(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcAssignment(t *testing.T) {
+	tests := []struct {
+		exprs []string
+		want  string
+		x     string
+	}{
+		{[]string{"x = 1GB + 1MB", "x * 1000"}, "1.001TB", "1.001GB"},
+		{[]string{"x = 1 / 3", "x * 3"}, "1", "0.3333333333333333"},
+		{[]string{"x = 1KiB / 3", "x * 3"}, "1KiB", "341.3333333333333B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exprs[0], func(t *testing.T) {
+			v := vars{}
+			var got Value
+			for _, expr := range tt.exprs {
+				var err error
+				if got, err = Calc(expr, v); err != nil {
+					t.Fatalf("Calc(%q) error: %v", expr, err)
+				}
+			}
+			if got.String() != tt.want || v["x"] != tt.x {
+				t.Errorf("got %s with x=%s, want %s with x=%s", got, v["x"], tt.want, tt.x)
+			}
+		})
+	}
+}
+
+func TestCalcErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want error
+	}{
+		{"1 / 0", shellerrors.ErrDivisionByZero},
+		{"1GiB * 1GiB", shellerrors.ErrInvalidSyntax},
+		{"1 / 1GiB", shellerrors.ErrInvalidSyntax},
+		{"1GiB ** 2", shellerrors.ErrInvalidSyntax},
+		{"1.5 & 1", shellerrors.ErrInvalidSyntax},
+		{"1XB", shellerrors.ErrInvalidSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Calc(tt.expr, nil)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Calc(%q) error = %v, want %v", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package arith
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+)
+
+// maxDepth limits the nesting of variables holding expressions, so that
+// x=x does not loop forever.
+const maxDepth = 32
+
+// value is the value of an expression: an integer for $((...)), or a
+// decimal number, possibly a size, for calc.
+type value struct {
+	i    int64
+	f    float64
+	size bool
+	si   bool
+}
+
+// evaluator evaluates expression trees.
+type evaluator struct {
+	vars  Variables
+	float bool // Decimal numbers and sizes, for calc
+	depth int  // Nesting of variables holding expressions
+}
+
+// evalString parses and evaluates an expression.
+func (e *evaluator) evalString(expr string) (value, error) {
+	n, err := parse(expr)
+	if err != nil {
+		return value{}, err
+	}
+	return e.eval(n)
+}
+
+func (e *evaluator) eval(n node) (value, error) {
+	switch n := n.(type) {
+	case numberNode:
+		return e.number(n.tok)
+
+	case varNode:
+		return e.variable(n.name)
+
+	case unaryNode:
+		x, err := e.eval(n.x)
+		if err != nil {
+			return value{}, err
+		}
+		return e.unary(n.op, x)
+
+	case binaryNode:
+		x, err := e.eval(n.x)
+		if err != nil {
+			return value{}, err
+		}
+		// && and || only evaluate their right side when needed
+		if (n.op == "&&" && !e.truth(x)) || (n.op == "||" && e.truth(x)) {
+			return e.boolean(n.op == "||"), nil
+		}
+		y, err := e.eval(n.y)
+		if err != nil {
+			return value{}, err
+		}
+		return e.binary(n.op, x, y)
+
+	case condNode:
+		cond, err := e.eval(n.cond)
+		if err != nil {
+			return value{}, err
+		}
+		if e.truth(cond) {
+			return e.eval(n.then)
+		}
+		return e.eval(n.els)
+
+	case assignNode:
+		v, err := e.eval(n.x)
+		if err != nil {
+			return value{}, err
+		}
+		if n.op != "=" {
+			current, err := e.variable(n.name)
+			if err != nil {
+				return value{}, err
+			}
+			if v, err = e.binary(strings.TrimSuffix(n.op, "="), current, v); err != nil {
+				return value{}, err
+			}
+		}
+		e.assign(n.name, v)
+		return v, nil
+
+	case incDecNode:
+		current, err := e.variable(n.name)
+		if err != nil {
+			return value{}, err
+		}
+		v, err := e.binary(n.op[:1], current, value{i: 1, f: 1})
+		if err != nil {
+			return value{}, err
+		}
+		e.assign(n.name, v)
+		if n.prefix {
+			return v, nil
+		}
+		return current, nil
+	}
+	return value{}, fmt.Errorf("%w: unknown expression", errors.ErrInvalidSyntax)
+}
+
+// number returns the value of a number token.
+func (e *evaluator) number(tok token) (value, error) {
+	if e.float {
+		// kb, mb... are decimal units; k, kib, m, mib... binary units
+		return value{f: tok.num, size: tok.unit != "", si: len(tok.unit) == 2}, nil
+	}
+	if !tok.isInt {
+		return value{}, fmt.Errorf("%w: %s: not an integer (calc handles decimal numbers and sizes)", errors.ErrInvalidSyntax, tok.text)
+	}
+	return value{i: tok.int}, nil
+}
+
+// variable returns the value of a variable, evaluating its content as an
+// expression. Unset and empty variables are 0.
+func (e *evaluator) variable(name string) (value, error) {
+	if e.vars == nil {
+		return value{}, nil
+	}
+	s := strings.TrimSpace(e.vars.Get(name))
+	if s == "" {
+		return value{}, nil
+	}
+	if i, err := parseInt(s); err == nil {
+		return value{i: i, f: float64(i)}, nil
+	}
+
+	if e.depth >= maxDepth {
+		return value{}, fmt.Errorf("%w: %s: expression recursion level exceeded", errors.ErrInvalidSyntax, name)
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+	return e.evalString(s)
+}
+
+// assign sets a variable to a value. calc stores the number exactly, not
+// rounded as it is printed.
+func (e *evaluator) assign(name string, v value) {
+	if e.vars == nil {
+		return
+	}
+	if e.float {
+		e.vars.Set(name, Value{Number: v.f, Size: v.size, SI: v.si}.format(-1))
+		return
+	}
+	e.vars.Set(name, strconv.FormatInt(v.i, 10))
+}
+
+// truth returns true if a value is not zero.
+func (e *evaluator) truth(v value) bool {
+	if e.float {
+		return v.f != 0
+	}
+	return v.i != 0
+}
+
+// boolean returns 1 for true and 0 for false.
+func (e *evaluator) boolean(b bool) value {
+	if b {
+		return value{i: 1, f: 1}
+	}
+	return value{}
+}
+
+func (e *evaluator) unary(op string, x value) (value, error) {
+	switch op {
+	case "-":
+		return value{i: -x.i, f: -x.f, size: x.size, si: x.si}, nil
+	case "+":
+		return x, nil
+	case "!":
+		return e.boolean(!e.truth(x)), nil
+	}
+
+	// ~
+	i, err := e.integer(op, x)
+	if err != nil {
+		return value{}, err
+	}
+	return value{i: ^i, f: float64(^i)}, nil
+}
+
+func (e *evaluator) binary(op string, x, y value) (value, error) {
+	switch op {
+	case "&&":
+		return e.boolean(e.truth(x) && e.truth(y)), nil
+	case "||":
+		return e.boolean(e.truth(x) || e.truth(y)), nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		c := compareInt(x.i, y.i)
+		if e.float {
+			c = compareFloat(x.f, y.f)
+		}
+		return e.boolean(compared(op, c)), nil
+	}
+
+	if e.float {
+		switch op {
+		case "+", "-", "*", "/", "%", "**":
+			return calcOp(op, x, y)
+		}
+	}
+
+	a, err := e.integer(op, x)
+	if err != nil {
+		return value{}, err
+	}
+	b, err := e.integer(op, y)
+	if err != nil {
+		return value{}, err
+	}
+	i, err := intOp(op, a, b)
+	return value{i: i, f: float64(i)}, err
+}
+
+// integer returns the integer value of an operand of op. In calc, the
+// operand must be a number without fraction.
+func (e *evaluator) integer(op string, v value) (int64, error) {
+	if !e.float {
+		return v.i, nil
+	}
+	if v.size || v.f != math.Trunc(v.f) {
+		return 0, fmt.Errorf("%w: %s needs integers", errors.ErrInvalidSyntax, op)
+	}
+	return int64(v.f), nil
+}
+
+// intOp applies an integer operator.
+func intOp(op string, a, b int64) (int64, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, errors.ErrDivisionByZero
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	case "**":
+		if b < 0 {
+			return 0, fmt.Errorf("%w: negative exponent %d", errors.ErrInvalidSyntax, b)
+		}
+		r := int64(1)
+		for ; b > 0; b-- {
+			r *= a
+		}
+		return r, nil
+	case "<<", ">>":
+		if b < 0 {
+			return 0, fmt.Errorf("%w: negative shift %d", errors.ErrInvalidSyntax, b)
+		}
+		if op == "<<" {
+			return a << b, nil
+		}
+		return a >> b, nil
+	case "&":
+		return a & b, nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	}
+	return 0, fmt.Errorf("%w: unknown operator %s", errors.ErrInvalidSyntax, op)
+}
+
+// calcOp applies an arithmetic operator to decimal numbers and sizes.
+// A plain number added to a size is a number of bytes.
+func calcOp(op string, x, y value) (value, error) {
+	r := value{si: x.si || y.si}
+	switch op {
+	case "+":
+		r.f, r.size = x.f+y.f, x.size || y.size
+	case "-":
+		r.f, r.size = x.f-y.f, x.size || y.size
+	case "*":
+		if x.size && y.size {
+			return value{}, fmt.Errorf("%w: cannot multiply two sizes", errors.ErrInvalidSyntax)
+		}
+		r.f, r.size = x.f*y.f, x.size || y.size
+	case "/", "%":
+		if y.f == 0 {
+			return value{}, errors.ErrDivisionByZero
+		}
+		if y.size && !x.size {
+			return value{}, fmt.Errorf("%w: cannot divide a number by a size", errors.ErrInvalidSyntax)
+		}
+		if op == "/" {
+			r.f, r.size = x.f/y.f, x.size && !y.size
+		} else {
+			r.f, r.size = math.Mod(x.f, y.f), x.size
+		}
+	case "**":
+		if x.size || y.size {
+			return value{}, fmt.Errorf("%w: cannot raise a size to a power", errors.ErrInvalidSyntax)
+		}
+		r.f = math.Pow(x.f, y.f)
+	}
+	if !r.size {
+		r.si = false
+	}
+	return r, nil
+}
+
+// compareInt returns -1, 0 or 1 as a is less than, equal to or greater than b.
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareFloat returns -1, 0 or 1 as a is less than, equal to or greater than b.
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compared returns the result of a comparison operator given the order of its operands.
+func compared(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
@@ -0,0 +1,246 @@
+package arith
+
+import (
+	"fmt"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+)
+
+// node is a node of an expression tree.
+type node interface{}
+
+// numberNode is a number.
+type numberNode struct {
+	tok token
+}
+
+// varNode is a variable reference.
+type varNode struct {
+	name string
+}
+
+// unaryNode is -x, +x, !x or ~x.
+type unaryNode struct {
+	op string
+	x  node
+}
+
+// binaryNode is x op y.
+type binaryNode struct {
+	op   string
+	x, y node
+}
+
+// condNode is cond ? then : els.
+type condNode struct {
+	cond, then, els node
+}
+
+// assignNode is name = x, name += x...
+type assignNode struct {
+	name, op string
+	x        node
+}
+
+// incDecNode is ++name, name--...
+type incDecNode struct {
+	name, op string
+	prefix   bool
+}
+
+// precedence gives the precedence of the binary operators, loosest first.
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+	"**": 11,
+}
+
+// assignOps are the assignment operators.
+var assignOps = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"<<=": true, ">>=": true, "&=": true, "|=": true, "^=": true,
+}
+
+// parser builds the tree of an expression from its tokens.
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+}
+
+// parse parses an expression into a tree.
+func parse(expr string) (node, error) {
+	tokens, err := scan(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: expr, tokens: tokens}
+	if p.current().kind == tokEOF {
+		return nil, fmt.Errorf("%w: empty expression", errors.ErrInvalidSyntax)
+	}
+
+	n, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.current(); tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", tok.text)
+	}
+	return n, nil
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isOp returns true if the current token is the operator op.
+func (p *parser) isOp(op string) bool {
+	tok := p.current()
+	return tok.kind == tokOp && tok.text == op
+}
+
+// errorf returns a syntax error at the current token of the expression.
+func (p *parser) errorf(format string, args ...any) error {
+	return p.errorAt(p.current(), format, args...)
+}
+
+// errorAt returns a syntax error at the given token of the expression.
+func (p *parser) errorAt(tok token, format string, args ...any) error {
+	return syntaxError(p.expr, tok.pos, fmt.Sprintf(format, args...))
+}
+
+// assignment parses name op= expression, or a conditional expression.
+func (p *parser) assignment() (node, error) {
+	if p.current().kind == tokIdent {
+		next := p.tokens[p.pos+1]
+		if next.kind == tokOp && assignOps[next.text] {
+			name := p.next().text
+			op := p.next().text
+			x, err := p.assignment()
+			if err != nil {
+				return nil, err
+			}
+			return assignNode{name: name, op: op, x: x}, nil
+		}
+	}
+	return p.conditional()
+}
+
+// conditional parses cond ? then : else, or a binary expression.
+func (p *parser) conditional() (node, error) {
+	cond, err := p.binary(1)
+	if err != nil || !p.isOp("?") {
+		return cond, err
+	}
+	p.next()
+
+	then, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(":") {
+		return nil, p.errorf("missing : after ?")
+	}
+	p.next()
+	els, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	return condNode{cond: cond, then: then, els: els}, nil
+}
+
+// binary parses a sequence of binary operators of at least the given precedence.
+func (p *parser) binary(minPrec int) (node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.current()
+		prec, ok := precedence[tok.text]
+		if tok.kind != tokOp || !ok || prec < minPrec {
+			return x, nil
+		}
+		p.next()
+
+		// ** is right-associative
+		next := prec + 1
+		if tok.text == "**" {
+			next = prec
+		}
+		y, err := p.binary(next)
+		if err != nil {
+			return nil, err
+		}
+		x = binaryNode{op: tok.text, x: x, y: y}
+	}
+}
+
+// unary parses prefix operators.
+func (p *parser) unary() (node, error) {
+	tok := p.current()
+	if tok.kind != tokOp {
+		return p.postfix()
+	}
+	switch tok.text {
+	case "+", "-", "!", "~":
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: tok.text, x: x}, nil
+	case "++", "--":
+		p.next()
+		if p.current().kind != tokIdent {
+			return nil, p.errorf("%s needs a variable", tok.text)
+		}
+		return incDecNode{name: p.next().text, op: tok.text, prefix: true}, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses an operand, with an optional ++ or -- after a variable.
+func (p *parser) postfix() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return numberNode{tok: tok}, nil
+	case tokIdent:
+		if p.isOp("++") || p.isOp("--") {
+			return incDecNode{name: tok.text, op: p.next().text}, nil
+		}
+		return varNode{name: tok.text}, nil
+	case tokEOF:
+		return nil, p.errorf("missing operand")
+	}
+
+	if tok.text != "(" {
+		return nil, p.errorAt(tok, "unexpected %q", tok.text)
+	}
+	x, err := p.assignment()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(")") {
+		return nil, p.errorf("missing )")
+	}
+	p.next()
+	return x, nil
+}
@@ -0,0 +1,212 @@
+package arith
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+)
+
+// tokenKind identifies the kind of an expression token.
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota // End of the expression
+	tokNumber                  // Number, with an optional size unit
+	tokIdent                   // Variable name
+	tokOp                      // Operator or parenthesis
+)
+
+// token is a token of an expression.
+type token struct {
+	kind  tokenKind
+	text  string  // Source text
+	pos   int     // Byte offset in the expression
+	num   float64 // Value of a number, in bytes for a size
+	int   int64   // Value of an integer
+	isInt bool    // The number is an integer without unit
+	unit  string  // Size unit of a number, lower case
+}
+
+// operators lists the operators, longest first.
+var operators = []string{
+	"<<=", ">>=",
+	"**", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "&", "|", "^", "?", ":", "(", ")",
+}
+
+// units are the size units of calc, by lower case name, in bytes.
+// Single letters are binary units, as in the output of ls -h or du -h.
+var units = map[string]float64{
+	"b": 1,
+	"k": 1 << 10, "kb": 1e3, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1e6, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1e9, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1e12, "tib": 1 << 40,
+	"p": 1 << 50, "pb": 1e15, "pib": 1 << 50,
+}
+
+// scan splits an expression into tokens.
+func scan(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isDigit(c) || (c == '.' && i+1 < len(expr) && isDigit(expr[i+1])):
+			tok, n, ok := scanNumber(expr[i:])
+			if !ok {
+				return nil, syntaxError(expr, i, tok.text+": invalid number")
+			}
+			tok.pos = i
+			tokens = append(tokens, tok)
+			i += n
+
+		case c == '$' || c == '_' || isLetter(c):
+			n := scanName(expr[i:])
+			if n == 0 {
+				return nil, syntaxError(expr, i, "invalid variable reference")
+			}
+			name := strings.Trim(expr[i:i+n], "${}")
+			tokens = append(tokens, token{kind: tokIdent, text: name, pos: i})
+			i += n
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(expr[i:])
+				return nil, syntaxError(expr, i, fmt.Sprintf("unexpected character %q", r))
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	// The end is right after the last token, for errors about it
+	end := len(strings.TrimRight(expr, " \t\r\n"))
+	return append(tokens, token{kind: tokEOF, pos: end}), nil
+}
+
+// syntaxError returns a syntax error at the byte offset pos of expr, which
+// is shown with a caret under that position.
+func syntaxError(expr string, pos int, msg string) error {
+	start := strings.LastIndexByte(expr[:pos], '\n') + 1
+	return &errors.Diagnostic{
+		Err:      fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, msg),
+		Line:     strings.Count(expr[:pos], "\n") + 1,
+		Column:   utf8.RuneCountInString(expr[start:pos]) + 1,
+		Source:   expr,
+		Fragment: true,
+	}
+}
+
+// scanNumber reads the number at the start of s: a decimal, hexadecimal
+// (0x1f) or octal (017) integer, or a decimal number with a fraction or an
+// exponent, followed by an optional size unit. Returns false if it is not
+// a valid number.
+func scanNumber(s string) (token, int, bool) {
+	n := 0
+	for n < len(s) && (isDigit(s[n]) || isLetter(s[n]) || s[n] == '.' || s[n] == '_') {
+		// The sign of an exponent belongs to the number
+		if (s[n] == 'e' || s[n] == 'E') && n+1 < len(s) && (s[n+1] == '+' || s[n+1] == '-') && !isHex(s[:n]) {
+			n++
+		}
+		n++
+	}
+	text := s[:n]
+
+	// A size unit follows the digits
+	digits, unit := text, ""
+	if !isHex(text) {
+		if i := strings.IndexFunc(text, func(r rune) bool {
+			return r != 'e' && r != 'E' && (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+		}); i >= 0 {
+			digits, unit = text[:i], strings.ToLower(text[i:])
+		}
+	}
+
+	tok := token{kind: tokNumber, text: text}
+	if unit != "" {
+		scale, ok := units[unit]
+		if !ok {
+			return tok, 0, false
+		}
+		tok.unit = unit
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil {
+			return tok, 0, false
+		}
+		tok.num = f * scale
+		return tok, n, true
+	}
+
+	if i, err := parseInt(digits); err == nil {
+		tok.num, tok.int, tok.isInt = float64(i), i, true
+		return tok, n, true
+	}
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil || isHex(digits) {
+		return tok, 0, false
+	}
+	tok.num = f
+	return tok, n, true
+}
+
+// parseInt parses a decimal, hexadecimal or octal integer.
+func parseInt(s string) (int64, error) {
+	if len(s) > 1 && s[0] == '0' && isDigit(s[1]) {
+		return strconv.ParseInt(s[1:], 8, 64)
+	}
+	return strconv.ParseInt(s, 0, 64)
+}
+
+// scanName returns the length of the variable reference at the start of s:
+// name, $name or ${name}. Returns 0 if there is none.
+func scanName(s string) int {
+	i := 0
+	braced := false
+	if strings.HasPrefix(s, "${") {
+		i, braced = 2, true
+	} else if s[0] == '$' {
+		i = 1
+	}
+	start := i
+	for i < len(s) && (s[i] == '_' || isLetter(s[i]) || (i > start && isDigit(s[i]))) {
+		i++
+	}
+	if i == start {
+		return 0
+	}
+	if braced {
+		if i >= len(s) || s[i] != '}' {
+			return 0
+		}
+		i++
+	}
+	return i
+}
+
+// isHex returns true if s starts like a hexadecimal number.
+func isHex(s string) bool {
+	return len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+// isDigit returns true if c is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLetter returns true if c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,160 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/config"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// AliasDefinition returns the alias command definition.
+func AliasDefinition() Definition {
+	return Definition{
+		Name:        "alias",
+		Description: "Define or display aliases",
+		Usage:       "alias [name[=value]...]",
+		Handler:     aliasHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// UnaliasDefinition returns the unalias command definition.
+func UnaliasDefinition() Definition {
+	return Definition{
+		Name:        "unalias",
+		Description: "Remove aliases",
+		Usage:       "unalias [-a] name...",
+		Handler:     unaliasHandler,
+		Options: []OptionDef{
+			{Short: "-a", Long: "--all", Description: "Remove all aliases"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func aliasHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showAliasHelp(execCtx)
+		return 0, nil
+	}
+
+	if execCtx.Aliases == nil {
+		execCtx.WriteErrorln("alias: aliases are not available")
+		return 1, nil
+	}
+
+	// Without arguments, display all aliases
+	if len(cmd.Args) == 0 {
+		for _, name := range execCtx.Aliases.Names() {
+			value, _ := execCtx.Aliases.Get(name)
+			printAlias(execCtx, name, value)
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, arg := range cmd.Args {
+		name, value, isAssignment := strings.Cut(arg, "=")
+		if !isAssignment {
+			// Just a name - display that alias
+			value, ok := execCtx.Aliases.Get(name)
+			if !ok {
+				execCtx.WriteErrorln("alias: %s: not found", name)
+				status = 1
+				continue
+			}
+			printAlias(execCtx, name, value)
+			continue
+		}
+
+		if !config.IsValidAliasName(name) {
+			execCtx.WriteErrorln("alias: %s: invalid alias name", name)
+			status = 1
+			continue
+		}
+		execCtx.Aliases.Set(name, value)
+	}
+
+	return status, nil
+}
+
+func unaliasHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showUnaliasHelp(execCtx)
+		return 0, nil
+	}
+
+	if execCtx.Aliases == nil {
+		execCtx.WriteErrorln("unalias: aliases are not available")
+		return 1, nil
+	}
+
+	if cmd.HasFlag("-a", "--all") {
+		execCtx.Aliases.Clear()
+		return 0, nil
+	}
+
+	if len(cmd.Args) == 0 {
+		execCtx.WriteErrorln("unalias: missing alias name")
+		return 1, nil
+	}
+
+	status := 0
+	for _, name := range cmd.Args {
+		if !execCtx.Aliases.Remove(name) {
+			execCtx.WriteErrorln("unalias: %s: not found", name)
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+// printAlias prints an alias in a form that can be read back by alias.
+func printAlias(execCtx *Context, name, value string) {
+	fmt.Fprintf(execCtx.Stdout, "alias %s=%s\n", name, shellQuote(value))
+}
+
+func showAliasHelp(execCtx *Context) {
+	help := `alias - Define or display aliases
+
+Usage: alias [name[=value]...]
+
+Description:
+  Without arguments, displays all aliases.
+  With NAME arguments, displays those aliases.
+  With NAME=VALUE arguments, defines those aliases.
+
+  When a command starts with an alias name, the name is replaced by the
+  value of the alias. Aliases can refer to other aliases; an alias is
+  not expanded again within its own value. Aliases can be abbreviated
+  like commands, and can also be defined in the configuration file under
+  "aliases:".
+
+Examples:
+  alias ll='ls -l'           Define ll
+  alias gs='git status'      Define gs
+  alias ll                   Display ll
+  alias                      Display all aliases
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showUnaliasHelp(execCtx *Context) {
+	help := `unalias - Remove aliases
+
+Usage: unalias [-a] name...
+
+Options:
+  -a, --all    Remove all aliases
+  --help       Show this help message
+
+Examples:
+  unalias ll   Remove the ll alias
+  unalias -a   Remove all aliases
+`
+	execCtx.Stdout.Write([]byte(help))
+}
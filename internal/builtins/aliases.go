@@ -0,0 +1,104 @@
+package builtins
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Aliases manages command aliases.
+type Aliases struct {
+	mu      sync.RWMutex
+	aliases map[string]string
+}
+
+// NewAliases creates a new empty alias table.
+func NewAliases() *Aliases {
+	return &Aliases{
+		aliases: make(map[string]string),
+	}
+}
+
+// Clone returns a copy of the table, which a subshell changes without
+// affecting the shell.
+func (a *Aliases) Clone() *Aliases {
+	return &Aliases{aliases: a.All()}
+}
+
+// Set defines or replaces an alias.
+func (a *Aliases) Set(name, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.aliases[name] = value
+}
+
+// Get returns the value of an alias.
+// Returns the value and true if found, empty string and false otherwise.
+func (a *Aliases) Get(name string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	value, ok := a.aliases[name]
+	return value, ok
+}
+
+// Has returns true if an alias with the given name exists.
+func (a *Aliases) Has(name string) bool {
+	_, ok := a.Get(name)
+	return ok
+}
+
+// Remove deletes an alias. Returns false if it did not exist.
+func (a *Aliases) Remove(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.aliases[name]
+	delete(a.aliases, name)
+	return ok
+}
+
+// Clear deletes all aliases.
+func (a *Aliases) Clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.aliases = make(map[string]string)
+}
+
+// Names returns all alias names in sorted order.
+func (a *Aliases) Names() []string {
+	return a.Match("")
+}
+
+// Match finds aliases whose names start with the given prefix.
+// Returns matching names in sorted order.
+func (a *Aliases) Match(prefix string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var matches []string
+	for name := range a.aliases {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// All returns a copy of all aliases.
+func (a *Aliases) All() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	result := make(map[string]string, len(a.aliases))
+	for k, v := range a.aliases {
+		result[k] = v
+	}
+	return result
+}
+
+// Count returns the number of aliases.
+func (a *Aliases) Count() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.aliases)
+}
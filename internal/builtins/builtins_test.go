@@ -3,10 +3,16 @@ package builtins
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/jobs"
 	"github.com/sdejongh/jsishell/internal/parser"
 )
 
@@ -201,6 +207,28 @@ func TestContextFields(t *testing.T) {
 	}
 }
 
+func TestContextPath(t *testing.T) {
+	work := filepath.Join(t.TempDir(), "work")
+	abs := filepath.Join(t.TempDir(), "file.txt")
+	tests := []struct {
+		workDir string
+		name    string
+		want    string
+	}{
+		{work, "file.txt", filepath.Join(work, "file.txt")},
+		{work, filepath.Join("..", "file.txt"), filepath.Join(filepath.Dir(work), "file.txt")},
+		{work, abs, abs},
+		{"", "file.txt", "file.txt"},
+	}
+
+	for _, tt := range tests {
+		ctx := &Context{WorkDir: tt.workDir}
+		if got := ctx.Path(tt.name); got != tt.want {
+			t.Errorf("Path(%q) in %q = %q, want %q", tt.name, tt.workDir, got, tt.want)
+		}
+	}
+}
+
 func TestRegistryConcurrentAccess(t *testing.T) {
 	r := NewRegistry()
 	done := make(chan bool, 20)
@@ -625,6 +653,7 @@ func TestRegisterAll(t *testing.T) {
 
 	expectedCommands := []string{
 		"echo", "exit", "help", "clear", "env",
+		"true", "false", "break", "continue", "return", "local",
 		"cd", "pwd", "ls", "mkdir", "cp", "mv", "rm", "search",
 		"reload", "history",
 	}
@@ -644,6 +673,7 @@ func TestAllBuiltinsHaveHelp(t *testing.T) {
 	// Commands that should have --help (all except 'help' itself)
 	commandsWithHelp := []string{
 		"echo", "exit", "clear", "env",
+		"true", "false", "break", "continue", "return", "local",
 		"cd", "pwd", "ls", "mkdir", "cp", "mv", "rm", "search",
 		"reload", "history",
 	}
@@ -682,6 +712,12 @@ func TestHelpFlagProducesOutput(t *testing.T) {
 	}{
 		{"echo", echoHandler},
 		{"exit", exitHandler},
+		{"true", trueHandler},
+		{"false", falseHandler},
+		{"break", breakHandler},
+		{"continue", continueHandler},
+		{"return", returnHandler},
+		{"local", localHandler},
 		{"clear", clearHandler},
 		{"env", envHandler},
 		{"cd", cdHandler},
@@ -754,8 +790,10 @@ func TestEchoHelp(t *testing.T) {
 
 // Mock history provider for testing
 type mockHistoryProvider struct {
-	entries []HistoryEntry
-	cleared bool
+	entries  []HistoryEntry
+	cleared  bool
+	format   string // Format of the last export or import
+	imported string // Content of the last import
 }
 
 func (m *mockHistoryProvider) Len() int {
@@ -771,6 +809,183 @@ func (m *mockHistoryProvider) Clear() {
 	m.cleared = true
 }
 
+func (m *mockHistoryProvider) Delete(first, last int) error {
+	m.entries = append(m.entries[:first-1], m.entries[last:]...)
+	return nil
+}
+
+func (m *mockHistoryProvider) Export(w io.Writer, format string) error {
+	m.format = format
+	for _, entry := range m.entries {
+		fmt.Fprintln(w, entry.Command)
+	}
+	return nil
+}
+
+func (m *mockHistoryProvider) Import(r io.Reader, format string) (int, error) {
+	m.format = format
+	data, err := io.ReadAll(r)
+	m.imported = string(data)
+	return strings.Count(m.imported, "\n"), err
+}
+
+// runHistory runs the history command with the words of a command line and
+// returns its exit status and output.
+func runHistory(t *testing.T, mock *mockHistoryProvider, workDir string, words ...string) (int, string, string) {
+	t.Helper()
+	SetHistoryProvider(func() HistoryProvider {
+		return mock
+	})
+	defer SetHistoryProvider(nil)
+
+	cmd := parser.NewCommand()
+	cmd.Name = "history"
+	cmd.Words = words
+	for _, word := range words {
+		if name, value, ok := strings.Cut(word, "="); ok && strings.HasPrefix(word, "--") {
+			cmd.Options[name] = value
+		} else if strings.HasPrefix(word, "-") {
+			cmd.Flags[word] = true
+		} else {
+			cmd.Args = append(cmd.Args, word)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	execCtx := &Context{Stdout: &stdout, Stderr: &stderr, Env: env.New(), WorkDir: workDir}
+	code, err := historyHandler(context.Background(), cmd, execCtx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return code, stdout.String(), stderr.String()
+}
+
+func TestHistorySearchDelete(t *testing.T) {
+	entries := func() []HistoryEntry {
+		return []HistoryEntry{
+			{Command: "git status"},
+			{Command: "make test"},
+			{Command: "git push origin main"},
+			{Command: "ls"},
+		}
+	}
+	tests := []struct {
+		name     string
+		words    []string
+		wantCode int
+		wantOut  string
+		wantErr  string
+		wantLeft int
+	}{
+		{"search", []string{"search", "^git"}, 0, "    1  git status\n    3  git push origin main\n", "", 4},
+		{"search no match", []string{"search", "docker"}, 1, "", "", 4},
+		{"search invalid", []string{"search", "(git"}, 2, "", "invalid pattern", 4},
+		{"search usage", []string{"search"}, 2, "", "usage", 4},
+		{"delete one", []string{"delete", "2"}, 0, "", "", 3},
+		{"delete range", []string{"delete", "2-4"}, 0, "", "", 1},
+		{"delete out of range", []string{"delete", "3-5"}, 1, "", "out of range", 4},
+		{"delete invalid", []string{"delete", "3-2"}, 2, "", "invalid range", 4},
+		{"delete zero", []string{"delete", "0"}, 2, "", "invalid range", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockHistoryProvider{entries: entries()}
+			code, out, errOut := runHistory(t, mock, "", tt.words...)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if out != tt.wantOut {
+				t.Errorf("output = %q, want %q", out, tt.wantOut)
+			}
+			if !strings.Contains(errOut, tt.wantErr) || tt.wantErr == "" && errOut != "" {
+				t.Errorf("error output = %q, want %q", errOut, tt.wantErr)
+			}
+			if len(mock.entries) != tt.wantLeft {
+				t.Errorf("%d entries left, want %d", len(mock.entries), tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestHistoryStats(t *testing.T) {
+	mock := &mockHistoryProvider{
+		entries: []HistoryEntry{
+			{Command: "ls"},
+			{Command: "make test", Dir: "/src/app", ExitCode: 2, Detailed: true},
+			{Command: "make build", Dir: "/src/app", Detailed: true},
+			{Command: "CC=clang make", Dir: "/src/lib", Detailed: true},
+			{Command: "git push", Dir: "/src/app", ExitCode: 1, Detailed: true},
+		},
+	}
+	code, out, _ := runHistory(t, mock, "", "stats", "2")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	want := `Commands: 5, 4 with an exit status, 2 failed (50.0%)
+
+Top commands:
+      3   60.0%  make
+      1   20.0%  git
+
+Top directories:
+      3   75.0%  /src/app
+      1   25.0%  /src/lib
+
+Failures:
+    1/1  100.0%  git
+    1/3   33.3%  make
+`
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestHistoryExportImport(t *testing.T) {
+	mock := &mockHistoryProvider{entries: []HistoryEntry{{Command: "ls"}, {Command: "pwd"}}}
+
+	code, out, _ := runHistory(t, mock, "", "export")
+	if code != 0 || out != "ls\npwd\n" || mock.format != "json" {
+		t.Errorf("export = %d %q in %q, want 0 \"ls\\npwd\\n\" in json", code, out, mock.format)
+	}
+	for _, words := range [][]string{{"export", "--format=zsh"}, {"export", "--format", "zsh"}} {
+		mock.format = ""
+		if code, _, errOut := runHistory(t, mock, "", words...); code != 0 || mock.format != "zsh" {
+			t.Errorf("%q: exit code %d, format %q, want zsh: %s", words, code, mock.format, errOut)
+		}
+	}
+	if code, _, _ := runHistory(t, mock, "", "export", "--format"); code != 2 {
+		t.Errorf("export without format value: exit code %d, want 2", code)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".bash_history"), []byte("echo a\necho b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, out, _ = runHistory(t, mock, dir, "import", ".bash_history")
+	if code != 0 || out != "Imported 2 commands from .bash_history\n" {
+		t.Errorf("import = %d %q", code, out)
+	}
+	if mock.imported != "echo a\necho b\n" || mock.format != "" {
+		t.Errorf("imported %q in format %q", mock.imported, mock.format)
+	}
+	runHistory(t, mock, dir, "import", "--format", "bash", ".bash_history")
+	if mock.format != "bash" {
+		t.Errorf("import format = %q, want bash", mock.format)
+	}
+	if code, _, _ := runHistory(t, mock, dir, "import", ".bash_history", "--format"); code != 2 {
+		t.Errorf("import without format value: exit code %d, want 2", code)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "one"), []byte("echo a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, out, _ = runHistory(t, mock, dir, "import", "one"); out != "Imported 1 command from one\n" {
+		t.Errorf("import of one command = %q", out)
+	}
+	if code, _, errOut := runHistory(t, mock, dir, "import", "missing"); code != 1 || errOut == "" {
+		t.Errorf("import of a missing file = %d %q, want an error", code, errOut)
+	}
+}
+
 func TestHistoryCommand(t *testing.T) {
 	// Create mock provider
 	mock := &mockHistoryProvider{
@@ -870,6 +1085,33 @@ func TestHistoryCommandWithCount(t *testing.T) {
 	}
 }
 
+func TestHistoryCommandVerbose(t *testing.T) {
+	ts := time.Date(2026, 3, 14, 9, 26, 0, 0, time.Local)
+	mock := &mockHistoryProvider{
+		entries: []HistoryEntry{
+			{Command: "old", Timestamp: ts},
+			{Command: "make", Timestamp: ts, Dir: "/src/app", ExitCode: 2, Duration: 1500 * time.Millisecond, Detailed: true},
+		},
+	}
+	SetHistoryProvider(func() HistoryProvider {
+		return mock
+	})
+	defer SetHistoryProvider(nil)
+
+	var stdout, stderr bytes.Buffer
+	execCtx := &Context{Stdout: &stdout, Stderr: &stderr, Env: env.New()}
+	cmd := &parser.Command{Name: "history", Flags: map[string]bool{"-v": true}}
+	if code, _ := historyHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+
+	want := "    1  2026-03-14 09:26    -         -  -  old\n" +
+		"    2  2026-03-14 09:26    2      1.5s  /src/app  make\n"
+	if stdout.String() != want {
+		t.Errorf("output = %q, want %q", stdout.String(), want)
+	}
+}
+
 func TestHistoryCommandClear(t *testing.T) {
 	mock := &mockHistoryProvider{
 		entries: []HistoryEntry{
@@ -1542,3 +1784,737 @@ func TestSearchDirectories(t *testing.T) {
 		t.Errorf("output should NOT contain 'otherdir', got: %s", output)
 	}
 }
+
+// TestLoopControlHandlers tests the break and continue builtin commands.
+func TestLoopControlHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler Handler
+		args    []string
+		want    LoopControl
+	}{
+		{"break", breakHandler, nil, LoopControl{Levels: 1}},
+		{"break", breakHandler, []string{"2"}, LoopControl{Levels: 2}},
+		{"continue", continueHandler, nil, LoopControl{Continue: true, Levels: 1}},
+		{"continue", continueHandler, []string{"3"}, LoopControl{Continue: true, Levels: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCtx, _, _ := createTestContext()
+			cmd := &parser.Command{Name: tt.name, Args: tt.args, Flags: make(map[string]bool)}
+
+			code, err := tt.handler(context.Background(), cmd, execCtx)
+			if code != 0 {
+				t.Errorf("exit code = %d, want 0", code)
+			}
+			lc, ok := err.(LoopControl)
+			if !ok {
+				t.Fatalf("expected LoopControl error, got %T", err)
+			}
+			if lc != tt.want {
+				t.Errorf("LoopControl = %+v, want %+v", lc, tt.want)
+			}
+		})
+	}
+
+	// Invalid counts are reported without leaving the loop
+	execCtx, _, stderr := createTestContext()
+	cmd := &parser.Command{Name: "break", Args: []string{"0"}, Flags: make(map[string]bool)}
+	code, err := breakHandler(context.Background(), cmd, execCtx)
+	if code != 1 || err != nil {
+		t.Errorf("break 0 = (%d, %v), want (1, nil)", code, err)
+	}
+	if stderr.Len() == 0 {
+		t.Error("expected error message in stderr for invalid loop count")
+	}
+}
+
+func TestReturnHandler(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, 0},
+		{[]string{"3"}, 3},
+		{[]string{"abc"}, 2},
+	}
+
+	for _, tt := range tests {
+		execCtx, _, _ := createTestContext()
+		cmd := &parser.Command{Name: "return", Args: tt.args, Flags: make(map[string]bool)}
+
+		code, err := returnHandler(context.Background(), cmd, execCtx)
+		rc, ok := err.(ReturnCode)
+		if !ok {
+			t.Fatalf("return %v: expected ReturnCode error, got %T", tt.args, err)
+		}
+		if code != tt.want || rc.Code != tt.want {
+			t.Errorf("return %v = (%d, %d), want %d", tt.args, code, rc.Code, tt.want)
+		}
+	}
+}
+
+func TestLocalHandler(t *testing.T) {
+	execCtx, _, stderr := createTestContext()
+	execCtx.Env.Set("x", "global")
+	cmd := &parser.Command{Name: "local", Args: []string{"x=1"}, Flags: make(map[string]bool)}
+
+	// Outside a function
+	code, _ := localHandler(context.Background(), cmd, execCtx)
+	if code != 1 || stderr.Len() == 0 {
+		t.Errorf("local outside a function: code = %d, stderr = %q", code, stderr.String())
+	}
+
+	global := execCtx.Env
+	execCtx.Env = global.NewScope(nil)
+	cmd.Args = []string{"x=1", "y", "1bad"}
+	code, _ = localHandler(context.Background(), cmd, execCtx)
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1 for invalid name", code)
+	}
+	if execCtx.Env.Get("x") != "1" || global.Get("x") != "global" {
+		t.Errorf("x = %q in function, %q outside", execCtx.Env.Get("x"), global.Get("x"))
+	}
+
+	// Declared without a value: empty and local
+	execCtx.Env.Set("y", "set")
+	if _, ok := global.All()["y"]; ok {
+		t.Error("local y should not be visible outside the function")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	a := NewAliases()
+	a.Set("ll", "ls -l")
+	a.Set("la", "ls -a")
+	a.Set("gs", "git status")
+
+	if value, ok := a.Get("ll"); !ok || value != "ls -l" {
+		t.Errorf("Get(ll) = (%q, %v), want (\"ls -l\", true)", value, ok)
+	}
+	if got := a.Match("l"); strings.Join(got, " ") != "la ll" {
+		t.Errorf("Match(l) = %v, want [la ll]", got)
+	}
+	if got := a.Names(); strings.Join(got, " ") != "gs la ll" {
+		t.Errorf("Names() = %v, want [gs la ll]", got)
+	}
+	if !a.Remove("gs") || a.Remove("gs") || a.Has("gs") {
+		t.Error("Remove(gs) should remove the alias once")
+	}
+	a.Clear()
+	if a.Count() != 0 {
+		t.Errorf("Count() = %d after Clear, want 0", a.Count())
+	}
+}
+
+func TestAliasHandler(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+	execCtx.Aliases = NewAliases()
+
+	cmd := &parser.Command{Name: "alias", Args: []string{"ll=ls -l", "q=echo 'hi'"}, Flags: make(map[string]bool)}
+	if code, err := aliasHandler(context.Background(), cmd, execCtx); code != 0 || err != nil {
+		t.Fatalf("alias = (%d, %v), want (0, nil)", code, err)
+	}
+	if value, _ := execCtx.Aliases.Get("ll"); value != "ls -l" {
+		t.Errorf("ll = %q, want %q", value, "ls -l")
+	}
+
+	cmd.Args = nil
+	aliasHandler(context.Background(), cmd, execCtx)
+	want := "alias ll='ls -l'\nalias q='echo '\"'\"'hi'\"'\"''\n"
+	if stdout.String() != want {
+		t.Errorf("alias output = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	cmd.Args = []string{"ll", "missing", "bad/name=x"}
+	code, _ := aliasHandler(context.Background(), cmd, execCtx)
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if stdout.String() != "alias ll='ls -l'\n" {
+		t.Errorf("alias ll output = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "missing: not found") || !strings.Contains(stderr.String(), "invalid alias name") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestUnaliasHandler(t *testing.T) {
+	execCtx, _, stderr := createTestContext()
+	execCtx.Aliases = NewAliases()
+	execCtx.Aliases.Set("ll", "ls -l")
+	execCtx.Aliases.Set("la", "ls -a")
+
+	cmd := &parser.Command{Name: "unalias", Args: []string{"ll", "missing"}, Flags: make(map[string]bool)}
+	code, _ := unaliasHandler(context.Background(), cmd, execCtx)
+	if code != 1 || !strings.Contains(stderr.String(), "missing: not found") {
+		t.Errorf("unalias = %d, stderr = %q", code, stderr.String())
+	}
+	if execCtx.Aliases.Has("ll") || !execCtx.Aliases.Has("la") {
+		t.Error("unalias ll should only remove ll")
+	}
+
+	cmd = &parser.Command{Name: "unalias", Flags: map[string]bool{"-a": true}}
+	if code, _ := unaliasHandler(context.Background(), cmd, execCtx); code != 0 || execCtx.Aliases.Count() != 0 {
+		t.Errorf("unalias -a = %d, %d aliases left", code, execCtx.Aliases.Count())
+	}
+}
+
+func TestJobsHandler(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+	cmd := &parser.Command{Name: "jobs", Flags: make(map[string]bool)}
+	if code, _ := jobsHandler(context.Background(), cmd, execCtx); code != 1 || !strings.Contains(stderr.String(), "not available") {
+		t.Errorf("jobs without table = %d, stderr = %q", code, stderr.String())
+	}
+
+	execCtx.Jobs = jobs.NewTable()
+	done := execCtx.Jobs.NewJob("make", func() {}, false)
+	execCtx.Jobs.Add(done)
+	done.Finish(0)
+	execCtx.Jobs.Add(execCtx.Jobs.NewJob("sleep 10", func() {}, false))
+
+	if code, _ := jobsHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Errorf("jobs = %d, want 0", code)
+	}
+	want := "[1]-  Done                    make\n[2]+  Running                 sleep 10 &\n"
+	if stdout.String() != want {
+		t.Errorf("jobs output = %q, want %q", stdout.String(), want)
+	}
+	// Finished jobs are listed once
+	if n := len(execCtx.Jobs.Jobs()); n != 1 {
+		t.Errorf("%d jobs left, want 1", n)
+	}
+}
+
+func TestKillHandler(t *testing.T) {
+	execCtx, _, stderr := createTestContext()
+	execCtx.Jobs = jobs.NewTable()
+	canceled := false
+	execCtx.Jobs.Add(execCtx.Jobs.NewJob("while true; do true; done", func() { canceled = true }, false))
+
+	tests := []struct {
+		name  string
+		words []string
+		code  int
+		err   string
+	}{
+		{"job", []string{"%1"}, 0, ""},
+		{"signal name", []string{"-s", "KILL", "%while"}, 0, ""},
+		{"no such job", []string{"-TERM", "%2"}, 1, "no such job"},
+		{"invalid signal", []string{"-NOPE", "%1"}, 1, "invalid signal specification"},
+		{"not a pid", []string{"abc"}, 1, "must be process or job IDs"},
+		{"usage", []string{"-9"}, 1, "usage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr.Reset()
+			cmd := &parser.Command{Name: "kill", Words: tt.words, Flags: make(map[string]bool)}
+			code, _ := killHandler(context.Background(), cmd, execCtx)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if !strings.Contains(stderr.String(), tt.err) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.err)
+			}
+		})
+	}
+	if !canceled {
+		t.Error("kill %1 should stop the commands of the job")
+	}
+}
+
+func TestJobSpecErrors(t *testing.T) {
+	execCtx, _, stderr := createTestContext()
+	execCtx.Jobs = jobs.NewTable()
+
+	cmd := &parser.Command{Name: "fg", Args: []string{"%1"}, Flags: make(map[string]bool)}
+	if code, _ := fgHandler(context.Background(), cmd, execCtx); code != 1 || !strings.Contains(stderr.String(), "fg: no such job: %1") {
+		t.Errorf("fg %%1 = %d, stderr = %q", code, stderr.String())
+	}
+
+	stderr.Reset()
+	cmd = &parser.Command{Name: "wait", Args: []string{"4242"}, Flags: make(map[string]bool)}
+	if code, _ := waitHandler(context.Background(), cmd, execCtx); code != 127 || !strings.Contains(stderr.String(), "not a child") {
+		t.Errorf("wait 4242 = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestExportHandler(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+
+	cmd := &parser.Command{Name: "export", Args: []string{"JSI_A=it's", "JSI_B", "bad-name"}, Flags: make(map[string]bool)}
+	if code, _ := exportHandler(context.Background(), cmd, execCtx); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr.String(), "bad-name: not a valid identifier") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if !execCtx.Env.IsExported("JSI_A") || !execCtx.Env.IsExported("JSI_B") {
+		t.Error("JSI_A and JSI_B should be exported")
+	}
+
+	cmd = &parser.Command{Name: "export", Flags: make(map[string]bool)}
+	exportHandler(context.Background(), cmd, execCtx)
+	if !strings.Contains(stdout.String(), "export JSI_A='it'\"'\"'s'\nexport JSI_B=''\n") {
+		t.Errorf("export output = %q", stdout.String())
+	}
+
+	cmd = &parser.Command{Name: "export", Args: []string{"JSI_A"}, Flags: map[string]bool{"-n": true}}
+	exportHandler(context.Background(), cmd, execCtx)
+	if execCtx.Env.IsExported("JSI_A") || execCtx.Env.Get("JSI_A") != "it's" {
+		t.Error("export -n should only stop exporting JSI_A")
+	}
+}
+
+func TestUnsetHandler(t *testing.T) {
+	execCtx, _, stderr := createTestContext()
+	execCtx.Env.Set("JSI_A", "1")
+
+	cmd := &parser.Command{Name: "unset", Args: []string{"JSI_A", "JSI_MISSING"}, Flags: make(map[string]bool)}
+	if code, _ := unsetHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	if _, ok := execCtx.Env.All()["JSI_A"]; ok {
+		t.Error("JSI_A should be unset")
+	}
+
+	execCtx.Functions = NewFunctions()
+	execCtx.Functions.Set(Definition{Name: "ls"})
+	cmd = &parser.Command{Name: "unset", Args: []string{"ls", "missing"}, Flags: map[string]bool{"-f": true}}
+	if code, _ := unsetHandler(context.Background(), cmd, execCtx); code != 0 || execCtx.Functions.Has("ls") {
+		t.Errorf("unset -f ls = %d, function defined = %v", code, execCtx.Functions.Has("ls"))
+	}
+
+	cmd = &parser.Command{Name: "unset", Args: []string{"f"}, Flags: map[string]bool{"-z": true}}
+	if code, _ := unsetHandler(context.Background(), cmd, execCtx); code != 1 || !strings.Contains(stderr.String(), "-z: invalid option") {
+		t.Errorf("unset -z = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestSetHandler(t *testing.T) {
+	execCtx, stdout, _ := createTestContext()
+
+	cmd := &parser.Command{Name: "set", Words: []string{"--", "-v", "b"}, Flags: make(map[string]bool)}
+	if code, _ := setHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	if args := execCtx.Env.Args(); strings.Join(args, " ") != "-v b" {
+		t.Errorf("Args() = %q, want [-v b]", args)
+	}
+
+	execCtx.Env.Set("JSI_A", "a b")
+	cmd = &parser.Command{Name: "set", Flags: make(map[string]bool)}
+	setHandler(context.Background(), cmd, execCtx)
+	if !strings.Contains(stdout.String(), "JSI_A='a b'\n") {
+		t.Errorf("set output does not list JSI_A: %q", stdout.String())
+	}
+}
+
+func TestSetShellOptions(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+
+	cmd := &parser.Command{Name: "set", Words: []string{"-o", "nullglob", "+o", "failglob"}, Flags: map[string]bool{"-o": true}}
+	if code, _ := setHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, stderr.String())
+	}
+	if !execCtx.Env.Option("nullglob") || execCtx.Env.Option("failglob") {
+		t.Error("set -o nullglob +o failglob did not set the options")
+	}
+
+	cmd = &parser.Command{Name: "set", Words: []string{"+o"}, Flags: make(map[string]bool)}
+	setHandler(context.Background(), cmd, execCtx)
+	want := "set +o errexit\nset +o failglob\nset +o histexpand\nset +o histverify\nset +o nounset\nset -o nullglob\nset +o pipefail\nset +o xtrace\n"
+	if stdout.String() != want {
+		t.Errorf("set +o output = %q, want %q", stdout.String(), want)
+	}
+
+	cmd = &parser.Command{Name: "set", Words: []string{"-euo", "pipefail", "+u", "-x", "a"}, Flags: make(map[string]bool)}
+	if code, _ := setHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, stderr.String())
+	}
+	for name, want := range map[string]bool{"errexit": true, "pipefail": true, "nounset": false, "xtrace": true} {
+		if execCtx.Env.Option(name) != want {
+			t.Errorf("set -euo pipefail +u -x: %s = %v, want %v", name, !want, want)
+		}
+	}
+	if args := execCtx.Env.Args(); len(args) != 1 || args[0] != "a" {
+		t.Errorf("Args() = %q, want [a]", args)
+	}
+
+	cmd = &parser.Command{Name: "set", Words: []string{"-q"}, Flags: make(map[string]bool)}
+	if code, _ := setHandler(context.Background(), cmd, execCtx); code != 1 || !strings.Contains(stderr.String(), "-q: invalid option") {
+		t.Errorf("set -q = %d, stderr = %q", code, stderr.String())
+	}
+
+	cmd = &parser.Command{Name: "set", Words: []string{"-o", "nosuch"}, Flags: map[string]bool{"-o": true}}
+	if code, _ := setHandler(context.Background(), cmd, execCtx); code != 1 || !strings.Contains(stderr.String(), "nosuch: invalid option name") {
+		t.Errorf("set -o nosuch = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestMatchesExcludePattern(t *testing.T) {
+	patterns := []string{"*.@(log|tmp)", "build"}
+	for name, want := range map[string]bool{"a.log": true, "a.tmp": true, "build": true, "a.go": false} {
+		if got := matchesExcludePattern(name, patterns); got != want {
+			t.Errorf("matchesExcludePattern(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCalcHandler(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+	execCtx.Env.Set("disk", "500GB")
+
+	tests := []struct {
+		name   string
+		words  []string
+		code   int
+		stdout string
+		err    string
+	}{
+		{"decimal", []string{"7", "/", "2"}, 0, "3.5\n", ""},
+		{"size", []string{"1.5GiB", "/", "3"}, 0, "512MiB\n", ""},
+		{"one word", []string{"(1GB + 500MB) * 2"}, 0, "3GB\n", ""},
+		{"negative", []string{"-3", "+", "1"}, 0, "-2\n", ""},
+		{"variable", []string{"disk", "/", "4"}, 0, "125GB\n", ""},
+		{"bytes", []string{"-b", "1.5KiB"}, 0, "1536\n", ""},
+		{"division by zero", []string{"1", "/", "0"}, 1, "", "division by zero"},
+		{"syntax", []string{"1", "+"}, 1, "", "calc:"},
+		{"usage", nil, 1, "", "usage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout.Reset()
+			stderr.Reset()
+			cmd := &parser.Command{Name: "calc", Words: tt.words, Flags: make(map[string]bool)}
+			code, _ := calcHandler(context.Background(), cmd, execCtx)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if !strings.Contains(stderr.String(), tt.err) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.err)
+			}
+		})
+	}
+}
+
+func TestTrapHandler(t *testing.T) {
+	execCtx, stdout, stderr := createTestContext()
+	execCtx.Traps = NewTraps()
+
+	run := func(words ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		cmd := &parser.Command{Name: "trap", Words: words, Flags: make(map[string]bool)}
+		code, _ := trapHandler(context.Background(), cmd, execCtx)
+		return code
+	}
+
+	if code := run(`rm -rf "$tmp"`, "EXIT", "sigint", "2"); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, stderr.String())
+	}
+	run()
+	want := "trap -- 'rm -rf \"$tmp\"' EXIT\ntrap -- 'rm -rf \"$tmp\"' INT\n"
+	if stdout.String() != want {
+		t.Errorf("trap output = %q, want %q", stdout.String(), want)
+	}
+
+	run("echo it's", "ERR")
+	run("-p", "ERR")
+	if stdout.String() != `trap -- 'echo it'\''s' ERR`+"\n" {
+		t.Errorf("trap -p ERR = %q", stdout.String())
+	}
+
+	run("INT")
+	run("-", "EXIT", "ERR")
+	if names := execCtx.Traps.Names(); len(names) != 0 {
+		t.Errorf("Names() = %v after reset, want none", names)
+	}
+
+	if code := run("echo", "HUP"); code != 1 || !strings.Contains(stderr.String(), "HUP: invalid signal specification") {
+		t.Errorf("trap echo HUP = %d, stderr = %q", code, stderr.String())
+	}
+	if code := run("echo"); code != 2 {
+		t.Errorf("trap echo = %d, want 2", code)
+	}
+}
+
+func TestTraps(t *testing.T) {
+	traps := NewTraps()
+	traps.Set("INT", "echo int")
+	traps.Set("TERM", "")
+
+	if !traps.Catch("INT") || !traps.Catch("TERM") {
+		t.Error("Catch() = false for a signal with a trap")
+	}
+	if traps.Catch("EXIT") {
+		t.Error("Catch(EXIT) = true without a trap")
+	}
+	// An empty trap ignores the signal
+	if pending := traps.TakePending(); len(pending) != 1 || pending[0] != "INT" {
+		t.Errorf("TakePending() = %v, want [INT]", pending)
+	}
+	if pending := traps.TakePending(); len(pending) != 0 {
+		t.Errorf("TakePending() = %v after taking, want none", pending)
+	}
+
+	for spec, want := range map[string]string{"exit": "EXIT", "SIGINT": "INT", "15": "TERM", "0": "EXIT", "debug": "DEBUG", "HUP": ""} {
+		if got := TrapName(spec); got != want {
+			t.Errorf("TrapName(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestReadHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		input string
+		code  int
+		want  map[string]string
+	}{
+		{"fields", []string{"a", "b"}, "  one two  three \n", 0, map[string]string{"a": "one", "b": "two  three"}},
+		{"more names", []string{"a", "b", "c"}, "one\n", 0, map[string]string{"a": "one", "b": "", "c": ""}},
+		{"reply", nil, "  as is  \n", 0, map[string]string{"REPLY": "  as is  "}},
+		{"escapes", []string{"a", "b"}, `one\ two\\ x` + "\n", 0, map[string]string{"a": "one two\\", "b": "x"}},
+		{"continuation", []string{"a"}, "one\\\ntwo\n", 0, map[string]string{"a": "onetwo"}},
+		{"raw", []string{"-r", "a", "b"}, `one\ two` + "\n", 0, map[string]string{"a": `one\`, "b": "two"}},
+		{"end of input", []string{"a"}, "partial", 1, map[string]string{"a": "partial"}},
+		{"empty input", []string{"a"}, "", 1, map[string]string{"a": ""}},
+		{"timeout option", []string{"-rt5", "a"}, "x\n", 0, map[string]string{"a": "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCtx, _, stderr := createTestContext()
+			execCtx.Stdin = strings.NewReader(tt.input)
+
+			cmd := &parser.Command{Name: "read", Words: tt.words, Flags: make(map[string]bool)}
+			code, _ := readHandler(context.Background(), cmd, execCtx)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.code, stderr.String())
+			}
+			for name, want := range tt.want {
+				if got := execCtx.Env.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadLeavesInput(t *testing.T) {
+	execCtx, _, _ := createTestContext()
+	execCtx.Stdin = strings.NewReader("one\ntwo\n")
+
+	cmd := &parser.Command{Name: "read", Words: []string{"line"}, Flags: make(map[string]bool)}
+	for _, want := range []string{"one", "two"} {
+		if code, _ := readHandler(context.Background(), cmd, execCtx); code != 0 {
+			t.Fatalf("exit code = %d, want 0", code)
+		}
+		if got := execCtx.Env.Get("line"); got != want {
+			t.Errorf("line = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		words []string
+		code  int
+		err   string
+	}{
+		{[]string{"-t", "soon"}, 2, "soon: invalid timeout specification"},
+		{[]string{"-p"}, 2, "-p: option requires an argument"},
+		{[]string{"-q"}, 2, "-q: invalid option"},
+		{[]string{"not-a-name"}, 1, "not-a-name: not a valid identifier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.words, " "), func(t *testing.T) {
+			execCtx, _, stderr := createTestContext()
+			execCtx.Stdin = strings.NewReader("x\n")
+
+			cmd := &parser.Command{Name: "read", Words: tt.words, Flags: make(map[string]bool)}
+			code, _ := readHandler(context.Background(), cmd, execCtx)
+			if code != tt.code || !strings.Contains(stderr.String(), tt.err) {
+				t.Errorf("exit code = %d, stderr = %q, want %d and %q", code, stderr.String(), tt.code, tt.err)
+			}
+		})
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		line string
+		ifs  string
+		n    int
+		want []string
+	}{
+		{"a b c", " \t\n", 2, []string{"a", "b c"}},
+		{"  a   b  ", " \t\n", 3, []string{"a", "b"}},
+		{"a::b", ":", 3, []string{"a", "", "b"}},
+		{"a : b", ": ", 2, []string{"a", "b"}},
+		{"root:x:0", ":", 2, []string{"root", "x:0"}},
+		{"a b", "", 2, []string{"a b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			line := []rune(tt.line)
+			got := splitFields(line, make([]bool, len(line)), tt.ifs, tt.n)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("splitFields(%q, %q, %d) = %q, want %q", tt.line, tt.ifs, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintfHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		words  []string
+		code   int
+		stdout string
+	}{
+		{"string", []string{`%s\n`, "hi"}, 0, "hi\n"},
+		{"reused format", []string{`%s=%d\n`, "a", "1", "b", "2"}, 0, "a=1\nb=2\n"},
+		{"missing args", []string{`[%s|%d]\n`}, 0, "[|0]\n"},
+		{"width and flags", []string{`[%-5s|%5s|%05d|%+d]`, "ab", "cd", "42", "7"}, 0, "[ab   |   cd|00042|+7]"},
+		{"star width", []string{`[%*s]`, "4", "x"}, 0, "[   x]"},
+		{"float", []string{`%.2f %e %g %g`, "3.14159", "1500", "0.0001", "1e20"}, 0, "3.14 1.500000e+03 0.0001 1e+20"},
+		{"integers", []string{`%x %X %o %#x %u`, "255", "255", "8", "255", "-1"}, 0, "ff FF 10 0xff 18446744073709551615"},
+		{"number forms", []string{`%d %d %d %d`, "0x10", "010", "-3", "'A"}, 0, "16 8 -3 65"},
+		{"char", []string{`%c%c`, "hello", "world"}, 0, "hw"},
+		{"percent", []string{`100%%`}, 0, "100%"},
+		{"escapes", []string{`a\tb\x41\101é\\`}, 0, "a\tbAAé\\"},
+		{"b conversion", []string{`%b|`, `x\ny\0101`}, 0, "x\nyA|"},
+		{"stop output", []string{`%b%s`, `a\cb`, "c"}, 0, "a"},
+		{"quote", []string{`%q`, "it's"}, 0, `'it'"'"'s'`},
+		{"invalid number", []string{`%d`, "abc"}, 1, "0"},
+		{"invalid format", []string{`a%zb`}, 1, "a"},
+		{"negative argument", []string{`%d`, "-5"}, 0, "-5"},
+		{"no format", nil, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCtx, stdout, _ := createTestContext()
+
+			cmd := &parser.Command{Name: "printf", Words: tt.words, Flags: make(map[string]bool)}
+			code, _ := printfHandler(context.Background(), cmd, execCtx)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestPrintfVariable(t *testing.T) {
+	execCtx, stdout, _ := createTestContext()
+
+	cmd := &parser.Command{Name: "printf", Words: []string{"-v", "name", "%s-%03d", "img", "7"}, Flags: make(map[string]bool)}
+	if code, _ := printfHandler(context.Background(), cmd, execCtx); code != 0 {
+		t.Fatalf("exit code = %d, want 0", code)
+	}
+	if got := execCtx.Env.Get("name"); got != "img-007" {
+		t.Errorf("name = %q, want %q", got, "img-007")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", stdout.String())
+	}
+}
+
+func TestTestHandler(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	older := filepath.Join(dir, "older")
+	if err := os.WriteFile(older, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(older, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no args", nil, 1},
+		{"string", []string{"x"}, 0},
+		{"empty string", []string{""}, 1},
+		{"-n", []string{"-n", "x"}, 0},
+		{"-z", []string{"-z", ""}, 0},
+		{"-n alone", []string{"-n"}, 0},
+		{"equal", []string{"a", "=", "a"}, 0},
+		{"double equal", []string{"a", "==", "b"}, 1},
+		{"not equal", []string{"a", "!=", "b"}, 0},
+		{"less", []string{"abc", "<", "abd"}, 0},
+		{"-eq", []string{"10", "-eq", "010"}, 0},
+		{"-lt", []string{"-3", "-lt", "2"}, 0},
+		{"-ge", []string{"2", "-ge", "3"}, 1},
+		{"not integer", []string{"a", "-eq", "1"}, 2},
+		{"-f", []string{"-f", file}, 0},
+		{"-f relative", []string{"-f", "file.txt"}, 0},
+		{"-f dir", []string{"-f", dir}, 1},
+		{"-d", []string{"-d", dir}, 0},
+		{"-e missing", []string{"-e", filepath.Join(dir, "missing")}, 1},
+		{"-s", []string{"-s", file}, 0},
+		{"-s empty", []string{"-s", empty}, 1},
+		{"-r", []string{"-r", file}, 0},
+		{"-nt", []string{file, "-nt", older}, 0},
+		{"-nt missing", []string{file, "-nt", "missing"}, 0},
+		{"-ot", []string{file, "-ot", older}, 1},
+		{"-ef", []string{file, "-ef", "file.txt"}, 0},
+		{"not", []string{"!", "-d", file}, 0},
+		{"and", []string{"-f", file, "-a", "-d", dir}, 0},
+		{"or", []string{"-z", "x", "-o", "a", "=", "a"}, 0},
+		{"and binds tighter", []string{"x", "-o", "", "-a", ""}, 0},
+		{"parentheses", []string{"(", "x", "-o", "", ")", "-a", ""}, 1},
+		{"not parentheses", []string{"!", "(", "a", "=", "b", ")"}, 0},
+		{"unary expected", []string{"x", "y"}, 2},
+		{"missing paren", []string{"(", "x", "-a", "y"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCtx, _, _ := createTestContext()
+			execCtx.WorkDir = dir
+
+			cmd := &parser.Command{Name: "test", Words: tt.args, Flags: make(map[string]bool)}
+			if code, _ := testHandler(context.Background(), cmd, execCtx); code != tt.code {
+				t.Errorf("test %q = %d, want %d", tt.args, code, tt.code)
+			}
+
+			cmd = &parser.Command{Name: "[", Words: append(tt.args, "]"), Flags: make(map[string]bool)}
+			if code, _ := bracketHandler(context.Background(), cmd, execCtx); code != tt.code {
+				t.Errorf("[ %q ] = %d, want %d", tt.args, code, tt.code)
+			}
+		})
+	}
+
+	execCtx, _, stderr := createTestContext()
+	cmd := &parser.Command{Name: "[", Words: []string{"-n", "x"}, Flags: make(map[string]bool)}
+	if code, _ := bracketHandler(context.Background(), cmd, execCtx); code != 2 || !strings.Contains(stderr.String(), "missing ']'") {
+		t.Errorf("[ without ] = %d, stderr = %q", code, stderr.String())
+	}
+}
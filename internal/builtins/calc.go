@@ -0,0 +1,96 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/arith"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// CalcDefinition returns the calc command definition.
+func CalcDefinition() Definition {
+	return Definition{
+		Name:        "calc",
+		Description: "Evaluate an expression with decimal numbers and sizes",
+		Usage:       "calc [options] expression...",
+		Handler:     calcHandler,
+		Options: []OptionDef{
+			{Long: "--bytes", Short: "-b", Description: "Print sizes as a number of bytes"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func calcHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showCalcHelp(execCtx)
+		return 0, nil
+	}
+
+	// Words keep negative numbers such as -3, which are parsed as options
+	words := cmd.Words
+	bytes := false
+	for len(words) > 0 && (words[0] == "-b" || words[0] == "--bytes") {
+		bytes = true
+		words = words[1:]
+	}
+	if len(words) > 0 && words[0] == "--" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		execCtx.WriteErrorln("calc: usage: calc [-b] expression...")
+		return 1, nil
+	}
+
+	var vars arith.Variables
+	if execCtx.Env != nil {
+		vars = execCtx.Env
+	}
+	v, err := arith.Calc(strings.Join(words, " "), vars)
+	if err != nil {
+		execCtx.WriteErrorln("calc: %s", shellerrors.Format(err, ""))
+		return 1, nil
+	}
+
+	if bytes {
+		fmt.Fprintln(execCtx.Stdout, arith.FormatNumber(v.Number))
+	} else {
+		fmt.Fprintln(execCtx.Stdout, v)
+	}
+	return 0, nil
+}
+
+func showCalcHelp(execCtx *Context) {
+	help := `calc - Evaluate an expression with decimal numbers and sizes
+
+Usage: calc [options] expression...
+
+Options:
+  -b, --bytes   Print sizes as a number of bytes
+      --help    Show this help message
+
+Expressions use the operators of $((...)): + - * / % ** for arithmetic,
+< <= > >= == != for comparisons, && || ! for logic, & | ^ ~ << >> on
+integers, cond ? a : b and parentheses. Variables can be used by name.
+
+Numbers can have a fraction (1.5) and a size unit: B, K/KiB, M/MiB, G/GiB,
+T/TiB, P/PiB (powers of 1024) or kB, MB, GB, TB, PB (powers of 1000).
+Sizes can be added, divided by a size to get a ratio, or multiplied and
+divided by a number. Results are printed with the largest unit they reach
+and up to 12 significant digits; x = expr stores the exact value in x.
+
+Quote expressions containing *, <, >, ( or ) so that the shell does not
+interpret them.
+
+Examples:
+  calc 7 / 2                 3.5
+  calc 1.5GiB / 3            512MiB
+  calc '(1GB + 500MB) * 2'   3GB
+  calc 10MiB / 1KiB          10240
+  calc -b 1.5KiB             1536
+`
+	execCtx.Stdout.Write([]byte(help))
+}
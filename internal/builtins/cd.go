@@ -71,7 +71,7 @@ func cdHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 	}
 
 	// Convert to absolute path
-	absPath, err := filepath.Abs(targetDir)
+	absPath, err := filepath.Abs(execCtx.Path(targetDir))
 	if err != nil {
 		execCtx.WriteErrorln("cd: %s: %v", targetDir, err)
 		return 1, nil
@@ -96,19 +96,13 @@ func cdHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 	// Save current directory as OLDPWD
 	currentPwd := execCtx.Env.Get("PWD")
 	if currentPwd == "" {
-		currentPwd, _ = os.Getwd()
-	}
-
-	// Change directory
-	if err := os.Chdir(absPath); err != nil {
-		execCtx.WriteErrorln("cd: %s: %v", targetDir, err)
-		return 1, nil
+		currentPwd = execCtx.WorkDir
 	}
 
 	// Update environment variables
 	execCtx.Env.Set("OLDPWD", currentPwd)
 	execCtx.Env.Set("PWD", absPath)
-	execCtx.WorkDir = absPath
+	execCtx.WorkDir = absPath // The executor syncs it to its frame
 
 	return 0, nil
 }
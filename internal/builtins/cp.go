@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/sdejongh/jsishell/internal/glob"
 	"github.com/sdejongh/jsishell/internal/parser"
 )
 
@@ -59,7 +60,7 @@ func cpHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 	sources := cmd.Args[:len(cmd.Args)-1]
 
 	// Check if destination is a directory (or should be)
-	destInfo, destErr := os.Stat(dest)
+	destInfo, destErr := os.Stat(execCtx.Path(dest))
 	destIsDir := destErr == nil && destInfo.IsDir()
 
 	// Multiple sources require directory destination
@@ -76,7 +77,7 @@ func cpHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 			continue
 		}
 
-		srcInfo, err := os.Stat(src)
+		srcInfo, err := os.Stat(execCtx.Path(src))
 		if err != nil {
 			execCtx.WriteErrorln("cp: cannot stat '%s': %v", src, err)
 			exitCode = 1
@@ -114,7 +115,7 @@ func cpHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 // matchesCpExcludePattern checks if a name matches any of the exclude patterns.
 func matchesCpExcludePattern(name string, patterns []string) bool {
 	for _, pattern := range patterns {
-		if matched, _ := filepath.Match(pattern, name); matched {
+		if glob.Match(pattern, name) {
 			return true
 		}
 	}
@@ -123,14 +124,14 @@ func matchesCpExcludePattern(name string, patterns []string) bool {
 
 func cpFile(src, dest string, opts cpOptions, execCtx *Context) error {
 	// Check if destination exists
-	if _, err := os.Stat(dest); err == nil {
+	if _, err := os.Stat(execCtx.Path(dest)); err == nil {
 		if !opts.force {
 			execCtx.WriteErrorln("cp: '%s' already exists", dest)
 			return nil
 		}
 	}
 
-	srcFile, err := os.Open(src)
+	srcFile, err := os.Open(execCtx.Path(src))
 	if err != nil {
 		return err
 	}
@@ -141,7 +142,7 @@ func cpFile(src, dest string, opts cpOptions, execCtx *Context) error {
 		return err
 	}
 
-	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
+	destFile, err := os.OpenFile(execCtx.Path(dest), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
 	if err != nil {
 		return err
 	}
@@ -159,13 +160,13 @@ func cpFile(src, dest string, opts cpOptions, execCtx *Context) error {
 }
 
 func cpDir(src, dest string, opts cpOptions, execCtx *Context) error {
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := os.Stat(execCtx.Path(src))
 	if err != nil {
 		return err
 	}
 
 	// Create destination directory
-	if err := os.MkdirAll(dest, srcInfo.Mode()); err != nil {
+	if err := os.MkdirAll(execCtx.Path(dest), srcInfo.Mode()); err != nil {
 		return err
 	}
 
@@ -174,7 +175,7 @@ func cpDir(src, dest string, opts cpOptions, execCtx *Context) error {
 	}
 
 	// Read directory contents
-	entries, err := os.ReadDir(src)
+	entries, err := os.ReadDir(execCtx.Path(src))
 	if err != nil {
 		return err
 	}
@@ -217,7 +218,8 @@ Options:
       --help             Show this help message
 
 Exclude patterns:
-  Standard glob patterns: *, ?, [abc], [a-z]
+  Glob patterns: *, ?, [abc], [a-z] and extended patterns such as
+  !(*.go) or *.@(log|tmp)
   Can be specified multiple times to exclude several patterns.
 
 Examples:
@@ -0,0 +1,118 @@
+package builtins
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// ReturnCode is a special error type that signals return from a function.
+type ReturnCode struct {
+	Code int
+}
+
+func (r ReturnCode) Error() string {
+	return "return: can only be used in a function"
+}
+
+// ReturnDefinition returns the return command definition.
+func ReturnDefinition() Definition {
+	return Definition{
+		Name:        "return",
+		Description: "Return from a shell function",
+		Usage:       "return [n]",
+		Handler:     returnHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// LocalDefinition returns the local command definition.
+func LocalDefinition() Definition {
+	return Definition{
+		Name:        "local",
+		Description: "Declare variables local to a function",
+		Usage:       "local NAME[=VALUE]...",
+		Handler:     localHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func returnHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showReturnHelp(execCtx)
+		return 0, nil
+	}
+
+	code := 0
+	if len(cmd.Args) > 0 {
+		var err error
+		code, err = strconv.Atoi(cmd.Args[0])
+		if err != nil {
+			execCtx.WriteErrorln("return: %s: numeric argument required", cmd.Args[0])
+			code = 2
+		}
+	}
+
+	// Return special ReturnCode error to signal the function should end
+	return code, ReturnCode{Code: code}
+}
+
+func localHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showLocalHelp(execCtx)
+		return 0, nil
+	}
+
+	if execCtx.Env.Parent() == nil {
+		execCtx.WriteErrorln("local: can only be used in a function")
+		return 1, nil
+	}
+
+	status := 0
+	for _, arg := range cmd.Args {
+		name, value, _ := strings.Cut(arg, "=")
+		if !env.ValidName(name) {
+			execCtx.WriteErrorln("local: %s: not a valid identifier", name)
+			status = 1
+			continue
+		}
+		execCtx.Env.Local(name, value)
+	}
+	return status, nil
+}
+
+func showReturnHelp(execCtx *Context) {
+	help := `return - Return from a shell function
+
+Usage: return [n]
+
+Arguments:
+  n      Exit status of the function (default: 0)
+
+Examples:
+  check() { if cd "$1"; then return 0; fi; return 1; }
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showLocalHelp(execCtx *Context) {
+	help := `local - Declare variables local to a function
+
+Usage: local NAME[=VALUE]...
+
+Description:
+  Local variables hide variables of the same name until the function
+  returns. Variables that are not declared local are shared with the caller.
+
+Examples:
+  greet() { local name=$1; echo "hello $name"; }
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -0,0 +1,87 @@
+package builtins
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Functions manages shell functions. They are commands like builtins, looked
+// up before them, and are kept apart so that removing a function brings back
+// the builtin it hid.
+type Functions struct {
+	mu   sync.RWMutex
+	defs map[string]Definition
+}
+
+// NewFunctions creates a new empty function table.
+func NewFunctions() *Functions {
+	return &Functions{
+		defs: make(map[string]Definition),
+	}
+}
+
+// Clone returns a copy of the table, which a subshell changes without
+// affecting the shell.
+func (f *Functions) Clone() *Functions {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	defs := make(map[string]Definition, len(f.defs))
+	for name, def := range f.defs {
+		defs[name] = def
+	}
+	return &Functions{defs: defs}
+}
+
+// Set defines or replaces a function.
+func (f *Functions) Set(def Definition) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.defs[def.Name] = def
+}
+
+// Get returns the definition of a function.
+// Returns the definition and true if found, empty definition and false otherwise.
+func (f *Functions) Get(name string) (Definition, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	def, ok := f.defs[name]
+	return def, ok
+}
+
+// Has returns true if a function with the given name exists.
+func (f *Functions) Has(name string) bool {
+	_, ok := f.Get(name)
+	return ok
+}
+
+// Remove deletes a function. Returns false if it did not exist.
+func (f *Functions) Remove(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.defs[name]
+	delete(f.defs, name)
+	return ok
+}
+
+// Names returns all function names in sorted order.
+func (f *Functions) Names() []string {
+	return f.Match("")
+}
+
+// Match finds functions whose names start with the given prefix.
+// Returns matching names in sorted order.
+func (f *Functions) Match(prefix string) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var matches []string
+	for name := range f.defs {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -3,9 +3,15 @@ package builtins
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/sdejongh/jsishell/internal/env"
 	"github.com/sdejongh/jsishell/internal/parser"
 )
 
@@ -13,6 +19,10 @@ import (
 type HistoryEntry struct {
 	Command   string
 	Timestamp time.Time
+	Dir       string
+	ExitCode  int
+	Duration  time.Duration
+	Detailed  bool // Dir, ExitCode and Duration are known
 }
 
 // HistoryProvider provides access to command history.
@@ -21,6 +31,13 @@ type HistoryProvider interface {
 	Len() int
 	All() []HistoryEntry
 	Clear()
+	// Delete removes the entries numbered first to last, from 1
+	Delete(first, last int) error
+	// Export writes the entries in the format json, bash or zsh
+	Export(w io.Writer, format string) error
+	// Import adds the commands of a history file of another shell, in the
+	// given format or the detected one if empty, and returns their number
+	Import(r io.Reader, format string) (int, error)
 }
 
 // historyAdapter adapts the actual history.History to HistoryProvider.
@@ -37,10 +54,12 @@ func HistoryDefinition() Definition {
 	return Definition{
 		Name:        "history",
 		Description: "Display or manage command history",
-		Usage:       "history [count] [-c|--clear]",
+		Usage:       "history [count] [-v|--verbose] [-c|--clear] | history search|delete|stats|export|import ...",
 		Handler:     historyHandler,
 		Options: []OptionDef{
 			{Long: "--clear", Short: "-c", Description: "Clear the history"},
+			{Long: "--verbose", Short: "-v", Description: "Show time, exit status, duration and directory"},
+			{Long: "--format", Description: "Format of export and import: json, bash or zsh", HasValue: true},
 			{Long: "--help", Description: "Show help message"},
 		},
 	}
@@ -53,6 +72,9 @@ func historyHandler(ctx context.Context, cmd *parser.Command, execCtx *Context)
 		return 0, nil
 	}
 
+	// --format takes its value as the next word too, as in --format json
+	cmd.BindValue("--format")
+
 	// Get history provider
 	if historyProviderFunc == nil {
 		execCtx.WriteErrorln("history: history not available")
@@ -65,6 +87,22 @@ func historyHandler(ctx context.Context, cmd *parser.Command, execCtx *Context)
 		return 1, nil
 	}
 
+	if len(cmd.Args) > 0 {
+		args := cmd.Args[1:]
+		switch cmd.Args[0] {
+		case "search":
+			return historySearch(provider, cmd, args, execCtx), nil
+		case "delete":
+			return historyDelete(provider, args, execCtx), nil
+		case "stats":
+			return historyStats(provider, args, execCtx), nil
+		case "export":
+			return historyExport(provider, cmd, args, execCtx), nil
+		case "import":
+			return historyImport(provider, cmd, args, execCtx), nil
+		}
+	}
+
 	// Check for --clear or -c
 	if cmd.HasFlag("--clear") || cmd.HasFlag("-c") {
 		provider.Clear()
@@ -98,31 +136,313 @@ func historyHandler(ctx context.Context, cmd *parser.Command, execCtx *Context)
 		startIdx = 0
 	}
 
+	verbose := cmd.HasFlag("--verbose") || cmd.HasFlag("-v")
 	for i := startIdx; i < len(entries); i++ {
-		entry := entries[i]
-		// Format: number  command
-		fmt.Fprintf(execCtx.Stdout, "%5d  %s\n", i+1, entry.Command)
+		printHistoryEntry(execCtx, i+1, entries[i], verbose)
 	}
 
 	return 0, nil
 }
 
+// printHistoryEntry prints an entry with its number, and its details if
+// verbose.
+func printHistoryEntry(execCtx *Context, number int, entry HistoryEntry, verbose bool) {
+	if verbose {
+		fmt.Fprintf(execCtx.Stdout, "%5d  %s\n", number, formatHistoryDetails(entry))
+		return
+	}
+	// Format: number  command
+	fmt.Fprintf(execCtx.Stdout, "%5d  %s\n", number, entry.Command)
+}
+
+// historySearch lists the entries whose command matches a regular
+// expression. Exit status is 1 if none does.
+func historySearch(provider HistoryProvider, cmd *parser.Command, args []string, execCtx *Context) int {
+	if len(args) != 1 {
+		execCtx.WriteErrorln("history: usage: history search [-v] PATTERN")
+		return 2
+	}
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		execCtx.WriteErrorln("history: search: invalid pattern: %v", err)
+		return 2
+	}
+
+	verbose := cmd.HasFlag("--verbose") || cmd.HasFlag("-v")
+	status := 1
+	for i, entry := range provider.All() {
+		if re.MatchString(entry.Command) {
+			printHistoryEntry(execCtx, i+1, entry, verbose)
+			status = 0
+		}
+	}
+	return status
+}
+
+// historyDelete deletes an entry, or a range of entries as in 10-20.
+func historyDelete(provider HistoryProvider, args []string, execCtx *Context) int {
+	if len(args) != 1 {
+		execCtx.WriteErrorln("history: usage: history delete N|FIRST-LAST")
+		return 2
+	}
+	first, last, ok := parseHistoryRange(args[0])
+	if !ok {
+		execCtx.WriteErrorln("history: delete: invalid range: %s", args[0])
+		return 2
+	}
+	if n := provider.Len(); last > n {
+		execCtx.WriteErrorln("history: delete: %s: out of range, the history has %d entries", args[0], n)
+		return 1
+	}
+	if err := provider.Delete(first, last); err != nil {
+		execCtx.WriteErrorln("history: delete: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// parseHistoryRange parses N or FIRST-LAST, numbers from 1.
+func parseHistoryRange(s string) (first, last int, ok bool) {
+	from, to, isRange := strings.Cut(s, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil || first < 1 {
+		return 0, 0, false
+	}
+	if !isRange {
+		return first, first, true
+	}
+	last, err = strconv.Atoi(to)
+	if err != nil || last < first {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// historyCount counts the runs of a command or in a directory.
+type historyCount struct {
+	name    string
+	runs    int
+	checked int // Runs with a known exit status
+	failed  int
+}
+
+// historyStats shows the most used commands and directories, and the
+// commands that fail the most. Entries recorded without details only count
+// for the commands.
+func historyStats(provider HistoryProvider, args []string, execCtx *Context) int {
+	top := 10
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 || len(args) > 1 {
+			execCtx.WriteErrorln("history: usage: history stats [count]")
+			return 2
+		}
+		top = n
+	}
+
+	entries := provider.All()
+	if len(entries) == 0 {
+		fmt.Fprintln(execCtx.Stdout, "No history")
+		return 0
+	}
+
+	commands := make(map[string]*historyCount)
+	dirs := make(map[string]*historyCount)
+	count := func(counts map[string]*historyCount, name string) *historyCount {
+		c, ok := counts[name]
+		if !ok {
+			c = &historyCount{name: name}
+			counts[name] = c
+		}
+		return c
+	}
+	checked, failed := 0, 0
+	for _, entry := range entries {
+		c := count(commands, commandName(entry.Command))
+		c.runs++
+		if !entry.Detailed {
+			continue
+		}
+		count(dirs, entry.Dir).runs++
+		c.checked++
+		checked++
+		if entry.ExitCode != 0 {
+			c.failed++
+			failed++
+		}
+	}
+
+	out := execCtx.Stdout
+	fmt.Fprintf(out, "Commands: %d, %d with an exit status, %d failed (%s)\n",
+		len(entries), checked, failed, percent(failed, checked))
+
+	fmt.Fprintln(out, "\nTop commands:")
+	for _, c := range sortCounts(commands, top, func(c *historyCount) int { return c.runs }) {
+		fmt.Fprintf(out, "%7d  %6s  %s\n", c.runs, percent(c.runs, len(entries)), c.name)
+	}
+
+	if len(dirs) > 0 {
+		fmt.Fprintln(out, "\nTop directories:")
+		for _, c := range sortCounts(dirs, top, func(c *historyCount) int { return c.runs }) {
+			fmt.Fprintf(out, "%7d  %6s  %s\n", c.runs, percent(c.runs, checked), c.name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintln(out, "\nFailures:")
+		for _, c := range sortCounts(commands, top, func(c *historyCount) int { return c.failed }) {
+			if c.failed == 0 {
+				break
+			}
+			runs := fmt.Sprintf("%d/%d", c.failed, c.checked)
+			fmt.Fprintf(out, "%7s  %6s  %s\n", runs, percent(c.failed, c.checked), c.name)
+		}
+	}
+	return 0
+}
+
+// sortCounts returns the top counts by the given key, then by name.
+func sortCounts(counts map[string]*historyCount, top int, key func(*historyCount) int) []*historyCount {
+	list := make([]*historyCount, 0, len(counts))
+	for _, c := range counts {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ki, kj := key(list[i]), key(list[j]); ki != kj {
+			return ki > kj
+		}
+		return list[i].name < list[j].name
+	})
+	return list[:min(top, len(list))]
+}
+
+// percent formats n out of total as a percentage.
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)*100/float64(total))
+}
+
+// commandName returns the name of the command of a line: its first word
+// after variable assignments.
+func commandName(line string) string {
+	words := strings.Fields(line)
+	for _, word := range words {
+		if name, _, ok := strings.Cut(word, "="); !ok || !env.ValidName(name) {
+			return word
+		}
+	}
+	if len(words) > 0 {
+		return words[0]
+	}
+	return line
+}
+
+// historyExport writes the history to standard output, in JSON Lines by
+// default.
+func historyExport(provider HistoryProvider, cmd *parser.Command, args []string, execCtx *Context) int {
+	// --format without a value is left as a flag
+	if cmd.HasFlag("--format") || len(args) > 0 {
+		execCtx.WriteErrorln("history: usage: history export [--format json|bash|zsh]")
+		return 2
+	}
+	format := cmd.GetOption("--format")
+	if format == "" {
+		format = "json"
+	}
+	if err := provider.Export(execCtx.Stdout, format); err != nil {
+		execCtx.WriteErrorln("history: export: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// historyImport adds the commands of a bash, zsh or exported history file
+// to the history.
+func historyImport(provider HistoryProvider, cmd *parser.Command, args []string, execCtx *Context) int {
+	if cmd.HasFlag("--format") || len(args) != 1 {
+		execCtx.WriteErrorln("history: usage: history import [--format json|bash|zsh] FILE")
+		return 2
+	}
+	f, err := os.Open(execCtx.Path(args[0]))
+	if err != nil {
+		execCtx.WriteErrorln("history: import: %v", err)
+		return 1
+	}
+	defer f.Close()
+
+	n, err := provider.Import(f, cmd.GetOption("--format"))
+	if err != nil {
+		execCtx.WriteErrorln("history: import: %s: %v", args[0], err)
+		return 1
+	}
+	noun := "commands"
+	if n == 1 {
+		noun = "command"
+	}
+	fmt.Fprintf(execCtx.Stdout, "Imported %d %s from %s\n", n, noun, args[0])
+	return 0
+}
+
+// formatHistoryDetails formats an entry with its time, exit status, duration
+// and directory, which are shown as - for entries without details.
+func formatHistoryDetails(entry HistoryEntry) string {
+	status, duration, dir := "-", "-", "-"
+	if entry.Detailed {
+		status = strconv.Itoa(entry.ExitCode)
+		duration = entry.Duration.Round(time.Millisecond).String()
+		if entry.Dir != "" {
+			dir = entry.Dir
+		}
+	}
+	when := fmt.Sprintf("%-16s", "-") // Imported without a time
+	if !entry.Timestamp.IsZero() {
+		when = entry.Timestamp.Format("2006-01-02 15:04")
+	}
+	return fmt.Sprintf("%s  %3s  %8s  %s  %s", when, status, duration, dir, entry.Command)
+}
+
 func showHistoryHelp(execCtx *Context) {
 	help := `history - Display or manage command history
 
 Usage: history [count] [options]
+       history search [-v] PATTERN
+       history delete N|FIRST-LAST
+       history stats [count]
+       history export [--format json|bash|zsh]
+       history import [--format json|bash|zsh] FILE
 
 Arguments:
   count         Number of recent entries to display (default: all)
 
 Options:
+  -v, --verbose Show the time, exit status, duration and directory of
+                each command
   -c, --clear   Clear the history
+  --format FMT  Format of export (default: json) and import (default:
+                detected from the file)
   --help        Show this help message
 
+Subcommands:
+  search        List the commands matching a regular expression
+  delete        Delete an entry, or the entries of a range
+  stats         Show the most used commands and directories, and the
+                commands that fail the most (default: top 10)
+  export        Write the history to standard output, as JSON Lines with
+                all details, or in the history format of bash or zsh
+  import        Add the commands of a bash, zsh or exported history file
+                before the current ones
+
 Examples:
   history       Display all history entries
   history 10    Display the last 10 entries
+  history -v 20 Display the last 20 entries with their details
   history -c    Clear the history
+  history search '^git (push|pull)'
+  history delete 120-125
+  history export --format zsh > jsishell_history
+  history import ~/.bash_history
 `
 	execCtx.Stdout.Write([]byte(help))
 }
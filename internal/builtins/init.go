@@ -42,6 +42,7 @@ func defaultConfigContent() string {
 #   %T  - Time with seconds (HH:MM:SS)
 #   %n  - Newline
 #   %$  - Shell indicator ($ for user, # for root)
+#   %?  - Exit status of the last command
 #   %%  - Literal %
 #
 # Color codes (use %{color} and %{reset} or %{/}):
@@ -59,6 +60,10 @@ func defaultConfigContent() string {
 #
 prompt: "%{green}%u@%h%{/}:%{blue}%~%{/}%$ "
 
+# Prompt of the continuation lines of a multi-line command (unclosed quote
+# or block, or a line ending with \). Supports the same variables.
+continuation_prompt: "> "
+
 # History settings
 history:
   # Maximum number of commands to keep in history
@@ -74,6 +79,9 @@ history:
   # Useful for sensitive commands you don't want in history
   ignore_space_prefix: true
 
+  # Show the commands run in other sessions, from the next prompt on
+  share_history: false
+
 # Color scheme settings
 colors:
   # Enable/disable colors globally
@@ -104,10 +112,22 @@ abbreviations:
   # Enable command abbreviations (e.g., 'l' for 'ls' if unambiguous)
   enabled: true
 
+# Command aliases (the alias name is replaced by its value)
+# aliases:
+#   ll: "ls -l"
+#   gs: "git status"
+
 # Line editor settings
 editor:
   # Tab width for indentation
   tab_width: 4
+
+# Shell code run by interactive shells, which may call functions of rc.jsi
+# hooks:
+#   on_start: "echo Welcome"        # Once, before the first prompt
+#   preexec: "echo \"> $1\""        # Before each command line, given as $1
+#   precmd: "jobs"                  # Before each prompt
+#   chpwd: "ls"                     # After a command changed directory
 `
 }
 
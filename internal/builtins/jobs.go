@@ -0,0 +1,423 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/jobs"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// JobsDefinition returns the jobs command definition.
+func JobsDefinition() Definition {
+	return Definition{
+		Name:        "jobs",
+		Description: "List background and stopped jobs",
+		Usage:       "jobs [-p] [%job...]",
+		Handler:     jobsHandler,
+		Options: []OptionDef{
+			{Short: "-p", Long: "--pids", Description: "Show only process group IDs"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// FgDefinition returns the fg command definition.
+func FgDefinition() Definition {
+	return Definition{
+		Name:        "fg",
+		Description: "Bring a job to the foreground",
+		Usage:       "fg [%job]",
+		Handler:     fgHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// BgDefinition returns the bg command definition.
+func BgDefinition() Definition {
+	return Definition{
+		Name:        "bg",
+		Description: "Resume a stopped job in the background",
+		Usage:       "bg [%job...]",
+		Handler:     bgHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// WaitDefinition returns the wait command definition.
+func WaitDefinition() Definition {
+	return Definition{
+		Name:        "wait",
+		Description: "Wait for jobs to finish",
+		Usage:       "wait [%job|pid...]",
+		Handler:     waitHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// KillDefinition returns the kill command definition.
+func KillDefinition() Definition {
+	return Definition{
+		Name:        "kill",
+		Description: "Send a signal to jobs or processes",
+		Usage:       "kill [-s SIGNAL | -SIGNAL] %job|pid... | kill -l",
+		Handler:     killHandler,
+		Options: []OptionDef{
+			{Short: "-s", Description: "Signal to send (name or number)", HasValue: true},
+			{Short: "-l", Long: "--list", Description: "List signal names"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func jobsHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showJobsHelp(execCtx)
+		return 0, nil
+	}
+	if execCtx.Jobs == nil {
+		execCtx.WriteErrorln("jobs: jobs are not available")
+		return 1, nil
+	}
+
+	status := 0
+	list := execCtx.Jobs.Jobs()
+	if len(cmd.Args) > 0 {
+		list = nil
+		for _, spec := range cmd.Args {
+			job, err := execCtx.Jobs.Find(spec)
+			if err != nil {
+				execCtx.WriteErrorln("jobs: %v", err)
+				status = 1
+				continue
+			}
+			list = append(list, job)
+		}
+	}
+
+	for _, job := range list {
+		if cmd.HasFlag("-p", "--pids") {
+			if pgid := job.Pgid(); pgid != 0 {
+				fmt.Fprintln(execCtx.Stdout, pgid)
+			}
+			continue
+		}
+		fmt.Fprintln(execCtx.Stdout, execCtx.Jobs.Format(job))
+
+		// Finished jobs are reported once
+		if job.State() == jobs.Done {
+			execCtx.Jobs.Remove(job)
+		}
+	}
+	return status, nil
+}
+
+func fgHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showFgHelp(execCtx)
+		return 0, nil
+	}
+	if execCtx.Jobs == nil {
+		execCtx.WriteErrorln("fg: jobs are not available")
+		return 1, nil
+	}
+
+	job, err := execCtx.Jobs.Find(cmd.Arg(0))
+	if err != nil {
+		execCtx.WriteErrorln("fg: %v", err)
+		return 1, nil
+	}
+
+	fmt.Fprintln(execCtx.Stdout, job.Command)
+	if err := job.Continue(true); err != nil {
+		execCtx.WriteErrorln("fg: %v", err)
+		return 1, nil
+	}
+	if job.RunForeground(ctx) == jobs.Stopped {
+		return jobs.StoppedStatus, nil
+	}
+
+	execCtx.Jobs.Remove(job)
+	return job.ExitCode(), nil
+}
+
+func bgHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showBgHelp(execCtx)
+		return 0, nil
+	}
+	if execCtx.Jobs == nil {
+		execCtx.WriteErrorln("bg: jobs are not available")
+		return 1, nil
+	}
+
+	specs := cmd.Args
+	if len(specs) == 0 {
+		specs = []string{""}
+	}
+
+	status := 0
+	for _, spec := range specs {
+		job, err := execCtx.Jobs.Find(spec)
+		if err != nil {
+			execCtx.WriteErrorln("bg: %v", err)
+			status = 1
+			continue
+		}
+		if job.State() != jobs.Stopped {
+			execCtx.WriteErrorln("bg: job %d already in background", job.ID)
+			continue
+		}
+		if err := job.Continue(false); err != nil {
+			execCtx.WriteErrorln("bg: %v", err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(execCtx.Stdout, "[%d] %s &\n", job.ID, job.Command)
+	}
+	return status, nil
+}
+
+func waitHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showWaitHelp(execCtx)
+		return 0, nil
+	}
+	if execCtx.Jobs == nil {
+		execCtx.WriteErrorln("wait: jobs are not available")
+		return 1, nil
+	}
+
+	// Without arguments, wait for all running jobs
+	if len(cmd.Args) == 0 {
+		for _, job := range execCtx.Jobs.Jobs() {
+			if job.State() == jobs.Stopped {
+				continue
+			}
+			if job.Wait(ctx) == jobs.Done {
+				execCtx.Jobs.Remove(job)
+			}
+			if ctx.Err() != nil {
+				return 130, nil
+			}
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, arg := range cmd.Args {
+		job, err := findJob(execCtx.Jobs, arg)
+		if err != nil {
+			execCtx.WriteErrorln("wait: %v", err)
+			status = 127
+			continue
+		}
+
+		switch job.Wait(ctx) {
+		case jobs.Done:
+			execCtx.Jobs.Remove(job)
+			status = job.ExitCode()
+		case jobs.Stopped:
+			status = jobs.StoppedStatus
+		}
+		if ctx.Err() != nil {
+			return 130, nil
+		}
+	}
+	return status, nil
+}
+
+// findJob returns the job designated by a job specification or by the
+// process ID of one of its processes.
+func findJob(table *jobs.Table, arg string) (*jobs.Job, error) {
+	if strings.HasPrefix(arg, "%") {
+		return table.Find(arg)
+	}
+	pid, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a pid or valid job spec", arg)
+	}
+	for _, job := range table.Jobs() {
+		if job.Pid() == pid || job.Pgid() == pid || job.HasProcess(pid) {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("pid %d is not a child of this shell", pid)
+}
+
+func killHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showKillHelp(execCtx)
+		return 0, nil
+	}
+
+	words := cmd.Words
+	if len(words) > 0 && (words[0] == "-l" || words[0] == "--list") {
+		fmt.Fprintln(execCtx.Stdout, strings.Join(jobs.SignalNames(), " "))
+		return 0, nil
+	}
+
+	// The signal is given with -s NAME or -NAME; the default is TERM
+	sigName := "TERM"
+	switch {
+	case len(words) > 0 && words[0] == "-s":
+		if len(words) < 2 {
+			execCtx.WriteErrorln("kill: -s: option requires an argument")
+			return 1, nil
+		}
+		sigName, words = words[1], words[2:]
+	case len(words) > 0 && words[0] == "--":
+		words = words[1:]
+	case len(words) > 0 && len(words[0]) > 1 && words[0][0] == '-':
+		sigName, words = words[0][1:], words[1:]
+	}
+	if len(words) > 0 && words[0] == "--" {
+		words = words[1:]
+	}
+
+	sig, err := jobs.ParseSignal(sigName)
+	if err != nil {
+		execCtx.WriteErrorln("kill: %v", err)
+		return 1, nil
+	}
+	if len(words) == 0 {
+		execCtx.WriteErrorln("kill: usage: kill [-s SIGNAL | -SIGNAL] %%job|pid...")
+		return 1, nil
+	}
+
+	status := 0
+	for _, target := range words {
+		if strings.HasPrefix(target, "%") {
+			if execCtx.Jobs == nil {
+				execCtx.WriteErrorln("kill: %s: jobs are not available", target)
+				status = 1
+				continue
+			}
+			job, err := execCtx.Jobs.Find(target)
+			if err == nil {
+				err = job.Signal(sig)
+			}
+			if err != nil {
+				execCtx.WriteErrorln("kill: %v", err)
+				status = 1
+			}
+			continue
+		}
+
+		pid, err := strconv.Atoi(target)
+		if err != nil {
+			execCtx.WriteErrorln("kill: %s: arguments must be process or job IDs", target)
+			status = 1
+			continue
+		}
+		if err := jobs.Kill(pid, sig); err != nil {
+			execCtx.WriteErrorln("kill: (%d): %v", pid, err)
+			status = 1
+		}
+	}
+	return status, nil
+}
+
+func showJobsHelp(execCtx *Context) {
+	help := `jobs - List background and stopped jobs
+
+Usage: jobs [-p] [%job...]
+
+Description:
+  Lists the jobs started with & and the jobs stopped with Ctrl+Z, with
+  their job number and state. The current job is marked with + and the
+  previous one with -. Finished jobs are listed once.
+
+Options:
+  -p, --pids   Show only the process group ID of each job
+  --help       Show this help message
+
+Job specifications:
+  %n           Job number n
+  %+, %%       Current job
+  %-           Previous job
+  %prefix      Job whose command starts with prefix
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showFgHelp(execCtx *Context) {
+	help := `fg - Bring a job to the foreground
+
+Usage: fg [%job]
+
+Description:
+  Resumes a stopped or background job in the foreground and waits for it.
+  Without argument, the current job is used. Press Ctrl+Z to stop it again.
+
+Examples:
+  fg           Resume the current job
+  fg %2        Resume job 2
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showBgHelp(execCtx *Context) {
+	help := `bg - Resume a stopped job in the background
+
+Usage: bg [%job...]
+
+Description:
+  Resumes stopped jobs in the background, as if they had been started
+  with &. Without argument, the current job is used.
+
+Examples:
+  bg           Resume the current job
+  bg %1 %3     Resume jobs 1 and 3
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showWaitHelp(execCtx *Context) {
+	help := `wait - Wait for jobs to finish
+
+Usage: wait [%job|pid...]
+
+Description:
+  Waits for the given jobs, or for all running jobs without argument.
+  The exit status is that of the last job waited for. Press Ctrl+C to
+  stop waiting.
+
+Examples:
+  make & make test & wait
+  wait %1
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showKillHelp(execCtx *Context) {
+	help := `kill - Send a signal to jobs or processes
+
+Usage: kill [-s SIGNAL | -SIGNAL] %job|pid...
+       kill -l
+
+Description:
+  Sends a signal (TERM by default) to the processes of jobs or to
+  processes given by ID. Signals can be given by name (TERM, SIGTERM)
+  or number (15).
+
+Options:
+  -s SIGNAL    Signal to send
+  -l, --list   List signal names
+  --help       Show this help message
+
+Examples:
+  kill %1            Terminate job 1
+  kill -9 1234       Kill process 1234
+  kill -s STOP %2    Stop job 2
+`
+	execCtx.Stdout.Write([]byte(help))
+}
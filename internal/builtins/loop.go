@@ -0,0 +1,106 @@
+package builtins
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// LoopControl is a special error type that signals break or continue.
+// The executor unwinds the given number of enclosing loops.
+type LoopControl struct {
+	Continue bool // True for continue, false for break
+	Levels   int  // Number of enclosing loops to leave (at least 1)
+}
+
+func (l LoopControl) Error() string {
+	if l.Continue {
+		return "continue: only meaningful in a loop"
+	}
+	return "break: only meaningful in a loop"
+}
+
+// BreakDefinition returns the break command definition.
+func BreakDefinition() Definition {
+	return Definition{
+		Name:        "break",
+		Description: "Exit from a for, while or until loop",
+		Usage:       "break [n]",
+		Handler:     breakHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// ContinueDefinition returns the continue command definition.
+func ContinueDefinition() Definition {
+	return Definition{
+		Name:        "continue",
+		Description: "Resume the next iteration of a loop",
+		Usage:       "continue [n]",
+		Handler:     continueHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func breakHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showLoopControlHelp(execCtx, "break")
+		return 0, nil
+	}
+	return loopControl(cmd, execCtx, false)
+}
+
+func continueHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showLoopControlHelp(execCtx, "continue")
+		return 0, nil
+	}
+	return loopControl(cmd, execCtx, true)
+}
+
+// loopControl returns the LoopControl error for break or continue.
+func loopControl(cmd *parser.Command, execCtx *Context, cont bool) (int, error) {
+	levels := 1
+	if len(cmd.Args) > 0 {
+		n, err := strconv.Atoi(cmd.Args[0])
+		if err != nil || n < 1 {
+			execCtx.WriteErrorln("%s: %s: loop count out of range", cmd.Name, cmd.Args[0])
+			return 1, nil
+		}
+		levels = n
+	}
+	return 0, LoopControl{Continue: cont, Levels: levels}
+}
+
+func showLoopControlHelp(execCtx *Context, name string) {
+	var help string
+	if name == "break" {
+		help = `break - Exit from a loop
+
+Usage: break [n]
+
+Arguments:
+  n      Number of enclosing loops to exit (default: 1)
+
+Examples:
+  for f in a b c; do if ls $f; then break; fi; done
+`
+	} else {
+		help = `continue - Resume the next iteration of a loop
+
+Usage: continue [n]
+
+Arguments:
+  n      Resume the nth enclosing loop (default: 1)
+
+Examples:
+  for f in a b c; do if ls $f; then continue; fi; echo missing $f; done
+`
+	}
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/sdejongh/jsishell/internal/glob"
 	"github.com/sdejongh/jsishell/internal/parser"
 )
 
@@ -285,7 +286,7 @@ func compareEntries(a, b *lsEntryInfo, field string) int {
 // matchesExcludePattern checks if a name matches any of the exclude patterns.
 func matchesExcludePattern(name string, patterns []string) bool {
 	for _, pattern := range patterns {
-		if matched, _ := filepath.Match(pattern, name); matched {
+		if glob.Match(pattern, name) {
 			return true
 		}
 	}
@@ -298,7 +299,7 @@ func lsPath(path string, opts lsOptions, execCtx *Context) error {
 
 func lsPathInternal(path string, opts lsOptions, execCtx *Context, isRecursiveCall bool) error {
 	// Check if path exists
-	info, err := os.Stat(path)
+	info, err := os.Stat(execCtx.Path(path))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("no such file or directory")
@@ -322,7 +323,7 @@ func lsPathInternal(path string, opts lsOptions, execCtx *Context, isRecursiveCa
 	}
 
 	// Read directory entries
-	entries, err := os.ReadDir(path)
+	entries, err := os.ReadDir(execCtx.Path(path))
 	if err != nil {
 		return err
 	}
@@ -565,7 +566,8 @@ Sort specification:
     --sort=!size,name Sort by size descending, then by name for equal sizes
 
 Exclude patterns:
-  Standard glob patterns: *, ?, [abc], [a-z]
+  Glob patterns: *, ?, [abc], [a-z] and extended patterns such as
+  !(*.go) or *.@(log|tmp)
   Can be specified multiple times to exclude several patterns.
 
 Long format shows: permissions, owner, group, size, date, name
@@ -43,9 +43,9 @@ func mkdirHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (i
 	for _, dir := range cmd.Args {
 		var err error
 		if parents {
-			err = os.MkdirAll(dir, 0755)
+			err = os.MkdirAll(execCtx.Path(dir), 0755)
 		} else {
-			err = os.Mkdir(dir, 0755)
+			err = os.Mkdir(execCtx.Path(dir), 0755)
 		}
 
 		if err != nil {
@@ -44,7 +44,7 @@ func mvHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 	sources := cmd.Args[:len(cmd.Args)-1]
 
 	// Check if destination is a directory (or should be)
-	destInfo, destErr := os.Stat(dest)
+	destInfo, destErr := os.Stat(execCtx.Path(dest))
 	destIsDir := destErr == nil && destInfo.IsDir()
 
 	// Multiple sources require directory destination
@@ -57,7 +57,7 @@ func mvHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 
 	for _, src := range sources {
 		// Check if source exists
-		if _, err := os.Stat(src); err != nil {
+		if _, err := os.Stat(execCtx.Path(src)); err != nil {
 			execCtx.WriteErrorln("mv: cannot stat '%s': %v", src, err)
 			exitCode = 1
 			continue
@@ -70,14 +70,14 @@ func mvHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 		}
 
 		// Check if destination exists
-		if _, err := os.Stat(actualDest); err == nil {
+		if _, err := os.Stat(execCtx.Path(actualDest)); err == nil {
 			if !force {
 				execCtx.WriteErrorln("mv: '%s' already exists", actualDest)
 				exitCode = 1
 				continue
 			}
 			// Force: remove destination first
-			if err := os.RemoveAll(actualDest); err != nil {
+			if err := os.RemoveAll(execCtx.Path(actualDest)); err != nil {
 				execCtx.WriteErrorln("mv: cannot remove '%s': %v", actualDest, err)
 				exitCode = 1
 				continue
@@ -85,7 +85,7 @@ func mvHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 		}
 
 		// Perform the move
-		if err := os.Rename(src, actualDest); err != nil {
+		if err := os.Rename(execCtx.Path(src), execCtx.Path(actualDest)); err != nil {
 			execCtx.WriteErrorln("mv: cannot move '%s' to '%s': %v", src, actualDest, err)
 			exitCode = 1
 			continue
@@ -0,0 +1,381 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// PrintfDefinition returns the printf command definition.
+func PrintfDefinition() Definition {
+	return Definition{
+		Name:        "printf",
+		Description: "Format and print arguments",
+		Usage:       "printf [-v name] format [arguments...]",
+		Handler:     printfHandler,
+		Options: []OptionDef{
+			{Short: "-v", Description: "Assign the output to a variable", HasValue: true},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func printfHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	// Words keep arguments such as -5, which are parsed as options
+	words := cmd.Words
+	varName := ""
+	if len(words) > 0 {
+		switch words[0] {
+		case "--help":
+			showPrintfHelp(execCtx)
+			return 0, nil
+		case "-v":
+			if len(words) < 2 {
+				execCtx.WriteErrorln("printf: -v: option requires an argument")
+				return 2, nil
+			}
+			varName, words = words[1], words[2:]
+			if !env.ValidName(varName) {
+				execCtx.WriteErrorln("printf: %s: not a valid identifier", varName)
+				return 2, nil
+			}
+		}
+	}
+	if len(words) > 0 && words[0] == "--" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		execCtx.WriteErrorln("printf: usage: printf [-v name] format [arguments...]")
+		return 2, nil
+	}
+
+	f := &formatter{args: words[1:]}
+	output := f.run(words[0])
+	for _, msg := range f.errs {
+		execCtx.WriteErrorln("printf: %s", msg)
+	}
+
+	if varName != "" {
+		execCtx.Env.Set(varName, output)
+	} else {
+		execCtx.Stdout.Write([]byte(output))
+	}
+	if len(f.errs) > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// errStopOutput is returned by escape expansion at \c, which ends the
+// output of printf.
+var errStopOutput = errors.New("stop output")
+
+// formatter formats the arguments of printf.
+type formatter struct {
+	args []string
+	errs []string // Invalid numbers and format characters
+	sb   strings.Builder
+}
+
+// run formats the arguments with the format, which is reused as long as
+// arguments remain, and returns the output.
+func (f *formatter) run(format string) string {
+	for {
+		before := len(f.args)
+		if err := f.format(format); err != nil {
+			break
+		}
+		if len(f.args) == 0 || len(f.args) == before {
+			break
+		}
+	}
+	return f.sb.String()
+}
+
+// format formats arguments once with the format. Returns errStopOutput at
+// \c or an invalid format character.
+func (f *formatter) format(format string) error {
+	for i := 0; i < len(format); {
+		switch format[i] {
+		case '\\':
+			n, err := expandEscape(&f.sb, format[i:], false)
+			if err != nil {
+				return err
+			}
+			i += n
+		case '%':
+			n, err := f.conversion(format[i:])
+			if err != nil {
+				return err
+			}
+			i += n
+		default:
+			f.sb.WriteByte(format[i])
+			i++
+		}
+	}
+	return nil
+}
+
+// conversion formats the next argument with the conversion specification
+// at the start of spec, such as %-10s or %.2f, and returns its length.
+func (f *formatter) conversion(spec string) (int, error) {
+	i := 1
+	var flags strings.Builder
+	for i < len(spec) && strings.IndexByte("-+ #0'", spec[i]) >= 0 {
+		// The thousands separator flag of C is not supported and ignored
+		if spec[i] != '\'' {
+			flags.WriteByte(spec[i])
+		}
+		i++
+	}
+	width, n := f.count(spec[i:])
+	i += n
+	precision := ""
+	if i < len(spec) && spec[i] == '.' {
+		p, n := f.count(spec[i+1:])
+		precision = "." + p
+		i += 1 + n
+	}
+	if i >= len(spec) {
+		f.errs = append(f.errs, fmt.Sprintf("%s: missing format character", spec))
+		return i, errStopOutput
+	}
+
+	verb := spec[i]
+	i++
+	goSpec := "%" + flags.String() + width + precision
+	switch verb {
+	case '%':
+		f.sb.WriteByte('%')
+	case 's', 'v':
+		fmt.Fprintf(&f.sb, goSpec+"s", f.next())
+	case 'q':
+		fmt.Fprintf(&f.sb, goSpec+"s", shellQuote(f.next()))
+	case 'c':
+		arg := f.next()
+		if r, size := utf8.DecodeRuneInString(arg); size > 0 {
+			fmt.Fprintf(&f.sb, goSpec+"c", r)
+		}
+	case 'b':
+		var expanded strings.Builder
+		arg := f.next()
+		var err error
+		for j := 0; j < len(arg) && err == nil; {
+			if arg[j] != '\\' {
+				expanded.WriteByte(arg[j])
+				j++
+				continue
+			}
+			var n int
+			n, err = expandEscape(&expanded, arg[j:], true)
+			j += n
+		}
+		fmt.Fprintf(&f.sb, goSpec+"s", expanded.String())
+		if err != nil {
+			return i, err
+		}
+	case 'd', 'i':
+		fmt.Fprintf(&f.sb, goSpec+"d", f.integer())
+	case 'u', 'o', 'x', 'X':
+		goVerb := string(verb)
+		if verb == 'u' {
+			goVerb = "d"
+		}
+		fmt.Fprintf(&f.sb, goSpec+goVerb, uint64(f.integer()))
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		// C prints 6 significant digits for %g by default, Go the fewest needed
+		if precision == "" && (verb == 'g' || verb == 'G') {
+			goSpec += ".6"
+		}
+		fmt.Fprintf(&f.sb, goSpec+string(verb), f.float())
+	default:
+		f.errs = append(f.errs, fmt.Sprintf("%%%c: invalid format character", verb))
+		return i, errStopOutput
+	}
+	return i, nil
+}
+
+// count parses a width or precision, digits or * for the next argument,
+// and returns it with its length in the format.
+func (f *formatter) count(s string) (string, int) {
+	if strings.HasPrefix(s, "*") {
+		return strconv.FormatInt(f.integer(), 10), 1
+	}
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return s[:n], n
+}
+
+// next consumes the next argument, or returns "" if none remain.
+func (f *formatter) next() string {
+	if len(f.args) == 0 {
+		return ""
+	}
+	arg := f.args[0]
+	f.args = f.args[1:]
+	return arg
+}
+
+// integer consumes the next argument as an integer. A leading quote gives
+// the code of the character that follows, as in 'A.
+func (f *formatter) integer() int64 {
+	arg := strings.TrimSpace(f.next())
+	if arg == "" {
+		return 0
+	}
+	if code, ok := charCode(arg); ok {
+		return int64(code)
+	}
+	n, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil {
+		f.errs = append(f.errs, fmt.Sprintf("%s: invalid number", arg))
+	}
+	return n
+}
+
+// float consumes the next argument as a floating-point number.
+func (f *formatter) float() float64 {
+	arg := strings.TrimSpace(f.next())
+	if arg == "" {
+		return 0
+	}
+	if code, ok := charCode(arg); ok {
+		return float64(code)
+	}
+	x, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		f.errs = append(f.errs, fmt.Sprintf("%s: invalid number", arg))
+	}
+	return x
+}
+
+// charCode returns the code of the character after a leading quote.
+func charCode(arg string) (rune, bool) {
+	if arg[0] != '\'' && arg[0] != '"' {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(arg[1:])
+	if r == utf8.RuneError {
+		return 0, true
+	}
+	return r, true
+}
+
+// expandEscape writes the character of the backslash escape at the start
+// of s and returns the length of the escape. Octal escapes are \NNN in
+// formats and \0NNN in %b arguments. Returns errStopOutput at \c.
+func expandEscape(sb *strings.Builder, s string, argument bool) (int, error) {
+	if len(s) < 2 {
+		sb.WriteByte('\\')
+		return 1, nil
+	}
+
+	c := s[1]
+	switch c {
+	case 'a':
+		sb.WriteByte('\a')
+	case 'b':
+		sb.WriteByte('\b')
+	case 'e', 'E':
+		sb.WriteByte(0x1b)
+	case 'f':
+		sb.WriteByte('\f')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'r':
+		sb.WriteByte('\r')
+	case 't':
+		sb.WriteByte('\t')
+	case 'v':
+		sb.WriteByte('\v')
+	case '\\', '"', '\'':
+		sb.WriteByte(c)
+	case 'c':
+		return 2, errStopOutput
+	case 'x', 'u', 'U':
+		digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		n := 0
+		for n < digits && 2+n < len(s) && isHexDigit(s[2+n]) {
+			n++
+		}
+		if n == 0 {
+			sb.WriteString(s[:2])
+			return 2, nil
+		}
+		code, _ := strconv.ParseUint(s[2:2+n], 16, 32)
+		if c == 'x' {
+			sb.WriteByte(byte(code))
+		} else {
+			sb.WriteRune(rune(code))
+		}
+		return 2 + n, nil
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		start := 1
+		if argument {
+			// %b arguments take \0NNN; \1 to \7 are not escapes there
+			if c != '0' {
+				sb.WriteString(s[:2])
+				return 2, nil
+			}
+			start = 2
+		}
+		n := 0
+		for n < 3 && start+n < len(s) && s[start+n] >= '0' && s[start+n] <= '7' {
+			n++
+		}
+		code, _ := strconv.ParseUint(s[start:start+n], 8, 32)
+		sb.WriteByte(byte(code))
+		return start + n, nil
+	default:
+		sb.WriteString(s[:2])
+	}
+	return 2, nil
+}
+
+// isHexDigit returns true if c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func showPrintfHelp(execCtx *Context) {
+	help := `printf - Format and print arguments
+
+Usage: printf [-v name] format [arguments...]
+
+Options:
+  -v NAME   Assign the output to the variable NAME instead of printing it
+  --help    Show this help message
+
+The format is printed with its conversions replaced by the arguments. It is
+reused as long as arguments remain; missing arguments are empty or zero.
+
+Conversions take optional flags (- + space # 0), a width and a precision,
+where * takes the value from an argument:
+  %s, %v    String
+  %q        String quoted for reuse as shell input
+  %b        String with backslash escapes expanded
+  %c        First character of the argument
+  %d, %i    Integer; 0x10, 010 and 'A (code of A) are accepted
+  %u %o %x %X   Unsigned integer in decimal, octal or hexadecimal
+  %f %e %g  Floating-point number (and %F %E %G)
+  %%        A percent sign
+
+Escapes: \n \t \r \\ \a \b \e \f \v \NNN (octal), \xHH, \uHHHH, and \c to
+stop output.
+
+Examples:
+  printf '%s\n' one two three      Print each argument on its own line
+  printf '%-10s %5.1f%%\n' cpu 42.5
+  printf -v name '%s-%03d' img 7   Set name to img-007
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -0,0 +1,410 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/parser"
+	"golang.org/x/term"
+)
+
+// Exit statuses of read besides 0 and 1 (end of input).
+const (
+	readTimeoutStatus     = 142 // 128 + SIGALRM, as for bash
+	readInterruptedStatus = 130
+)
+
+// defaultIFS separates the fields read into several variables when IFS is unset.
+const defaultIFS = " \t\n"
+
+// pollInterval is how often read checks for interruption while it waits
+// for input.
+const pollInterval = 100 * time.Millisecond
+
+// errReadTimeout is returned when the input is not complete before the
+// timeout of read -t.
+var errReadTimeout = errors.New("timeout")
+
+// errHelp is returned by option parsing when --help is given.
+var errHelp = errors.New("help")
+
+// ReadDefinition returns the read command definition.
+func ReadDefinition() Definition {
+	return Definition{
+		Name:        "read",
+		Description: "Read a line of input into variables",
+		Usage:       "read [-rs] [-p prompt] [-t timeout] [name...]",
+		Handler:     readHandler,
+		Options: []OptionDef{
+			{Short: "-p", Description: "Display a prompt when reading from a terminal", HasValue: true},
+			{Short: "-r", Description: "Do not treat backslashes as escapes"},
+			{Short: "-s", Description: "Do not echo input from a terminal"},
+			{Short: "-t", Description: "Fail after a timeout in seconds", HasValue: true},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// readOptions holds the options of read.
+type readOptions struct {
+	prompt  string
+	raw     bool
+	silent  bool
+	timeout time.Duration
+	poll    bool // -t 0: only check whether input is available
+}
+
+func readHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	opts, names, err := parseReadOptions(cmd.Words)
+	if err != nil {
+		if errors.Is(err, errHelp) {
+			showReadHelp(execCtx)
+			return 0, nil
+		}
+		execCtx.WriteErrorln("read: %v", err)
+		return 2, nil
+	}
+	for _, name := range names {
+		if !env.ValidName(name) {
+			execCtx.WriteErrorln("read: %s: not a valid identifier", name)
+			return 1, nil
+		}
+	}
+	if execCtx.Stdin == nil {
+		return 1, nil
+	}
+
+	in := &inputReader{ctx: ctx, r: execCtx.Stdin}
+	if opts.poll {
+		if in.ready(0) {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	if opts.timeout > 0 {
+		in.deadline = time.Now().Add(opts.timeout)
+	}
+
+	// Prompts and silent input only make sense on a terminal
+	if f, ok := execCtx.Stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if opts.prompt != "" {
+			fmt.Fprint(execCtx.Stderr, opts.prompt)
+		}
+		if opts.silent {
+			if state, err := term.MakeRaw(int(f.Fd())); err == nil {
+				defer term.Restore(int(f.Fd()), state)
+				in.terminal = true
+			}
+		}
+	}
+
+	line, quoted, err := in.readLine(opts.raw)
+	assignFields(execCtx.Env, names, line, quoted)
+
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.Is(err, errReadTimeout):
+		return readTimeoutStatus, nil
+	case errors.Is(err, io.EOF):
+		return 1, nil
+	case ctx.Err() != nil || errors.Is(err, context.Canceled):
+		return readInterruptedStatus, nil
+	default:
+		execCtx.WriteErrorln("read: %v", err)
+		return 1, nil
+	}
+}
+
+// parseReadOptions parses the options of read, which may be grouped as in
+// -rs or carry their value as in -t5, and returns the variable names.
+func parseReadOptions(words []string) (readOptions, []string, error) {
+	var opts readOptions
+	for len(words) > 0 {
+		word := words[0]
+		if word == "--" {
+			return opts, words[1:], nil
+		}
+		if word == "--help" {
+			return opts, nil, errHelp
+		}
+		if len(word) < 2 || word[0] != '-' {
+			break
+		}
+		words = words[1:]
+
+		for i := 1; i < len(word); i++ {
+			switch c := word[i]; c {
+			case 'r':
+				opts.raw = true
+			case 's':
+				opts.silent = true
+			case 'p', 't':
+				value := word[i+1:]
+				if value == "" {
+					if len(words) == 0 {
+						return opts, nil, fmt.Errorf("-%c: option requires an argument", c)
+					}
+					value, words = words[0], words[1:]
+				}
+				if c == 'p' {
+					opts.prompt = value
+				} else {
+					seconds, err := strconv.ParseFloat(value, 64)
+					if err != nil || seconds < 0 {
+						return opts, nil, fmt.Errorf("%s: invalid timeout specification", value)
+					}
+					opts.timeout = time.Duration(seconds * float64(time.Second))
+					opts.poll = seconds == 0
+				}
+				i = len(word)
+			default:
+				return opts, nil, fmt.Errorf("-%c: invalid option", c)
+			}
+		}
+	}
+	return opts, words, nil
+}
+
+// inputReader reads the input of read one byte at a time, so that the
+// input after the line remains for the commands that follow.
+type inputReader struct {
+	ctx      context.Context
+	r        io.Reader
+	deadline time.Time // Zero for no timeout
+	terminal bool      // Terminal in raw mode, for silent input
+}
+
+// ready returns true if input can be read from a file within the timeout.
+// Other readers are always ready.
+func (in *inputReader) ready(timeout time.Duration) bool {
+	f, ok := in.r.(*os.File)
+	if !ok {
+		return true
+	}
+	ready, err := waitInput(f, timeout)
+	return ready || err != nil
+}
+
+// readByte reads the next byte, giving up when the context is canceled or
+// the deadline passes while waiting for a file.
+func (in *inputReader) readByte() (byte, error) {
+	if f, ok := in.r.(*os.File); ok {
+		for {
+			if err := in.ctx.Err(); err != nil {
+				return 0, err
+			}
+			wait := pollInterval
+			if !in.deadline.IsZero() {
+				left := time.Until(in.deadline)
+				if left <= 0 {
+					return 0, errReadTimeout
+				}
+				wait = min(wait, left)
+			}
+			ready, err := waitInput(f, wait)
+			if ready || err != nil {
+				// On errors, fall back to a plain read
+				break
+			}
+		}
+	}
+
+	var b [1]byte
+	for {
+		n, err := in.r.Read(b[:])
+		if n == 1 {
+			return b[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readLine reads up to a newline or the end of input. Unless raw, a
+// backslash quotes the next character and a backslash-newline continues
+// the line. quoted marks the runes of the line that were escaped.
+// On a raw terminal, Enter ends the line, Backspace erases and Ctrl+C
+// interrupts.
+func (in *inputReader) readLine(raw bool) ([]rune, []bool, error) {
+	var buf []byte
+	var escaped []bool
+	escape := false
+
+	for {
+		b, err := in.readByte()
+		if err != nil {
+			line, quoted := decodeLine(buf, escaped)
+			return line, quoted, err
+		}
+
+		if in.terminal {
+			switch b {
+			case '\r':
+				b = '\n'
+			case 0x7f, '\b':
+				buf, escaped = eraseRune(buf, escaped)
+				continue
+			case 0x03:
+				line, quoted := decodeLine(buf, escaped)
+				return line, quoted, context.Canceled
+			case 0x04:
+				if len(buf) == 0 {
+					return nil, nil, io.EOF
+				}
+				continue
+			}
+		}
+
+		switch {
+		case escape:
+			escape = false
+			if b == '\n' {
+				continue
+			}
+			buf, escaped = append(buf, b), append(escaped, true)
+		case b == '\\' && !raw:
+			escape = true
+		case b == '\n':
+			line, quoted := decodeLine(buf, escaped)
+			return line, quoted, nil
+		default:
+			buf, escaped = append(buf, b), append(escaped, false)
+		}
+	}
+}
+
+// eraseRune removes the last rune of the input typed so far.
+func eraseRune(buf []byte, escaped []bool) ([]byte, []bool) {
+	if len(buf) == 0 {
+		return buf, escaped
+	}
+	_, size := utf8.DecodeLastRune(buf)
+	return buf[:len(buf)-size], escaped[:len(escaped)-size]
+}
+
+// decodeLine decodes the bytes of a line, marking the runes whose first
+// byte was escaped.
+func decodeLine(buf []byte, escaped []bool) ([]rune, []bool) {
+	var line []rune
+	var quoted []bool
+	for i := 0; i < len(buf); {
+		r, size := utf8.DecodeRune(buf[i:])
+		line = append(line, r)
+		quoted = append(quoted, escaped[i])
+		i += size
+	}
+	return line, quoted
+}
+
+// assignFields assigns the fields of a line to the variables, the last one
+// getting the rest of the line, or the whole line to REPLY without names.
+func assignFields(environment *env.Environment, names []string, line []rune, quoted []bool) {
+	if len(names) == 0 {
+		environment.Set("REPLY", string(line))
+		return
+	}
+
+	ifs := defaultIFS
+	if value, ok := environment.All()["IFS"]; ok {
+		ifs = value
+	}
+	fields := splitFields(line, quoted, ifs, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(fields) {
+			value = fields[i]
+		}
+		environment.Set(name, value)
+	}
+}
+
+// splitFields splits a line into at most n fields at the unquoted
+// characters of ifs; the last field gets the rest of the line. Runs of IFS
+// whitespace separate fields once, other IFS characters each end a field.
+func splitFields(line []rune, quoted []bool, ifs string, n int) []string {
+	isDelim := func(i int) bool {
+		return !quoted[i] && strings.ContainsRune(ifs, line[i])
+	}
+	isSpace := func(i int) bool {
+		return isDelim(i) && unicode.IsSpace(line[i])
+	}
+	skipSpace := func(i int) int {
+		for i < len(line) && isSpace(i) {
+			i++
+		}
+		return i
+	}
+
+	var fields []string
+	i := skipSpace(0)
+	for i < len(line) && len(fields) < n-1 {
+		start := i
+		for i < len(line) && !isDelim(i) {
+			i++
+		}
+		fields = append(fields, string(line[start:i]))
+		if i == len(line) {
+			return fields
+		}
+
+		// Consume the separator: whitespace around at most one other character
+		if isSpace(i) {
+			i = skipSpace(i)
+			if i < len(line) && isDelim(i) {
+				i = skipSpace(i + 1)
+			}
+		} else {
+			i = skipSpace(i + 1)
+		}
+	}
+
+	if i < len(line) {
+		end := len(line)
+		for end > i && isSpace(end-1) {
+			end--
+		}
+		fields = append(fields, string(line[i:end]))
+	}
+	return fields
+}
+
+func showReadHelp(execCtx *Context) {
+	help := `read - Read a line of input into variables
+
+Usage: read [-rs] [-p prompt] [-t timeout] [name...]
+
+Options:
+  -p PROMPT    Display PROMPT on standard error when reading from a terminal
+  -r           Do not treat backslashes as escapes
+  -s           Do not echo input from a terminal, e.g. for passwords
+  -t SECONDS   Fail if the line is not complete after SECONDS; -t 0 only
+               checks whether input is available
+      --help   Show this help message
+
+The line is split into fields at the characters of IFS (space, tab and
+newline by default) and assigned to the names in order; the last name gets
+the rest of the line. Without names, the line is assigned to REPLY.
+Unless -r is given, a backslash quotes the next character and a backslash
+at the end of a line continues it.
+
+Exit status is 0 if a line was read, 1 at the end of input, 142 on timeout.
+
+Examples:
+  read -p "Name: " name           Ask for a name
+  read -rs -p "Password: " pass   Ask for a password without echoing it
+  while read -r line; do echo "$line"; done < notes.txt
+  IFS=: read -r user _ uid _ < /etc/passwd
+`
+	execCtx.Stdout.Write([]byte(help))
+}
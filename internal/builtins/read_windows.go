@@ -0,0 +1,27 @@
+//go:build windows
+
+package builtins
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// waitInput waits until input can be read from a console without
+// blocking, for at most the timeout. Returns false if the timeout expired
+// first. Pipes and files are always reported ready, so that read -t only
+// times out on a console.
+func waitInput(f *os.File, timeout time.Duration) (bool, error) {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if windows.GetConsoleMode(handle, &mode) != nil {
+		return true, nil
+	}
+	event, err := windows.WaitForSingleObject(handle, uint32(timeout.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return event == windows.WAIT_OBJECT_0, nil
+}
@@ -11,6 +11,34 @@ func RegisterAll(r *Registry) {
 	r.Register(HelpDefinition())
 	r.Register(ClearDefinition())
 	r.Register(EnvDefinition())
+	r.Register(ExportDefinition())
+	r.Register(UnsetDefinition())
+	r.Register(SetDefinition())
+	r.Register(AliasDefinition())
+	r.Register(UnaliasDefinition())
+	r.Register(CalcDefinition())
+	r.Register(PrintfDefinition())
+
+	// Scripting commands
+	r.Register(TrueDefinition())
+	r.Register(FalseDefinition())
+	r.Register(BreakDefinition())
+	r.Register(ContinueDefinition())
+	r.Register(ReturnDefinition())
+	r.Register(LocalDefinition())
+	r.Register(SourceDefinition())
+	r.Register(DotDefinition())
+	r.Register(TrapDefinition())
+	r.Register(ReadDefinition())
+	r.Register(TestDefinition())
+	r.Register(BracketDefinition())
+
+	// Job control commands
+	r.Register(JobsDefinition())
+	r.Register(FgDefinition())
+	r.Register(BgDefinition())
+	r.Register(WaitDefinition())
+	r.Register(KillDefinition())
 
 	// File system commands
 	r.Register(CdDefinition())
@@ -5,23 +5,41 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 
 	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/jobs"
 	"github.com/sdejongh/jsishell/internal/parser"
 	"github.com/sdejongh/jsishell/internal/terminal"
 )
 
 // Context provides execution context to builtin commands.
 type Context struct {
-	Stdin   io.Reader             // Standard input
-	Stdout  io.Writer             // Standard output
-	Stderr  io.Writer             // Standard error
-	Env     *env.Environment      // Environment variables
-	WorkDir string                // Current working directory
-	Colors  *terminal.ColorScheme // Color scheme for output
+	Stdin     io.Reader             // Standard input
+	Stdout    io.Writer             // Standard output
+	Stderr    io.Writer             // Standard error
+	Env       *env.Environment      // Environment variables
+	WorkDir   string                // Current working directory
+	Colors    *terminal.ColorScheme // Color scheme for output
+	Aliases   *Aliases              // Command aliases
+	Functions *Functions            // Shell functions
+	Jobs      *jobs.Table           // Background and stopped jobs
+	Traps     *Traps                // Commands run on signals and shell events
+
+	// Run executes parsed commands in the state of the caller, for source
+	Run func(ctx context.Context, node parser.Node) (int, error)
+}
+
+// Path resolves a file name given to a command against the working
+// directory, which may differ from the process one inside a subshell.
+func (c *Context) Path(name string) string {
+	if c.WorkDir == "" || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return name
+	}
+	return filepath.Join(c.WorkDir, name)
 }
 
 // WriteError writes an error message to stderr with red color if colors are enabled.
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/sdejongh/jsishell/internal/glob"
 	"github.com/sdejongh/jsishell/internal/parser"
 )
 
@@ -65,7 +66,7 @@ func rmHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 			continue
 		}
 
-		info, err := os.Stat(path)
+		info, err := os.Stat(execCtx.Path(path))
 		if err != nil {
 			if os.IsNotExist(err) {
 				if !opts.force && !opts.quiet {
@@ -100,7 +101,7 @@ func rmHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 			}
 		} else {
 			// Remove file
-			if err := os.Remove(path); err != nil {
+			if err := os.Remove(execCtx.Path(path)); err != nil {
 				if !opts.quiet {
 					execCtx.WriteErrorln("rm: cannot remove '%s': %v", path, err)
 				}
@@ -119,7 +120,7 @@ func rmHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int,
 // matchesRmExcludePattern checks if a name matches any of the exclude patterns.
 func matchesRmExcludePattern(name string, patterns []string) bool {
 	for _, pattern := range patterns {
-		if matched, _ := filepath.Match(pattern, name); matched {
+		if glob.Match(pattern, name) {
 			return true
 		}
 	}
@@ -129,7 +130,7 @@ func matchesRmExcludePattern(name string, patterns []string) bool {
 // rmDirRecursive removes a directory recursively, respecting exclude patterns.
 func rmDirRecursive(path string, opts rmOptions, execCtx *Context) error {
 	// Read directory contents
-	entries, err := os.ReadDir(path)
+	entries, err := os.ReadDir(execCtx.Path(path))
 	if err != nil {
 		return err
 	}
@@ -149,7 +150,7 @@ func rmDirRecursive(path string, opts rmOptions, execCtx *Context) error {
 				return err
 			}
 		} else {
-			if err := os.Remove(entryPath); err != nil {
+			if err := os.Remove(execCtx.Path(entryPath)); err != nil {
 				return err
 			}
 			if opts.verbose && !opts.quiet {
@@ -159,14 +160,14 @@ func rmDirRecursive(path string, opts rmOptions, execCtx *Context) error {
 	}
 
 	// Check if directory is empty before removing it
-	remaining, err := os.ReadDir(path)
+	remaining, err := os.ReadDir(execCtx.Path(path))
 	if err != nil {
 		return err
 	}
 
 	// Only remove directory if it's empty (all contents were removed or excluded)
 	if len(remaining) == 0 {
-		if err := os.Remove(path); err != nil {
+		if err := os.Remove(execCtx.Path(path)); err != nil {
 			return err
 		}
 		if opts.verbose && !opts.quiet {
@@ -192,7 +193,8 @@ Options:
       --help             Show this help message
 
 Exclude patterns:
-  Standard glob patterns: *, ?, [abc], [a-z]
+  Glob patterns: *, ?, [abc], [a-z] and extended patterns such as
+  !(*.go) or *.@(log|tmp)
   Can be specified multiple times to exclude several patterns.
   When using --exclude with -r, directories containing excluded files
   will not be removed (they won't be empty).
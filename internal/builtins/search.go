@@ -97,7 +97,7 @@ func searchHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (
 	}
 
 	// Verify directory exists
-	info, err := os.Stat(searchDir)
+	info, err := os.Stat(execCtx.Path(searchDir))
 	if err != nil {
 		if os.IsNotExist(err) {
 			execCtx.WriteErrorln("search: %s: no such directory", searchDir)
@@ -126,7 +126,7 @@ func searchHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (
 // searchDirectoryWithExpr searches for files matching the expression in the given directory.
 func searchDirectoryWithExpr(dir string, expr SearchExpr, opts searchOptions, execCtx *Context, currentLevel int, found *bool) error {
 	// Read directory entries
-	entries, err := os.ReadDir(dir)
+	entries, err := os.ReadDir(execCtx.Path(dir))
 	if err != nil {
 		// Silently skip permission errors (common on Windows for system folders)
 		if os.IsPermission(err) {
@@ -156,7 +156,7 @@ func searchDirectoryWithExpr(dir string, expr SearchExpr, opts searchOptions, ex
 
 		// If it's a symlink, try to get the target
 		if fileInfo.IsLink {
-			if target, err := os.Readlink(fullPath); err == nil {
+			if target, err := os.Readlink(execCtx.Path(fullPath)); err == nil {
 				fileInfo.LinkTarget = target
 			}
 		}
@@ -167,7 +167,7 @@ func searchDirectoryWithExpr(dir string, expr SearchExpr, opts searchOptions, ex
 			// Determine display path
 			displayPath := fullPath
 			if opts.absolute {
-				if absPath, err := filepath.Abs(fullPath); err == nil {
+				if absPath, err := filepath.Abs(execCtx.Path(fullPath)); err == nil {
 					displayPath = absPath
 				}
 			}
@@ -246,6 +246,8 @@ Pattern syntax:
   ?             Matches any single character
   [abc]         Matches any character in the brackets
   [a-z]         Matches any character in the range
+  @(a|b)        Matches one of the patterns (also ?(..), *(..), +(..))
+  !(a|b)        Matches anything except the patterns
 
 Type predicates (case-insensitive):
   isFile        Match regular files only
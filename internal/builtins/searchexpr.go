@@ -3,9 +3,10 @@ package builtins
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"unicode"
+
+	"github.com/sdejongh/jsishell/internal/glob"
 )
 
 // FileInfo contains information about a file for expression evaluation.
@@ -31,11 +32,7 @@ type PatternExpr struct {
 }
 
 func (p *PatternExpr) Evaluate(info *FileInfo) bool {
-	matched, err := filepath.Match(p.Pattern, info.Name)
-	if err != nil {
-		return false
-	}
-	return matched
+	return glob.Match(p.Pattern, info.Name)
 }
 
 func (p *PatternExpr) String() string {
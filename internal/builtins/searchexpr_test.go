@@ -57,6 +57,9 @@ func TestPatternExpr(t *testing.T) {
 		{"*.?", "file.ab", false},
 		{"[abc]*", "afile", true},
 		{"[abc]*", "dfile", false},
+		{"*.@(go|md)", "README.md", true},
+		{"!(*_test).go", "main_test.go", false},
+		{"!(*_test).go", "main.go", true},
 	}
 
 	for _, tt := range tests {
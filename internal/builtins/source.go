@@ -0,0 +1,90 @@
+package builtins
+
+import (
+	"context"
+	"os"
+
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// SourceDefinition returns the source command definition.
+func SourceDefinition() Definition {
+	return Definition{
+		Name:        "source",
+		Description: "Run the commands of a file in the current shell",
+		Usage:       "source FILE [args...]",
+		Handler:     sourceHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// DotDefinition returns the definition of ".", the POSIX name of source.
+func DotDefinition() Definition {
+	def := SourceDefinition()
+	def.Name = "."
+	def.Usage = ". FILE [args...]"
+	return def
+}
+
+func sourceHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if len(cmd.Words) == 0 {
+		execCtx.WriteErrorln("source: missing file operand")
+		return 2, nil
+	}
+	if len(cmd.Words) == 1 && cmd.HasFlag("--help") {
+		showSourceHelp(execCtx)
+		return 0, nil
+	}
+	if execCtx.Run == nil {
+		execCtx.WriteErrorln("source: not supported in this context")
+		return 1, nil
+	}
+
+	name, args := cmd.Words[0], cmd.Words[1:]
+	data, err := os.ReadFile(execCtx.Path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			execCtx.WriteErrorln("source: %s: No such file or directory", name)
+		} else {
+			execCtx.WriteErrorln("source: %s: %v", name, err)
+		}
+		return 1, nil
+	}
+
+	node, err := parser.ParseScriptInput(string(data))
+	if err != nil {
+		execCtx.WriteErrorln("source: %s", shellerrors.Format(err, name))
+		return 2, nil
+	}
+
+	// Arguments replace the positional parameters while the file runs
+	if len(args) > 0 {
+		saved := execCtx.Env.Args()
+		execCtx.Env.SetArgs(args)
+		defer execCtx.Env.SetArgs(saved)
+	}
+
+	return execCtx.Run(ctx, node)
+}
+
+func showSourceHelp(execCtx *Context) {
+	help := `source - Run the commands of a file in the current shell
+
+Usage: source FILE [args...]
+       . FILE [args...]
+
+Description:
+  The commands run in the shell itself, so that the variables, functions,
+  aliases and working directory they set remain afterwards. Arguments
+  become the positional parameters ($1, $2, ...) while the file runs.
+  return leaves the file early.
+
+Examples:
+  source ~/team.jsi
+  . ./env.jsi staging
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -0,0 +1,380 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// TestDefinition returns the test command definition.
+func TestDefinition() Definition {
+	return Definition{
+		Name:        "test",
+		Description: "Evaluate a conditional expression",
+		Usage:       "test expression",
+		Handler:     testHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// BracketDefinition returns the [ command definition, test with a closing ].
+func BracketDefinition() Definition {
+	return Definition{
+		Name:        "[",
+		Description: "Evaluate a conditional expression",
+		Usage:       "[ expression ]",
+		Handler:     bracketHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func testHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	// Expressions are made of words such as -f, which are parsed as options
+	if len(cmd.Words) == 1 && cmd.Words[0] == "--help" {
+		showTestHelp(execCtx)
+		return 0, nil
+	}
+	return runTest("test", cmd.Words, execCtx), nil
+}
+
+func bracketHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	words := cmd.Words
+	if len(words) == 1 && words[0] == "--help" {
+		showTestHelp(execCtx)
+		return 0, nil
+	}
+	if len(words) == 0 || words[len(words)-1] != "]" {
+		execCtx.WriteErrorln("[: missing ']'")
+		return 2, nil
+	}
+	return runTest("[", words[:len(words)-1], execCtx), nil
+}
+
+// runTest evaluates an expression and returns the exit status of test:
+// 0 if it is true, 1 if it is false and 2 if it is invalid.
+func runTest(name string, args []string, execCtx *Context) int {
+	t := &testExpr{args: args, execCtx: execCtx}
+	result, err := t.eval()
+	if err != nil {
+		execCtx.WriteErrorln("%s: %v", name, err)
+		return 2
+	}
+	if result {
+		return 0
+	}
+	return 1
+}
+
+// testExpr evaluates the expression of test.
+type testExpr struct {
+	args    []string
+	pos     int
+	execCtx *Context
+}
+
+// eval evaluates the whole expression. Up to four arguments, the POSIX
+// rules based on their number apply, so that [ -n ] or [ = = = ] test
+// strings; longer expressions are parsed with -o, -a, ! and parentheses.
+func (t *testExpr) eval() (bool, error) {
+	args := t.args
+	switch len(args) {
+	case 0:
+		return false, nil
+	case 1:
+		return args[0] != "", nil
+	case 2:
+		if args[0] == "!" {
+			return args[1] == "", nil
+		}
+		if isUnaryTest(args[0]) {
+			return t.unary(args[0], args[1])
+		}
+		return false, fmt.Errorf("%s: unary operator expected", args[0])
+	case 3:
+		if isBinaryTest(args[1]) {
+			return t.binary(args[0], args[1], args[2])
+		}
+		if args[0] == "!" {
+			result, err := t.sub(args[1:]).eval()
+			return !result, err
+		}
+		if args[0] == "(" && args[2] == ")" {
+			return args[1] != "", nil
+		}
+	case 4:
+		if args[0] == "!" {
+			result, err := t.sub(args[1:]).eval()
+			return !result, err
+		}
+		if args[0] == "(" && args[3] == ")" {
+			return t.sub(args[1:3]).eval()
+		}
+	}
+
+	result, err := t.or()
+	if err != nil {
+		return false, err
+	}
+	if t.pos < len(t.args) {
+		return false, fmt.Errorf("%s: unexpected argument", t.args[t.pos])
+	}
+	return result, nil
+}
+
+// sub returns an expression for part of the arguments.
+func (t *testExpr) sub(args []string) *testExpr {
+	return &testExpr{args: args, execCtx: t.execCtx}
+}
+
+// or parses expr [-o expr]...
+func (t *testExpr) or() (bool, error) {
+	result, err := t.and()
+	for err == nil && t.peek() == "-o" {
+		t.pos++
+		var right bool
+		right, err = t.and()
+		result = result || right
+	}
+	return result, err
+}
+
+// and parses expr [-a expr]...
+func (t *testExpr) and() (bool, error) {
+	result, err := t.not()
+	for err == nil && t.peek() == "-a" {
+		t.pos++
+		var right bool
+		right, err = t.not()
+		result = result && right
+	}
+	return result, err
+}
+
+// not parses [!]... primary
+func (t *testExpr) not() (bool, error) {
+	if t.peek() == "!" && t.pos+1 < len(t.args) {
+		t.pos++
+		result, err := t.not()
+		return !result, err
+	}
+	return t.primary()
+}
+
+// primary parses a parenthesized expression, a unary or binary test, or a
+// string, which is true if it is not empty.
+func (t *testExpr) primary() (bool, error) {
+	if t.pos >= len(t.args) {
+		return false, fmt.Errorf("argument expected")
+	}
+	arg := t.args[t.pos]
+
+	if t.pos+2 < len(t.args) && isBinaryTest(t.args[t.pos+1]) {
+		t.pos += 3
+		return t.binary(arg, t.args[t.pos-2], t.args[t.pos-1])
+	}
+	if arg == "(" {
+		t.pos++
+		result, err := t.or()
+		if err != nil {
+			return false, err
+		}
+		if t.peek() != ")" {
+			return false, fmt.Errorf("')' expected")
+		}
+		t.pos++
+		return result, nil
+	}
+	if isUnaryTest(arg) && t.pos+1 < len(t.args) {
+		t.pos += 2
+		return t.unary(arg, t.args[t.pos-1])
+	}
+	t.pos++
+	return arg != "", nil
+}
+
+// peek returns the current argument, or "" at the end.
+func (t *testExpr) peek() string {
+	if t.pos < len(t.args) {
+		return t.args[t.pos]
+	}
+	return ""
+}
+
+// isUnaryTest returns true if op is a unary operator of test.
+func isUnaryTest(op string) bool {
+	switch op {
+	case "-e", "-f", "-d", "-x", "-r", "-w", "-s", "-L", "-h", "-p", "-S", "-b", "-c", "-n", "-z":
+		return true
+	}
+	return false
+}
+
+// isBinaryTest returns true if op is a binary operator of test.
+func isBinaryTest(op string) bool {
+	switch op {
+	case "=", "==", "!=", "<", ">", "-eq", "-ne", "-lt", "-le", "-gt", "-ge", "-nt", "-ot", "-ef":
+		return true
+	}
+	return false
+}
+
+// unary evaluates a string test or a test on a file.
+func (t *testExpr) unary(op, arg string) (bool, error) {
+	switch op {
+	case "-n":
+		return arg != "", nil
+	case "-z":
+		return arg == "", nil
+	}
+
+	path := t.execCtx.Path(arg)
+	if op == "-L" || op == "-h" {
+		info, err := os.Lstat(path)
+		return err == nil && info.Mode()&os.ModeSymlink != 0, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, nil
+	}
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular(), nil
+	case "-d":
+		return mode.IsDir(), nil
+	case "-s":
+		return info.Size() > 0, nil
+	case "-p":
+		return mode&os.ModeNamedPipe != 0, nil
+	case "-S":
+		return mode&os.ModeSocket != 0, nil
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0, nil
+	case "-c":
+		return mode&os.ModeCharDevice != 0, nil
+	case "-r", "-w", "-x":
+		return fileAccess(path, info, op[1]), nil
+	}
+	// -e
+	return true, nil
+}
+
+// binary evaluates a string, integer or file comparison.
+func (t *testExpr) binary(left, op, right string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot":
+		return t.newer(left, right, op == "-nt"), nil
+	case "-ef":
+		a, errA := os.Stat(t.execCtx.Path(left))
+		b, errB := os.Stat(t.execCtx.Path(right))
+		return errA == nil && errB == nil && os.SameFile(a, b), nil
+	}
+
+	a, err := testInteger(left)
+	if err != nil {
+		return false, err
+	}
+	b, err := testInteger(right)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	default: // -ge
+		return a >= b, nil
+	}
+}
+
+// newer returns true if file a was modified after file b, or if only a
+// exists. With older, the files are swapped.
+func (t *testExpr) newer(a, b string, newer bool) bool {
+	if !newer {
+		a, b = b, a
+	}
+	infoA, err := os.Stat(t.execCtx.Path(a))
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(t.execCtx.Path(b))
+	if err != nil {
+		return true
+	}
+	return infoA.ModTime().After(infoB.ModTime())
+}
+
+// testInteger parses an operand of an integer comparison.
+func testInteger(s string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: integer expression expected", s)
+	}
+	return n, nil
+}
+
+func showTestHelp(execCtx *Context) {
+	help := `test - Evaluate a conditional expression
+
+Usage: test expression
+       [ expression ]
+
+Exit status is 0 if the expression is true, 1 if it is false and 2 if it
+is invalid.
+
+Files:
+  -e FILE    FILE exists
+  -f FILE    FILE is a regular file
+  -d FILE    FILE is a directory
+  -L FILE    FILE is a symbolic link (also -h)
+  -r/-w/-x FILE   FILE is readable/writable/executable
+  -s FILE    FILE is not empty
+  -p/-S/-b/-c FILE   FILE is a named pipe/socket/block/character device
+  A -nt B    A is newer than B, or only A exists (-ot: older)
+  A -ef B    A and B are the same file
+
+Strings:
+  -n STR     STR is not empty (also STR alone)
+  -z STR     STR is empty
+  A = B      Strings are equal (also ==); != compares them, and quoted
+             '<' '>' order them
+
+Integers:
+  A -eq B    Also -ne -lt -le -gt -ge
+
+Expressions:
+  ! EXPR     EXPR is false
+  A -a B     Both are true
+  A -o B     Either is true
+  '(' EXPR ')'  Grouping; parentheses must be quoted
+
+Examples:
+  [ -d build ] || mkdir build
+  if [ "$#" -lt 2 ]; then echo "usage: $0 src dst"; fi
+  test -f go.mod -a ! -f go.sum && echo "no dependencies"
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package builtins
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileAccess returns true if the shell may read ('r'), write ('w') or
+// execute ('x') the file, as decided by the system for its user.
+func fileAccess(path string, info os.FileInfo, access byte) bool {
+	mode := map[byte]uint32{'r': unix.R_OK, 'w': unix.W_OK, 'x': unix.X_OK}[access]
+	return unix.Access(path, mode) == nil
+}
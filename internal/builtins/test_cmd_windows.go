@@ -0,0 +1,29 @@
+//go:build windows
+
+package builtins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileAccess returns true if the file can be read ('r'), written ('w') or
+// executed ('x'). Windows has no execute permission: directories and files
+// with an executable extension are executable.
+func fileAccess(path string, info os.FileInfo, access byte) bool {
+	switch access {
+	case 'w':
+		return info.Mode().Perm()&0200 != 0
+	case 'x':
+		if info.IsDir() {
+			return true
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".cmd", ".bat", ".com", ".ps1":
+			return true
+		}
+		return false
+	}
+	return true
+}
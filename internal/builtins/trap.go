@@ -0,0 +1,140 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// TrapDefinition returns the trap command definition.
+func TrapDefinition() Definition {
+	return Definition{
+		Name:        "trap",
+		Description: "Run commands on signals and shell events",
+		Usage:       "trap [-p] [COMMAND] [SIGNAL...]",
+		Handler:     trapHandler,
+		Options: []OptionDef{
+			{Short: "-p", Description: "Print the traps that are set"},
+			{Short: "-l", Description: "List the signal names"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func trapHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	words := cmd.Words
+	if len(words) > 0 && words[0] == "--" {
+		words = words[1:]
+	} else if len(words) > 0 {
+		switch words[0] {
+		case "--help":
+			showTrapHelp(execCtx)
+			return 0, nil
+		case "-l":
+			fmt.Fprintln(execCtx.Stdout, strings.Join(TrapNames, " "))
+			return 0, nil
+		case "-p":
+			return printTraps(words[1:], execCtx), nil
+		}
+	}
+
+	if execCtx.Traps == nil {
+		execCtx.WriteErrorln("trap: not supported in this context")
+		return 1, nil
+	}
+	if len(words) == 0 {
+		return printTraps(nil, execCtx), nil
+	}
+
+	// A single signal name, or - as command, resets the traps
+	command, specs := words[0], words[1:]
+	reset := command == "-"
+	if len(words) == 1 && TrapName(command) != "" {
+		specs, reset = words, true
+	}
+	if len(specs) == 0 {
+		execCtx.WriteErrorln("trap: missing signal name")
+		return 2, nil
+	}
+
+	status := 0
+	for _, spec := range specs {
+		name := TrapName(spec)
+		switch {
+		case name == "":
+			execCtx.WriteErrorln("trap: %s: invalid signal specification", spec)
+			status = 1
+		case reset:
+			execCtx.Traps.Remove(name)
+		default:
+			execCtx.Traps.Set(name, command)
+		}
+	}
+	return status, nil
+}
+
+// printTraps prints the traps of the given signals, or all the traps that
+// are set, as commands that set them again.
+func printTraps(specs []string, execCtx *Context) int {
+	if execCtx.Traps == nil {
+		return 0
+	}
+	names := execCtx.Traps.Names()
+	status := 0
+	if len(specs) > 0 {
+		names = nil
+		for _, spec := range specs {
+			name := TrapName(spec)
+			if name == "" {
+				execCtx.WriteErrorln("trap: %s: invalid signal specification", spec)
+				status = 1
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		if command, ok := execCtx.Traps.Get(name); ok {
+			fmt.Fprintf(execCtx.Stdout, "trap -- %s %s\n", quoteTrap(command), name)
+		}
+	}
+	return status
+}
+
+// quoteTrap quotes a trap command with single quotes.
+func quoteTrap(command string) string {
+	return "'" + strings.ReplaceAll(command, "'", `'\''`) + "'"
+}
+
+func showTrapHelp(execCtx *Context) {
+	help := `trap - Run commands on signals and shell events
+
+Usage: trap [-p] [COMMAND] [SIGNAL...]
+
+Signals and events:
+  EXIT   The shell exits (also 0)
+  INT    Interrupt, Ctrl+C (also 2)
+  TERM   Termination request (also 15)
+  ERR    A command fails, outside of conditions and && or || lists
+  DEBUG  Before each simple command
+
+Description:
+  COMMAND runs when one of the signals is received or the event happens.
+  An empty COMMAND ignores the signals; "-" or no COMMAND resets them.
+  Without arguments, or with -p, the traps are printed. ERR and DEBUG
+  traps do not apply within functions and subshells.
+
+Options:
+  -p     Print the traps that are set
+  -l     List the signal names
+
+Examples:
+  tmp=$(mktemp -d); trap 'rm -rf "$tmp"' EXIT
+  trap 'echo interrupted' INT
+  trap - INT
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -0,0 +1,118 @@
+package builtins
+
+import (
+	"strings"
+	"sync"
+)
+
+// TrapNames are the signals and shell events a trap can be set on, in the
+// order they are listed.
+var TrapNames = []string{"EXIT", "INT", "TERM", "ERR", "DEBUG"}
+
+// trapNumbers maps signal numbers to trap names.
+var trapNumbers = map[string]string{"0": "EXIT", "2": "INT", "15": "TERM"}
+
+// TrapName returns the trap name of a signal specification, such as INT for
+// "int", "SIGINT" or "2". Returns "" if the specification is not valid.
+func TrapName(spec string) string {
+	if name, ok := trapNumbers[spec]; ok {
+		return name
+	}
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	for _, n := range TrapNames {
+		if n == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// Traps holds the commands run on signals and shell events, set with the
+// trap command, and the signals caught but not handled yet.
+type Traps struct {
+	mu       sync.Mutex
+	commands map[string]string
+	pending  []string
+}
+
+// NewTraps creates a new empty trap table.
+func NewTraps() *Traps {
+	return &Traps{
+		commands: make(map[string]string),
+	}
+}
+
+// Subshell returns the table of a subshell, which changes it without
+// affecting the shell. As in POSIX shells, the traps running a command are
+// reset in the subshell, and only ignored signals stay ignored. Caught
+// signals are not copied.
+func (t *Traps) Subshell() *Traps {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	commands := make(map[string]string)
+	for name, command := range t.commands {
+		if command == "" {
+			commands[name] = command
+		}
+	}
+	return &Traps{commands: commands}
+}
+
+// Set defines the command of a trap. An empty command ignores the signal.
+func (t *Traps) Set(name, command string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.commands[name] = command
+}
+
+// Get returns the command of a trap.
+// Returns the command and true if the trap is set, "" and false otherwise.
+func (t *Traps) Get(name string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	command, ok := t.commands[name]
+	return command, ok
+}
+
+// Remove resets a trap to the default behavior.
+func (t *Traps) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.commands, name)
+}
+
+// Names returns the names of the traps that are set, in TrapNames order.
+func (t *Traps) Names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var names []string
+	for _, name := range TrapNames {
+		if _, ok := t.commands[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Catch records a signal whose trap must run at the next opportunity.
+// Returns false if no trap is set for it, in which case the signal has its
+// default effect. An ignored signal is caught but nothing runs.
+func (t *Traps) Catch(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	command, ok := t.commands[name]
+	if ok && command != "" {
+		t.pending = append(t.pending, name)
+	}
+	return ok
+}
+
+// TakePending returns the signals caught since the last call.
+func (t *Traps) TakePending() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pending := t.pending
+	t.pending = nil
+	return pending
+}
@@ -0,0 +1,60 @@
+package builtins
+
+import (
+	"context"
+
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// TrueDefinition returns the true command definition.
+func TrueDefinition() Definition {
+	return Definition{
+		Name:        "true",
+		Description: "Do nothing, successfully",
+		Usage:       "true",
+		Handler:     trueHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// FalseDefinition returns the false command definition.
+func FalseDefinition() Definition {
+	return Definition{
+		Name:        "false",
+		Description: "Do nothing, unsuccessfully",
+		Usage:       "false",
+		Handler:     falseHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func trueHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		execCtx.Stdout.Write([]byte(`true - Do nothing, successfully
+
+Usage: true
+
+Description:
+  Returns exit status 0. Useful as a condition, as in: while true; do ...; done
+`))
+	}
+	return 0, nil
+}
+
+func falseHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		execCtx.Stdout.Write([]byte(`false - Do nothing, unsuccessfully
+
+Usage: false
+
+Description:
+  Returns exit status 1.
+`))
+		return 0, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,329 @@
+package builtins
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/env"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// ExportDefinition returns the export command definition.
+func ExportDefinition() Definition {
+	return Definition{
+		Name:        "export",
+		Description: "Export variables to commands",
+		Usage:       "export [-n] [name[=value]...]",
+		Handler:     exportHandler,
+		Options: []OptionDef{
+			{Short: "-n", Description: "Stop exporting the variables"},
+			{Short: "-p", Description: "Display exported variables"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// UnsetDefinition returns the unset command definition.
+func UnsetDefinition() Definition {
+	return Definition{
+		Name:        "unset",
+		Description: "Remove variables or functions",
+		Usage:       "unset [-v|-f] name...",
+		Handler:     unsetHandler,
+		Options: []OptionDef{
+			{Short: "-v", Description: "Remove variables (default)"},
+			{Short: "-f", Description: "Remove functions"},
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+// SetDefinition returns the set command definition.
+func SetDefinition() Definition {
+	return Definition{
+		Name:        "set",
+		Description: "Display variables, set shell options or positional parameters",
+		Usage:       "set [-euxH] [-o|+o [option]]... [--] [arg...]",
+		Handler:     setHandler,
+		Options: []OptionDef{
+			{Long: "--help", Description: "Show help message"},
+		},
+	}
+}
+
+func exportHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showExportHelp(execCtx)
+		return 0, nil
+	}
+
+	// Without names, display the exported variables
+	if len(cmd.Args) == 0 {
+		names := execCtx.Env.Exported()
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(execCtx.Stdout, "export %s=%s\n", name, shellQuote(execCtx.Env.Get(name)))
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, arg := range cmd.Args {
+		name, value, isAssignment := strings.Cut(arg, "=")
+		if !env.ValidName(name) {
+			execCtx.WriteErrorln("export: %s: not a valid identifier", name)
+			status = 1
+			continue
+		}
+		if isAssignment {
+			execCtx.Env.Set(name, value)
+		}
+		if cmd.HasFlag("-n") {
+			execCtx.Env.Unexport(name)
+			continue
+		}
+		// A variable exported before it is set gets an empty value
+		if _, exists := execCtx.Env.All()[name]; !exists {
+			execCtx.Env.Set(name, "")
+		}
+		execCtx.Env.Export(name)
+	}
+	return status, nil
+}
+
+func unsetHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showUnsetHelp(execCtx)
+		return 0, nil
+	}
+
+	for flag := range cmd.Flags {
+		if flag != "-v" && flag != "-f" {
+			execCtx.WriteErrorln("unset: %s: invalid option", flag)
+			return 1, nil
+		}
+	}
+
+	// Removing a function that is not defined is not an error
+	if cmd.HasFlag("-f") {
+		for _, name := range cmd.Args {
+			if execCtx.Functions != nil {
+				execCtx.Functions.Remove(name)
+			}
+		}
+		return 0, nil
+	}
+
+	status := 0
+	for _, name := range cmd.Args {
+		if !env.ValidName(name) {
+			execCtx.WriteErrorln("unset: %s: not a valid identifier", name)
+			status = 1
+			continue
+		}
+		execCtx.Env.Unset(name)
+	}
+	return status, nil
+}
+
+func setHandler(ctx context.Context, cmd *parser.Command, execCtx *Context) (int, error) {
+	if cmd.HasFlag("--help") {
+		showSetHelp(execCtx)
+		return 0, nil
+	}
+
+	// Without arguments, display all variables
+	words := cmd.Words
+	if len(words) == 0 {
+		vars := execCtx.Env.All()
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(execCtx.Stdout, "%s=%s\n", name, shellQuote(vars[name]))
+		}
+		return 0, nil
+	}
+
+	// -o NAME turns an option on, +o NAME turns it off; letters such as
+	// -eu or +x stand for options and can be grouped with o, as in -euo NAME
+	for len(words) > 0 && len(words[0]) > 1 && words[0] != "--" && strings.IndexByte("-+", words[0][0]) >= 0 {
+		flag := words[0]
+		on := flag[0] == '-'
+		words = words[1:]
+		for _, c := range flag[1:] {
+			name, ok := shortShellOptions[c]
+			if c == 'o' {
+				if len(words) == 0 {
+					printShellOptions(execCtx, on)
+					return 0, nil
+				}
+				name, words = words[0], words[1:]
+				if _, ok := shellOptions[name]; !ok {
+					execCtx.WriteErrorln("set: %s: invalid option name", name)
+					return 1, nil
+				}
+			} else if !ok {
+				execCtx.WriteErrorln("set: %c%c: invalid option", flag[0], c)
+				return 1, nil
+			}
+			execCtx.Env.SetOption(name, on)
+		}
+		if len(words) == 0 {
+			return 0, nil
+		}
+	}
+
+	switch {
+	case words[0] == "--":
+		words = words[1:]
+	case words[0] == "-" || words[0] == "+":
+		execCtx.WriteErrorln("set: %s: invalid option", words[0])
+		return 1, nil
+	}
+
+	// The remaining arguments replace the positional parameters
+	execCtx.Env.SetArgs(words)
+	return 0, nil
+}
+
+// shellOptions are the options set -o turns on, with their description.
+var shellOptions = map[string]string{
+	"errexit":    "Exit when a command fails outside of a condition (-e)",
+	"failglob":   "A glob matching no file is an error",
+	"histexpand": "Expand history references such as !! in typed commands (-H)",
+	"histverify": "Edit the expanded command instead of running it",
+	"nounset":    "Expanding a variable that is not set is an error (-u)",
+	"nullglob":   "A glob matching no file expands to nothing",
+	"pipefail":   "A pipeline fails with the status of its last failing command",
+	"xtrace":     "Print each command before running it, after PS4 (-x)",
+}
+
+// shortShellOptions are the shell options set -X turns on.
+var shortShellOptions = map[rune]string{
+	'H': "histexpand",
+	'e': "errexit",
+	'u': "nounset",
+	'x': "xtrace",
+}
+
+// printShellOptions displays the state of the shell options as a table or,
+// if table is false, as set commands that restore them.
+func printShellOptions(execCtx *Context, table bool) {
+	names := make([]string, 0, len(shellOptions))
+	for name := range shellOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		on := execCtx.Env.Option(name)
+		switch {
+		case table && on:
+			fmt.Fprintf(execCtx.Stdout, "%-15s on\n", name)
+		case table:
+			fmt.Fprintf(execCtx.Stdout, "%-15s off\n", name)
+		case on:
+			fmt.Fprintf(execCtx.Stdout, "set -o %s\n", name)
+		default:
+			fmt.Fprintf(execCtx.Stdout, "set +o %s\n", name)
+		}
+	}
+}
+
+// shellQuote quotes s with single quotes so that the shell reads it back as is.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+func showExportHelp(execCtx *Context) {
+	help := `export - Export variables to commands
+
+Usage: export [-n] [name[=value]...]
+
+Description:
+  Exported variables are passed to the environment of the commands the
+  shell runs. Without arguments, displays the exported variables.
+
+  Variables assigned with NAME=value stay in the shell unless exported.
+  NAME=value before a command exports NAME to that command only.
+
+Options:
+  -n       Stop exporting the variables, keeping their values
+  -p       Display the exported variables
+  --help   Show this help message
+
+Examples:
+  export EDITOR=vim          Set and export EDITOR
+  export PATH=$PATH:~/bin    Add a directory to PATH
+  export -n DEBUG            Keep DEBUG in the shell only
+  LANG=C sort names.txt      Export LANG to sort only
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showUnsetHelp(execCtx *Context) {
+	help := `unset - Remove variables or functions
+
+Usage: unset [-v|-f] name...
+
+Options:
+  -v     Remove variables (default)
+  -f     Remove functions, bringing back the commands they hid
+
+Description:
+  Removes the variables from the shell and from the environment of the
+  commands it runs. Within a function, removes local variables first.
+
+Examples:
+  unset DEBUG                Remove DEBUG
+  unset -f ls                Remove the ls function, ls is the builtin again
+`
+	execCtx.Stdout.Write([]byte(help))
+}
+
+func showSetHelp(execCtx *Context) {
+	help := `set - Display variables, set shell options or positional parameters
+
+Usage: set [-euxH] [-o|+o [option]]... [--] [arg...]
+
+Description:
+  Without arguments, displays all variables of the shell.
+  With arguments, replaces the positional parameters ($1, $2, ...).
+  Use -- to set parameters starting with a dash, or to clear them.
+
+Options:
+  -o option  Turn a shell option on (without option, display them all)
+  +o option  Turn a shell option off (without option, display them as commands)
+  -e, -u, -x Turn errexit, nounset or xtrace on (+e, +u, +x turn them off)
+  -H         Turn histexpand on (+H turns it off)
+  --help     Show this help message
+
+Shell options:
+  errexit    Exit when a command fails, except in the condition of an if,
+             while or until, or before the last && or ||
+  nounset    Expanding a variable that is not set is an error
+  pipefail   A pipeline fails with the status of its last failing command,
+             not only with that of its last command
+  xtrace     Print each command to stderr before running it, with its
+             resolved name and expanded arguments, after $PS4 ("+ ")
+  nullglob   A glob matching no file expands to nothing
+  failglob   A glob matching no file is an error: the command does not run
+  histexpand Expand history references such as !!, !$ or ^old^new in the
+             commands typed at the prompt (on in interactive shells)
+  histverify Put the expanded command in the editor to check it, instead of
+             running it
+
+Examples:
+  set -euo pipefail          Stop scripts at the first error
+  set -o nullglob            Drop globs that match no file
+  set a b c                  Set $1, $2 and $3
+  set -- -v file             Set $1 to -v and $2 to file
+  set --                     Clear the positional parameters
+`
+	execCtx.Stdout.Write([]byte(help))
+}
@@ -19,6 +19,15 @@ const (
 	DefaultPrompt      = "%{green}%u@%h%{/}:%{blue}%~%{/}%$ "
 	DefaultHistorySize = 1000
 	DefaultHistoryFile = ".jsishell_history"
+
+	// ProfileFile is run by login shells and RCFile by interactive shells,
+	// from the system-wide directory then from the configuration directory.
+	ProfileFile = "profile.jsi"
+	RCFile      = "rc.jsi"
+
+	// DefaultContinuationPrompt is shown on the continuation lines of a
+	// multi-line command, like PS2.
+	DefaultContinuationPrompt = "> "
 )
 
 // Valid color names for terminal output.
@@ -43,11 +52,14 @@ var validColors = map[string]bool{
 
 // Config represents the shell configuration.
 type Config struct {
-	Prompt        string              `yaml:"prompt"`
-	History       HistoryConfig       `yaml:"history"`
-	Colors        ColorScheme         `yaml:"colors"`
-	Abbreviations AbbreviationsConfig `yaml:"abbreviations"`
-	Editor        EditorConfig        `yaml:"editor"`
+	Prompt             string              `yaml:"prompt"`
+	ContinuationPrompt string              `yaml:"continuation_prompt"`
+	History            HistoryConfig       `yaml:"history"`
+	Colors             ColorScheme         `yaml:"colors"`
+	Abbreviations      AbbreviationsConfig `yaml:"abbreviations"`
+	Editor             EditorConfig        `yaml:"editor"`
+	Aliases            map[string]string   `yaml:"aliases"`
+	Hooks              HooksConfig         `yaml:"hooks"`
 }
 
 // HistoryConfig holds history-related settings.
@@ -56,6 +68,7 @@ type HistoryConfig struct {
 	File              string `yaml:"file"`
 	IgnoreDuplicates  bool   `yaml:"ignore_duplicates"`
 	IgnoreSpacePrefix bool   `yaml:"ignore_space_prefix"`
+	ShareHistory      bool   `yaml:"share_history"`
 }
 
 // ColorScheme defines colors for different output types.
@@ -82,10 +95,20 @@ type EditorConfig struct {
 	TabWidth int `yaml:"tab_width"`
 }
 
+// HooksConfig holds shell code run at points of the interactive loop.
+// Hooks may call functions defined in the rc file.
+type HooksConfig struct {
+	OnStart string `yaml:"on_start"` // Once, before the first prompt
+	Preexec string `yaml:"preexec"`  // Before each command line, given as $1
+	Precmd  string `yaml:"precmd"`   // Before each prompt
+	Chpwd   string `yaml:"chpwd"`    // After a command changed the working directory
+}
+
 // Default returns the default configuration.
 func Default() *Config {
 	return &Config{
-		Prompt: DefaultPrompt,
+		Prompt:             DefaultPrompt,
+		ContinuationPrompt: DefaultContinuationPrompt,
 		History: HistoryConfig{
 			MaxSize:           DefaultHistorySize,
 			File:              filepath.Join(ConfigDir(), DefaultHistoryFile),
@@ -134,6 +157,15 @@ func ConfigPath() string {
 	return filepath.Join(ConfigDir(), "config.yaml")
 }
 
+// StartupFiles returns the paths of a startup file in the system-wide
+// directory and in the configuration directory, in the order they run.
+func StartupFiles(name string) []string {
+	return []string{
+		filepath.Join(SystemConfigDir(), name),
+		filepath.Join(ConfigDir(), name),
+	}
+}
+
 // ExpandPath expands ~ to the user's home directory.
 func ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
@@ -199,6 +231,9 @@ func (c *Config) Merge(other *Config) *Config {
 	if other.Prompt != "" {
 		result.Prompt = other.Prompt
 	}
+	if other.ContinuationPrompt != "" {
+		result.ContinuationPrompt = other.ContinuationPrompt
+	}
 
 	// Merge history
 	if other.History.MaxSize != 0 {
@@ -210,6 +245,10 @@ func (c *Config) Merge(other *Config) *Config {
 	// Booleans are tricky - we can't distinguish false from unset
 	// For now, we only override if the YAML explicitly sets them
 	// This is handled by the YAML parser
+	// share_history is off by default, so setting it can only turn it on
+	if other.History.ShareHistory {
+		result.History.ShareHistory = true
+	}
 
 	// Merge colors
 	if other.Colors.Prompt != "" {
@@ -245,6 +284,32 @@ func (c *Config) Merge(other *Config) *Config {
 		result.Editor.TabWidth = other.Editor.TabWidth
 	}
 
+	// Merge hooks
+	if other.Hooks.OnStart != "" {
+		result.Hooks.OnStart = other.Hooks.OnStart
+	}
+	if other.Hooks.Preexec != "" {
+		result.Hooks.Preexec = other.Hooks.Preexec
+	}
+	if other.Hooks.Precmd != "" {
+		result.Hooks.Precmd = other.Hooks.Precmd
+	}
+	if other.Hooks.Chpwd != "" {
+		result.Hooks.Chpwd = other.Hooks.Chpwd
+	}
+
+	// Merge aliases (user aliases override aliases of the same name)
+	if len(other.Aliases) > 0 {
+		aliases := make(map[string]string, len(c.Aliases)+len(other.Aliases))
+		for name, value := range c.Aliases {
+			aliases[name] = value
+		}
+		for name, value := range other.Aliases {
+			aliases[name] = value
+		}
+		result.Aliases = aliases
+	}
+
 	return &result
 }
 
@@ -274,6 +339,13 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate aliases
+	for name := range c.Aliases {
+		if !IsValidAliasName(name) {
+			return fmt.Errorf("invalid alias name %q", name)
+		}
+	}
+
 	// Validate editor
 	if c.Editor.TabWidth < 1 {
 		c.Editor.TabWidth = 4 // Reset to default
@@ -287,6 +359,12 @@ func IsValidColor(color string) bool {
 	return validColors[color]
 }
 
+// IsValidAliasName checks if name can be used as an alias name.
+// Names cannot contain whitespace, quotes, expansions, operators or slashes.
+func IsValidAliasName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n\"'`$=/|&;<>(){}\\") && !strings.HasPrefix(name, "-")
+}
+
 // Save saves the configuration to a file.
 func (c *Config) Save(path string) error {
 	path = ExpandPath(path)
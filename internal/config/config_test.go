@@ -108,6 +108,10 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Default().Prompt = %q, want %q", cfg.Prompt, DefaultPrompt)
 	}
 
+	if cfg.ContinuationPrompt != DefaultContinuationPrompt {
+		t.Errorf("Default().ContinuationPrompt = %q, want %q", cfg.ContinuationPrompt, DefaultContinuationPrompt)
+	}
+
 	if cfg.History.MaxSize != DefaultHistorySize {
 		t.Errorf("Default().History.MaxSize = %d, want %d", cfg.History.MaxSize, DefaultHistorySize)
 	}
@@ -166,6 +170,14 @@ history:
 			yaml: `
 colors:
   directory: not_a_color
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid alias name",
+			yaml: `
+aliases:
+  "l l": ls -l
 `,
 			wantErr: true,
 		},
@@ -245,6 +257,100 @@ func TestConfigMerge(t *testing.T) {
 	}
 }
 
+func TestConfigAliases(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	yaml := `
+aliases:
+  ll: ls -l
+  gs: git status
+`
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if cfg.Aliases["ll"] != "ls -l" || cfg.Aliases["gs"] != "git status" {
+		t.Errorf("Aliases = %v", cfg.Aliases)
+	}
+
+	base := &Config{Aliases: map[string]string{"ll": "ls", "la": "ls -a"}}
+	merged := base.Merge(cfg)
+	if merged.Aliases["ll"] != "ls -l" || merged.Aliases["la"] != "ls -a" || len(merged.Aliases) != 3 {
+		t.Errorf("Merged Aliases = %v", merged.Aliases)
+	}
+}
+
+func TestConfigHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	yaml := `
+hooks:
+  on_start: echo hello
+  chpwd: auto_env
+`
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	base := &Config{Hooks: HooksConfig{OnStart: "echo hi", Precmd: "jobs"}}
+	merged := base.Merge(cfg)
+	want := HooksConfig{OnStart: "echo hello", Precmd: "jobs", Chpwd: "auto_env"}
+	if merged.Hooks != want {
+		t.Errorf("Merged Hooks = %+v, want %+v", merged.Hooks, want)
+	}
+}
+
+func TestConfigShareHistory(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("history:\n  share_history: true\n"), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	if Default().History.ShareHistory {
+		t.Error("Default().History.ShareHistory should be false")
+	}
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	if !cfg.History.ShareHistory || cfg.History.MaxSize != DefaultHistorySize {
+		t.Errorf("History = %+v, want share_history on and default max_size", cfg.History)
+	}
+}
+
+func TestIsValidAliasName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"ll", true},
+		{"git-st", true},
+		{"..", true},
+		{"", false},
+		{"-l", false},
+		{"a b", false},
+		{"a=b", false},
+		{"a/b", false},
+		{"a|b", false},
+		{"$a", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidAliasName(tt.name); got != tt.want {
+			t.Errorf("IsValidAliasName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestValidColors(t *testing.T) {
 	validColors := []string{
 		"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
@@ -265,3 +371,16 @@ func TestValidColors(t *testing.T) {
 		}
 	}
 }
+
+func TestStartupFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join("home", "config"))
+
+	want := []string{
+		filepath.Join(SystemConfigDir(), RCFile),
+		filepath.Join("home", "config", "jsishell", RCFile),
+	}
+	got := StartupFiles(RCFile)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("StartupFiles(%q) = %v, want %v", RCFile, got, want)
+	}
+}
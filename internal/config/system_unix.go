@@ -0,0 +1,8 @@
+//go:build !windows
+
+package config
+
+// SystemConfigDir returns the directory of the system-wide startup files.
+func SystemConfigDir() string {
+	return "/etc/jsishell"
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SystemConfigDir returns the directory of the system-wide startup files.
+func SystemConfigDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "jsishell")
+}
@@ -3,8 +3,10 @@
 package env
 
 import (
+	"maps"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -14,6 +16,12 @@ type Environment struct {
 	mu      sync.RWMutex
 	vars    map[string]string // Current shell variables
 	exports map[string]bool   // Variables marked for export to child processes
+	name    string            // Script or shell name ($0)
+	args    []string          // Positional parameters ($1, $2, ...)
+	parent  *Environment      // Enclosing scope of a function call, nil at top level
+	status  int               // Exit status of the last command ($?)
+	lastJob string            // Job spec of the last background job ($!)
+	options map[string]bool   // Shell options turned on with set -o
 }
 
 // New creates a new Environment initialized with current OS environment.
@@ -37,15 +45,174 @@ func New() *Environment {
 }
 
 // Get returns the value of an environment variable.
+// Positional and special parameters ($0, $1, $#, $@, $*, $?, $$, $!) are
+// also resolved.
 // Returns empty string if the variable is not set.
 func (e *Environment) Get(key string) string {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
-	return e.vars[key]
+	return e.lookup(key)
+}
+
+// Lookup returns the value of a variable or parameter, like Get, and
+// whether it is set. Special parameters are always set, except $! before
+// any background job; positional parameters beyond $# are not.
+func (e *Environment) Lookup(key string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	value := e.lookup(key)
+	switch key {
+	case "0", "#", "@", "*", "$", "?":
+		return value, true
+	case "!":
+		return value, value != ""
+	}
+	if n, err := strconv.Atoi(key); err == nil && n > 0 {
+		return value, n <= len(e.args)
+	}
+	return value, e.isSet(key)
+}
+
+// isSet returns true if a variable is set in the scope or an enclosing
+// one. The caller must hold the lock.
+func (e *Environment) isSet(key string) bool {
+	if _, ok := e.vars[key]; ok || e.parent == nil {
+		return ok
+	}
+	_, ok := e.parent.Lookup(key)
+	return ok
+}
+
+// lookup resolves a variable or parameter name. The caller must hold the lock.
+func (e *Environment) lookup(key string) string {
+	switch key {
+	case "0":
+		return e.name
+	case "#":
+		return strconv.Itoa(len(e.args))
+	case "@", "*":
+		return strings.Join(e.args, " ")
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	case "?", "!":
+		if e.parent != nil {
+			return e.parent.Get(key)
+		}
+		if key == "?" {
+			return strconv.Itoa(e.status)
+		}
+		return e.lastJob
+	}
+
+	if n, err := strconv.Atoi(key); err == nil && n > 0 {
+		if n <= len(e.args) {
+			return e.args[n-1]
+		}
+		return ""
+	}
+
+	if value, local := e.vars[key]; local || e.parent == nil {
+		return value
+	}
+	return e.parent.Get(key)
+}
+
+// SetPositional sets the script name ($0) and positional parameters ($1, $2, ...).
+func (e *Environment) SetPositional(name string, args []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.name = name
+	e.args = append([]string(nil), args...)
+}
+
+// SetArgs replaces the positional parameters ($1, $2, ...), keeping $0.
+func (e *Environment) SetArgs(args []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.args = append([]string(nil), args...)
+}
+
+// Args returns a copy of the positional parameters ($1, $2, ...).
+func (e *Environment) Args() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]string(nil), e.args...)
+}
+
+// Name returns the script or shell name ($0).
+func (e *Environment) Name() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.name
+}
+
+// SetStatus sets the exit status of the last command ($?).
+func (e *Environment) SetStatus(code int) {
+	if e.parent != nil {
+		e.parent.SetStatus(code)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = code
+}
+
+// Status returns the exit status of the last command ($?).
+func (e *Environment) Status() int {
+	if e.parent != nil {
+		return e.parent.Status()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.status
+}
+
+// SetLastJob sets the job spec of the last background job ($!), such as
+// "%1", which wait and kill accept.
+func (e *Environment) SetLastJob(spec string) {
+	if e.parent != nil {
+		e.parent.SetLastJob(spec)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastJob = spec
+}
+
+// SetOption turns a shell option, such as nullglob, on or off.
+// Options are shared by all the scopes of an environment.
+func (e *Environment) SetOption(name string, on bool) {
+	if e.parent != nil {
+		e.parent.SetOption(name, on)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.options == nil {
+		e.options = make(map[string]bool)
+	}
+	e.options[name] = on
+}
+
+// Option returns true if the shell option is turned on.
+func (e *Environment) Option(name string) bool {
+	if e.parent != nil {
+		return e.parent.Option(name)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.options[name]
 }
 
 // Set sets an environment variable.
+// Within a function scope, variables not declared local are set in the
+// enclosing scope that holds them.
 func (e *Environment) Set(key, value string) {
+	if owner := e.owner(key); owner != e {
+		owner.Set(key, value)
+		return
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.vars[key] = value
@@ -53,6 +220,10 @@ func (e *Environment) Set(key, value string) {
 
 // Unset removes an environment variable.
 func (e *Environment) Unset(key string) {
+	if owner := e.owner(key); owner != e {
+		owner.Unset(key)
+		return
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	delete(e.vars, key)
@@ -61,6 +232,10 @@ func (e *Environment) Unset(key string) {
 
 // Export marks a variable for export to child processes.
 func (e *Environment) Export(key string) {
+	if owner := e.owner(key); owner != e {
+		owner.Export(key)
+		return
+	}
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	if _, exists := e.vars[key]; exists {
@@ -68,15 +243,109 @@ func (e *Environment) Export(key string) {
 	}
 }
 
+// Unexport removes the export mark of a variable, keeping its value.
+func (e *Environment) Unexport(key string) {
+	if owner := e.owner(key); owner != e {
+		owner.Unexport(key)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.exports, key)
+}
+
 // IsExported returns true if the variable is marked for export.
 func (e *Environment) IsExported(key string) bool {
+	if owner := e.owner(key); owner != e {
+		return owner.IsExported(key)
+	}
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	return e.exports[key]
 }
 
-// varPattern matches $VAR and ${VAR} patterns.
-var varPattern = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}|\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+// NewScope returns a child environment for a function call with the given
+// positional parameters. Variables declared with Local live in the child
+// and disappear with it; all other variables are shared with e.
+func (e *Environment) NewScope(args []string) *Environment {
+	return &Environment{
+		vars:    make(map[string]string),
+		exports: make(map[string]bool),
+		name:    e.Name(),
+		args:    append([]string(nil), args...),
+		parent:  e,
+	}
+}
+
+// Parent returns the enclosing scope, or nil for the top-level environment.
+func (e *Environment) Parent() *Environment {
+	return e.parent
+}
+
+// Local declares a variable local to this scope, hiding any variable
+// of the same name in enclosing scopes.
+func (e *Environment) Local(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vars[key] = value
+}
+
+// owner returns the scope holding key: the innermost scope declaring it
+// local, or the top-level environment.
+func (e *Environment) owner(key string) *Environment {
+	for s := e; ; s = s.parent {
+		s.mu.RLock()
+		_, local := s.vars[key]
+		s.mu.RUnlock()
+		if local || s.parent == nil {
+			return s
+		}
+	}
+}
+
+// flatten returns copies of the variables and export flags visible in
+// this scope, with local variables hiding those of enclosing scopes.
+func (e *Environment) flatten() (map[string]string, map[string]bool) {
+	var vars map[string]string
+	var exports map[string]bool
+	if e.parent != nil {
+		vars, exports = e.parent.flatten()
+	} else {
+		vars = make(map[string]string)
+		exports = make(map[string]bool)
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for k, v := range e.vars {
+		vars[k] = v
+		exports[k] = e.exports[k]
+	}
+	if e.parent == nil {
+		for k, v := range e.exports {
+			exports[k] = v
+		}
+	}
+	return vars, exports
+}
+
+// varPattern matches $VAR and ${VAR} patterns, as well as positional
+// and special parameters ($1, ${10}, $#, $@, $*, $?, $$, $!).
+var varPattern = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*|[0-9]+|[#@*?$!])\}|\$([a-zA-Z_][a-zA-Z0-9_]*|[0-9#@*?$!])`)
+
+// ValidName returns true if s is a valid variable name: a letter or
+// underscore followed by letters, digits and underscores.
+func ValidName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
 
 // Expand expands $VAR and ${VAR} references in a string.
 // Unknown variables are replaced with empty string.
@@ -95,22 +364,18 @@ func (e *Environment) Expand(input string) string {
 			name = match[1:]
 		}
 
-		if val, exists := e.vars[name]; exists {
-			return val
-		}
-		return ""
+		return e.lookup(name)
 	})
 }
 
 // ToSlice returns all exported variables as a KEY=VALUE slice.
 // This is suitable for passing to exec.Cmd.Env.
 func (e *Environment) ToSlice() []string {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+	vars, exports := e.flatten()
 
-	result := make([]string, 0, len(e.exports))
-	for key := range e.exports {
-		if val, exists := e.vars[key]; exists {
+	result := make([]string, 0, len(exports))
+	for key, exported := range exports {
+		if val, exists := vars[key]; exists && exported {
 			result = append(result, key+"="+val)
 		}
 	}
@@ -119,44 +384,49 @@ func (e *Environment) ToSlice() []string {
 
 // All returns a copy of all variables as a map.
 func (e *Environment) All() map[string]string {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	result := make(map[string]string, len(e.vars))
-	for k, v := range e.vars {
-		result[k] = v
-	}
-	return result
+	vars, _ := e.flatten()
+	return vars
 }
 
 // Exported returns a copy of all exported variable names.
 func (e *Environment) Exported() []string {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+	_, exports := e.flatten()
 
-	result := make([]string, 0, len(e.exports))
-	for k := range e.exports {
-		result = append(result, k)
+	result := make([]string, 0, len(exports))
+	for k, exported := range exports {
+		if exported {
+			result = append(result, k)
+		}
 	}
 	return result
 }
 
 // Clone creates a deep copy of the environment.
+// Cloning a function scope yields a top-level environment holding all
+// the variables visible in that scope.
 func (e *Environment) Clone() *Environment {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+	vars, exports := e.flatten()
+	status := e.Status()
 
-	clone := &Environment{
-		vars:    make(map[string]string, len(e.vars)),
-		exports: make(map[string]bool, len(e.exports)),
+	root := e
+	for root.parent != nil {
+		root = root.parent
 	}
+	root.mu.RLock()
+	lastJob := root.lastJob
+	options := maps.Clone(root.options)
+	root.mu.RUnlock()
 
-	for k, v := range e.vars {
-		clone.vars[k] = v
-	}
-	for k, v := range e.exports {
-		clone.exports[k] = v
-	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 
-	return clone
+	return &Environment{
+		vars:    vars,
+		exports: exports,
+		name:    e.name,
+		args:    append([]string(nil), e.args...),
+		status:  status,
+		lastJob: lastJob,
+		options: options,
+	}
 }
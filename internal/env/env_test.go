@@ -3,6 +3,7 @@ package env
 import (
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -117,8 +118,8 @@ func TestExpand(t *testing.T) {
 		{"no vars", "plain text", "plain text"},
 		{"var in text", "Hello $NAME!", "Hello John!"},
 		{"adjacent vars", "$HOME$NAME", "/home/userJohn"},
-		{"escaped like", "$$HOME", "$/home/user"}, // $ followed by $HOME
-		{"partial match", "$", "$"},               // lone $ stays as-is
+		{"shell pid", "$$HOME", strconv.Itoa(os.Getpid()) + "HOME"}, // $$ followed by HOME
+		{"partial match", "$", "$"},                                 // lone $ stays as-is
 		{"braced nonexistent", "${UNKNOWN}", ""},
 	}
 
@@ -278,3 +279,219 @@ func TestOSEnvironmentImport(t *testing.T) {
 		t.Errorf("OS variable %s should be exported by default", key)
 	}
 }
+
+func TestPositionalParameters(t *testing.T) {
+	env := New()
+	env.SetPositional("script.jsi", []string{"one", "two words", "three"})
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"0", "script.jsi"},
+		{"1", "one"},
+		{"2", "two words"},
+		{"3", "three"},
+		{"4", ""},
+		{"#", "3"},
+		{"@", "one two words three"},
+		{"*", "one two words three"},
+	}
+
+	for _, tt := range tests {
+		if got := env.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	if got := env.Args(); len(got) != 3 || got[1] != "two words" {
+		t.Errorf("Args() = %v", got)
+	}
+
+	env.SetArgs([]string{"x"})
+	if env.Name() != "script.jsi" {
+		t.Errorf("Name() = %q, SetArgs should keep $0", env.Name())
+	}
+	if env.Get("#") != "1" || env.Get("1") != "x" {
+		t.Errorf("after SetArgs: $# = %q, $1 = %q", env.Get("#"), env.Get("1"))
+	}
+}
+
+func TestExpandPositionalParameters(t *testing.T) {
+	env := New()
+	env.SetPositional("run", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"$0 $1 $2", "run a b"},
+		{"count=$#", "count=10"},
+		{"all: $@", "all: a b c d e f g h i j"},
+		{"$10", "a0"},
+		{"${10}", "j"},
+		{"${1}x", "ax"},
+	}
+
+	for _, tt := range tests {
+		if got := env.Expand(tt.input); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestClonePositionalParameters(t *testing.T) {
+	env := New()
+	env.SetPositional("parent", []string{"a"})
+
+	clone := env.Clone()
+	clone.SetArgs([]string{"b", "c"})
+
+	if env.Get("1") != "a" || env.Get("#") != "1" {
+		t.Error("modifying clone's arguments should not affect original")
+	}
+	if clone.Get("0") != "parent" || clone.Get("#") != "2" {
+		t.Errorf("clone: $0 = %q, $# = %q", clone.Get("0"), clone.Get("#"))
+	}
+}
+
+func TestScope(t *testing.T) {
+	env := New()
+	env.SetPositional("script", []string{"a"})
+	env.Set("GLOBAL", "1")
+	env.Set("SHADOWED", "outer")
+
+	scope := env.NewScope([]string{"x", "y"})
+	if scope.Parent() != env {
+		t.Error("Parent() should return the enclosing environment")
+	}
+	if scope.Get("0") != "script" || scope.Get("1") != "x" || scope.Get("#") != "2" {
+		t.Errorf("scope: $0 = %q, $1 = %q, $# = %q", scope.Get("0"), scope.Get("1"), scope.Get("#"))
+	}
+	if scope.Get("GLOBAL") != "1" {
+		t.Errorf("scope.Get(GLOBAL) = %q, want 1", scope.Get("GLOBAL"))
+	}
+
+	// Locals hide outer variables until the scope is dropped
+	scope.Local("SHADOWED", "inner")
+	scope.Set("SHADOWED", "inner2")
+	if scope.Get("SHADOWED") != "inner2" {
+		t.Errorf("scope.Get(SHADOWED) = %q, want inner2", scope.Get("SHADOWED"))
+	}
+	if env.Get("SHADOWED") != "outer" {
+		t.Errorf("env.Get(SHADOWED) = %q, want outer", env.Get("SHADOWED"))
+	}
+
+	// Other assignments are global
+	scope.Set("NEW", "v")
+	if env.Get("NEW") != "v" {
+		t.Errorf("env.Get(NEW) = %q, want v", env.Get("NEW"))
+	}
+
+	// Nested scopes see the locals of their callers
+	nested := scope.NewScope(nil)
+	if nested.Get("SHADOWED") != "inner2" {
+		t.Errorf("nested.Get(SHADOWED) = %q, want inner2", nested.Get("SHADOWED"))
+	}
+	if env.Get("1") != "a" {
+		t.Errorf("env.Get(1) = %q, want a", env.Get("1"))
+	}
+
+	if got := scope.All()["SHADOWED"]; got != "inner2" {
+		t.Errorf("All()[SHADOWED] = %q, want inner2", got)
+	}
+}
+
+func TestSpecialParameters(t *testing.T) {
+	env := New()
+	if got := env.Get("?"); got != "0" {
+		t.Errorf("$? = %q, want 0", got)
+	}
+	if got := env.Get("!"); got != "" {
+		t.Errorf("$! = %q, want empty before any background job", got)
+	}
+
+	// A function scope shares the special parameters of the shell
+	scope := env.NewScope(nil)
+	scope.SetStatus(3)
+	scope.SetLastJob("%2")
+	if got := env.Expand("$? ${?} $!"); got != "3 3 %2" {
+		t.Errorf("Expand = %q, want %q", got, "3 3 %2")
+	}
+
+	clone := scope.Clone()
+	clone.SetStatus(1)
+	if clone.Get("?") != "1" || env.Get("?") != "3" || clone.Get("!") != "%2" {
+		t.Errorf("clone $? = %s $! = %s, shell $? = %s", clone.Get("?"), clone.Get("!"), env.Get("?"))
+	}
+}
+
+func TestUnexport(t *testing.T) {
+	env := New()
+	env.Set("JSI_VAR", "x")
+	env.Export("JSI_VAR")
+	env.Unexport("JSI_VAR")
+	if env.IsExported("JSI_VAR") || env.Get("JSI_VAR") != "x" {
+		t.Error("Unexport should only remove the export mark")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"a", "_x", "PATH", "var_2"} {
+		if !ValidName(name) {
+			t.Errorf("ValidName(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "2x", "a-b", "a.b", "é"} {
+		if ValidName(name) {
+			t.Errorf("ValidName(%q) = true", name)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	env := New()
+	if env.Option("nullglob") {
+		t.Error("options should be off by default")
+	}
+
+	// Options are shared by function scopes
+	scope := env.NewScope(nil)
+	scope.SetOption("nullglob", true)
+	if !env.Option("nullglob") || !scope.Option("nullglob") {
+		t.Error("option set in a scope should be on in the shell")
+	}
+
+	clone := env.Clone()
+	clone.SetOption("nullglob", false)
+	if !env.Option("nullglob") || clone.Option("nullglob") {
+		t.Error("clone should have its own copy of the options")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	env := New()
+	env.Set("EMPTY", "")
+	env.SetPositional("script", []string{"a"})
+	scope := env.NewScope([]string{"x", "y"})
+
+	tests := []struct {
+		env  *Environment
+		key  string
+		want bool
+	}{
+		{env, "EMPTY", true},
+		{env, "JSI_UNSET_VAR", false},
+		{env, "?", true},
+		{env, "!", false},
+		{env, "1", true},
+		{env, "2", false},
+		{scope, "2", true},
+		{scope, "EMPTY", true},
+	}
+	for _, tt := range tests {
+		if _, ok := tt.env.Lookup(tt.key); ok != tt.want {
+			t.Errorf("Lookup(%q) set = %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Diagnostic is an error at a position of the source of a command or
+// script, such as a syntax error. It wraps the underlying error, so that
+// errors.Is(d, ErrInvalidSyntax) still holds.
+type Diagnostic struct {
+	Err    error  // Underlying error
+	Line   int    // Line number (1-indexed)
+	Column int    // Column number in characters (1-indexed)
+	Hint   string // Note shown next to the caret, e.g. "unterminated double quote started here"
+	Source string // Source the position refers to, if known
+
+	// Fragment is true if Source is a part of a command, such as an
+	// arithmetic expression, rather than the script or line being run
+	Fragment bool
+}
+
+// Error returns the message of the underlying error.
+func (d *Diagnostic) Error() string {
+	return d.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Render returns the message of the diagnostic followed by the offending
+// line of the source and a caret under the position:
+//
+//	deploy.jsi:3:6: invalid syntax: unterminated string
+//	  3 | echo "done
+//	    |      ^ unterminated double quote started here
+//
+// name prefixes the message with name:line:column, as for a script file.
+// Without a name, the position is only given for sources of several lines.
+// The position in a fragment is not that in the script, and is left out.
+func (d *Diagnostic) Render(name string) string {
+	lines := strings.Split(strings.TrimSuffix(d.Source, "\n"), "\n")
+	line, column := d.Line, d.Column
+	if d.Source != "" && line > len(lines) {
+		// End of input, after a final newline
+		line = len(lines)
+		column = utf8.RuneCountInString(lines[line-1]) + 1
+	}
+	multiLine := len(lines) > 1
+
+	var sb strings.Builder
+	switch {
+	case d.Fragment && name != "":
+		fmt.Fprintf(&sb, "%s: %v", name, d.Err)
+	case d.Fragment:
+		sb.WriteString(d.Err.Error())
+	case name != "":
+		fmt.Fprintf(&sb, "%s:%d:%d: %v", name, line, column, d.Err)
+	case multiLine:
+		fmt.Fprintf(&sb, "line %d: %v", line, d.Err)
+	default:
+		sb.WriteString(d.Err.Error())
+	}
+	if d.Source == "" || line < 1 {
+		return sb.String()
+	}
+
+	text := lines[line-1]
+	gutter, margin := "  ", "  "
+	if multiLine {
+		width := len(fmt.Sprint(line))
+		gutter = fmt.Sprintf("  %d | ", line)
+		margin = "  " + strings.Repeat(" ", width) + " | "
+	}
+	fmt.Fprintf(&sb, "\n%s%s\n%s%s^", gutter, text, margin, caretPadding(text, column))
+	if d.Hint != "" {
+		sb.WriteString(" " + d.Hint)
+	}
+	return sb.String()
+}
+
+// caretPadding returns the blanks that bring a caret under the given
+// column of text. Tabs are kept so that the caret lines up with them.
+func caretPadding(text string, column int) string {
+	var sb strings.Builder
+	for i, c := range []rune(text) {
+		if i >= column-1 {
+			break
+		}
+		if c == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	// Past the end of the line
+	if n := column - 1 - utf8.RuneCountInString(text); n > 0 {
+		sb.WriteString(strings.Repeat(" ", n))
+	}
+	return sb.String()
+}
+
+// WithSource records the source a diagnostic refers to, unless it already
+// has one. Returns err unchanged if it is not a diagnostic.
+func WithSource(err error, source string) error {
+	var d *Diagnostic
+	if errors.As(err, &d) && d.Source == "" {
+		d.Source = source
+	}
+	return err
+}
+
+// Format returns the message of an error for display. Diagnostics are
+// rendered with the offending line of their source; name is the name of
+// the script, or empty for interactive input.
+func Format(err error, name string) string {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d.Render(name)
+	}
+	return err.Error()
+}
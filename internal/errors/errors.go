@@ -14,6 +14,27 @@ var (
 
 	// ErrInvalidSyntax indicates the input could not be parsed.
 	ErrInvalidSyntax = errors.New("invalid syntax")
+
+	// ErrIncompleteInput indicates the input ended inside a construct that
+	// needs more lines, such as an unclosed quote or an if without fi.
+	ErrIncompleteInput = errors.New("unexpected end of input")
+
+	// ErrDivisionByZero indicates an arithmetic expression divides by zero.
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrNoMatch indicates a glob pattern matches no file while the
+	// failglob option is on.
+	ErrNoMatch = errors.New("no match")
+
+	// ErrUnboundVariable indicates an unset variable is expanded while the
+	// nounset option is on.
+	ErrUnboundVariable = errors.New("unbound variable")
+
+	// ErrNoSuchJob indicates a job specification matches no job.
+	ErrNoSuchJob = errors.New("no such job")
+
+	// ErrAmbiguousJob indicates a job specification matches several jobs.
+	ErrAmbiguousJob = errors.New("ambiguous job")
 )
 
 // Sentinel errors for file operations.
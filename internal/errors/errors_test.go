@@ -16,6 +16,9 @@ func TestSentinelErrors(t *testing.T) {
 		{"ErrCommandNotFound", ErrCommandNotFound, "command not found"},
 		{"ErrAmbiguousCommand", ErrAmbiguousCommand, "ambiguous command"},
 		{"ErrInvalidSyntax", ErrInvalidSyntax, "invalid syntax"},
+		{"ErrIncompleteInput", ErrIncompleteInput, "unexpected end of input"},
+		{"ErrNoSuchJob", ErrNoSuchJob, "no such job"},
+		{"ErrAmbiguousJob", ErrAmbiguousJob, "ambiguous job"},
 
 		// File operation errors
 		{"ErrPermissionDenied", ErrPermissionDenied, "permission denied"},
@@ -99,3 +102,81 @@ func TestErrorUniqueness(t *testing.T) {
 		}
 	}
 }
+
+func TestDiagnosticRender(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Diagnostic
+		file string
+		want string
+	}{
+		{
+			"single line",
+			Diagnostic{Err: ErrInvalidSyntax, Line: 1, Column: 6, Hint: "here", Source: "echo |"},
+			"",
+			"invalid syntax\n  echo |\n       ^ here",
+		},
+		{
+			"script",
+			Diagnostic{Err: ErrInvalidSyntax, Line: 2, Column: 3, Source: "a\n\tb c\n"},
+			"run.jsi",
+			"run.jsi:2:3: invalid syntax\n  2 | \tb c\n    | \t ^",
+		},
+		{
+			"several lines",
+			Diagnostic{Err: ErrInvalidSyntax, Line: 2, Column: 1, Source: "a\nb"},
+			"",
+			"line 2: invalid syntax\n  2 | b\n    | ^",
+		},
+		{
+			"end of input",
+			Diagnostic{Err: ErrIncompleteInput, Line: 3, Column: 1, Source: "a\nbc\n"},
+			"s",
+			"s:2:3: unexpected end of input\n  2 | bc\n    |   ^",
+		},
+		{
+			"fragment",
+			Diagnostic{Err: ErrInvalidSyntax, Line: 1, Column: 5, Source: " 1 + ", Fragment: true},
+			"s",
+			"s: invalid syntax\n   1 + \n      ^",
+		},
+		{
+			"no source",
+			Diagnostic{Err: ErrInvalidSyntax, Line: 4, Column: 2},
+			"s",
+			"s:4:2: invalid syntax",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.Render(tt.file); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnosticWrapping(t *testing.T) {
+	d := &Diagnostic{Err: fmt.Errorf("%w: unexpected token", ErrInvalidSyntax), Line: 1, Column: 1}
+	err := WithSource(d, "fi")
+
+	if !errors.Is(err, ErrInvalidSyntax) {
+		t.Error("a diagnostic should wrap its error")
+	}
+	if err.Error() != "invalid syntax: unexpected token" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if d.Source != "fi" {
+		t.Errorf("Source = %q, want %q", d.Source, "fi")
+	}
+	if WithSource(d, "other"); d.Source != "fi" {
+		t.Error("WithSource should keep the first source")
+	}
+	if got := Format(err, ""); got != "invalid syntax: unexpected token\n  fi\n  ^" {
+		t.Errorf("Format() = %q", got)
+	}
+	if got := Format(ErrNoMatch, "s"); got != "no match" {
+		t.Errorf("Format() = %q, want %q", got, "no match")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/sdejongh/jsishell/internal/builtins"
@@ -282,6 +285,48 @@ func TestAbbreviationWithExternalCommands(t *testing.T) {
 	}
 }
 
+// TestResolutionPrecedence tests the order in which a command name is
+// resolved: builtins, then external commands of the exact name, then
+// abbreviations.
+func TestResolutionPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"li", "list"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	if _, err := exec.LookPath("li"); err != nil {
+		t.Skipf("skipping: scripts are not executable commands here: %v", err)
+	}
+
+	reg := builtins.NewRegistry()
+	reg.Register(builtins.Definition{Name: "list"})
+	reg.Register(builtins.Definition{Name: "lisp"})
+	e := New(WithRegistry(reg))
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"list", "list", nil},                        // builtin before external
+		{"li", "li", nil},                            // external before abbreviation
+		{"lis", "", shellerrors.ErrAmbiguousCommand}, // abbreviation
+		{"lisp", "lisp", nil},                        // builtin
+		{"nope", "", shellerrors.ErrCommandNotFound}, // neither
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			resolved, _, err := e.ResolveCommand(tt.input)
+			if !errors.Is(err, tt.wantErr) || resolved != tt.want {
+				t.Errorf("ResolveCommand(%q) = %q, %v, want %q, %v", tt.input, resolved, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestAbbreviationExecution tests that abbreviated commands execute correctly.
 func TestAbbreviationExecution(t *testing.T) {
 	var stdout bytes.Buffer
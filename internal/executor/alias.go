@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/sdejongh/jsishell/internal/lexer"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// aliasName returns the name of the alias a simple command starts with,
+// or "" if its first word is not an alias. Only an unquoted first word is
+// looked up, and aliases being expanded are not expanded again.
+func (e *Executor) aliasName(sc *parser.SimpleCommand, fr *frame) string {
+	if fr.aliases.Count() == 0 || len(sc.Tokens) == 0 {
+		return ""
+	}
+
+	first := sc.Tokens[0]
+	if first.Type != lexer.TokenWord || (len(sc.Tokens) > 1 && sc.Tokens[1].Type != lexer.TokenWhitespace) {
+		return ""
+	}
+
+	name, _, err := e.resolveCommand(first.Value, fr, true)
+	if err != nil || !fr.aliases.Has(name) || fr.aliasing[name] {
+		return ""
+	}
+	return name
+}
+
+// executeAlias runs a simple command starting with an alias: the alias
+// name is replaced by its value, and the resulting text is parsed again so
+// that aliases may contain pipelines and lists. Aliases in the value are
+// expanded in turn, except those already being expanded.
+func (e *Executor) executeAlias(ctx context.Context, sc *parser.SimpleCommand, name string, fr *frame) (int, error) {
+	value, _ := fr.aliases.Get(name)
+	node, err := parser.ParseScriptInput(value + parser.JoinTokens(sc.Tokens[1:]))
+	if err != nil {
+		return 1, fmt.Errorf("alias %s: %w", name, err)
+	}
+
+	redirected, files, err := e.applyRedirects(ctx, sc.Redirects, fr)
+	if err != nil {
+		return 1, err
+	}
+	defer closeFiles(files)
+
+	expanded := *redirected
+	expanded.aliasing = make(map[string]bool, len(fr.aliasing)+1)
+	for n := range fr.aliasing {
+		expanded.aliasing[n] = true
+	}
+	expanded.aliasing[name] = true
+
+	return e.executeNode(ctx, node, &expanded)
+}
+
+// mergeNames merges two sorted lists of names, dropping duplicates.
+func mergeNames(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+
+	seen := make(map[string]bool, len(a))
+	merged := append([]string(nil), a...)
+	for _, name := range a {
+		seen[name] = true
+	}
+	for _, name := range b {
+		if !seen[name] {
+			merged = append(merged, name)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
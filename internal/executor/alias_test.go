@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+)
+
+// newAliasTestExecutor creates a control test executor with the alias
+// and unalias builtins.
+func newAliasTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newControlTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.AliasDefinition())
+	e.registry.Register(builtins.UnaliasDefinition())
+	return e
+}
+
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		input   string
+		stdout  string
+		code    int
+	}{
+		{"simple", map[string]string{"hi": "echo hello"}, "hi", "hello\n", 0},
+		{"arguments", map[string]string{"hi": "echo hello"}, "hi bob  alice", "hello bob alice\n", 0},
+		{"list", map[string]string{"two": "echo a; echo b"}, "two", "a\nb\n", 0},
+		{"pipeline", map[string]string{"shout": "echo hey | upper"}, "shout", "HEY\n", 0},
+		{"in pipeline", map[string]string{"hi": "echo hello"}, "hi | upper", "HELLO\n", 0},
+		{"status", map[string]string{"f": "fail"}, "f", "", 3},
+		{"nested", map[string]string{"hi": "say hello", "say": "echo"}, "hi", "hello\n", 0},
+		{"self reference", map[string]string{"echo": "echo -n"}, "echo hi", "hi", 0},
+		{"loop", map[string]string{"a": "b", "b": "a"}, "a; echo done", "done\n", 0},
+		{"quoted name", map[string]string{"hi": "echo hello"}, "'hi'", "", 127},
+		{"not first word", map[string]string{"hi": "echo hello"}, "echo hi", "hi\n", 0},
+		{"after list operator", map[string]string{"hi": "echo hello"}, "true && hi", "hello\n", 0},
+		{"in control flow", map[string]string{"hi": "echo hello"}, "for x in 1 2; do hi $x; done", "hello 1\nhello 2\n", 0},
+		{"variables", map[string]string{"show": "echo $x"}, "for x in v; do show; done", "v\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newAliasTestExecutor(&stdout, &stderr)
+			for name, value := range tt.aliases {
+				e.Aliases().Set(name, value)
+			}
+
+			exitCode, _ := e.ExecuteInput(context.Background(), tt.input)
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestAliasBuiltins(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newAliasTestExecutor(&stdout, &stderr)
+
+	input := "alias hi='echo hello'; hi; unalias hi; alias"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "hello\n")
+	}
+	if e.Aliases().Count() != 0 {
+		t.Errorf("aliases = %v, want none", e.Aliases().All())
+	}
+}
+
+func TestAliasAbbreviation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newAliasTestExecutor(&stdout, &stderr)
+	e.abbreviationsEnable = true
+	e.Aliases().Set("greeting", "echo hi")
+
+	if _, err := e.ExecuteInput(context.Background(), "greet"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "hi\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "hi\n")
+	}
+}
+
+func TestAliasRedirect(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.Aliases().Set("two", "echo a; echo b")
+
+	// The redirection applies to the whole value of the alias
+	if _, err := e.ExecuteInput(context.Background(), "two > out.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if string(data) != "a\nb\n" {
+		t.Errorf("out.txt = %q, want %q", data, "a\nb\n")
+	}
+}
@@ -0,0 +1,261 @@
+package executor
+
+import (
+	"context"
+	goerrors "errors"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/glob"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// statusInterrupted is the exit status of a loop stopped by an interrupt.
+const statusInterrupted = 130
+
+// executeIf runs the branch of an if clause selected by its conditions.
+// Returns 0 if no branch was run.
+func (e *Executor) executeIf(ctx context.Context, c *parser.IfClause, fr *frame) (int, error) {
+	code, err := e.executeCondition(ctx, c.Cond, fr)
+	if err != nil {
+		return code, err
+	}
+	if code == 0 {
+		return e.executeNode(ctx, c.Then, fr)
+	}
+	if c.Else != nil {
+		return e.executeNode(ctx, c.Else, fr)
+	}
+	return 0, nil
+}
+
+// executeWhile runs a while or until loop.
+// Returns the status of the last iteration of the body, or 0 if it never ran.
+func (e *Executor) executeWhile(ctx context.Context, c *parser.WhileClause, fr *frame) (int, error) {
+	body := e.loopFrame(fr)
+	status := 0
+
+	for {
+		if ctx.Err() != nil {
+			return statusInterrupted, nil
+		}
+
+		code, err := e.executeCondition(ctx, c.Cond, body)
+		if err != nil {
+			if stop, code, err := e.loopControl(err, body, code); stop {
+				return code, err
+			}
+			continue
+		}
+		if (code == 0) == c.Until {
+			return status, nil
+		}
+
+		code, err = e.executeLoopBody(ctx, c.Body, body)
+		status = code
+		if stop, code, err := e.loopControl(err, body, code); stop {
+			return code, err
+		}
+	}
+}
+
+// executeFor runs the body of a for loop once per word.
+func (e *Executor) executeFor(ctx context.Context, c *parser.ForClause, fr *frame) (int, error) {
+	var words []string
+	if c.HasIn {
+		p := e.newParser(ctx, c.Words, fr)
+		words = p.ExpandWords()
+		if err := p.Err(); err != nil {
+			return 1, err
+		}
+	} else {
+		words = fr.env.Args()
+	}
+
+	body := e.loopFrame(fr)
+	status := 0
+
+	for _, word := range words {
+		if ctx.Err() != nil {
+			return statusInterrupted, nil
+		}
+
+		fr.env.Set(c.Var, word)
+		code, err := e.executeLoopBody(ctx, c.Body, body)
+		status = code
+		if stop, code, err := e.loopControl(err, body, code); stop {
+			return code, err
+		}
+	}
+
+	return status, nil
+}
+
+// executeCase runs the commands of the first case item with a pattern
+// matching the word. Returns 0 if no pattern matches.
+func (e *Executor) executeCase(ctx context.Context, c *parser.CaseClause, fr *frame) (int, error) {
+	p := e.newParser(ctx, c.Word, fr)
+	word := strings.Join(p.ExpandWords(), " ")
+	if err := p.Err(); err != nil {
+		return 1, err
+	}
+
+	for _, item := range c.Items {
+		for _, pattern := range item.Patterns {
+			if !glob.Match(e.newParser(ctx, pattern, fr).ExpandPattern(), word) {
+				continue
+			}
+			if item.Body == nil {
+				return 0, nil
+			}
+			return e.executeNode(ctx, item.Body, fr)
+		}
+	}
+
+	return 0, nil
+}
+
+// executeRedirected runs a compound command with redirections applied.
+func (e *Executor) executeRedirected(ctx context.Context, r *parser.RedirectedCommand, fr *frame) (int, error) {
+	redirected, files, err := e.applyRedirects(ctx, r.Redirects, fr)
+	if err != nil {
+		return 1, err
+	}
+	defer closeFiles(files)
+	return e.executeNode(ctx, r.Node, redirected)
+}
+
+// executeSubshell runs the commands of ( ... ) in a subshell frame.
+// exit, break and return only leave the subshell.
+func (e *Executor) executeSubshell(ctx context.Context, s *parser.Subshell, fr *frame) (int, error) {
+	sub := subshell(fr)
+	code, err := e.executeList(ctx, s.Body, sub)
+	if isControlFlow(err) {
+		err = nil
+	}
+	// An aborted command line only ends the subshell
+	if isAbort(err) {
+		reportError(fr.stderr, err)
+		err = nil
+	}
+
+	// The EXIT trap set within the subshell runs as it ends; exit within
+	// the trap sets the status of the subshell
+	sub.env.SetStatus(code)
+	var exitErr builtins.ExitCode
+	if goerrors.As(e.runTrap(context.WithoutCancel(ctx), "EXIT", sub), &exitErr) {
+		code = exitErr.Code
+	}
+	return code, err
+}
+
+// subshell returns a copy of the frame with its own variables, working
+// directory, functions, aliases and traps, which cannot leave enclosing
+// loops or functions.
+func subshell(fr *frame) *frame {
+	sub := *fr
+	dir := *fr.dir
+	sub.env = fr.env.Clone()
+	sub.dir = &dir
+	sub.functions = fr.functions.Clone()
+	sub.aliases = fr.aliases.Clone()
+	sub.traps = fr.traps.Subshell()
+	sub.loops = 0
+	sub.inFunc = false
+	sub.trapsOff = true
+	return &sub
+}
+
+// executeCondition runs the condition of an if or loop.
+// Errors are reported to stderr and count as a failed condition; only
+// requests to leave the shell or a loop, and errors aborting the command
+// line, are returned.
+func (e *Executor) executeCondition(ctx context.Context, cond *parser.List, fr *frame) (int, error) {
+	tested := *fr
+	tested.condition = true
+	code, err := e.executeNode(ctx, cond, &tested)
+	if err == nil || isControlFlow(err) || isAbort(err) {
+		return code, err
+	}
+
+	reportError(fr.stderr, err)
+	if code == 0 {
+		code = 1
+	}
+	return code, nil
+}
+
+// executeLoopBody runs one iteration of a loop body.
+// Errors other than control flow are reported so that the loop carries on,
+// unless they abort the command line.
+func (e *Executor) executeLoopBody(ctx context.Context, body *parser.List, fr *frame) (int, error) {
+	code, err := e.executeNode(ctx, body, fr)
+	if err != nil && !isControlFlow(err) && !isAbort(err) {
+		reportError(fr.stderr, err)
+		return code, nil
+	}
+	return code, err
+}
+
+// loopFrame returns the frame for the body of a loop nested in fr.
+func (e *Executor) loopFrame(fr *frame) *frame {
+	body := *fr
+	body.loops++
+	return &body
+}
+
+// loopControl handles the error of a loop iteration.
+// stop is true if the loop must end, in which case code and err are its result:
+// break ends the loop, break n and continue n with n > 1 are passed on to the
+// enclosing loop, and other errors (exit) propagate.
+func (e *Executor) loopControl(err error, body *frame, status int) (stop bool, code int, _ error) {
+	if err == nil {
+		return false, status, nil
+	}
+
+	var lc builtins.LoopControl
+	if !goerrors.As(err, &lc) {
+		return true, status, err
+	}
+
+	// Leaving more loops than there are leaves them all
+	if lc.Levels > 1 && body.loops > 1 {
+		lc.Levels--
+		return true, 0, lc
+	}
+	if lc.Continue {
+		return false, 0, nil
+	}
+	return true, 0, nil
+}
+
+// isControlFlow returns true if err is a request to leave the shell, a loop
+// or a function.
+func isControlFlow(err error) bool {
+	var lc builtins.LoopControl
+	var rc builtins.ReturnCode
+	return isExitRequest(err) || goerrors.As(err, &lc) || goerrors.As(err, &rc)
+}
+
+// isAbort returns true if err abandons the rest of the command line, as does
+// the expansion of a variable that is not set with the nounset option.
+func isAbort(err error) bool {
+	return goerrors.Is(err, shellerrors.ErrUnboundVariable)
+}
+
+// unwinds returns true if err leaves a construct enclosing fr, in which case
+// the commands following it must not run.
+func unwinds(err error, fr *frame) bool {
+	var lc builtins.LoopControl
+	var rc builtins.ReturnCode
+	switch {
+	case isExitRequest(err), isAbort(err):
+		return true
+	case goerrors.As(err, &lc):
+		return fr.loops > 0
+	case goerrors.As(err, &rc):
+		return fr.inFunc
+	}
+	return false
+}
@@ -0,0 +1,315 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// newControlTestExecutor creates a pipeline test executor with the
+// true, false, break, continue and exit builtins.
+func newControlTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newPipelineTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.TrueDefinition())
+	e.registry.Register(builtins.FalseDefinition())
+	e.registry.Register(builtins.BreakDefinition())
+	e.registry.Register(builtins.ContinueDefinition())
+	e.registry.Register(builtins.ExitDefinition())
+	return e
+}
+
+func TestControlFlow(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		code   int
+	}{
+		{"if true", "if true; then echo yes; fi", "yes\n", 0},
+		{"if false", "if false; then echo yes; fi", "", 0},
+		{"if else", "if fail; then echo yes; else echo no; fi", "no\n", 0},
+		{"elif", "if false; then echo 1; elif true; then echo 2; else echo 3; fi", "2\n", 0},
+		{"if status", "if true; then fail; fi", "", 3},
+		{"if list condition", "if true && false; then echo yes; else echo no; fi", "no\n", 0},
+		{"nested if", "if true; then if false; then echo a; else echo b; fi; fi", "b\n", 0},
+		{"for", "for x in a b c; do echo $x; done", "a\nb\nc\n", 0},
+		{"for empty", "for x in; do echo $x; done", "", 0},
+		{"for quoted", `for x in "a b" c; do echo "[$x]"; done`, "[a b]\n[c]\n", 0},
+		{"for status", "for x in a; do fail; done", "", 3},
+		{"while false", "while false; do echo never; done", "", 0},
+		{"until true", "until true; do echo never; done", "", 0},
+		{"break", "for x in a b c; do echo $x; break; done", "a\n", 0},
+		{"continue", "for x in a b c; do continue; echo $x; done", "", 0},
+		{"while break", "while true; do echo once; break; done", "once\n", 0},
+		{"break in if", "for x in a b c; do if true; then break; fi; echo $x; done", "", 0},
+		{"break 2", "for x in a b; do for y in 1 2; do echo $x$y; break 2; done; done", "a1\n", 0},
+		{"continue 2", "for x in a b; do for y in 1 2; do echo $x$y; continue 2; done; echo no; done", "a1\nb1\n", 0},
+		{"break n too large", "for x in a b; do break 5; done; echo after", "after\n", 0},
+		{"case", "case b in a) echo A;; b) echo B;; *) echo other;; esac", "B\n", 0},
+		{"case default", "case zzz in a) echo A;; *) echo other;; esac", "other\n", 0},
+		{"case alternatives", "case y in x|y) echo xy;; esac", "xy\n", 0},
+		{"case glob", "case main.go in *.go) echo go;; esac", "go\n", 0},
+		{"case slash", "case a/b/c.go in */*.go) echo go;; esac", "go\n", 0},
+		{"case class", "case b2 in [a-c][0-9]) echo ok;; esac", "ok\n", 0},
+		{"case quoted pattern", `case abc in "*") echo star;; *) echo other;; esac`, "other\n", 0},
+		{"case no match", "case x in a) echo a;; esac", "", 0},
+		{"case empty body", "case x in x) ;; *) echo other;; esac", "", 0},
+		{"case last item", "case x in x) echo x\nesac", "x\n", 0},
+		{"pipeline", "for x in a b; do echo $x; done | upper", "A\nB\n", 0},
+		{"multi-line", "for x in a b\ndo\n  if true\n  then\n    echo $x\n  fi\ndone", "a\nb\n", 0},
+		{"subshell", "(echo a; echo b)", "a\nb\n", 0},
+		{"subshell variables", "x=1; (x=2; echo $x); echo $x", "2\n1\n", 0},
+		{"subshell exit", "(exit 4; echo never); echo $?", "4\n", 0},
+		{"subshell status", "(fail) || echo failed", "failed\n", 0},
+		{"subshell pipeline", "(echo a; echo b) | upper", "A\nB\n", 0},
+		{"brace group", "{ echo a; echo b; }", "a\nb\n", 0},
+		{"brace group variables", "x=1; { x=2; }; echo $x", "2\n", 0},
+		{"brace group and list", "false || { echo a; echo b; }", "a\nb\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newControlTestExecutor(&stdout, &stderr)
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
+
+func TestSubshellWorkDir(t *testing.T) {
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(original)
+
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.registry.Register(builtins.CdDefinition())
+	e.registry.Register(builtins.PwdDefinition())
+	if err := e.SetWorkDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "(cd build && echo data > out.txt && pwd); pwd"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	build := filepath.Join(dir, "build")
+	if want := build + "\n" + dir + "\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if got := readFile(t, filepath.Join(build, "out.txt")); got != "data\n" {
+		t.Errorf("out.txt = %q, want %q", got, "data\n")
+	}
+	if e.WorkDir() != dir {
+		t.Errorf("WorkDir() = %q, want %q", e.WorkDir(), dir)
+	}
+
+	// A brace group shares the working directory
+	if _, err := e.ExecuteInput(context.Background(), "{ cd build; }"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if e.WorkDir() != build {
+		t.Errorf("WorkDir() = %q, want %q", e.WorkDir(), build)
+	}
+}
+
+func TestSubshellTables(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newTrapTestExecutor(&stdout, &stderr)
+	e.registry.Register(builtins.AliasDefinition())
+	e.Aliases().Set("hi", "echo hello")
+
+	// Functions, aliases and traps defined in a subshell are its own
+	input := "(f() { echo F; }; alias zz='echo Z'; unalias hi; trap 'echo T' TERM; f; zz); hi"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "F\nZ\nhello\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "F\nZ\nhello\n")
+	}
+	if e.Functions().Has("f") {
+		t.Error("function f defined in the shell")
+	}
+	if e.Aliases().Has("zz") {
+		t.Error("alias zz defined in the shell")
+	}
+	if _, ok := e.Traps().Get("TERM"); ok {
+		t.Error("trap on TERM set in the shell")
+	}
+}
+
+func TestForPositionalParameters(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+	e.env.SetPositional("script", []string{"one", "two"})
+
+	if _, err := e.ExecuteInput(context.Background(), "for arg; do echo $arg; done"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "one\ntwo\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "one\ntwo\n")
+	}
+}
+
+func TestForGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	input := "for f in " + filepath.Join(dir, "*.go") + "; do echo $f; done"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	want := filepath.Join(dir, "a.go") + "\n" + filepath.Join(dir, "b.go") + "\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestWhileCondition(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	// The condition is re-evaluated before each iteration
+	e.registry.Register(builtins.Definition{
+		Name: "countdown",
+		Handler: func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+			n := len(execCtx.Env.Get("N"))
+			if n == 0 {
+				return 1, nil
+			}
+			execCtx.Env.Set("N", strings.Repeat("x", n-1))
+			return 0, nil
+		},
+	})
+	e.env.Set("N", "xxx")
+
+	exitCode, err := e.ExecuteInput(context.Background(), "while countdown; do echo $N; done")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if stdout.String() != "xx\nx\n\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "xx\nx\n\n")
+	}
+}
+
+func TestLoopInterrupted(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.registry.Register(builtins.Definition{
+		Name: "interrupt",
+		Handler: func(context.Context, *parser.Command, *builtins.Context) (int, error) {
+			cancel()
+			return 0, nil
+		},
+	})
+
+	exitCode, err := e.ExecuteInput(ctx, "while true; do interrupt; done")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != statusInterrupted {
+		t.Errorf("exitCode = %d, want %d", exitCode, statusInterrupted)
+	}
+}
+
+func TestExitInsideLoop(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "for x in a b; do exit 4; done; echo after")
+	if !isExitRequest(err) {
+		t.Errorf("error = %v, want exit request", err)
+	}
+	if exitCode != 4 {
+		t.Errorf("exitCode = %d, want 4", exitCode)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", stdout.String())
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	// break outside a loop is reported and does not stop the list
+	_, err := e.ExecuteInput(context.Background(), "break; echo after")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "after\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "after\n")
+	}
+	if !strings.Contains(stderr.String(), "only meaningful in a loop") {
+		t.Errorf("stderr = %q, want loop error", stderr.String())
+	}
+}
+
+func TestLoopBodyErrorsReported(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newControlTestExecutor(&stdout, &stderr)
+
+	_, err := e.ExecuteInput(context.Background(), "for x in a b; do nonexistentcommand12345; echo $x; done")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "a\nb\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "a\nb\n")
+	}
+	if strings.Count(stderr.String(), "command not found") != 2 {
+		t.Errorf("stderr = %q, want two errors", stderr.String())
+	}
+}
+
+func TestCompoundRedirect(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.registry.Register(builtins.TrueDefinition())
+
+	input := "for x in a b; do echo $x; done > out.txt; if true; then echo c; fi >> out.txt; { echo d; echo e; } >> out.txt"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "out.txt")); got != "a\nb\nc\nd\ne\n" {
+		t.Errorf("out.txt = %q, want %q", got, "a\nb\nc\nd\ne\n")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", stdout.String())
+	}
+}
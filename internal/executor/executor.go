@@ -7,10 +7,12 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/sdejongh/jsishell/internal/builtins"
 	"github.com/sdejongh/jsishell/internal/env"
 	"github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/jobs"
 	"github.com/sdejongh/jsishell/internal/parser"
 	"github.com/sdejongh/jsishell/internal/terminal"
 )
@@ -18,6 +20,10 @@ import (
 // Executor executes parsed commands.
 type Executor struct {
 	registry            *builtins.Registry
+	aliases             *builtins.Aliases
+	functions           *builtins.Functions
+	jobs                *jobs.Table
+	traps               *builtins.Traps
 	env                 *env.Environment
 	stdin               io.Reader
 	stdout              io.Writer
@@ -25,6 +31,32 @@ type Executor struct {
 	workDir             string
 	abbreviationsEnable bool
 	colors              *terminal.ColorScheme
+	funcDepth           int32 // Nesting level of running function calls
+}
+
+// frame holds the streams a command executes with.
+// Pipelines give each stage its own frame so that stages can run concurrently.
+type frame struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	colors *terminal.ColorScheme
+	env    *env.Environment // Variables; a function call gets its own scope
+	dir    *string          // Working directory; a subshell gets its own
+	loops  int              // Number of enclosing loops, for break and continue
+	inFunc bool             // True within a function body, for return
+	job    *jobs.Job        // Job the commands run in, nil outside jobs
+
+	// Functions, aliases and traps; a subshell gets its own copies
+	functions *builtins.Functions
+	aliases   *builtins.Aliases
+	traps     *builtins.Traps
+
+	condition bool // Failures are tested, as in if conditions: no ERR trap or errexit
+	trapsOff  bool // ERR and DEBUG traps are not inherited by functions and subshells
+	inTrap    bool // Running a trap or hook, which does not run traps itself
+
+	aliasing map[string]bool // Aliases being expanded, not to be expanded again
 }
 
 // Option is a functional option for configuring the Executor.
@@ -37,6 +69,27 @@ func WithRegistry(r *builtins.Registry) Option {
 	}
 }
 
+// WithAliases sets the alias table.
+func WithAliases(a *builtins.Aliases) Option {
+	return func(e *Executor) {
+		e.aliases = a
+	}
+}
+
+// WithJobs sets the job table.
+func WithJobs(t *jobs.Table) Option {
+	return func(e *Executor) {
+		e.jobs = t
+	}
+}
+
+// WithTraps sets the trap table.
+func WithTraps(t *builtins.Traps) Option {
+	return func(e *Executor) {
+		e.traps = t
+	}
+}
+
 // WithEnv sets the environment.
 func WithEnv(env *env.Environment) Option {
 	return func(e *Executor) {
@@ -90,6 +143,10 @@ func WithColors(colors *terminal.ColorScheme) Option {
 func New(opts ...Option) *Executor {
 	e := &Executor{
 		registry:            builtins.NewRegistry(),
+		aliases:             builtins.NewAliases(),
+		functions:           builtins.NewFunctions(),
+		jobs:                jobs.NewTable(),
+		traps:               builtins.NewTraps(),
 		env:                 env.New(),
 		stdin:               os.Stdin,
 		stdout:              os.Stdout,
@@ -107,6 +164,11 @@ func New(opts ...Option) *Executor {
 		opt(e)
 	}
 
+	// $PWD follows the working directory, even if inherited from elsewhere
+	if e.workDir != "" && e.env.Get("PWD") != e.workDir {
+		e.env.Set("PWD", e.workDir)
+	}
+
 	return e
 }
 
@@ -115,18 +177,152 @@ func (e *Executor) Registry() *builtins.Registry {
 	return e.registry
 }
 
+// Aliases returns the alias table.
+func (e *Executor) Aliases() *builtins.Aliases {
+	return e.aliases
+}
+
+// Functions returns the shell function table.
+func (e *Executor) Functions() *builtins.Functions {
+	return e.functions
+}
+
+// Jobs returns the job table.
+func (e *Executor) Jobs() *jobs.Table {
+	return e.jobs
+}
+
+// Traps returns the trap table.
+func (e *Executor) Traps() *builtins.Traps {
+	return e.traps
+}
+
 // Env returns the environment.
 func (e *Executor) Env() *env.Environment {
 	return e.env
 }
 
+// rootFrame returns a frame using the executor's own streams.
+func (e *Executor) rootFrame() *frame {
+	return &frame{
+		stdin:  e.stdin,
+		stdout: e.stdout,
+		stderr: e.stderr,
+		colors: e.colors,
+		env:    e.env,
+		dir:    &e.workDir,
+
+		functions: e.functions,
+		aliases:   e.aliases,
+		traps:     e.traps,
+	}
+}
+
 // Execute executes a parsed command.
 // Returns the exit code and any error that occurred.
 func (e *Executor) Execute(ctx context.Context, cmd *parser.Command) (int, error) {
+	return e.executeCommand(ctx, cmd, e.rootFrame())
+}
+
+// ExecuteNode executes a syntax tree produced by the parser.
+// Returns the exit code of the last command executed and any error that occurred.
+func (e *Executor) ExecuteNode(ctx context.Context, node parser.Node) (int, error) {
+	return e.executeNode(ctx, node, e.rootFrame())
+}
+
+// executeNode executes a syntax tree node within the given frame.
+func (e *Executor) executeNode(ctx context.Context, node parser.Node, fr *frame) (int, error) {
+	switch n := node.(type) {
+	case nil:
+		return 0, nil
+	case *parser.SimpleCommand:
+		if !fr.trapsOff {
+			if err := e.runTrap(ctx, "DEBUG", fr); err != nil {
+				return 1, err
+			}
+		}
+		if name := e.aliasName(n, fr); name != "" {
+			return e.executeAlias(ctx, n, name, fr)
+		}
+		cmd, substStatus, err := e.expandCommand(ctx, n, fr)
+		if err != nil {
+			return 1, err
+		}
+		redirected, files, err := e.applyRedirects(ctx, n.Redirects, fr)
+		if err != nil {
+			return 1, err
+		}
+		defer closeFiles(files)
+		code, err := e.executeCommand(ctx, cmd, redirected)
+		// NAME=$(cmd) alone has the status of the substitution
+		if cmd != nil && cmd.Name == "" && len(cmd.Args) == 0 {
+			code = substStatus
+		}
+		return code, err
+	case *parser.Pipeline:
+		return e.executePipeline(ctx, n, fr)
+	case *parser.List:
+		return e.executeList(ctx, n, fr)
+	case *parser.Background:
+		return e.executeBackground(ctx, n, fr)
+	case *parser.IfClause:
+		return e.executeIf(ctx, n, fr)
+	case *parser.WhileClause:
+		return e.executeWhile(ctx, n, fr)
+	case *parser.ForClause:
+		return e.executeFor(ctx, n, fr)
+	case *parser.CaseClause:
+		return e.executeCase(ctx, n, fr)
+	case *parser.Subshell:
+		return e.executeSubshell(ctx, n, fr)
+	case *parser.BraceGroup:
+		return e.executeList(ctx, n.Body, fr)
+	case *parser.RedirectedCommand:
+		return e.executeRedirected(ctx, n, fr)
+	case *parser.FunctionDef:
+		e.defineFunction(n, fr)
+		return 0, nil
+	default:
+		return 1, fmt.Errorf("%w: unsupported node %T", errors.ErrInvalidSyntax, node)
+	}
+}
+
+// expandCommand expands the tokens of a simple command into a Command
+// using the environment of the frame. Also returns the exit status of the
+// last command substitution, or 0 if there is none.
+func (e *Executor) expandCommand(ctx context.Context, sc *parser.SimpleCommand, fr *frame) (*parser.Command, int, error) {
+	status := 0
+	cmd, err := e.newStatusParser(ctx, sc.Tokens, fr, &status).Parse()
+	if cmd != nil {
+		cmd.RawInput = sc.RawInput
+	}
+	return cmd, status, err
+}
+
+// executeCommand executes an expanded command within the given frame.
+func (e *Executor) executeCommand(ctx context.Context, cmd *parser.Command, fr *frame) (int, error) {
 	if cmd == nil {
 		return 0, nil // Empty command
 	}
 
+	if len(cmd.Assignments) > 0 {
+		if cmd.Name == "" && len(cmd.Args) == 0 {
+			// NAME=value alone sets shell variables
+			if fr.env.Option("xtrace") {
+				e.trace(cmd, fr)
+			}
+			for _, a := range cmd.Assignments {
+				fr.env.Set(a.Name, a.Value)
+			}
+			return 0, nil
+		}
+		fr = e.assignmentFrame(cmd.Assignments, fr)
+	}
+
+	if cmd.Name == "" && len(cmd.Args) == 0 {
+		return 0, nil // Command that expanded to nothing
+	}
+
 	// Handle Windows drive letters (e.g., "c:", "D:") as "cd <drive>:"
 	if isWindowsDriveLetter(cmd.Name) {
 		// Transform into a cd command
@@ -139,8 +335,8 @@ func (e *Executor) Execute(ctx context.Context, cmd *parser.Command) (int, error
 		cmd = cdCmd
 	}
 
-	// Resolve command name (handle abbreviations)
-	resolved, alternatives, err := e.ResolveCommand(cmd.Name)
+	// Resolve command name (handle abbreviations); aliases were expanded before
+	resolved, alternatives, err := e.resolveCommand(cmd.Name, fr, false)
 	if err != nil {
 		if err == errors.ErrAmbiguousCommand {
 			return 1, fmt.Errorf("%w: %s (did you mean: %v?)", err, cmd.Name, alternatives)
@@ -149,30 +345,98 @@ func (e *Executor) Execute(ctx context.Context, cmd *parser.Command) (int, error
 	}
 
 	cmd.Resolved = resolved
+	if fr.env.Option("xtrace") {
+		e.trace(cmd, fr)
+	}
 
-	// Try builtin first
+	// Functions hide builtins of the same name
+	if def, ok := fr.functions.Get(resolved); ok {
+		return e.executeBuiltin(ctx, cmd, def, fr)
+	}
 	if def, ok := e.registry.Get(resolved); ok {
-		return e.executeBuiltin(ctx, cmd, def)
+		return e.executeBuiltin(ctx, cmd, def, fr)
 	}
 
 	// Try external command
-	return e.executeExternal(ctx, cmd)
+	return e.executeExternal(ctx, cmd, fr)
+}
+
+// trace prints a command about to run to stderr for the xtrace option: its
+// assignments, resolved name and expanded words, after the expansion of
+// PS4 ("+ " by default).
+func (e *Executor) trace(cmd *parser.Command, fr *frame) {
+	prefix, ok := fr.env.Lookup("PS4")
+	if ok {
+		prefix = fr.env.Expand(prefix)
+	} else {
+		prefix = "+ "
+	}
+
+	words := make([]string, 0, len(cmd.Assignments)+len(cmd.Words)+1)
+	for _, a := range cmd.Assignments {
+		words = append(words, a.Name+"="+traceQuote(a.Value))
+	}
+	if cmd.Resolved != "" {
+		words = append(words, traceQuote(cmd.Resolved))
+	}
+	for _, word := range cmd.Words {
+		words = append(words, traceQuote(word))
+	}
+	fmt.Fprintln(fr.stderr, prefix+strings.Join(words, " "))
+}
+
+// traceQuote quotes a traced word with single quotes if it is empty or
+// contains characters that are special to the shell.
+func traceQuote(word string) string {
+	safe := word != "" && strings.IndexFunc(word, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-+=.,:/@%^", r))
+	}) < 0
+	if safe {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// assignmentFrame returns the frame for a command preceded by NAME=value
+// words: the variables are exported in a scope that ends with the command.
+func (e *Executor) assignmentFrame(assignments []parser.Assignment, fr *frame) *frame {
+	scope := fr.env.NewScope(fr.env.Args())
+	for _, a := range assignments {
+		scope.Local(a.Name, a.Value)
+		scope.Export(a.Name)
+	}
+
+	assigned := *fr
+	assigned.env = scope
+	return &assigned
 }
 
 // ExecuteInput parses and executes a command string.
 func (e *Executor) ExecuteInput(ctx context.Context, input string) (int, error) {
-	cmd, err := parser.ParseInputWithEnv(input, e.env)
+	node, err := parser.ParseScriptInput(input)
 	if err != nil {
+		e.env.SetStatus(1)
 		return 1, err
 	}
-	return e.Execute(ctx, cmd)
+	return e.ExecuteNode(ctx, node)
 }
 
-// ResolveCommand resolves a command name, handling abbreviations.
+// ResolveCommand resolves a command name, handling aliases and abbreviations.
 // Returns the resolved name, any alternatives (for ambiguous commands), and error.
 func (e *Executor) ResolveCommand(name string) (string, []string, error) {
+	return e.resolveCommand(name, e.rootFrame(), true)
+}
+
+// resolveCommand resolves a command name with the functions and aliases of
+// the frame. Alias names are only candidates if withAliases is true; an
+// alias takes precedence over a command of the same name.
+func (e *Executor) resolveCommand(name string, fr *frame, withAliases bool) (string, []string, error) {
+	if withAliases && fr.aliases.Has(name) {
+		return name, nil, nil
+	}
+
 	// Check for exact match first
-	if e.registry.Has(name) {
+	if fr.functions.Has(name) || e.registry.Has(name) {
 		return name, nil, nil
 	}
 
@@ -185,15 +449,20 @@ func (e *Executor) ResolveCommand(name string) (string, []string, error) {
 		return "", nil, fmt.Errorf("%w: %s", errors.ErrCommandNotFound, name)
 	}
 
+	// An external command with this exact name takes precedence over
+	// abbreviations (e.g., "tr" is not an abbreviation of "true")
+	if _, err := exec.LookPath(name); err == nil {
+		return name, nil, nil
+	}
+
 	// Try to match as prefix
-	matches := e.registry.Match(name)
+	matches := mergeNames(e.registry.Match(name), fr.functions.Match(name))
+	if withAliases {
+		matches = mergeNames(matches, fr.aliases.Match(name))
+	}
 
 	switch len(matches) {
 	case 0:
-		// No builtin matches, check external command
-		if _, err := exec.LookPath(name); err == nil {
-			return name, nil, nil
-		}
 		return "", nil, fmt.Errorf("%w: %s", errors.ErrCommandNotFound, name)
 
 	case 1:
@@ -207,26 +476,54 @@ func (e *Executor) ResolveCommand(name string) (string, []string, error) {
 }
 
 // executeBuiltin executes a builtin command.
-func (e *Executor) executeBuiltin(ctx context.Context, cmd *parser.Command, def builtins.Definition) (int, error) {
+func (e *Executor) executeBuiltin(ctx context.Context, cmd *parser.Command, def builtins.Definition, fr *frame) (int, error) {
 	execCtx := &builtins.Context{
-		Stdin:   e.stdin,
-		Stdout:  e.stdout,
-		Stderr:  e.stderr,
-		Env:     e.env,
-		WorkDir: e.workDir,
-		Colors:  e.colors,
+		Stdin:     fr.stdin,
+		Stdout:    fr.stdout,
+		Stderr:    fr.stderr,
+		Env:       fr.env,
+		WorkDir:   *fr.dir,
+		Colors:    fr.colors,
+		Aliases:   fr.aliases,
+		Functions: fr.functions,
+		Jobs:      e.jobs,
+		Traps:     fr.traps,
+	}
+	execCtx.Run = func(ctx context.Context, node parser.Node) (int, error) {
+		return e.runSourced(ctx, node, execCtx, fr)
+	}
+
+	// Commands run by functions belong to the job of the call, and are
+	// tested along with it
+	if fr.job != nil {
+		ctx = context.WithValue(ctx, jobKey{}, fr.job)
+	}
+	if fr.condition {
+		ctx = context.WithValue(ctx, conditionKey{}, true)
 	}
 
 	code, err := def.Handler(ctx, cmd, execCtx)
 
-	// Sync workDir from context (in case builtin changed it, e.g., goto/cd)
-	if execCtx.WorkDir != e.workDir {
-		e.workDir = execCtx.WorkDir
+	// Sync the working directory from context (in case builtin changed it, e.g., cd).
+	// The shell's own directory is also the process one, for completion.
+	if execCtx.WorkDir != *fr.dir {
+		*fr.dir = execCtx.WorkDir
+		if fr.dir == &e.workDir {
+			if chErr := os.Chdir(e.workDir); chErr != nil && err == nil {
+				err = chErr
+			}
+		}
 	}
 
 	return code, err
 }
 
+// reportError writes an error that does not stop the commands to w, with
+// the offending source of diagnostics.
+func reportError(w io.Writer, err error) {
+	fmt.Fprintf(w, "error: %s\n", errors.Format(err, ""))
+}
+
 // Colors returns the color scheme.
 func (e *Executor) Colors() *terminal.ColorScheme {
 	return e.colors
@@ -238,7 +535,7 @@ func (e *Executor) SetColors(colors *terminal.ColorScheme) {
 }
 
 // executeExternal executes an external command.
-func (e *Executor) executeExternal(ctx context.Context, cmd *parser.Command) (int, error) {
+func (e *Executor) executeExternal(ctx context.Context, cmd *parser.Command, fr *frame) (int, error) {
 	// Look up the command
 	path, err := exec.LookPath(cmd.Resolved)
 	if err != nil {
@@ -249,15 +546,27 @@ func (e *Executor) executeExternal(ctx context.Context, cmd *parser.Command) (in
 	args := cmd.AllArgs()
 
 	// Create the command
-	extCmd := exec.CommandContext(ctx, path, args...)
-	extCmd.Stdin = e.stdin
-	extCmd.Stdout = e.stdout
-	extCmd.Stderr = e.stderr
-	extCmd.Env = e.env.ToSlice()
-	extCmd.Dir = e.workDir
+	newCmd := func() *exec.Cmd {
+		extCmd := exec.CommandContext(ctx, path, args...)
+		extCmd.Stdin = fr.stdin
+		extCmd.Stdout = fr.stdout
+		extCmd.Stderr = fr.stderr
+		extCmd.Env = fr.env.ToSlice()
+		extCmd.Dir = *fr.dir
+		return extCmd
+	}
+
+	// Processes of a job join its process group
+	if fr.job != nil {
+		extCmd, err := fr.job.StartProcess(newCmd)
+		if err != nil {
+			return 1, err
+		}
+		return fr.job.WaitProcess(extCmd)
+	}
 
 	// Run the command
-	err = extCmd.Run()
+	err = newCmd().Run()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return exitErr.ExitCode(), nil
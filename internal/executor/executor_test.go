@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/sdejongh/jsishell/internal/builtins"
@@ -384,3 +387,161 @@ func TestResolveCommandPrefersBuiltin(t *testing.T) {
 		t.Error("should resolve to builtin, not external")
 	}
 }
+
+// newVariableTestExecutor creates a function test executor with the
+// export, unset and set builtins.
+func newVariableTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newFunctionTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.ExportDefinition())
+	e.registry.Register(builtins.UnsetDefinition())
+	e.registry.Register(builtins.SetDefinition())
+	return e
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		code   int
+	}{
+		{"assignment", "x=hello; echo $x", "hello\n", 0},
+		{"no word splitting", `x="a  b"; y=$x; echo "$y"`, "a  b\n", 0},
+		{"several", "a=1 b=2; echo $a$b", "12\n", 0},
+		{"prefix is temporary", "x=1; x=2 true; echo $x", "1\n", 0},
+		{"prefix for function", "show() { echo $x; }; x=2 show; echo [$x]", "2\n[]\n", 0},
+		{"function sets global", "f() { y=in; }; f; echo $y", "in\n", 0},
+		{"unset", "x=1; unset x; echo [$x]", "[]\n", 0},
+		{"status", "fail; echo $?; echo $?", "3\n0\n", 0},
+		{"status of and-or", "fail || echo $?", "3\n", 0},
+		{"status of function", "f() { return 4; }; f; echo $?", "4\n", 0},
+		{"status in condition", "if fail; then true; else echo $?; fi", "3\n", 0},
+		{"set positional", "set a b c; echo $# $2", "3 b\n", 0},
+		{"set dash", "set -- -v x; echo $1 $#", "-v 2\n", 0},
+		{"set invalid option", "set -Q", "", 1},
+		{"export invalid", "export 1x=2", "", 1},
+		{"no background job", "echo [$!]", "[]\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newVariableTestExecutor(&stdout, &stderr)
+			e.env = env.New()
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestGlobExpansion(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"glob", "echo *.go", "a.go b.go\n"},
+		{"globstar", "echo **/*.go", "a.go b.go sub/c.go\n"},
+		{"extglob", "echo !(*.go)", "notes.txt sub\n"},
+		{"braces", "for f in {a,b}.go x{1..2}; do echo $f; done", "a.go\nb.go\nx1\nx2\n"},
+		{"no match", "echo *.md", "*.md\n"},
+		{"nullglob", "set -o nullglob; echo x *.md", "x\n"},
+		{"failglob", "set -o failglob; echo *.md; echo after", "after\n"},
+		{"failglob in for", "set -o failglob; for f in *.md; do echo $f; done", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newVariableTestExecutor(&stdout, &stderr)
+			e.env = env.New()
+			e.workDir = t.TempDir()
+			for _, name := range []string{"a.go", "b.go", "notes.txt", "sub/c.go"} {
+				path := filepath.Join(e.workDir, filepath.FromSlash(name))
+				os.MkdirAll(filepath.Dir(path), 0755)
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e.ExecuteInput(context.Background(), tt.input)
+			if got := filepath.ToSlash(stdout.String()); got != tt.stdout {
+				t.Errorf("stdout = %q, want %q", got, tt.stdout)
+			}
+		})
+	}
+}
+
+func TestVariableExport(t *testing.T) {
+	if _, err := exec.LookPath("printenv"); err != nil {
+		t.Skipf("skipping: external command 'printenv' not found: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"shell variable", "JSI_X=1; printenv JSI_X", ""},
+		{"exported", "JSI_X=1; export JSI_X; printenv JSI_X", "1\n"},
+		{"export assignment", "export JSI_X=2; printenv JSI_X", "2\n"},
+		{"prefix", "JSI_X=3 printenv JSI_X; printenv JSI_X", "3\n"},
+		{"export -n", "export JSI_X=4; export -n JSI_X; printenv JSI_X; echo $JSI_X", "4\n"},
+		{"assignment keeps export", "export JSI_X=5; JSI_X=6; printenv JSI_X", "6\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newVariableTestExecutor(&stdout, &stderr)
+			e.env = env.New()
+
+			e.ExecuteInput(context.Background(), tt.input)
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestLastBackgroundJob(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skipf("skipping: external command 'sleep' not found: %v", err)
+	}
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"process", "sleep 0.1 &\nwait $!; echo $?", "0\n"},
+		{"no process", "(echo a; exit 3) & wait $!; echo $?", "a\n3\n"},
+		{"loop", "i=0; while true; do i=$((i+1)); done & echo $!; kill $!", "%1\n"},
+		{"whole job", "while true; do sleep 0.1; done & kill $!; wait $!; echo $?", "143\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newJobTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// maxFunctionDepth limits nested function calls so that runaway recursion
+// fails with an error instead of exhausting the stack.
+const maxFunctionDepth = 1000
+
+// defineFunction defines a shell function as a command so that it is
+// resolved, abbreviated and completed like a builtin.
+func (e *Executor) defineFunction(fn *parser.FunctionDef, fr *frame) {
+	fr.functions.Set(builtins.Definition{
+		Name:        fn.Name,
+		Description: "Shell function",
+		Usage:       fn.Name + " [args...]",
+		Handler:     e.functionHandler(fn),
+	})
+}
+
+// runSourced runs the commands of a file read by the source builtin, in the
+// variables, working directory and I/O of the command calling it. Like a
+// function body, the file can be left with return.
+func (e *Executor) runSourced(ctx context.Context, node parser.Node, execCtx *builtins.Context, fr *frame) (int, error) {
+	if depth := atomic.AddInt32(&e.funcDepth, 1); depth > maxFunctionDepth {
+		atomic.AddInt32(&e.funcDepth, -1)
+		return 1, fmt.Errorf("source: maximum nesting level (%d) exceeded", maxFunctionDepth)
+	}
+	defer atomic.AddInt32(&e.funcDepth, -1)
+
+	sourced := *fr
+	sourced.dir = &execCtx.WorkDir
+	sourced.loops = 0
+	sourced.inFunc = true
+
+	code, err := e.executeNode(ctx, node, &sourced)
+
+	var rc builtins.ReturnCode
+	if goerrors.As(err, &rc) {
+		return rc.Code, nil
+	}
+	return code, err
+}
+
+// functionHandler returns the handler running the body of a function.
+// The body sees the arguments as positional parameters and runs in a new
+// variable scope, so that local declarations disappear when it returns.
+func (e *Executor) functionHandler(fn *parser.FunctionDef) builtins.Handler {
+	return func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+		if depth := atomic.AddInt32(&e.funcDepth, 1); depth > maxFunctionDepth {
+			atomic.AddInt32(&e.funcDepth, -1)
+			return 1, fmt.Errorf("%s: maximum function nesting level (%d) exceeded", fn.Name, maxFunctionDepth)
+		}
+		defer atomic.AddInt32(&e.funcDepth, -1)
+
+		fr := &frame{
+			stdin:  execCtx.Stdin,
+			stdout: execCtx.Stdout,
+			stderr: execCtx.Stderr,
+			colors: execCtx.Colors,
+			env:    execCtx.Env.NewScope(cmd.Words),
+			dir:    &execCtx.WorkDir,
+			inFunc: true,
+			job:    jobFromContext(ctx),
+
+			functions: execCtx.Functions,
+			aliases:   execCtx.Aliases,
+			traps:     execCtx.Traps,
+
+			condition: inCondition(ctx),
+			trapsOff:  true,
+		}
+
+		code, err := e.executeNode(ctx, fn.Body, fr)
+
+		var rc builtins.ReturnCode
+		if goerrors.As(err, &rc) {
+			return rc.Code, nil
+		}
+		return code, err
+	}
+}
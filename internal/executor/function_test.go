@@ -0,0 +1,259 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+)
+
+// newFunctionTestExecutor creates a control test executor with the
+// return and local builtins.
+func newFunctionTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newControlTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.ReturnDefinition())
+	e.registry.Register(builtins.LocalDefinition())
+	return e
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		code   int
+	}{
+		{"call", "greet() { echo hello; }; greet", "hello\n", 0},
+		{"function keyword", "function greet { echo hello; }; greet", "hello\n", 0},
+		{"arguments", `show() { echo "$# $1 $2"; }; show a "b c"`, "2 a b c\n", 0},
+		{"all arguments", `show() { for a; do echo "[$a]"; done; }; show x -v --all`, "[x]\n[-v]\n[--all]\n", 0},
+		{"status", "f() { fail; }; f", "", 3},
+		{"return", "f() { echo a; return; echo b; }; f", "a\n", 0},
+		{"return status", "f() { return 5; }; f", "", 5},
+		{"return in loop", "f() { for x in a b c; do echo $x; return 2; done; echo no; }; f", "a\n", 2},
+		{"return in if", "f() { if true; then return 4; fi; echo no; }; f; echo after", "after\n", 0},
+		{"condition", "ok() { return 0; }; if ok; then echo yes; fi", "yes\n", 0},
+		{"nested", "inner() { echo inner $1; }; outer() { inner $1; echo outer; }; outer x", "inner x\nouter\n", 0},
+		{"recursion", "count() { if true; then echo $1; fi; case $1 in xxx) return;; esac; count x$1; }; count x", "x\nxx\nxxx\n", 0},
+		{"redefine", "f() { echo 1; }; f() { echo 2; }; f", "2\n", 0},
+		{"pipeline", "f() { echo hello; }; f | upper", "HELLO\n", 0},
+		{"multi-line", "greet()\n{\n  echo hello $1\n}\ngreet bob", "hello bob\n", 0},
+		{"exit", "f() { exit 6; echo no; }; f; echo no", "", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newFunctionTestExecutor(&stdout, &stderr)
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil && !isExitRequest(err) {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
+
+func TestFunctionRegistered(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+
+	if _, err := e.ExecuteInput(context.Background(), "greet() { echo hello; }"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	def, ok := e.Functions().Get("greet")
+	if !ok {
+		t.Fatal("function should be defined as a command")
+	}
+	if def.Description == "" {
+		t.Error("function should have a description for completion")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("defining a function should not run it, stdout = %q", stdout.String())
+	}
+}
+
+func TestFunctionHidesBuiltin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+	e.registry.Register(builtins.UnsetDefinition())
+
+	input := "upper() { echo function; }; echo a | upper; unset -f upper; echo b | upper"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if want := "function\nB\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if _, ok := e.Registry().Get("upper"); !ok {
+		t.Error("a function should not replace the builtin it hides")
+	}
+}
+
+func TestFunctionLocalVariables(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+	e.env.Set("name", "outer")
+
+	// for assigns its variable like NAME=value would
+	input := `f() { local name=$1 tmp; for name in changed$name; do true; done; for shared in set; do true; done; echo "in: $name"; }; f inner; echo "out: $name $shared"`
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if want := "in: changedinner\nout: outer set\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if _, ok := e.env.All()["tmp"]; ok {
+		t.Error("local variable should not leak into the caller")
+	}
+}
+
+func TestFunctionAbbreviation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+	e.abbreviationsEnable = true
+
+	if _, err := e.ExecuteInput(context.Background(), "greetings() { echo hi; }; greetin"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "hi\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "hi\n")
+	}
+}
+
+func TestFunctionPositionalParametersRestored(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+	e.env.SetPositional("script", []string{"one"})
+
+	if _, err := e.ExecuteInput(context.Background(), `f() { echo "$0 $1"; }; f two; echo "$0 $1"`); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if want := "script two\nscript one\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestFunctionRedirect(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+	// Redirections of the definition apply to every call
+	input := "log() { echo \"$@\"; } >> log.txt; log one; log two > other.txt"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "log.txt")); got != "one\ntwo\n" {
+		t.Errorf("log.txt = %q, want %q", got, "one\ntwo\n")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", stdout.String())
+	}
+}
+
+func TestReturnOutsideFunction(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+
+	_, err := e.ExecuteInput(context.Background(), "return 1; echo after")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "after\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "after\n")
+	}
+	if !strings.Contains(stderr.String(), "only be used in a function") {
+		t.Errorf("stderr = %q, want return error", stderr.String())
+	}
+}
+
+func TestFunctionRecursionLimit(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newFunctionTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "loop() { loop; }; loop")
+	if err == nil || !strings.Contains(err.Error(), "nesting level") {
+		t.Errorf("error = %v, want nesting level error", err)
+	}
+	if exitCode == 0 {
+		t.Error("exitCode = 0, want failure")
+	}
+}
+
+func TestSource(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.registry.Register(builtins.ReturnDefinition())
+	e.registry.Register(builtins.SourceDefinition())
+	e.registry.Register(builtins.DotDefinition())
+	e.env.SetPositional("script", []string{"one"})
+
+	lib := "greeting=hello\ngreet() { echo \"$greeting $1\"; }\necho \"sourced $# $1\"\nreturn 3\necho never\n"
+	if err := os.WriteFile(filepath.Join(dir, "lib.jsi"), []byte(lib), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "source lib.jsi a b; echo $?; greet bob; . lib.jsi; echo $1"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	want := "sourced 2 a\n3\nhello bob\nsourced 1 one\none\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want empty", stderr.String())
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stderr string
+		code   int
+	}{
+		{"missing", "source missing.jsi", "source: missing.jsi: No such file or directory\n", 1},
+		{"no file", "source", "source: missing file operand\n", 2},
+		{"syntax", "source broken.jsi", "source: broken.jsi:2:1: invalid syntax: unexpected token \"fi\"\n  2 | fi\n    | ^\n", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+			e.registry.Register(builtins.SourceDefinition())
+			e.colors = nil
+			if err := os.WriteFile(filepath.Join(dir, "broken.jsi"), []byte("echo a\nfi\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stderr.String() != tt.stderr {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.stderr)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want empty", stdout.String())
+			}
+		})
+	}
+}
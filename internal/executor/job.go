@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/jobs"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// jobKey is the context key of the job a builtin runs in.
+type jobKey struct{}
+
+// jobFromContext returns the job a builtin runs in, or nil.
+func jobFromContext(ctx context.Context) *jobs.Job {
+	job, _ := ctx.Value(jobKey{}).(*jobs.Job)
+	return job
+}
+
+// conditionKey is the context key marking a builtin, such as a function,
+// that runs as a tested condition.
+type conditionKey struct{}
+
+// inCondition returns true if a builtin runs as a tested condition.
+func inCondition(ctx context.Context) bool {
+	condition, _ := ctx.Value(conditionKey{}).(bool)
+	return condition
+}
+
+// executeBackground starts commands as a background job and returns at once.
+// Like a subshell, the job has its own copy of the variables and cannot
+// leave enclosing loops or functions. It reads an empty input unless its
+// input is redirected. $! expands to the job spec of the job, such as %1,
+// known at once, so that wait and kill reach all of its processes.
+func (e *Executor) executeBackground(ctx context.Context, b *parser.Background, fr *frame) (int, error) {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := e.jobs.NewJob(b.RawInput, cancel, false)
+	id := e.jobs.Add(job)
+
+	bg := subshell(fr)
+	bg.stdin = strings.NewReader("")
+	bg.job = job
+
+	go func() {
+		defer cancel()
+		code, err := e.executeNode(jobCtx, b.Node, bg)
+		// A job ended by kill has the status of the signal, unless its
+		// processes handled the signal and exited on their own
+		if sig := job.Signaled(); sig != nil && (code == statusInterrupted || goerrors.Is(err, context.Canceled)) {
+			code, err = jobs.SignalStatus(sig), nil
+		}
+		if err != nil && !isControlFlow(err) {
+			reportError(bg.stderr, err)
+		}
+		job.Finish(code)
+	}()
+
+	fr.env.SetLastJob("%" + strconv.Itoa(id))
+	if e.jobs.JobControl() {
+		fmt.Fprintf(fr.stderr, "[%d]\n", id)
+	}
+	return 0, nil
+}
+
+// executeForeground runs a pipeline as a foreground job, whose processes get
+// the terminal. If the job is stopped with Ctrl+Z, it is added to the job
+// table and the shell goes on while the job waits to be continued.
+func (e *Executor) executeForeground(ctx context.Context, p *parser.Pipeline, fr *frame) (int, error) {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := e.jobs.NewJob(p.RawInput, cancel, true)
+
+	fg := *fr
+	fg.job = job
+
+	var code int
+	var err error
+	go func() {
+		defer cancel()
+		code, err = e.executePipeline(jobCtx, p, &fg)
+		job.Finish(code)
+	}()
+
+	if job.RunForeground(ctx) == jobs.Stopped {
+		e.jobs.Add(job)
+		return jobs.StoppedStatus, nil
+	}
+	return code, err
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/jobs"
+)
+
+// newJobTestExecutor creates a function test executor with the job
+// control builtins.
+func newJobTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newFunctionTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.JobsDefinition())
+	e.registry.Register(builtins.WaitDefinition())
+	e.registry.Register(builtins.KillDefinition())
+	return e
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		code   int
+	}{
+		{"wait all", "echo a &\nwait; echo b", "a\nb\n", 0},
+		{"wait job", "fail &\nwait %1", "", 3},
+		{"and-or list", "fail || echo recovered &\nwait %1", "recovered\n", 0},
+		{"status of background", "fail &\necho $x", "\n", 0},
+		{"variables are copied", "for x in 1; do true; done; for x in 2; do true; done &\nwait; echo $x", "1\n", 0},
+		{"loop in background", "for x in a b; do echo $x; done & wait", "a\nb\n", 0},
+		{"no such job", "wait %3", "", 127},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newJobTestExecutor(&stdout, &stderr)
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestBackgroundJobTable(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newJobTestExecutor(&stdout, &stderr)
+
+	if _, err := e.ExecuteInput(context.Background(), "echo a &"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+
+	list := e.Jobs().Jobs()
+	if len(list) != 1 {
+		t.Fatalf("got %d jobs, want 1", len(list))
+	}
+	if state := list[0].Wait(context.Background()); list[0].Command != "echo a" || state != jobs.Done {
+		t.Errorf("job = %q (%v), want \"echo a\" (Done)", list[0].Command, state)
+	}
+	// Without job control, the job number is not printed
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want empty", stderr.String())
+	}
+}
+
+func TestKillBackgroundJob(t *testing.T) {
+	for _, name := range []string{"sh", "sleep"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("skipping: external command '%s' not found: %v", name, err)
+		}
+	}
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"loop", "while true; do true; done & kill %1; wait %1; echo $?", "143\n"},
+		{"process", "sh -c 'sleep 10' & kill %1; wait %1; echo $?", "143\n"},
+		{"trap", `sh -c 'exec 2>/dev/null; trap "echo bye; exit 3" TERM; while :; do sleep 0.05; done' & sleep 0.3 >/dev/null 2>&1; kill %1; wait %1; echo $?`, "bye\n3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newJobTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// executeList runs the items of a command list in order.
+// Items joined by && only run if the previous status is zero, items joined by ||
+// only if it is non-zero. Errors of commands that are followed by further
+// commands are reported to stderr; the status and error of the last command
+// run are returned. The status of each item is available to the next as $?.
+func (e *Executor) executeList(ctx context.Context, l *parser.List, fr *frame) (int, error) {
+	status := 0
+	var lastErr error
+
+	for i, item := range l.Items {
+		switch item.Op {
+		case parser.ListAnd:
+			if status != 0 {
+				continue
+			}
+		case parser.ListOr:
+			if status == 0 {
+				continue
+			}
+		}
+
+		if lastErr != nil {
+			reportError(fr.stderr, lastErr)
+			lastErr = nil
+		}
+
+		// Traps of the signals caught so far run between commands
+		if err := e.runPendingTraps(ctx, fr); err != nil {
+			return status, err
+		}
+
+		// The status of an item followed by && or || is tested
+		itemFrame := fr
+		if i+1 < len(l.Items) && l.Items[i+1].Op != parser.ListSeq {
+			tested := *fr
+			tested.condition = true
+			itemFrame = &tested
+		}
+
+		code, err := e.executeNode(ctx, item.Node, itemFrame)
+		status = code
+		fr.env.SetStatus(code)
+		// exit, break or continue within a loop, and return within a
+		// function skip the rest of the list
+		if unwinds(err, fr) || ctx.Err() != nil {
+			return code, err
+		}
+		if code != 0 {
+			if exitErr := e.failed(ctx, item.Node, code, itemFrame); exitErr != nil {
+				if err != nil && !isControlFlow(err) {
+					reportError(fr.stderr, err)
+				}
+				return code, exitErr
+			}
+		}
+		lastErr = err
+	}
+
+	return status, lastErr
+}
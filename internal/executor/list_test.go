@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+func TestListExecution(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdout string
+		code   int
+	}{
+		{"echo a ; echo b", "a\nb\n", 0},
+		{"echo a && echo b", "a\nb\n", 0},
+		{"fail && echo b", "", 3},
+		{"fail || echo b", "b\n", 0},
+		{"echo a || echo b", "a\n", 0},
+		{"fail && echo b || echo c", "c\n", 0},
+		{"echo a || echo b && echo c", "a\nc\n", 0},
+		{"fail ; echo b", "b\n", 0},
+		{"echo a ; fail", "a\n", 3},
+		{"echo a | fail && echo b", "", 3},
+		{"echo a\necho b", "a\nb\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newPipelineTestExecutor(&stdout, &stderr)
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if exitCode != tt.code {
+				t.Errorf("exitCode = %d, want %d", exitCode, tt.code)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestListErrorPropagation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	// The error of a command followed by others is reported, not returned
+	exitCode, err := e.ExecuteInput(context.Background(), "nonexistentcommand12345 ; echo next")
+	if err != nil {
+		t.Errorf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "command not found") {
+		t.Errorf("stderr = %q, want command not found error", stderr.String())
+	}
+
+	// The error of the last command run is returned
+	stderr.Reset()
+	exitCode, err = e.ExecuteInput(context.Background(), "nonexistentcommand12345 && echo skipped")
+	if !errors.Is(err, shellerrors.ErrCommandNotFound) {
+		t.Errorf("error = %v, want ErrCommandNotFound", err)
+	}
+	if exitCode != 127 {
+		t.Errorf("exitCode = %d, want 127", exitCode)
+	}
+	if strings.Contains(stdout.String(), "skipped") {
+		t.Errorf("stdout = %q, command after && should not run", stdout.String())
+	}
+}
+
+func TestListStopsOnExit(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+	e.registry.Register(builtins.ExitDefinition())
+
+	exitCode, err := e.ExecuteInput(context.Background(), "echo before ; exit 4 ; echo after")
+	var exitErr builtins.ExitCode
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("error = %v, want ExitCode", err)
+	}
+	if exitCode != 4 {
+		t.Errorf("exitCode = %d, want 4", exitCode)
+	}
+	if stdout.String() != "before\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "before\n")
+	}
+}
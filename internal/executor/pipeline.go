@@ -0,0 +1,130 @@
+package executor
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// executePipeline runs the commands of a pipeline concurrently, connecting
+// the standard output of each stage to the standard input of the next.
+// Returns the exit code and error of the last stage.
+func (e *Executor) executePipeline(ctx context.Context, p *parser.Pipeline, fr *frame) (int, error) {
+	// With job control, each pipeline the shell runs is a job
+	if fr.job == nil && e.jobs.JobControl() {
+		return e.executeForeground(ctx, p, fr)
+	}
+
+	if len(p.Commands) == 1 {
+		return e.executeNode(ctx, p.Commands[0], fr)
+	}
+
+	n := len(p.Commands)
+	codes := make([]int, n)
+	errs := make([]error, n)
+
+	// All stages share stderr; serialize writes unless it is a file
+	stderr := fr.stderr
+	if _, ok := fr.stderr.(*os.File); !ok {
+		stderr = &syncWriter{w: fr.stderr}
+	}
+
+	var wg sync.WaitGroup
+	var prevReader *os.File
+
+	for i, node := range p.Commands {
+		// Each stage runs in a subshell, with its own variables and directory
+		stage := *subshell(fr)
+		stage.stderr = stderr
+		if prevReader != nil {
+			stage.stdin = prevReader
+		}
+
+		var writer *os.File
+		var nextReader *os.File
+		if i < n-1 {
+			r, w, err := os.Pipe()
+			if err != nil {
+				// Release the pipes created so far; running stages see EOF
+				if prevReader != nil {
+					prevReader.Close()
+				}
+				wg.Wait()
+				return 1, fmt.Errorf("pipe: %w", err)
+			}
+			writer = w
+			nextReader = r
+			stage.stdout = w
+			// Colors are meant for the terminal, not for the next command
+			stage.colors = nil
+		}
+
+		reader := prevReader
+		wg.Add(1)
+		go func(i int, node parser.Node, stage frame) {
+			defer wg.Done()
+			codes[i], errs[i] = e.executeNode(ctx, node, &stage)
+
+			// Closing our write end signals EOF to the next stage; closing our
+			// read end makes writers of the previous stage fail instead of blocking.
+			if writer != nil {
+				writer.Close()
+			}
+			if reader != nil {
+				reader.Close()
+			}
+		}(i, node, stage)
+
+		prevReader = nextReader
+	}
+
+	wg.Wait()
+
+	// Report failures of intermediate stages; only the last stage's result is returned
+	for i := 0; i < n-1; i++ {
+		if errs[i] != nil && !isControlFlow(errs[i]) {
+			reportError(fr.stderr, errs[i])
+		}
+	}
+
+	// With pipefail, the status is that of the last stage that failed
+	code := codes[n-1]
+	if fr.env.Option("pipefail") {
+		for i := n - 1; i >= 0; i-- {
+			if codes[i] != 0 {
+				code = codes[i]
+				break
+			}
+		}
+	}
+
+	// exit inside a pipeline only ends that stage, not the shell
+	if isControlFlow(errs[n-1]) {
+		return code, nil
+	}
+	return code, errs[n-1]
+}
+
+// syncWriter serializes writes to an underlying writer shared by pipeline stages.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// isExitRequest returns true if err is the exit builtin's request to leave the shell.
+func isExitRequest(err error) bool {
+	var exitErr builtins.ExitCode
+	return goerrors.As(err, &exitErr)
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// newPipelineTestExecutor creates an executor with echo, upper and fail builtins.
+func newPipelineTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	reg := builtins.NewRegistry()
+	reg.Register(builtins.EchoDefinition())
+	reg.Register(builtins.Definition{
+		Name: "upper",
+		Handler: func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+			data, err := io.ReadAll(execCtx.Stdin)
+			if err != nil {
+				return 1, err
+			}
+			execCtx.Stdout.Write([]byte(strings.ToUpper(string(data))))
+			return 0, nil
+		},
+	})
+	reg.Register(builtins.Definition{
+		Name: "fail",
+		Handler: func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+			return 3, nil
+		},
+	})
+
+	return New(
+		WithRegistry(reg),
+		WithStdin(&bytes.Buffer{}),
+		WithStdout(stdout),
+		WithStderr(stderr),
+		WithAbbreviations(false),
+	)
+}
+
+func TestPipelineBuiltins(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "echo hello pipes | upper")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if stdout.String() != "HELLO PIPES\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "HELLO PIPES\n")
+	}
+}
+
+func TestPipelineExitCodeOfLastStage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "echo hi | fail")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 3 {
+		t.Errorf("exitCode = %d, want 3", exitCode)
+	}
+
+	exitCode, err = e.ExecuteInput(context.Background(), "fail | echo ok")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+}
+
+func TestPipelineReportsIntermediateErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "nonexistentcommand12345 | echo done")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if stdout.String() != "done\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "done\n")
+	}
+	if !strings.Contains(stderr.String(), "command not found") {
+		t.Errorf("stderr = %q, want command not found error", stderr.String())
+	}
+}
+
+func TestPipelineWithExternalCommands(t *testing.T) {
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skipf("skipping: external command 'tr' not found: %v", err)
+	}
+	if _, err := exec.LookPath("wc"); err != nil {
+		t.Skipf("skipping: external command 'wc' not found: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	exitCode, err := e.ExecuteInput(context.Background(), "echo a b c | tr ' ' '\\n' | upper | wc -l")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if strings.TrimSpace(stdout.String()) != "3" {
+		t.Errorf("stdout = %q, want 3 lines counted", stdout.String())
+	}
+}
+
+func TestPipelineWriterStopsWhenReaderExits(t *testing.T) {
+	if _, err := exec.LookPath("yes"); err != nil {
+		t.Skipf("skipping: external command 'yes' not found: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	// yes never terminates by itself; it must be stopped once echo is done
+	exitCode, err := e.ExecuteInput(context.Background(), "yes | echo stop")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("exitCode = %d, want 0", exitCode)
+	}
+	if stdout.String() != "stop\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "stop\n")
+	}
+}
+
+func TestPipelineStagesAreSubshells(t *testing.T) {
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(original)
+
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.registry.Register(builtins.CdDefinition())
+	e.registry.Register(builtins.PwdDefinition())
+	if err := e.SetWorkDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the directory nor variables of a stage reach the shell
+	input := "x=0; cd build | upper; x=1 | upper; pwd; echo $x"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if want := dir + "\n0\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if e.WorkDir() != dir {
+		t.Errorf("WorkDir() = %q, want %q", e.WorkDir(), dir)
+	}
+}
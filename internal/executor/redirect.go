@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// applyRedirects returns a copy of fr with the given redirections applied.
+// Redirections are applied left to right, so "> out 2>&1" sends both streams
+// to out while "2>&1 > out" only sends stdout there.
+// The returned files must be closed by the caller once the command has finished.
+func (e *Executor) applyRedirects(ctx context.Context, redirects []parser.Redirect, fr *frame) (*frame, []*os.File, error) {
+	if len(redirects) == 0 {
+		return fr, nil, nil
+	}
+
+	result := *fr
+	var files []*os.File
+
+	fail := func(err error) (*frame, []*os.File, error) {
+		closeFiles(files)
+		return nil, nil, err
+	}
+
+	for _, r := range redirects {
+		if r.Op == parser.RedirectDup {
+			var w io.Writer
+			if r.DupFD == 1 {
+				w = result.stdout
+			} else {
+				w = result.stderr
+			}
+			if r.FD == 1 {
+				result.stdout = w
+			} else {
+				result.stderr = w
+			}
+			continue
+		}
+
+		input := r.Op == parser.RedirectInput || r.Op == parser.RedirectHereDoc || r.Op == parser.RedirectHereString
+		if input != (r.FD == 0) {
+			return fail(fmt.Errorf("%w: cannot redirect descriptor %d", errors.ErrInvalidSyntax, r.FD))
+		}
+
+		if r.Op == parser.RedirectHereDoc || r.Op == parser.RedirectHereString {
+			input, err := e.hereDocument(ctx, r, fr)
+			if err != nil {
+				return fail(err)
+			}
+			result.stdin = strings.NewReader(input)
+			continue
+		}
+
+		name, err := e.expandRedirectTarget(ctx, r, fr)
+		if err != nil {
+			return fail(err)
+		}
+
+		f, err := e.openRedirectTarget(r.Op, name, *fr.dir)
+		if err != nil {
+			return fail(err)
+		}
+		files = append(files, f)
+
+		switch r.FD {
+		case 0:
+			result.stdin = f
+		case 1:
+			result.stdout = f
+		case 2:
+			result.stderr = f
+		}
+		// Escape sequences do not belong in files, which both the output
+		// and the error messages of builtins may be written to
+		if r.FD != 0 {
+			result.colors = nil
+		}
+	}
+
+	return &result, files, nil
+}
+
+// expandRedirectTarget expands the target of a redirection to a single file name.
+func (e *Executor) expandRedirectTarget(ctx context.Context, r parser.Redirect, fr *frame) (string, error) {
+	p := e.newParser(ctx, r.Target, fr)
+	words := p.ExpandWords()
+	if err := p.Err(); err != nil {
+		return "", err
+	}
+	if len(words) != 1 || words[0] == "" {
+		return "", fmt.Errorf("%w: ambiguous redirect %s", errors.ErrInvalidSyntax, parser.JoinTokens(r.Target))
+	}
+	return words[0], nil
+}
+
+// hereDocument returns the input of a here-document or here-string.
+// The body of a here-document is expanded unless its delimiter is quoted;
+// a here-string is expanded as a single word and ends with a newline.
+func (e *Executor) hereDocument(ctx context.Context, r parser.Redirect, fr *frame) (string, error) {
+	p := e.newParser(ctx, r.Target, fr)
+	switch {
+	case r.Op == parser.RedirectHereString:
+		input := p.ExpandString() + "\n"
+		return input, p.Err()
+	case r.Quoted:
+		return r.Body, nil
+	default:
+		input := p.ExpandHereDoc(r.Body)
+		return input, p.Err()
+	}
+}
+
+// openRedirectTarget opens a redirection target relative to the working directory dir.
+func (e *Executor) openRedirectTarget(op parser.RedirectOp, name, dir string) (*os.File, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	var f *os.File
+	var err error
+	switch op {
+	case parser.RedirectInput:
+		f, err = os.Open(path)
+	case parser.RedirectAppend:
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	default:
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err == nil {
+		return f, nil
+	}
+
+	switch {
+	case goerrors.Is(err, syscall.EISDIR):
+		return nil, fmt.Errorf("%w: %s", errors.ErrIsADirectory, name)
+	case os.IsPermission(err):
+		return nil, fmt.Errorf("%w: %s", errors.ErrPermissionDenied, name)
+	case os.IsNotExist(err) && op == parser.RedirectInput:
+		return nil, fmt.Errorf("%w: %s", errors.ErrFileNotFound, name)
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("%w: %s", errors.ErrDirectoryNotFound, filepath.Dir(name))
+	default:
+		return nil, err
+	}
+}
+
+// closeFiles closes all files opened for redirections.
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/parser"
+	"github.com/sdejongh/jsishell/internal/terminal"
+)
+
+// newRedirectTestExecutor creates a pipeline test executor working in a temporary directory.
+// It adds a "warn" builtin that writes its arguments to stderr.
+func newRedirectTestExecutor(t *testing.T, stdout, stderr *bytes.Buffer) (*Executor, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	e := newPipelineTestExecutor(stdout, stderr)
+	e.workDir = dir
+	e.registry.Register(builtins.Definition{
+		Name: "warn",
+		Handler: func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+			execCtx.Stdout.Write([]byte("out\n"))
+			execCtx.Stderr.Write([]byte("err\n"))
+			return 0, nil
+		},
+	})
+	return e, dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRedirectOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	ctx := context.Background()
+
+	if _, err := e.ExecuteInput(ctx, "echo first > out.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if _, err := e.ExecuteInput(ctx, "echo second >> out.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "out.txt")); got != "first\nsecond\n" {
+		t.Errorf("out.txt = %q, want %q", got, "first\nsecond\n")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want empty", stdout.String())
+	}
+
+	// > truncates
+	if _, err := e.ExecuteInput(ctx, "echo third > out.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "out.txt")); got != "third\n" {
+		t.Errorf("out.txt = %q, want %q", got, "third\n")
+	}
+}
+
+func TestRedirectInput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("shout\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.ExecuteInput(context.Background(), "upper < in.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "SHOUT\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "SHOUT\n")
+	}
+}
+
+func TestRedirectStderr(t *testing.T) {
+	tests := []struct {
+		input   string
+		file    string
+		stdout  string
+		stderr  string
+		content string
+	}{
+		{"warn 2> errs.log", "errs.log", "out\n", "", "err\n"},
+		{"warn > all.log 2>&1", "all.log", "", "", "out\nerr\n"},
+		{"warn 2>&1 > out.log", "out.log", "err\n", "", "out\n"},
+		{"warn &> all.log", "all.log", "", "", "out\nerr\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.String() != tt.stderr {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.stderr)
+			}
+			if got := readFile(t, filepath.Join(dir, tt.file)); got != tt.content {
+				t.Errorf("%s = %q, want %q", tt.file, got, tt.content)
+			}
+		})
+	}
+}
+
+func TestRedirectStderrColors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+	e.SetColors(terminal.NewColorScheme(nil))
+	e.registry.Register(builtins.Definition{
+		Name: "oops",
+		Handler: func(ctx context.Context, cmd *parser.Command, execCtx *builtins.Context) (int, error) {
+			execCtx.WriteErrorln("oops: failed")
+			return 1, nil
+		},
+	})
+	ctx := context.Background()
+
+	e.ExecuteInput(ctx, "oops")
+	if !strings.Contains(stderr.String(), "\033[") {
+		t.Skip("skipping: colors are not supported in this environment")
+	}
+
+	// Error messages written to a file have no escape sequences
+	e.ExecuteInput(ctx, "oops 2>> errs.log")
+	if got := readFile(t, filepath.Join(dir, "errs.log")); got != "oops: failed\n" {
+		t.Errorf("errs.log = %q, want %q", got, "oops: failed\n")
+	}
+}
+
+func TestRedirectInPipeline(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+	if _, err := e.ExecuteInput(context.Background(), "echo piped | upper > up.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "up.txt")); got != "PIPED\n" {
+		t.Errorf("up.txt = %q, want %q", got, "PIPED\n")
+	}
+}
+
+func TestRedirectExternalCommand(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skipf("skipping: external command 'cat' not found: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+	if err := os.WriteFile(filepath.Join(dir, "in.txt"), []byte("data\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ExecuteInput(context.Background(), "cat < in.txt > copy.txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "copy.txt")); got != "data\n" {
+		t.Errorf("copy.txt = %q, want %q", got, "data\n")
+	}
+}
+
+func TestRedirectHereDoc(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x=world\nupper <<EOF\nhello $x\nEOF", "HELLO WORLD\n"},
+		{"x=world\nupper <<'EOF'\nhello $x\nEOF", "HELLO $X\n"},
+		{"upper <<-EOF\n\tone\n\t\ttwo\n\tEOF", "ONE\nTWO\n"},
+		{"upper <<EOF | upper\n$(echo sub)\nEOF", "SUB\n"},
+		{"upper <<A; upper <<B\na\nA\nb\nB", "A\nB\n"},
+		{"x='a  b'\nupper <<< $x", "A  B\n"},
+		{"upper <<< 'one two'", "ONE TWO\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e, _ := newRedirectTestExecutor(t, &stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"upper < missing.txt", shellerrors.ErrFileNotFound},
+		{"echo hi > nodir/out.txt", shellerrors.ErrDirectoryNotFound},
+		{"echo hi > .", shellerrors.ErrIsADirectory},
+		{"echo hi 2<<< x", shellerrors.ErrInvalidSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e, _ := newRedirectTestExecutor(t, &stdout, &stderr)
+
+			exitCode, err := e.ExecuteInput(context.Background(), tt.input)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if exitCode != 1 {
+				t.Errorf("exitCode = %d, want 1", exitCode)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("command should not run, stdout = %q", stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/lexer"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// newParser returns a parser expanding tokens with the variables of the frame.
+// Command substitutions run within the frame and relative globs are matched
+// in the working directory.
+func (e *Executor) newParser(ctx context.Context, tokens []lexer.Token, fr *frame) *parser.Parser {
+	return e.newStatusParser(ctx, tokens, fr, new(int))
+}
+
+// newStatusParser returns a parser like newParser, which records the exit
+// status of the last command substitution it runs in status.
+func (e *Executor) newStatusParser(ctx context.Context, tokens []lexer.Token, fr *frame, status *int) *parser.Parser {
+	return parser.NewWithEnv(tokens, fr.env).WithDir(*fr.dir).WithSubstitution(func(command string) string {
+		out, code := e.substitute(ctx, command, fr)
+		*status = code
+		return out
+	})
+}
+
+// substitute runs the command of a $(...) substitution and returns its
+// output and exit status. The command runs in a subshell, so that neither
+// its variables nor a change of directory outlive it. Errors are reported
+// to the frame's stderr.
+func (e *Executor) substitute(ctx context.Context, command string, fr *frame) (string, int) {
+	node, err := parser.ParseScriptInput(command)
+	if err != nil {
+		reportError(fr.stderr, err)
+		return "", 2
+	}
+
+	var out bytes.Buffer
+	sub := subshell(fr)
+	sub.stdout = &out
+	sub.colors = nil
+
+	code, err := e.executeNode(ctx, node, sub)
+	var exitErr builtins.ExitCode
+	if goerrors.As(err, &exitErr) {
+		code = exitErr.Code
+	} else if err != nil && !isControlFlow(err) {
+		reportError(fr.stderr, err)
+	}
+
+	return out.String(), code
+}
@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+)
+
+func TestCommandSubstitution(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"builtin", "echo [$(echo hello)]", "[hello]\n"},
+		{"backticks", "echo [`echo hello`]", "[hello]\n"},
+		{"quoted", `echo "at $(echo "a   b")"`, "at a   b\n"},
+		{"split", "for w in $(echo a   b); do echo [$w]; done", "[a]\n[b]\n"},
+		{"pipeline", "echo $(echo abc | upper)", "ABC\n"},
+		{"nested", `echo "$(echo "$(echo deep)")"`, "deep\n"},
+		{"command name", "$(echo echo) ok", "ok\n"},
+		{"list", "echo $(echo a; echo b)", "a b\n"},
+		{"condition", "if true; then echo $(echo yes); fi", "yes\n"},
+		{"case word", "case $(echo b) in b) echo matched;; esac", "matched\n"},
+		{"case inside", "echo [$(case x in x) echo cx;; *) echo other;; esac)]", "[cx]\n"},
+		{"function", "f() { echo from $1; }; echo [$(f fn)]", "[from fn]\n"},
+		{"exit", "echo [$(exit 3; echo no)]; echo after", "[]\nafter\n"},
+		{"break", "for x in a b; do echo $(break)$x; done", "a\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newFunctionTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
+
+func TestCommandSubstitutionExternal(t *testing.T) {
+	if _, err := exec.LookPath("printf"); err != nil {
+		t.Skipf("printf not available: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	// Only trailing newlines are removed
+	if _, err := e.ExecuteInput(context.Background(), `echo "[$(printf 'a\n\nb\n\n')]"`); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if want := "[a\n\nb]\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestCommandSubstitutionErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+
+	// Errors are reported and the substitution expands to its output so far
+	if _, err := e.ExecuteInput(context.Background(), "echo [$(nonexistentcommand12345)]"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if stdout.String() != "[]\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "[]\n")
+	}
+	if !strings.Contains(stderr.String(), "command not found") {
+		t.Errorf("stderr = %q, want command not found", stderr.String())
+	}
+}
+
+func TestArithmeticExpansion(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"power", "echo $((2 ** 10))", "1024\n"},
+		{"counter", "n=0; for x in a b c; do n=$((n + 1)); done; echo $n", "3\n"},
+		{"increment", "i=5; echo $((i++)) $((++i)) $i", "5 7 7\n"},
+		{"parameter", "f() { echo $(($1 * 2)); }; f 21", "42\n"},
+		{"substitution", "echo $(( $(echo 6) * 7 ))", "42\n"},
+		{"case word", "case $((1 + 1)) in 2) echo two;; esac", "two\n"},
+		{"here-string", "upper <<< $((0x10))", "16\n"},
+		{"pipeline", "echo $((3 > 2)) | upper", "1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newFunctionTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestArithmeticExpansionErrors(t *testing.T) {
+	for _, input := range []string{"echo $((1 / 0))", "upper <<< $((1 +))", "case $((x / 0)) in *) echo no;; esac"} {
+		t.Run(input, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newFunctionTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), input); err == nil {
+				t.Error("ExecuteInput succeeded, want error")
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want empty", stdout.String())
+			}
+		})
+	}
+}
+
+func TestCommandSubstitutionRedirectTarget(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e, dir := newRedirectTestExecutor(t, &stdout, &stderr)
+
+	if _, err := e.ExecuteInput(context.Background(), "echo data > $(echo out).txt"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "out.txt")); got != "data\n" {
+		t.Errorf("out.txt = %q, want %q", got, "data\n")
+	}
+}
+
+func TestCommandSubstitutionKeepsDirectory(t *testing.T) {
+	original, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(original)
+
+	var stdout, stderr bytes.Buffer
+	e := newPipelineTestExecutor(&stdout, &stderr)
+	e.registry.Register(builtins.CdDefinition())
+	e.registry.Register(builtins.PwdDefinition())
+
+	start := t.TempDir()
+	if err := e.SetWorkDir(start); err != nil {
+		t.Fatal(err)
+	}
+	sub := t.TempDir()
+
+	if _, err := e.ExecuteInput(context.Background(), "echo $(cd "+sub+"; pwd)"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if strings.TrimSpace(stdout.String()) != sub {
+		t.Errorf("stdout = %q, want %q", stdout.String(), sub)
+	}
+	if e.WorkDir() != start {
+		t.Errorf("WorkDir() = %q, want %q", e.WorkDir(), start)
+	}
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/parser"
+)
+
+// RunTrap runs the command of the trap set on a signal or event, if any,
+// in the shell's own state. Returns the request to exit the shell if the
+// command ran exit; other errors are reported.
+func (e *Executor) RunTrap(ctx context.Context, name string) error {
+	return e.runTrap(ctx, name, e.rootFrame())
+}
+
+// RunHook runs the code of a hook of the interactive loop, such as precmd,
+// in the shell's own state. args are the positional parameters of the code,
+// which can return like a function. Returns the request to exit the shell
+// if the code ran exit; other errors are reported.
+func (e *Executor) RunHook(ctx context.Context, name, code string, args []string) error {
+	fr := e.rootFrame()
+	fr.env = fr.env.NewScope(args)
+	fr.inFunc = true
+	return e.runHandler(ctx, "hook "+name, code, fr)
+}
+
+// RunPendingTraps runs the traps of the signals caught since the last call.
+func (e *Executor) RunPendingTraps(ctx context.Context) error {
+	return e.runPendingTraps(ctx, e.rootFrame())
+}
+
+// runPendingTraps runs the traps of the signals caught since the last call
+// within the frame.
+func (e *Executor) runPendingTraps(ctx context.Context, fr *frame) error {
+	// Signals are caught by the shell, whose traps run once subshells end
+	if fr.inTrap || fr.traps != e.traps {
+		return nil
+	}
+	for _, name := range e.traps.TakePending() {
+		if err := e.runTrap(ctx, name, fr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTrap runs the command of a trap within the frame.
+func (e *Executor) runTrap(ctx context.Context, name string, fr *frame) error {
+	command, ok := fr.traps.Get(name)
+	if !ok || command == "" || fr.inTrap {
+		return nil
+	}
+	return e.runHandler(ctx, "trap "+name, command, fr)
+}
+
+// runHandler runs the code of a trap or hook, named in errors, within the
+// frame. $? is left as it was, so that the code does not change the status
+// seen by the commands that follow it.
+func (e *Executor) runHandler(ctx context.Context, name, code string, fr *frame) error {
+	node, err := parser.ParseScriptInput(code)
+	if err != nil {
+		fmt.Fprintf(fr.stderr, "error: %s: %s\n", name, shellerrors.Format(err, ""))
+		return nil
+	}
+
+	status := fr.env.Status()
+	handler := *fr
+	handler.inTrap = true
+	_, err = e.executeNode(ctx, node, &handler)
+	if isExitRequest(err) {
+		return err
+	}
+	if err != nil && !isControlFlow(err) {
+		fmt.Fprintf(fr.stderr, "error: %s: %s\n", name, shellerrors.Format(err, ""))
+	}
+	fr.env.SetStatus(status)
+	return nil
+}
+
+// failed handles a list item that failed with a status that is not tested
+// by a condition: it runs the ERR trap and, with the errexit option, returns
+// the request to exit the shell with the status.
+func (e *Executor) failed(ctx context.Context, node parser.Node, code int, fr *frame) error {
+	if fr.condition || !reportsFailure(node) {
+		return nil
+	}
+	if !fr.trapsOff {
+		if err := e.runTrap(ctx, "ERR", fr); err != nil {
+			return err
+		}
+	}
+	if fr.env.Option("errexit") {
+		return builtins.ExitCode{Code: code}
+	}
+	return nil
+}
+
+// reportsFailure returns true if the failure of a pipeline is its own,
+// rather than that of a command within a compound command, which was
+// reported already.
+func reportsFailure(node parser.Node) bool {
+	p, ok := node.(*parser.Pipeline)
+	if !ok || len(p.Commands) > 1 {
+		return ok
+	}
+	cmd := p.Commands[0]
+	if r, ok := cmd.(*parser.RedirectedCommand); ok {
+		cmd = r.Node
+	}
+	switch cmd.(type) {
+	case *parser.SimpleCommand, *parser.Subshell:
+		return true
+	}
+	return false
+}
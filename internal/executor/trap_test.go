@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+)
+
+// newTrapTestExecutor creates a control test executor with the trap and
+// return builtins.
+func newTrapTestExecutor(stdout, stderr *bytes.Buffer) *Executor {
+	e := newControlTestExecutor(stdout, stderr)
+	e.registry.Register(builtins.TrapDefinition())
+	e.registry.Register(builtins.ReturnDefinition())
+	e.colors = nil
+	return e
+}
+
+func TestErrTrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"command", "false; echo after", "ERR 1\nafter\n"},
+		{"status", "fail", "ERR 3\n"},
+		{"success", "true", ""},
+		{"if condition", "if false; then echo yes; fi", ""},
+		{"if body", "if true; then false; fi", "ERR 1\n"},
+		{"while condition", "while false; do echo never; done", ""},
+		{"and list", "false && echo never", ""},
+		{"or list", "false || echo handled", "handled\n"},
+		{"last of list", "true && false", "ERR 1\n"},
+		{"brace group in list", "{ false; } || echo handled", "handled\n"},
+		{"function", "f() { false; }; f", "ERR 1\n"},
+		{"subshell", "(false)", "ERR 1\n"},
+		{"pipeline", "true | false", "ERR 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newTrapTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), `trap 'echo ERR $?' ERR`); err != nil {
+				t.Fatalf("trap error: %v", err)
+			}
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
+
+func TestDebugTrap(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newTrapTestExecutor(&stdout, &stderr)
+
+	input := "trap 'echo debug' DEBUG; echo a; f() { echo b; }; f; trap - DEBUG; echo c"
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	want := "debug\na\ndebug\nb\ndebug\nc\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestSubshellExitTrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+	}{
+		{"end of subshell", `(trap "echo x" EXIT; echo in); echo out`, "in\nx\nout\n"},
+		{"exit", "(trap 'echo bye $?' EXIT; exit 4; echo never); echo $?", "bye 4\n4\n"},
+		{"exit in trap", "(trap 'exit 3' EXIT; true); echo $?", "3\n"},
+		{"shell trap", "trap 'echo shell' EXIT; (echo in); echo out", "in\nout\n"},
+		{"shell trap in subshell", "trap 'echo shell' TERM; (trap)", ""},
+		{"ignored", "trap '' TERM; (trap)", "trap -- '' TERM\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newTrapTestExecutor(&stdout, &stderr)
+
+			if _, err := e.ExecuteInput(context.Background(), tt.input); err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+		})
+	}
+}
+
+func TestPendingTraps(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newTrapTestExecutor(&stdout, &stderr)
+
+	// The trap runs between commands, keeping the status
+	e.Traps().Set("INT", "echo caught")
+	e.Traps().Catch("INT")
+	code, err := e.ExecuteInput(context.Background(), "fail; echo $?")
+	if err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if code != 0 || stdout.String() != "caught\n3\n" {
+		t.Errorf("code = %d, stdout = %q, want 0 and %q", code, stdout.String(), "caught\n3\n")
+	}
+
+	// exit within a trap exits the shell
+	e.Traps().Set("TERM", "exit 5")
+	e.Traps().Catch("TERM")
+	var exitErr builtins.ExitCode
+	if err := e.RunPendingTraps(context.Background()); !errors.As(err, &exitErr) || exitErr.Code != 5 {
+		t.Errorf("RunPendingTraps() = %v, want exit 5", err)
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newTrapTestExecutor(&stdout, &stderr)
+
+	if _, err := e.ExecuteInput(context.Background(), "greet() { echo \"hi $1\"; }; fail"); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	if err := e.RunHook(context.Background(), "preexec", `greet "$1"; return 1; echo never`, []string{"ls -l"}); err != nil {
+		t.Fatalf("RunHook error: %v", err)
+	}
+	if stdout.String() != "hi ls -l\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "hi ls -l\n")
+	}
+	if e.env.Status() != 3 {
+		t.Errorf("$? = %d after hook, want 3", e.env.Status())
+	}
+
+	stdout.Reset()
+	if err := e.RunHook(context.Background(), "precmd", "echo 'broken", nil); err != nil {
+		t.Fatalf("RunHook error: %v", err)
+	}
+	if stdout.Len() != 0 || !bytes.Contains(stderr.Bytes(), []byte("hook precmd")) {
+		t.Errorf("stdout = %q, stderr = %q, want error naming the hook", stdout.String(), stderr.String())
+	}
+}
+
+func TestStrictModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		stdout string
+		code   int
+	}{
+		{"errexit", "set -e; echo a; false; echo never", "a\n", 1},
+		{"errexit status", "set -e; fail; echo never", "", 3},
+		{"errexit if condition", "set -e; if false; then echo yes; fi; echo after", "after\n", 0},
+		{"errexit and list", "set -e; false && echo never; echo after", "after\n", 0},
+		{"errexit or list", "set -e; false || echo handled", "handled\n", 0},
+		{"errexit function in condition", "set -e; f() { false; echo in f; }; f || echo never", "in f\n", 0},
+		{"errexit function", "set -e; f() { false; echo never; }; f; echo never", "", 1},
+		{"errexit subshell", "set -e; (false; echo never); echo never", "", 1},
+		{"errexit substitution", "set -e; x=$(fail); echo never", "", 3},
+		{"substitution status", "x=$(fail); echo $?", "3\n", 0},
+		{"pipeline", "fail | echo x; echo $?", "x\n0\n", 0},
+		{"pipefail", "set -o pipefail; fail | echo x; echo $?", "x\n3\n", 0},
+		{"pipefail success", "set -o pipefail; true | echo x; echo $?", "x\n0\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			e := newTrapTestExecutor(&stdout, &stderr)
+			e.registry.Register(builtins.SetDefinition())
+
+			code, err := e.ExecuteInput(context.Background(), tt.input)
+			var exitErr builtins.ExitCode
+			if errors.As(err, &exitErr) {
+				code, err = exitErr.Code, nil
+			}
+			if err != nil {
+				t.Fatalf("ExecuteInput error: %v", err)
+			}
+			if stdout.String() != tt.stdout || code != tt.code {
+				t.Errorf("stdout = %q, code = %d, want %q and %d", stdout.String(), code, tt.stdout, tt.code)
+			}
+		})
+	}
+}
+
+func TestXtrace(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	e := newTrapTestExecutor(&stdout, &stderr)
+	e.registry.Register(builtins.SetDefinition())
+
+	input := `set -x; x=1 echo "a b" $x; PS4='> '; echo c; set +x; echo d`
+	if _, err := e.ExecuteInput(context.Background(), input); err != nil {
+		t.Fatalf("ExecuteInput error: %v", err)
+	}
+	want := "+ x=1 echo 'a b' ''\n+ PS4='> '\n> echo c\n> set +x\n"
+	if stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if stdout.String() != "a b \nc\nd\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
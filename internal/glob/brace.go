@@ -0,0 +1,163 @@
+package glob
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ExpandBraces performs brace expansion on s: a{b,c}d gives abd and acd,
+// {1..5} the numbers from 1 to 5, {a..e} the letters from a to e, and
+// {01..10..2} every other number padded with zeros. Braces may be nested.
+// Braces that hold neither a comma nor a sequence are left as is, as are
+// braces escaped with a backslash.
+func ExpandBraces(s string) []string {
+	open, close, items := findBraces(s)
+	if open < 0 {
+		return []string{s}
+	}
+
+	prefix, suffixes := s[:open], ExpandBraces(s[close+1:])
+	var words []string
+	for _, item := range items {
+		for _, middle := range ExpandBraces(item) {
+			for _, suffix := range suffixes {
+				words = append(words, prefix+middle+suffix)
+			}
+		}
+	}
+	return words
+}
+
+// findBraces returns the position of the first brace expression of s and
+// its items, or -1 if s holds none.
+func findBraces(s string) (open, close int, items []string) {
+	for open = 0; open < len(s); open++ {
+		switch s[open] {
+		case '\\':
+			open++
+		case '{':
+			if close, items = braceItems(s, open); close >= 0 {
+				return open, close, items
+			}
+		}
+	}
+	return -1, -1, nil
+}
+
+// braceItems returns the position of the brace that closes the one at open,
+// and the items between them: the parts separated by top-level commas, or
+// the elements of a sequence. Returns -1 if the braces are not an expression.
+func braceItems(s string, open int) (int, []string) {
+	depth := 0
+	start := open + 1
+	var items []string
+	for i := open + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if items != nil {
+				return i, append(items, s[start:i])
+			}
+			if seq, ok := sequence(s[start:i]); ok {
+				return i, seq
+			}
+			return -1, nil
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return -1, nil
+}
+
+// sequence expands the body of a sequence expression: x..y or x..y..step,
+// where x and y are both integers or both single letters.
+func sequence(body string) ([]string, bool) {
+	parts := strings.Split(body, "..")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, false
+	}
+
+	step := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, false
+		}
+		step = max(n, -n, 1)
+	}
+
+	if isLetter(parts[0]) && isLetter(parts[1]) {
+		var seq []string
+		for _, c := range intRange(int(parts[0][0]), int(parts[1][0]), step) {
+			seq = append(seq, string(rune(c)))
+		}
+		return seq, true
+	}
+
+	from, err1 := strconv.Atoi(parts[0])
+	to, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return nil, false
+	}
+
+	// A leading zero pads all numbers to the width of the widest bound
+	width := 0
+	if hasLeadingZero(parts[0]) || hasLeadingZero(parts[1]) {
+		width = max(len(parts[0]), len(parts[1]))
+	}
+
+	var seq []string
+	for _, n := range intRange(from, to, step) {
+		seq = append(seq, padNumber(n, width))
+	}
+	return seq, true
+}
+
+// intRange returns the integers from from to to, both included, every step.
+func intRange(from, to, step int) []int {
+	var r []int
+	if from <= to {
+		for n := from; n <= to; n += step {
+			r = append(r, n)
+		}
+	} else {
+		for n := from; n >= to; n -= step {
+			r = append(r, n)
+		}
+	}
+	return r
+}
+
+// padNumber formats n with zeros up to width characters, sign included.
+func padNumber(n, width int) string {
+	digits := strconv.Itoa(max(n, -n))
+	sign := ""
+	if n < 0 {
+		sign = "-"
+	}
+	if pad := width - len(sign) - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	return sign + digits
+}
+
+// hasLeadingZero returns true if the number s is written with a leading zero.
+func hasLeadingZero(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+// isLetter returns true if s is a single ASCII letter.
+func isLetter(s string) bool {
+	return len(s) == 1 && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
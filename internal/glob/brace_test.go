@@ -0,0 +1,45 @@
+package glob
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"plain", []string{"plain"}},
+		{"a{b,c}d", []string{"abd", "acd"}},
+		{"{src,test}/*.go", []string{"src/*.go", "test/*.go"}},
+		{"{a,b}{1,2}", []string{"a1", "a2", "b1", "b2"}},
+		{"x{a,{b,c}}", []string{"xa", "xb", "xc"}},
+		{"file{,.bak}", []string{"file", "file.bak"}},
+		{"{1..5}", []string{"1", "2", "3", "4", "5"}},
+		{"{3..1}", []string{"3", "2", "1"}},
+		{"{-1..1}", []string{"-1", "0", "1"}},
+		{"{1..10..3}", []string{"1", "4", "7", "10"}},
+		{"{01..10..3}", []string{"01", "04", "07", "10"}},
+		{"img{08..11}.png", []string{"img08.png", "img09.png", "img10.png", "img11.png"}},
+		{"{-05..5..5}", []string{"-05", "000", "005"}},
+		{"{a..e..2}", []string{"a", "c", "e"}},
+		{"{c..a}", []string{"c", "b", "a"}},
+		{"{}", []string{"{}"}},
+		{"{a}", []string{"{a}"}},
+		{"{a..}", []string{"{a..}"}},
+		{"{1..b}", []string{"{1..b}"}},
+		{"{a,b", []string{"{a,b"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+		{"{x}{a,b}", []string{"{x}a", "{x}b"}},
+		{"{{a,b}}", []string{"{a}", "{b}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := ExpandBraces(tt.s); !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandBraces(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Glob returns the paths matching pattern, sorted. Relative patterns are
+// resolved against dir, or the current directory if dir is empty; the paths
+// are returned as written in the pattern.
+//
+// ** as a whole path component matches any number of directories, including
+// none. Names starting with a dot are only matched by a pattern component
+// starting with a dot. A pattern ending with a separator only matches
+// directories.
+func Glob(pattern, dir string) []string {
+	// The volume and leading separators are kept as written
+	prefix := filepath.VolumeName(pattern)
+	rest := pattern[len(prefix):]
+	for rest != "" && os.IsPathSeparator(rest[0]) {
+		prefix, rest = prefix+rest[:1], rest[1:]
+	}
+
+	var matches []string
+	expand(dir, prefix, splitPattern(rest), &matches)
+	slices.Sort(matches)
+	return slices.Compact(matches)
+}
+
+// splitPattern splits a pattern into its path components. Empty components
+// are dropped, except a trailing one that marks a directory.
+func splitPattern(pattern string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i <= len(pattern); i++ {
+		if i < len(pattern) && !os.IsPathSeparator(pattern[i]) {
+			continue
+		}
+		if i > start || (i == len(pattern) && i > 0) {
+			parts = append(parts, pattern[start:i])
+		}
+		start = i + 1
+	}
+	return parts
+}
+
+// expand appends to matches the paths below path that match the remaining
+// pattern components.
+func expand(dir, path string, parts []string, matches *[]string) {
+	if len(parts) == 0 {
+		if path != "" {
+			*matches = append(*matches, path)
+		}
+		return
+	}
+	part, rest := parts[0], parts[1:]
+
+	switch {
+	case part == "":
+		// Trailing separator: only directories match
+		if isDir(resolve(dir, path)) {
+			*matches = append(*matches, path+string(filepath.Separator))
+		}
+
+	case part == "**":
+		if len(rest) > 0 {
+			expand(dir, path, rest, matches)
+		}
+		for _, entry := range readDir(resolve(dir, path)) {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			next := join(path, entry.Name())
+			if len(rest) == 0 {
+				*matches = append(*matches, next)
+			}
+			// Symbolic links are not followed, so that loops end
+			if entry.IsDir() {
+				expand(dir, next, parts, matches)
+			}
+		}
+
+	case !HasMeta(part):
+		next := join(path, unescape(part))
+		if len(rest) > 0 {
+			expand(dir, next, rest, matches)
+		} else if _, err := os.Lstat(resolve(dir, next)); err == nil {
+			*matches = append(*matches, next)
+		}
+
+	default:
+		for _, entry := range readDir(resolve(dir, path)) {
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") && !strings.HasPrefix(part, ".") {
+				continue
+			}
+			if !Match(part, name) {
+				continue
+			}
+			next := join(path, name)
+			if len(rest) == 0 {
+				*matches = append(*matches, next)
+			} else if isDir(resolve(dir, next)) {
+				expand(dir, next, rest, matches)
+			}
+		}
+	}
+}
+
+// readDir returns the entries of a directory, or nil if it cannot be read.
+func readDir(path string) []os.DirEntry {
+	entries, _ := os.ReadDir(path)
+	return entries
+}
+
+// isDir returns true if path is a directory or a link to one.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// resolve returns the file system path of a path written in a pattern.
+func resolve(dir, path string) string {
+	switch {
+	case path == "" && dir == "":
+		return "."
+	case path == "":
+		return dir
+	case dir == "" || filepath.IsAbs(path) || filepath.VolumeName(path) != "":
+		return path
+	default:
+		return filepath.Join(dir, path)
+	}
+}
+
+// join appends a name to a path written in a pattern.
+func join(path, name string) string {
+	if path == "" || os.IsPathSeparator(path[len(path)-1]) || filepath.VolumeName(path) == path {
+		return path + name
+	}
+	return path + string(filepath.Separator) + name
+}
+
+// unescape removes the backslashes of a pattern component without wildcards.
+// On Windows, backslashes separate path components and are never escapes.
+func unescape(part string) string {
+	if filepath.Separator == '\\' || !strings.Contains(part, `\`) {
+		return part
+	}
+	var sb strings.Builder
+	for i := 0; i < len(part); i++ {
+		if part[i] == '\\' && i+1 < len(part) {
+			i++
+		}
+		sb.WriteByte(part[i])
+	}
+	return sb.String()
+}
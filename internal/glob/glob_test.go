@@ -0,0 +1,74 @@
+package glob
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// makeTree creates the given files, and their directories, below a temporary directory.
+func makeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGlob(t *testing.T) {
+	dir := makeTree(t,
+		"main.go", "main_test.go", "README.md", ".hidden.go",
+		"cmd/app/app.go", "cmd/app/app.txt",
+		"internal/x/x.go", "internal/x/y/y.go", "internal/.git/z.go",
+	)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"main.go", "main_test.go"}},
+		{".*.go", []string{".hidden.go"}},
+		{"main.go", []string{"main.go"}},
+		{"missing.go", nil},
+		{"*.txt", nil},
+		{"!(*_test).go", []string{"main.go"}},
+		{"*.@(go|md)", []string{"README.md", "main.go", "main_test.go"}},
+		{"*/", []string{"cmd/", "internal/"}},
+		{"cmd/*/*.go", []string{"cmd/app/app.go"}},
+		{"**/*.go", []string{"cmd/app/app.go", "internal/x/x.go", "internal/x/y/y.go", "main.go", "main_test.go"}},
+		{"internal/**/*.go", []string{"internal/x/x.go", "internal/x/y/y.go"}},
+		{"**/app", []string{"cmd/app"}},
+		{"cmd/**", []string{"cmd/app", "cmd/app/app.go", "cmd/app/app.txt"}},
+		{"./*.md", []string{"./README.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			var want []string
+			for _, w := range tt.want {
+				want = append(want, filepath.FromSlash(w))
+			}
+			if got := Glob(filepath.FromSlash(tt.pattern), dir); !slices.Equal(got, want) {
+				t.Errorf("Glob(%q) = %q, want %q", tt.pattern, got, want)
+			}
+		})
+	}
+}
+
+func TestGlobAbsolute(t *testing.T) {
+	dir := makeTree(t, "a.txt", "b.txt")
+
+	got := Glob(filepath.Join(dir, "*.txt"), "")
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	if !slices.Equal(got, want) {
+		t.Errorf("Glob = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+// Package glob implements the shell patterns: wildcards with extended
+// patterns, filename expansion with ** and brace expansion. The parser,
+// case and the file built-ins share it so that patterns behave the same
+// everywhere.
+package glob
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Match reports whether s matches the shell pattern. * matches any string,
+// including /, ? any character and [abc] one of a set of characters, with
+// ranges as in [a-z] and negation as in [!abc] or [^abc]. The extended
+// patterns ?(a|b), *(a|b), +(a|b) and @(a|b) match zero or one, zero or
+// more, one or more and exactly one of the patterns; !(a|b) matches
+// anything except them. A backslash makes the next character literal.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		if alts, rest, ok := extGroup(pattern); ok {
+			return matchExt(pattern[0], alts, rest, s)
+		}
+
+		switch pattern[0] {
+		case '*':
+			// Collapse consecutive stars, then try every split point
+			for len(pattern) > 0 && pattern[0] == '*' && !isExtGroup(pattern) {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if s == "" {
+				return false
+			}
+			_, n := utf8.DecodeRuneInString(s)
+			pattern, s = pattern[1:], s[n:]
+
+		case '[':
+			if s == "" {
+				return false
+			}
+			c, n := utf8.DecodeRuneInString(s)
+			matched, rest, ok := matchClass(pattern, c)
+			if !ok {
+				// Unterminated class: [ is literal
+				if s[0] != '[' {
+					return false
+				}
+				pattern, s = pattern[1:], s[1:]
+				continue
+			}
+			if !matched {
+				return false
+			}
+			pattern, s = rest, s[n:]
+
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return s == ""
+}
+
+// HasMeta returns true if s contains wildcards or extended patterns.
+func HasMeta(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[':
+			return true
+		case '+', '@', '!':
+			if isExtGroup(s[i:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchClass matches c against the bracket expression at the start of pattern
+// ([abc], [a-z], [!abc] or [^abc]). Returns whether c matched, the pattern
+// after the expression, and false if the expression is not terminated.
+func matchClass(pattern string, c rune) (matched bool, rest string, ok bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+
+	for first := true; i < len(pattern); first = false {
+		if pattern[i] == ']' && !first {
+			return matched != negate, pattern[i+1:], true
+		}
+
+		lo, n := utf8.DecodeRuneInString(pattern[i:])
+		i += n
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, n = utf8.DecodeRuneInString(pattern[i+1:])
+			i += 1 + n
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	return false, "", false
+}
+
+// isExtGroup returns true if pattern starts with an extended pattern.
+func isExtGroup(pattern string) bool {
+	_, _, ok := extGroup(pattern)
+	return ok
+}
+
+// extGroup splits the extended pattern at the start of pattern, as in
+// +(a|b)rest, into its alternatives and the pattern after it.
+// Returns false if pattern does not start with a terminated extended pattern.
+func extGroup(pattern string) (alts []string, rest string, ok bool) {
+	if len(pattern) < 2 || pattern[1] != '(' || !strings.ContainsRune("?*+@!", rune(pattern[0])) {
+		return nil, "", false
+	}
+
+	depth := 0
+	start := 2
+	for i := 2; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return append(alts, pattern[start:i]), pattern[i+1:], true
+			}
+			depth--
+		case '|':
+			if depth == 0 {
+				alts = append(alts, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// matchExt reports whether s starts with a match of the extended pattern
+// op(alts) and the remainder of s matches rest.
+func matchExt(op byte, alts []string, rest, s string) bool {
+	for i := 0; i <= len(s); i++ {
+		head := s[:i]
+		var ok bool
+		switch op {
+		case '@':
+			ok = matchAny(alts, head)
+		case '?':
+			ok = head == "" || matchAny(alts, head)
+		case '*':
+			ok = matchRepeat(alts, head)
+		case '+':
+			ok = matchAny(alts, head) || (head != "" && matchRepeat(alts, head))
+		case '!':
+			ok = !matchAny(alts, head)
+		}
+		if ok && Match(rest, s[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny reports whether s matches one of the patterns.
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if Match(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRepeat reports whether s is a sequence of zero or more matches of
+// the patterns.
+func matchRepeat(patterns []string, s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 1; i <= len(s); i++ {
+		if matchAny(patterns, s[:i]) && matchRepeat(patterns, s[i:]) {
+			return true
+		}
+	}
+	return false
+}
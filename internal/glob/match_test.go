@@ -0,0 +1,96 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+		{"*", "", true},
+		{"*", "a/b", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abbb", false},
+		{"?", "é", true},
+		{"a?c", "abc", true},
+		{"[abc]", "b", true},
+		{"[!abc]", "b", false},
+		{"[^abc]", "d", true},
+		{"[a-z]x", "qx", true},
+		{"[]]", "]", true},
+		{"[a", "[a", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{"**.go", "main.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.s, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchExtended(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"?(a|b)c", "c", true},
+		{"?(a|b)c", "ac", true},
+		{"?(a|b)c", "abc", false},
+		{"*(ab)", "", true},
+		{"*(ab)", "ababab", true},
+		{"*(ab)", "aba", false},
+		{"+(ab|c)", "", false},
+		{"+(ab|c)", "abcab", true},
+		{"@(foo|bar).go", "bar.go", true},
+		{"@(foo|bar).go", "foobar.go", false},
+		{"!(*.go)", "main.go", false},
+		{"!(*.go)", "README.md", true},
+		{"*.!(go|md)", "x.txt", true},
+		{"*.!(go|md)", "x.md", false},
+		{"@(a|+(b|c))d", "bcbd", true},
+		{"*(*.go)", "a.gob.go", true},
+		{`@(a\|b)`, "a|b", true},
+		{"+(a", "+(a", true},
+		{"+(a", "a", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.s, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.s); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasMeta(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"main.go", false},
+		{"*.go", true},
+		{"file?.txt", true},
+		{"[ab].txt", true},
+		{"+(a|b)", true},
+		{"!(x)", true},
+		{"user@host", false},
+		{"hello!", false},
+		{"(a|b)", false},
+	}
+
+	for _, tt := range tests {
+		if got := HasMeta(tt.s); got != tt.want {
+			t.Errorf("HasMeta(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
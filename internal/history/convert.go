@@ -0,0 +1,215 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of Export and Parse.
+const (
+	FormatJSON = "json" // JSON Lines, as in the history file
+	FormatBash = "bash" // ~/.bash_history, with #timestamp lines
+	FormatZsh  = "zsh"  // ~/.zsh_history, in the extended format
+)
+
+// ErrUnknownFormat is returned for a history format other than the
+// Format constants.
+var ErrUnknownFormat = errors.New("unknown history format")
+
+// zshExtended matches the start of a line of the extended zsh format,
+// ": start:elapsed;command".
+var zshExtended = regexp.MustCompile(`^: *(\d+):(\d+);`)
+
+// zshMeta marks a byte of a zsh history file that was xored with 0x20, as
+// zsh does for the bytes it uses internally, from zshMeta to zshMarker and
+// 0, which appear in some UTF-8 characters.
+const (
+	zshMeta   = 0x83
+	zshMarker = 0xa2
+)
+
+// Export writes entries in the given format. The bash and zsh formats keep
+// the time of the commands that have one, and zsh their duration in
+// seconds.
+func Export(w io.Writer, entries []HistoryEntry, format string) error {
+	bw := bufio.NewWriter(w)
+	var last time.Time // Time of the last entry that has one
+	for _, entry := range entries {
+		switch format {
+		case FormatJSON:
+			line, err := encodeEntry(entry)
+			if err != nil {
+				return fmt.Errorf("encoding history entry: %w", err)
+			}
+			bw.Write(line)
+			bw.WriteByte('\n')
+		case FormatBash:
+			// After a timestamp, a command needs one, or bash takes it for
+			// a line of the previous command
+			if !entry.Timestamp.IsZero() {
+				last = entry.Timestamp
+			}
+			if !last.IsZero() {
+				fmt.Fprintf(bw, "#%d\n", last.Unix())
+			}
+			bw.WriteString(entry.Command + "\n")
+		case FormatZsh:
+			// Lines of a multi-line command end with a backslash
+			command := metafy(strings.ReplaceAll(entry.Command, "\n", "\\\n"))
+			if entry.Timestamp.IsZero() {
+				bw.WriteString(command + "\n")
+				continue
+			}
+			fmt.Fprintf(bw, ": %d:%d;%s\n", entry.Timestamp.Unix(), int64(entry.Duration.Seconds()), command)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+		}
+	}
+	return bw.Flush()
+}
+
+// Parse reads the commands of a history file of the given format, or of
+// the format detected from its first line if format is empty. Commands of
+// bash and zsh files without a time get the zero time.
+func Parse(r io.Reader, format string) ([]HistoryEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = detectFormat(data)
+	}
+
+	var entries []HistoryEntry
+	switch format {
+	case FormatJSON:
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				entries = append(entries, decodeEntry(line))
+			}
+		}
+	case FormatBash:
+		entries = parseBash(string(data))
+	case FormatZsh:
+		entries = parseZsh(unmetafy(data))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	return entries, nil
+}
+
+// detectFormat returns the format of a history file from its first line.
+func detectFormat(data []byte) string {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	switch {
+	case bytes.HasPrefix(first, []byte("{")):
+		return FormatJSON
+	case zshExtended.Match(first):
+		return FormatZsh
+	}
+	return FormatBash
+}
+
+// parseBash parses a bash history file: one command per line, after an
+// optional #timestamp line written when HISTTIMEFORMAT is set. As for bash,
+// the lines up to the next timestamp are one multi-line command.
+func parseBash(data string) []HistoryEntry {
+	var entries []HistoryEntry
+	open := false // The last entry has a timestamp and takes the next lines
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "#") {
+			if sec, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				entries = append(entries, HistoryEntry{Timestamp: time.Unix(sec, 0)})
+				open = true
+				continue
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !open {
+			entries = append(entries, HistoryEntry{Command: line})
+		} else if last := &entries[len(entries)-1]; last.Command == "" {
+			last.Command = line
+		} else {
+			last.Command += "\n" + line
+		}
+	}
+
+	// Timestamps without a command
+	commands := entries[:0]
+	for _, entry := range entries {
+		if entry.Command != "" {
+			commands = append(commands, entry)
+		}
+	}
+	return commands
+}
+
+// parseZsh parses a zsh history file, in the extended format or with one
+// command per line. A line ending with a backslash continues the command.
+func parseZsh(data string) []HistoryEntry {
+	var entries []HistoryEntry
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + "\n" + lines[i]
+		}
+
+		var entry HistoryEntry
+		if m := zshExtended.FindStringSubmatch(line); m != nil {
+			start, _ := strconv.ParseInt(m[1], 10, 64)
+			elapsed, _ := strconv.ParseInt(m[2], 10, 64)
+			entry.Timestamp = time.Unix(start, 0)
+			entry.Duration = time.Duration(elapsed) * time.Second
+			line = line[len(m[0]):]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry.Command = line
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// metafy escapes the bytes of s that zsh uses internally.
+func metafy(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b == 0 || b >= zshMeta && b <= zshMarker {
+			sb.WriteByte(zshMeta)
+			sb.WriteByte(b ^ 0x20)
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// unmetafy decodes the bytes zsh escapes in its history file.
+func unmetafy(data []byte) string {
+	if bytes.IndexByte(data, zshMeta) < 0 {
+		return string(data)
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == zshMeta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return string(out)
+}
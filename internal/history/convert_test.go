@@ -0,0 +1,111 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	ts := func(sec int64) time.Time { return time.Unix(sec, 0) }
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []HistoryEntry
+	}{
+		{"bash", "", "ls -la\n\ncd /tmp\n# not a timestamp\n", []HistoryEntry{
+			{Command: "ls -la"},
+			{Command: "cd /tmp"},
+			{Command: "# not a timestamp"},
+		}},
+		{"bash timestamps", "", "#1700000000\nmake\n#1700000060\nfor f in *; do\necho $f\ndone\n#1700000099\n", []HistoryEntry{
+			{Command: "make", Timestamp: ts(1700000000)},
+			{Command: "for f in *; do\necho $f\ndone", Timestamp: ts(1700000060)},
+		}},
+		{"zsh extended", "", ": 1700000000:3;make test\n: 1700000010:0;echo one\\\ntwo\n", []HistoryEntry{
+			{Command: "make test", Timestamp: ts(1700000000), Duration: 3 * time.Second},
+			{Command: "echo one\ntwo", Timestamp: ts(1700000010)},
+		}},
+		{"zsh plain", FormatZsh, "git status\n", []HistoryEntry{
+			{Command: "git status"},
+		}},
+		// à is C3 A0 in UTF-8, where A0 is 83 80 in zsh files
+		{"zsh metafied", "", ": 1700000000:0;echo voil\xc3\x83\x80\n", []HistoryEntry{
+			{Command: "echo voilà", Timestamp: ts(1700000000)},
+		}},
+		{"json", "", `{"v":1,"time":"2023-11-14T22:13:20Z","cmd":"make","cwd":"/src","exit":2,"duration_ms":1500}` + "\n", []HistoryEntry{
+			{Command: "make", Timestamp: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), Dir: "/src", ExitCode: 2, Duration: 1500 * time.Millisecond},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			for i := range got {
+				// Compare instants, not locations
+				if got[i].Timestamp.Equal(tt.want[i].Timestamp) {
+					got[i].Timestamp = tt.want[i].Timestamp
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Parse(strings.NewReader(""), "fish"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Parse(fish) error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []HistoryEntry{
+		{Command: "make", Timestamp: time.Unix(1700000000, 0), Duration: 2500 * time.Millisecond},
+		{Command: "echo voilà\necho done", Timestamp: time.Unix(1700000060, 0)},
+		{Command: "imported"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatBash, "#1700000000\nmake\n#1700000060\necho voilà\necho done\n#1700000060\nimported\n"},
+		{FormatZsh, ": 1700000000:2;make\n: 1700000060:0;echo voil\xc3\x83\x80\\\necho done\nimported\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, entries, tt.format); err != nil {
+				t.Fatalf("Export() error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Export() = %q, want %q", buf.String(), tt.want)
+			}
+
+			// What is exported is imported back
+			back, err := Parse(&buf, "")
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if len(back) != len(entries) || !back[0].Timestamp.Equal(entries[0].Timestamp) {
+				t.Fatalf("Parse(Export()) = %+v, want %+v", back, entries)
+			}
+			for i := range back {
+				if back[i].Command != entries[i].Command {
+					t.Errorf("command %d = %q, want %q", i, back[i].Command, entries[i].Command)
+				}
+			}
+		})
+	}
+
+	if err := Export(&bytes.Buffer{}, entries, "csv"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Export(csv) error = %v, want ErrUnknownFormat", err)
+	}
+}
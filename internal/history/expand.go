@@ -0,0 +1,436 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrEventNotFound is returned when a history expansion refers to no entry.
+var ErrEventNotFound = errors.New("event not found")
+
+// ErrBadWordSpecifier is returned when a word designator selects words the
+// entry does not have.
+var ErrBadWordSpecifier = errors.New("bad word specifier")
+
+// ErrBadModifier is returned for an unknown modifier, as in !!:x.
+var ErrBadModifier = errors.New("unrecognized history modifier")
+
+// ErrSubstitutionFailed is returned when the text to replace by ^old^new
+// or :s/old/new/ is not found.
+var ErrSubstitutionFailed = errors.New("substitution failed")
+
+// Expansion is the result of the history expansion of a line.
+type Expansion struct {
+	Line      string // The line with its history references replaced
+	Expanded  bool   // The line had history references
+	PrintOnly bool   // The :p modifier: display the line without running it
+}
+
+// Expand replaces the history references of a line, as bash does:
+//
+//	!!  !n  !-n  !prefix  !?text?  !#    Event designators
+//	:n  :^  :$  :*  :x-y  :x*  :x-       Word designators; the colon may be
+//	                                     left out before ^ $ *, as in !$
+//	:h  :t  :r  :e  :p  :s/a/b/  :gs/a/b/  Modifiers
+//	^old^new^                            Repeat the last command, replacing old
+//
+// Numbers are those of the history listing, starting at 1. A ! is left as
+// is within single quotes, after a backslash, before a blank, = or ( and in
+// $!, ${! and [!.
+func (h *History) Expand(line string) (Expansion, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	x := &expander{entries: h.entries}
+	return x.expand(line)
+}
+
+// expander expands the history references of a line.
+type expander struct {
+	entries []HistoryEntry
+	result  Expansion
+	out     strings.Builder
+}
+
+func (x *expander) expand(line string) (Expansion, error) {
+	if strings.HasPrefix(line, "^") {
+		if err := x.quickSubstitution(line[1:]); err != nil {
+			return Expansion{}, err
+		}
+		x.result.Line = x.out.String()
+		return x.result, nil
+	}
+
+	var quote byte // Quote the scan is in: ' or "
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\' && i+1 < len(line) && (quote == '"' || line[i+1] == '!'):
+			// Keep the escaped character as is
+			x.out.WriteByte(c)
+			i++
+			c = line[i]
+		case c == '\'' && quote == 0, c == '"' && quote == 0:
+			quote = c
+		case c == '"':
+			quote = 0
+		case c == '!' && isReference(line, i, quote):
+			n, err := x.reference(line[i+1:], quote)
+			if err != nil {
+				return Expansion{}, err
+			}
+			x.result.Expanded = true
+			i += n
+			continue
+		}
+		x.out.WriteByte(c)
+	}
+	x.result.Line = x.out.String()
+	return x.result, nil
+}
+
+// isReference returns true if the ! at i starts a history reference.
+func isReference(line string, i int, quote byte) bool {
+	if i+1 >= len(line) {
+		return false
+	}
+	switch next := line[i+1]; {
+	case next == ' ', next == '\t', next == '\n', next == '=', next == '(':
+		return false
+	case next == '"' && quote == '"':
+		return false
+	}
+	if i > 0 && (line[i-1] == '$' || line[i-1] == '[') {
+		return false
+	}
+	return !strings.HasSuffix(line[:i], "${")
+}
+
+// reference expands the history reference after a !, writing the result,
+// and returns the length it took in the line.
+func (x *expander) reference(s string, quote byte) (int, error) {
+	text, n, err := x.event(s, quote)
+	if err != nil {
+		return 0, err
+	}
+
+	// Word designator, after a colon or directly for ^ $ *
+	if n < len(s) {
+		start := n
+		if s[n] == ':' && n+1 < len(s) && isDesignator(s[n+1]) {
+			start++
+		}
+		if start < len(s) && (start > n || strings.IndexByte("^$*", s[start]) >= 0) {
+			words, m, err := selectWords(splitWords(text), s[start:])
+			if err != nil {
+				return 0, err
+			}
+			text = words
+			n = start + m
+		}
+	}
+
+	text, m, err := x.modifiers(text, s[n:])
+	if err != nil {
+		return 0, err
+	}
+	x.out.WriteString(text)
+	return n + m, nil
+}
+
+// event returns the entry of the event designator at the start of s and
+// the length of the designator.
+func (x *expander) event(s string, quote byte) (string, int, error) {
+	switch {
+	case s[0] == '!':
+		return x.relative(1, "!!", 1)
+	case s[0] == '#':
+		return x.out.String(), 1, nil
+	case s[0] == ':' || strings.IndexByte("^$*", s[0]) >= 0:
+		// A word designator alone refers to the last command
+		return x.relative(1, "!"+s[:1], 0)
+	case s[0] == '-' || isDigit(s[0]):
+		n := 1
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+		number, err := strconv.Atoi(s[:n])
+		if err != nil {
+			return "", 0, fmt.Errorf("%w: !%s", ErrEventNotFound, s[:n])
+		}
+		if number < 0 {
+			return x.relative(-number, "!"+s[:n], n)
+		}
+		if number < 1 || number > len(x.entries) {
+			return "", 0, fmt.Errorf("%w: !%s", ErrEventNotFound, s[:n])
+		}
+		return x.entries[number-1].Command, n, nil
+	case s[0] == '?':
+		end := strings.IndexByte(s[1:], '?')
+		n := len(s)
+		if end >= 0 {
+			n = end + 2
+		} else {
+			end = len(s) - 1
+		}
+		text := s[1 : 1+end]
+		for i := len(x.entries) - 1; i >= 0 && text != ""; i-- {
+			if strings.Contains(x.entries[i].Command, text) {
+				return x.entries[i].Command, n, nil
+			}
+		}
+		return "", 0, fmt.Errorf("%w: !?%s", ErrEventNotFound, text)
+	}
+
+	n := 0
+	for n < len(s) && !isEventEnd(s[n], quote) {
+		n++
+	}
+	prefix := s[:n]
+	for i := len(x.entries) - 1; i >= 0; i-- {
+		if strings.HasPrefix(x.entries[i].Command, prefix) {
+			return x.entries[i].Command, n, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w: !%s", ErrEventNotFound, prefix)
+}
+
+// relative returns the entry n commands back, with the given length.
+func (x *expander) relative(n int, spec string, length int) (string, int, error) {
+	if n < 1 || n > len(x.entries) {
+		return "", 0, fmt.Errorf("%w: %s", ErrEventNotFound, spec)
+	}
+	return x.entries[len(x.entries)-n].Command, length, nil
+}
+
+// isEventEnd returns true if c ends the prefix of a !prefix reference.
+func isEventEnd(c byte, quote byte) bool {
+	return strings.IndexByte(" \t\n:;&|()<>'", c) >= 0 || c == '"' && quote == '"'
+}
+
+// isDesignator returns true if c starts a word designator after a colon.
+func isDesignator(c byte) bool {
+	return isDigit(c) || strings.IndexByte("^$*-", c) >= 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// selectWords returns the words selected by the word designator at the
+// start of s, joined by spaces, and the length of the designator.
+func selectWords(words []string, s string) (string, int, error) {
+	last := len(words) - 1
+	number := func(i int) (int, int) {
+		if i < len(s) && s[i] == '$' {
+			return last, i + 1
+		}
+		if i < len(s) && s[i] == '^' {
+			return 1, i + 1
+		}
+		j := i
+		for j < len(s) && isDigit(s[j]) {
+			j++
+		}
+		if j == i {
+			return -1, i
+		}
+		n, _ := strconv.Atoi(s[i:j])
+		return n, j
+	}
+
+	var from, to, n int
+	switch s[0] {
+	case '*':
+		// All the arguments, which may be none
+		if last < 1 {
+			return "", 1, nil
+		}
+		from, to, n = 1, last, 1
+	case '-':
+		from = 0
+		to, n = number(1)
+		if to < 0 {
+			to = last - 1
+		}
+	default:
+		from, n = number(0)
+		to = from
+		switch {
+		case n < len(s) && s[n] == '*':
+			to = last
+			n++
+		case n < len(s) && s[n] == '-':
+			var m int
+			if to, m = number(n + 1); to < 0 {
+				// x- leaves out the last word
+				to = last - 1
+			}
+			n = m
+		}
+	}
+
+	if from < 0 || to > last || from > to {
+		return "", 0, fmt.Errorf("%w: %s", ErrBadWordSpecifier, s[:n])
+	}
+	return strings.Join(words[from:to+1], " "), n, nil
+}
+
+// splitWords splits a command into words as the shell does: at blanks
+// outside of quotes, with the operators ; & | < > ( ) as words of their own.
+func splitWords(line string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(line) {
+				word.WriteByte(c)
+				i++
+				c = line[i]
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+			continue
+		case strings.IndexByte(";&|<>()", c) >= 0:
+			flush()
+			op := line[i : i+1]
+			if i+1 < len(line) && line[i+1] == c && strings.IndexByte(";&|<>", c) >= 0 {
+				op = line[i : i+2]
+				i++
+			}
+			words = append(words, op)
+			continue
+		}
+		word.WriteByte(c)
+	}
+	flush()
+	return words
+}
+
+// modifiers applies the modifiers at the start of s, such as :h or
+// :s/old/new/, to text and returns it with their length.
+func (x *expander) modifiers(text, s string) (string, int, error) {
+	n := 0
+	for n+1 < len(s) && s[n] == ':' {
+		i := n + 1
+		global := false
+		if s[i] == 'g' || s[i] == 'a' {
+			global = true
+			i++
+		}
+		if i >= len(s) {
+			return "", 0, fmt.Errorf("%w: %s", ErrBadModifier, s[n+1:])
+		}
+
+		switch s[i] {
+		case 'h':
+			if dir := path.Dir(text); dir != "." || strings.Contains(text, "/") {
+				text = dir
+			}
+		case 't':
+			text = text[strings.LastIndexByte(text, '/')+1:]
+		case 'r':
+			if ext := path.Ext(text); ext != "" {
+				text = strings.TrimSuffix(text, ext)
+			}
+		case 'e':
+			text = path.Ext(text)
+		case 'p':
+			x.result.PrintOnly = true
+		case 's':
+			var m int
+			var err error
+			text, m, err = substitute(text, s[i+1:], global)
+			if err != nil {
+				return "", 0, err
+			}
+			i += m
+		default:
+			if s[i] >= 'a' && s[i] <= 'z' {
+				return "", 0, fmt.Errorf("%w: %c", ErrBadModifier, s[i])
+			}
+			// Not a modifier: the colon is part of the line
+			return text, n, nil
+		}
+		n = i + 1
+	}
+	return text, n, nil
+}
+
+// substitute applies the substitution /old/new/ at the start of s, where
+// any character may replace /, to text and returns the length it took.
+// The final delimiter may be left out at the end of the line, and & in new
+// stands for old.
+func substitute(text, s string, global bool) (string, int, error) {
+	if s == "" {
+		return "", 0, fmt.Errorf("%w: s", ErrBadModifier)
+	}
+	delim := s[0]
+	old, n := delimited(s, 1, delim)
+	replacement, m := delimited(s, n, delim)
+	replacement = strings.ReplaceAll(replacement, "&", old)
+
+	if old == "" || !strings.Contains(text, old) {
+		return "", 0, fmt.Errorf("%w: %s", ErrSubstitutionFailed, old)
+	}
+	count := 1
+	if global {
+		count = -1
+	}
+	return strings.Replace(text, old, replacement, count), m, nil
+}
+
+// delimited returns the text of s from i to the delimiter, where a
+// backslash quotes the delimiter, and the index after the delimiter.
+func delimited(s string, i int, delim byte) (string, int) {
+	var sb strings.Builder
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == delim:
+			i++
+		case s[i] == delim:
+			return sb.String(), i + 1
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), i
+}
+
+// quickSubstitution expands ^old^new^rest: the last command with old
+// replaced by new, followed by rest.
+func (x *expander) quickSubstitution(s string) error {
+	text, _, err := x.relative(1, "^", 0)
+	if err != nil {
+		return err
+	}
+	old, n := delimited(s, 0, '^')
+	replacement, m := delimited(s, n, '^')
+	if old == "" || !strings.Contains(text, old) {
+		return fmt.Errorf("%w: %s", ErrSubstitutionFailed, old)
+	}
+	x.out.WriteString(strings.Replace(text, old, replacement, 1))
+	x.out.WriteString(s[m:])
+	x.result.Expanded = true
+	return nil
+}
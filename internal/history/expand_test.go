@@ -0,0 +1,95 @@
+package history
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	h := New(100)
+	for _, cmd := range []string{
+		"cd /src/app",
+		"vim main.go README.md",
+		`git commit -m "fix the build" && git push`,
+		"tar xzf archive/release.tar.gz",
+	} {
+		h.Add(cmd)
+	}
+
+	tests := []struct {
+		name      string
+		line      string
+		want      string
+		printOnly bool
+		err       error
+	}{
+		{"no reference", "echo hello", "echo hello", false, nil},
+		{"last command", "sudo !!", "sudo tar xzf archive/release.tar.gz", false, nil},
+		{"number", "!1", "cd /src/app", false, nil},
+		{"relative", "!-3", "vim main.go README.md", false, nil},
+		{"prefix", "!vim", "vim main.go README.md", false, nil},
+		{"contains", "!?commit?", `git commit -m "fix the build" && git push`, false, nil},
+		{"last word", "ls !$", "ls archive/release.tar.gz", false, nil},
+		{"first word", "echo !^", "echo xzf", false, nil},
+		{"all words", "echo !vim:*", "echo main.go README.md", false, nil},
+		{"word number", "echo !vim:2", "echo README.md", false, nil},
+		{"word range", "echo !vim:0-1", "echo vim main.go", false, nil},
+		{"word to end", "echo !?commit?:2*", `echo -m "fix the build" && git push`, false, nil},
+		{"quoted word", "echo !?commit?:3", `echo "fix the build"`, false, nil},
+		{"operator word", "echo !?commit?:4", "echo &&", false, nil},
+		{"head", "cd !!:$:h", "cd archive", false, nil},
+		{"tail", "echo !$:t", "echo release.tar.gz", false, nil},
+		{"root", "echo !$:t:r", "echo release.tar", false, nil},
+		{"extension", "echo !$:e", "echo .gz", false, nil},
+		{"substitute", "!vim:s/main/util/", "vim util.go README.md", false, nil},
+		{"substitute global", "!vim:gs/m/M/", "viM Main.go README.Md", false, nil},
+		{"substitute ampersand", "!!:s/tar/&.old/", "tar.old xzf archive/release.tar.gz", false, nil},
+		{"print only", "!vim:p", "vim main.go README.md", true, nil},
+		{"quick substitution", "^xzf^tzf", "tar tzf archive/release.tar.gz", false, nil},
+		{"quick substitution rest", "^xzf^xf^ -v", "tar xf archive/release.tar.gz -v", false, nil},
+		{"line so far", "mv file.txt !#:1.bak", "mv file.txt file.txt.bak", false, nil},
+		{"double quotes", `echo "!!"`, `echo "tar xzf archive/release.tar.gz"`, false, nil},
+		{"colon kept", "echo !!:/x", "echo tar xzf archive/release.tar.gz:/x", false, nil},
+
+		{"single quotes", "echo '!!'", "echo '!!'", false, nil},
+		{"escaped", `echo \!!`, `echo \!!`, false, nil},
+		{"end of word", "echo hi! there!", "echo hi! there!", false, nil},
+		{"not equal", "[ a != b ]", "[ a != b ]", false, nil},
+		{"extglob", "ls !(*.go)", "ls !(*.go)", false, nil},
+		{"last job", "wait $!", "wait $!", false, nil},
+		{"indirect", "echo ${!name}", "echo ${!name}", false, nil},
+		{"glob class", "ls [!a]*", "ls [!a]*", false, nil},
+		{"quote after", `echo "hi!"`, `echo "hi!"`, false, nil},
+
+		{"unknown prefix", "!nothing", "", false, ErrEventNotFound},
+		{"number out of range", "!42", "", false, ErrEventNotFound},
+		{"relative out of range", "!-9", "", false, ErrEventNotFound},
+		{"bad word", "echo !vim:7", "", false, ErrBadWordSpecifier},
+		{"bad modifier", "!!:z", "", false, ErrBadModifier},
+		{"failed substitution", "^zzz^y", "", false, ErrSubstitutionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Expand(tt.line)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expand(%q) error = %v, want %v", tt.line, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got.Line != tt.want || got.PrintOnly != tt.printOnly {
+				t.Errorf("Expand(%q) = %q (print only %v), want %q (%v)", tt.line, got.Line, got.PrintOnly, tt.want, tt.printOnly)
+			}
+			if got.Expanded != (tt.line != tt.want) {
+				t.Errorf("Expand(%q).Expanded = %v", tt.line, got.Expanded)
+			}
+		})
+	}
+}
+
+func TestExpandEmptyHistory(t *testing.T) {
+	if _, err := New(10).Expand("!!"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expand(!!) error = %v, want %v", err, ErrEventNotFound)
+	}
+}
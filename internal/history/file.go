@@ -0,0 +1,378 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// compactThreshold returns the number of records above which the history
+// file is compacted to the last maxSize entries.
+func compactThreshold(maxSize int) int {
+	return maxSize + maxSize/2
+}
+
+// lockFile takes an advisory lock on the lock file of the history file,
+// shared to read it or exclusive to write it, and returns a function that
+// releases the lock. The history file is only opened under the lock, so
+// that it can be replaced while no other session has it open.
+func lockFile(path string, exclusive bool) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating history directory: %w", err)
+	}
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening history lock file: %w", err)
+	}
+	if err := lock(f, exclusive); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking history file: %w", err)
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
+
+// Save replaces the history file with the entries of the history, one JSON
+// record per line.
+func (h *History) Save(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := h.writeFile(path, h.entries); err != nil {
+		return err
+	}
+	h.unsaved = 0
+	h.rewrite = false
+	h.deleted, h.imported = nil, nil
+	return nil
+}
+
+// Load loads history from a file in the JSON Lines format or the legacy
+// timestamp:command format.
+func (h *History) Load(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil // No history file yet
+	}
+	unlock, err := lockFile(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer file.Close()
+
+	h.entries = h.entries[:0] // Clear existing entries
+	h.pending = false
+	h.unsaved = 0
+	h.rewrite = false
+	h.deleted, h.imported = nil, nil
+	h.fileInfo = nil
+	entries, _, err := h.readNew(file)
+	if err != nil {
+		return err
+	}
+	h.entries = h.appendEntries(h.entries, entries)
+	h.trim()
+	return nil
+}
+
+// Flush appends the completed entries that were not written yet to the
+// history file. Entries other sessions wrote meanwhile are merged first
+// when the history is shared. The file is compacted when it holds too
+// many records, and rewritten after entries were deleted or imported,
+// keeping the records of other sessions whether the history is shared
+// or not.
+func (h *History) Flush(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.unsaved == 0 && !h.rewrite {
+		return nil
+	}
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer file.Close()
+
+	if err := h.sync(file); err != nil {
+		return err
+	}
+	if h.rewrite {
+		return h.rewriteFile(path, file)
+	}
+
+	var buf bytes.Buffer
+	if h.fileSize > 0 {
+		// A session that crashed while writing may have left a partial line
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, h.fileSize-1); err == nil && last[0] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	end := h.completed()
+	for _, entry := range h.entries[end-h.unsaved : end] {
+		line, err := encodeEntry(entry)
+		if err != nil {
+			return fmt.Errorf("encoding history entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	n, err := file.Write(buf.Bytes())
+	h.fileSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing history entry: %w", err)
+	}
+	h.fileLines += h.unsaved
+	h.unsaved = 0
+
+	if h.fileLines > compactThreshold(h.maxSize) {
+		return h.compact(path, file)
+	}
+	return nil
+}
+
+// Sync merges the entries other sessions wrote to the history file since
+// it was last read, when the history is shared.
+func (h *History) Sync(path string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.shareHistory {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	unlock, err := lockFile(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening history file: %w", err)
+	}
+	defer file.Close()
+	return h.sync(file)
+}
+
+// sync reads the records added to the history file and, when the history
+// is shared, merges them before the entries not written yet. If another
+// session replaced the file, the history is rebuilt from it. The caller
+// must hold the lock of the history and of the file.
+func (h *History) sync(file *os.File) error {
+	entries, replaced, err := h.readNew(file)
+	if err != nil || !h.shareHistory || len(entries) == 0 && !replaced {
+		return err
+	}
+
+	keep := len(h.entries) - h.completed() + h.unsaved
+	tail := append([]HistoryEntry(nil), h.entries[len(h.entries)-keep:]...)
+	if replaced {
+		h.entries = h.appendEntries(h.entries[:0], entries)
+	} else {
+		h.entries = h.entries[:len(h.entries)-keep]
+		for _, entry := range entries {
+			// Entries of this session are in the history already
+			if entry.SessionID == "" || entry.SessionID != h.sessionID {
+				h.entries = h.appendEntries(h.entries, []HistoryEntry{entry})
+			}
+		}
+	}
+	h.entries = append(h.entries, tail...)
+	h.trim()
+	h.navPosition = -1
+	return nil
+}
+
+// readNew reads the records added to the history file since it was last
+// read, or all of them if the file was replaced or truncated, and updates
+// the state of the file. The caller must hold the lock of the history and
+// of the file.
+func (h *History) readNew(file *os.File) (entries []HistoryEntry, replaced bool, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, fmt.Errorf("reading history file: %w", err)
+	}
+	if h.fileInfo == nil || !os.SameFile(h.fileInfo, info) || info.Size() < h.fileSize {
+		replaced = h.fileInfo != nil
+		h.fileSize, h.fileLines = 0, 0
+	}
+	h.fileInfo = info
+
+	if _, err := file.Seek(h.fileSize, io.SeekStart); err != nil {
+		return nil, replaced, fmt.Errorf("reading history file: %w", err)
+	}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		h.fileSize += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			h.fileLines++
+			entries = append(entries, decodeEntry(line))
+		}
+		if err == io.EOF {
+			return entries, replaced, nil
+		}
+		if err != nil {
+			return entries, replaced, fmt.Errorf("reading history file: %w", err)
+		}
+	}
+}
+
+// compact replaces the history file with its last maxSize entries. The
+// caller must hold the lock of the history and of the file.
+func (h *History) compact(path string, file *os.File) error {
+	h.fileInfo = nil
+	entries, _, err := h.readNew(file)
+	if err != nil {
+		return err
+	}
+	entries = h.appendEntries(nil, entries)
+	if len(entries) > h.maxSize {
+		entries = entries[len(entries)-h.maxSize:]
+	}
+	return h.writeFile(path, entries)
+}
+
+// rewriteFile replaces the history file after entries were deleted or
+// imported with the imported entries, the records of the file but the
+// deleted ones, and the entries not written yet. The caller must hold the
+// lock of the history and of the file.
+func (h *History) rewriteFile(path string, file *os.File) error {
+	h.fileInfo = nil
+	records, _, err := h.readNew(file)
+	if err != nil {
+		return err
+	}
+
+	deleted := make(map[recordKey]int)
+	for _, entry := range h.deleted {
+		deleted[keyOf(entry)]++
+	}
+	var entries []HistoryEntry
+	for _, entry := range slices.Concat(h.imported, records) {
+		key := keyOf(entry)
+		if deleted[key] == 0 {
+			entries = append(entries, entry)
+			continue
+		}
+		deleted[key]--
+		// With ignore_dups, a deleted entry stands for the records of its
+		// command just before it too
+		for h.ignoreDuplicates && len(entries) > 0 && entries[len(entries)-1].Command == entry.Command {
+			entries = entries[:len(entries)-1]
+		}
+	}
+	end := h.completed()
+	entries = h.appendEntries(nil, slices.Concat(entries, h.entries[end-h.unsaved:end]))
+	if len(entries) > h.maxSize {
+		entries = entries[len(entries)-h.maxSize:]
+	}
+	if err := h.writeFile(path, entries); err != nil {
+		return err
+	}
+	h.unsaved, h.rewrite = 0, false
+	h.deleted, h.imported = nil, nil
+	return nil
+}
+
+// recordKey identifies a record of the history file: the command, and
+// when and in which session it ran if the record tells.
+type recordKey struct {
+	command   string
+	timestamp int64
+	sessionID string
+}
+
+// keyOf returns the key of the record of an entry. Entries of the legacy
+// format are only told apart by their command.
+func keyOf(entry HistoryEntry) recordKey {
+	if !entry.HasDetails() {
+		return recordKey{command: entry.Command}
+	}
+	return recordKey{entry.Command, entry.Timestamp.UnixNano(), entry.SessionID}
+}
+
+// writeFile replaces the history file with entries, writing them to a
+// temporary file renamed over it, and updates the state of the file.
+// The caller must hold the lock of the history and of the file.
+func (h *History) writeFile(path string, entries []HistoryEntry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("creating history file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, entry := range entries {
+		line, err := encodeEntry(entry)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("encoding history entry: %w", err)
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing history file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing history file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing history file: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("reading history file: %w", err)
+	}
+	h.fileInfo, h.fileSize, h.fileLines = info, info.Size(), len(entries)
+	return nil
+}
+
+// appendEntries appends entries read from the history file to list. When
+// duplicates are ignored, a repeated command replaces the last entry, which
+// then has the outcome of its latest run.
+func (h *History) appendEntries(list, entries []HistoryEntry) []HistoryEntry {
+	for _, entry := range entries {
+		if h.ignoreDuplicates && len(list) > 0 && list[len(list)-1].Command == entry.Command {
+			list[len(list)-1] = entry
+			continue
+		}
+		list = append(list, entry)
+	}
+	return list
+}
@@ -0,0 +1,74 @@
+package history
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the version of the JSON Lines history format written by
+// Save. Each line of the file is a record of one entry.
+const FormatVersion = 1
+
+// record is the on-disk form of an entry.
+type record struct {
+	Version   int       `json:"v"`
+	Timestamp time.Time `json:"time"`
+	Command   string    `json:"cmd"`
+	Dir       string    `json:"cwd,omitempty"`
+	ExitCode  int       `json:"exit"`
+	Duration  int64     `json:"duration_ms"`
+	Hostname  string    `json:"host,omitempty"`
+	SessionID string    `json:"session,omitempty"`
+}
+
+// encodeEntry returns the line of an entry in the JSON Lines format.
+func encodeEntry(entry HistoryEntry) ([]byte, error) {
+	return json.Marshal(record{
+		Version:   FormatVersion,
+		Timestamp: entry.Timestamp,
+		Command:   entry.Command,
+		Dir:       entry.Dir,
+		ExitCode:  entry.ExitCode,
+		Duration:  entry.Duration.Milliseconds(),
+		Hostname:  entry.Hostname,
+		SessionID: entry.SessionID,
+	})
+}
+
+// decodeEntry parses a line of the history file: a JSON record, or the
+// legacy timestamp:command format, or a bare command.
+func decodeEntry(line string) HistoryEntry {
+	if strings.HasPrefix(line, "{") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err == nil && r.Version > 0 {
+			return HistoryEntry{
+				Command:   r.Command,
+				Timestamp: r.Timestamp,
+				Dir:       r.Dir,
+				ExitCode:  r.ExitCode,
+				Duration:  time.Duration(r.Duration) * time.Millisecond,
+				Hostname:  r.Hostname,
+				SessionID: r.SessionID,
+			}
+		}
+	}
+
+	colonIdx := strings.Index(line, ":")
+	if colonIdx == -1 {
+		// Legacy format without timestamp - just the command
+		return HistoryEntry{Command: line, Timestamp: time.Now()}
+	}
+
+	ts, err := strconv.ParseInt(line[:colonIdx], 10, 64)
+	if err != nil {
+		// Invalid timestamp, use current time
+		ts = time.Now().Unix()
+	}
+	return HistoryEntry{
+		// Unescape newlines
+		Command:   strings.ReplaceAll(line[colonIdx+1:], "\\n", "\n"),
+		Timestamp: time.Unix(ts, 0),
+	}
+}
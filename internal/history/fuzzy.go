@@ -0,0 +1,175 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of the fuzzy match score.
+const (
+	scoreMatch       = 16 // Each matched character
+	bonusBoundary    = 8  // Match at the start of the text or of a word
+	bonusConsecutive = 4  // Match right after the previous one
+	penaltyGapStart  = 3  // Characters skipped between two matches
+	penaltyGapExtend = 1  // Each skipped character after the first
+)
+
+// StatusFilter selects entries of a fuzzy search by exit status.
+type StatusFilter int
+
+const (
+	AnyStatus StatusFilter = iota
+	Succeeded              // Exit status 0
+	Failed                 // Non-zero exit status
+)
+
+// Filter restricts the entries of a fuzzy search. Entries recorded without
+// details only pass the zero filter.
+type Filter struct {
+	Dir    string // Directory the command ran in, if not empty
+	Status StatusFilter
+}
+
+// Match is an entry found by a fuzzy search.
+type Match struct {
+	Entry     HistoryEntry
+	Number    int   // Number of the entry in the history, from 1
+	Score     int   // Higher for better matches
+	Positions []int // Indices of the matched runes of the command
+}
+
+// FuzzyMatch reports whether the runes of pattern appear in text in order,
+// not necessarily next to each other. It returns a score, higher when the
+// matches are consecutive or start words, and the rune indices of text that
+// match. Case is ignored unless pattern has upper case letters.
+func FuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	fold := unicode.ToLower
+	if strings.IndexFunc(pattern, unicode.IsUpper) >= 0 {
+		fold = func(r rune) rune { return r }
+	}
+
+	// score[i][j] is the best score of p[:i+1] with p[i] matched at t[j], and
+	// from[i][j] the position of p[i-1] in that match
+	const none = math.MinInt32
+	score := make([][]int, len(p))
+	from := make([][]int, len(p))
+	for i := range p {
+		score[i] = make([]int, len(t))
+		from[i] = make([]int, len(t))
+		// Best score of p[i-1] at least two runes back, with the gap penalty
+		gap, gapFrom := none, -1
+		for j := range t {
+			score[i][j] = none
+			if i > 0 && j >= 2 {
+				if gap != none {
+					gap -= penaltyGapExtend
+				}
+				if prev := score[i-1][j-2]; prev != none && prev-penaltyGapStart > gap {
+					gap, gapFrom = prev-penaltyGapStart, j-2
+				}
+			}
+			if fold(t[j]) != fold(p[i]) {
+				continue
+			}
+
+			base := scoreMatch
+			if isBoundary(t, j) {
+				base += bonusBoundary
+			}
+			if i == 0 {
+				score[i][j] = base
+				continue
+			}
+			if j >= 1 && score[i-1][j-1] != none {
+				score[i][j], from[i][j] = base+score[i-1][j-1]+bonusConsecutive, j-1
+			}
+			if gap != none && base+gap > score[i][j] {
+				score[i][j], from[i][j] = base+gap, gapFrom
+			}
+		}
+	}
+
+	last := len(p) - 1
+	end := -1
+	for j := range t {
+		if score[last][j] != none && (end < 0 || score[last][j] > score[last][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions := make([]int, len(p))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return score[last][end], positions, true
+}
+
+// isBoundary returns true if the rune at i starts the text or a word.
+func isBoundary(t []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := t[i-1]
+	return unicode.IsSpace(prev) || strings.ContainsRune("/\\-_.,:;=|&()'\"`$", prev)
+}
+
+// FuzzySearch returns the entries that match query with FuzzyMatch and
+// pass the filter, best matches first and the most recent first among equal
+// ones, at most limit of them (0 for no limit). A command that was run
+// several times is returned once, as its most recent run.
+func (h *History) FuzzySearch(query string, filter Filter, limit int) []Match {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var matches []Match
+	seen := make(map[string]bool)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		entry := h.entries[i]
+		if seen[entry.Command] || !filter.accepts(entry) {
+			continue
+		}
+		score, positions, ok := FuzzyMatch(query, entry.Command)
+		if !ok {
+			continue
+		}
+		seen[entry.Command] = true
+		matches = append(matches, Match{Entry: entry, Number: i + 1, Score: score, Positions: positions})
+	}
+
+	// Stable: equal scores stay the most recent first
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// accepts returns true if the entry passes the filter.
+func (f Filter) accepts(entry HistoryEntry) bool {
+	if f.Dir == "" && f.Status == AnyStatus {
+		return true
+	}
+	if !entry.HasDetails() || f.Dir != "" && entry.Dir != f.Dir {
+		return false
+	}
+	switch f.Status {
+	case Succeeded:
+		return entry.ExitCode == 0
+	case Failed:
+		return entry.ExitCode != 0
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"", "anything", true, nil},
+		{"gco", "git checkout", true, []int{0, 4, 9}},
+		{"GCO", "git checkout", false, nil},
+		{"Make", "make test", false, nil},
+		{"mkt", "make test", true, []int{0, 2, 5}},
+		{"xyz", "make test", false, nil},
+		{"abc", "cba", false, nil},
+		// Word starts and consecutive runes win over the first match
+		{"gp", "grep x; git push", true, []int{8, 12}},
+		{"ab", "a a ab", true, []int{4, 5}},
+		{"été", "cd Été", true, []int{3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.text, func(t *testing.T) {
+			_, positions, ok := FuzzyMatch(tt.pattern, tt.text)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !reflect.DeepEqual(positions, tt.positions) {
+				t.Errorf("positions = %v, want %v", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	// Consecutive matches and word starts score higher
+	better := [][2]string{
+		{"make", "make test"},
+		{"mt", "make test"},
+		{"gst", "git status"},
+	}
+	worse := []string{"mxaxkxe", "smart", "big sister"}
+	for i, pair := range better {
+		good, _, _ := FuzzyMatch(pair[0], pair[1])
+		bad, _, ok := FuzzyMatch(pair[0], worse[i])
+		if !ok {
+			t.Fatalf("%q should match %q", pair[0], worse[i])
+		}
+		if good <= bad {
+			t.Errorf("%q: score %d of %q should be higher than %d of %q", pair[0], good, pair[1], bad, worse[i])
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	h := New(100)
+	run := func(cmd, dir string, status int) {
+		h.Add(cmd)
+		h.Complete(dir, status, time.Millisecond)
+	}
+	run("go test ./...", "/src/app", 1)
+	run("git status", "/src/app", 0)
+	run("go test ./...", "/src/lib", 0)
+	run("gofmt -l .", "/src/lib", 0)
+	run("grep -r todo", "/src/app", 0)
+	h.Add("legacy gt") // Without details
+
+	commands := func(matches []Match) []string {
+		var cmds []string
+		for _, m := range matches {
+			cmds = append(cmds, m.Entry.Command)
+		}
+		return cmds
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter Filter
+		limit  int
+		want   []string
+	}{
+		{"empty query, most recent first", "", Filter{}, 0,
+			[]string{"legacy gt", "grep -r todo", "gofmt -l .", "go test ./...", "git status"}},
+		{"best match first", "gt", Filter{}, 0,
+			[]string{"legacy gt", "go test ./...", "grep -r todo", "git status", "gofmt -l ."}},
+		{"limit", "", Filter{}, 2, []string{"legacy gt", "grep -r todo"}},
+		{"directory", "g", Filter{Dir: "/src/app"}, 0, []string{"grep -r todo", "git status", "go test ./..."}},
+		{"failed", "", Filter{Status: Failed}, 0, []string{"go test ./..."}},
+		{"succeeded here", "", Filter{Dir: "/src/lib", Status: Succeeded}, 0, []string{"gofmt -l .", "go test ./..."}},
+		{"no match", "zzz", Filter{}, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commands(h.FuzzySearch(tt.query, tt.filter, tt.limit))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FuzzySearch(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// A repeated command is found as its most recent run
+	matches := h.FuzzySearch("go test", Filter{}, 1)
+	if len(matches) != 1 || matches[0].Number != 3 || matches[0].Entry.Dir != "/src/lib" {
+		t.Errorf("FuzzySearch(\"go test\") = %+v, want entry 3 in /src/lib", matches)
+	}
+}
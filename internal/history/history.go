@@ -2,12 +2,12 @@
 package history
 
 import (
-	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -20,9 +20,30 @@ var ErrOutOfBounds = errors.New("history index out of bounds")
 var ErrEmptyHistory = errors.New("history is empty")
 
 // HistoryEntry represents a single command in the history.
+// The fields after Timestamp are empty for entries of the legacy format.
 type HistoryEntry struct {
-	Command   string    // The command text
-	Timestamp time.Time // When the command was executed
+	Command   string        // The command text
+	Timestamp time.Time     // When the command was executed
+	Dir       string        // Working directory the command ran in
+	ExitCode  int           // Exit status of the command
+	Duration  time.Duration // How long the command ran
+	Hostname  string        // Host of the shell that ran the command
+	SessionID string        // Shell session that ran the command
+}
+
+// HasDetails returns true if the entry records where and how the command
+// ran, which entries of the legacy format and running commands do not.
+func (e HistoryEntry) HasDetails() bool {
+	return e.Dir != ""
+}
+
+// NewSessionID returns a random identifier for a shell session.
+func NewSessionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x-%d", time.Now().UnixNano(), os.Getpid())
+	}
+	return hex.EncodeToString(b)
 }
 
 // History manages command history with navigation and search capabilities.
@@ -33,14 +54,26 @@ type History struct {
 	currentLine string // The line user was typing before navigating
 	mu          sync.RWMutex
 
-	// Search state
-	searchQuery   string
-	searchResults []int // Indices of matching entries
-	searchPos     int   // Position in search results
-
 	// Options
 	ignoreDuplicates  bool
 	ignoreSpacePrefix bool
+	shareHistory      bool
+
+	// Session of the shell, recorded in the entries it adds
+	hostname   string
+	sessionID  string
+	pending    bool // The last entry was added and its command has not completed
+	pendingNew bool // The pending entry is new, not a repeated last entry
+
+	// State of the history file, to append entries and read those of
+	// other sessions
+	unsaved   int            // Completed entries at the end not written to the file yet
+	rewrite   bool           // Entries were deleted or inserted: the file is replaced
+	deleted   []HistoryEntry // Entries deleted since the file was replaced
+	imported  []HistoryEntry // Entries imported since the file was replaced
+	fileInfo  os.FileInfo    // The file as last read, to notice it was replaced
+	fileSize  int64          // Bytes of the file read so far
+	fileLines int            // Records in the file
 }
 
 // New creates a new History with the specified maximum size.
@@ -69,11 +102,30 @@ func (h *History) SetIgnoreSpacePrefix(ignore bool) {
 	h.ignoreSpacePrefix = ignore
 }
 
-// Add adds a command to the history.
+// SetShareHistory sets whether the entries other sessions write to the
+// history file are merged into the history by Sync and Flush.
+func (h *History) SetShareHistory(share bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shareHistory = share
+}
+
+// SetSession sets the hostname and session id recorded in added entries.
+func (h *History) SetSession(hostname, sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hostname = hostname
+	h.sessionID = sessionID
+}
+
+// Add adds a command to the history. Its outcome is recorded by Complete
+// once it has run.
 func (h *History) Add(command string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	h.pending = false
+
 	// Skip empty commands
 	command = strings.TrimSpace(command)
 	if command == "" {
@@ -85,9 +137,12 @@ func (h *History) Add(command string) {
 		return
 	}
 
-	// Skip consecutive duplicates if configured
+	// Skip consecutive duplicates if configured; the last entry then
+	// records the outcome of the new run
 	if h.ignoreDuplicates && len(h.entries) > 0 {
-		if h.entries[len(h.entries)-1].Command == command {
+		if last := &h.entries[len(h.entries)-1]; last.Command == command {
+			last.Timestamp = time.Now()
+			h.pending, h.pendingNew = true, false
 			return
 		}
 	}
@@ -95,20 +150,44 @@ func (h *History) Add(command string) {
 	entry := HistoryEntry{
 		Command:   command,
 		Timestamp: time.Now(),
+		Hostname:  h.hostname,
+		SessionID: h.sessionID,
 	}
 
 	h.entries = append(h.entries, entry)
-
-	// Trim if exceeds max size
-	if len(h.entries) > h.maxSize {
-		h.entries = h.entries[len(h.entries)-h.maxSize:]
-	}
+	h.pending, h.pendingNew = true, true
+	h.trim()
 
 	// Reset navigation
 	h.navPosition = -1
 	h.currentLine = ""
 }
 
+// Complete records the outcome of the command added last: the directory
+// it ran in, its exit status and how long it ran. Does nothing if the
+// command was not added to the history.
+func (h *History) Complete(dir string, exitCode int, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.pending || len(h.entries) == 0 {
+		return
+	}
+	h.pending = false
+	// A repeated entry that was already written is written again with the
+	// new outcome
+	if h.pendingNew || h.unsaved == 0 {
+		h.unsaved = min(h.unsaved+1, len(h.entries))
+	}
+
+	last := &h.entries[len(h.entries)-1]
+	last.Dir = dir
+	last.ExitCode = exitCode
+	last.Duration = duration
+	last.Hostname = h.hostname
+	last.SessionID = h.sessionID
+}
+
 // Get returns the entry at the specified index.
 func (h *History) Get(index int) (HistoryEntry, error) {
 	h.mu.RLock()
@@ -161,6 +240,77 @@ func (h *History) Clear() {
 	h.entries = h.entries[:0]
 	h.navPosition = -1
 	h.currentLine = ""
+	h.pending = false
+	h.unsaved = 0
+	h.rewrite = false
+	h.deleted, h.imported = nil, nil
+}
+
+// Delete removes the entries numbered first to last, counted from 1 as
+// listed by the history command. The history file is rewritten by the next
+// Flush.
+func (h *History) Delete(first, last int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if first < 1 || last < first || last > len(h.entries) {
+		return ErrOutOfBounds
+	}
+	// Records of the deleted entries are dropped from the file, and
+	// deleted entries not written yet are not written
+	end := h.completed()
+	h.deleted = append(h.deleted, h.entries[first-1:min(last, end)]...)
+	h.unsaved -= max(0, min(last, end)-max(first-1, end-h.unsaved))
+	if last == len(h.entries) {
+		// The command that is running, if any, is deleted too
+		h.pending = false
+	}
+	h.entries = append(h.entries[:first-1], h.entries[last:]...)
+	h.unsaved = min(h.unsaved, h.completed())
+	h.navPosition = -1
+	h.rewrite = true
+	return nil
+}
+
+// Import adds commands of another shell before the entries of the history,
+// as older ones, and returns how many were kept: the oldest entries beyond
+// the maximum size are dropped. The history file is rewritten by the next
+// Flush.
+func (h *History) Import(entries []HistoryEntry) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	imported := h.appendEntries(nil, entries)
+	if len(imported) == 0 {
+		return 0
+	}
+	kept := len(imported) - max(0, len(imported)+len(h.entries)-h.maxSize)
+	if kept > 0 {
+		h.imported = append(slices.Clone(imported[len(imported)-kept:]), h.imported...)
+	}
+	h.entries = append(imported, h.entries...)
+	h.trim()
+	h.navPosition = -1
+	h.rewrite = true
+	return max(0, kept)
+}
+
+// trim removes the oldest entries beyond the maximum size. The caller must
+// hold the lock.
+func (h *History) trim() {
+	if len(h.entries) > h.maxSize {
+		h.entries = h.entries[len(h.entries)-h.maxSize:]
+	}
+	h.unsaved = min(h.unsaved, h.completed())
+}
+
+// completed returns the number of entries whose command has completed,
+// which excludes a new entry still running. The caller must hold the lock.
+func (h *History) completed() int {
+	if h.pending && h.pendingNew {
+		return len(h.entries) - 1
+	}
+	return len(h.entries)
 }
 
 // Navigation methods
@@ -268,165 +418,3 @@ func (h *History) SearchPrefix(prefix string) []HistoryEntry {
 
 	return results
 }
-
-// StartSearch initiates an interactive search with the given query.
-func (h *History) StartSearch(query string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	h.searchQuery = strings.ToLower(query)
-	h.searchResults = nil
-	h.searchPos = -1
-
-	// Build list of matching indices (in reverse order for most recent first)
-	for i := len(h.entries) - 1; i >= 0; i-- {
-		if h.searchQuery == "" || strings.Contains(strings.ToLower(h.entries[i].Command), h.searchQuery) {
-			h.searchResults = append(h.searchResults, i)
-		}
-	}
-}
-
-// PreviousMatch returns the previous matching command during search.
-func (h *History) PreviousMatch() (string, bool) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if len(h.searchResults) == 0 {
-		return "", false
-	}
-
-	if h.searchPos < len(h.searchResults)-1 {
-		h.searchPos++
-		idx := h.searchResults[h.searchPos]
-		return h.entries[idx].Command, true
-	}
-
-	return "", false
-}
-
-// NextMatch returns the next matching command during search.
-func (h *History) NextMatch() (string, bool) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if len(h.searchResults) == 0 || h.searchPos <= 0 {
-		return "", false
-	}
-
-	h.searchPos--
-	idx := h.searchResults[h.searchPos]
-	return h.entries[idx].Command, true
-}
-
-// EndSearch ends the interactive search.
-func (h *History) EndSearch() {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	h.searchQuery = ""
-	h.searchResults = nil
-	h.searchPos = -1
-}
-
-// Persistence methods
-
-// Save saves the history to a file.
-func (h *History) Save(path string) error {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("creating history directory: %w", err)
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("creating history file: %w", err)
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-
-	// Write entries in format: timestamp:command
-	for _, entry := range h.entries {
-		ts := entry.Timestamp.Unix()
-		// Escape newlines in command
-		cmd := strings.ReplaceAll(entry.Command, "\n", "\\n")
-		_, err := fmt.Fprintf(writer, "%d:%s\n", ts, cmd)
-		if err != nil {
-			return fmt.Errorf("writing history entry: %w", err)
-		}
-	}
-
-	if err := writer.Flush(); err != nil {
-		return fmt.Errorf("flushing history file: %w", err)
-	}
-
-	return nil
-}
-
-// Load loads history from a file.
-func (h *History) Load(path string) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	file, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // No history file yet
-		}
-		return fmt.Errorf("opening history file: %w", err)
-	}
-	defer file.Close()
-
-	h.entries = h.entries[:0] // Clear existing entries
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
-		// Parse format: timestamp:command
-		colonIdx := strings.Index(line, ":")
-		if colonIdx == -1 {
-			// Legacy format without timestamp - just the command
-			h.entries = append(h.entries, HistoryEntry{
-				Command:   line,
-				Timestamp: time.Now(),
-			})
-			continue
-		}
-
-		tsStr := line[:colonIdx]
-		cmd := line[colonIdx+1:]
-
-		// Unescape newlines
-		cmd = strings.ReplaceAll(cmd, "\\n", "\n")
-
-		ts, err := strconv.ParseInt(tsStr, 10, 64)
-		if err != nil {
-			// Invalid timestamp, use current time
-			ts = time.Now().Unix()
-		}
-
-		h.entries = append(h.entries, HistoryEntry{
-			Command:   cmd,
-			Timestamp: time.Unix(ts, 0),
-		})
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("reading history file: %w", err)
-	}
-
-	// Trim to max size if necessary
-	if len(h.entries) > h.maxSize {
-		h.entries = h.entries[len(h.entries)-h.maxSize:]
-	}
-
-	return nil
-}
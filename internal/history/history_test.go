@@ -278,49 +278,6 @@ func TestHistorySearchPrefix(t *testing.T) {
 	}
 }
 
-func TestHistorySearchNavigate(t *testing.T) {
-	h := New(100)
-	h.Add("ls -la")
-	h.Add("cd /home")
-	h.Add("ls Documents")
-	h.Add("cat file.txt")
-	h.Add("ls -l")
-
-	// Start search
-	h.StartSearch("ls")
-
-	// Navigate through search results
-	cmd, ok := h.PreviousMatch()
-	if !ok || cmd != "ls -l" {
-		t.Errorf("PreviousMatch() = %q, %v, want %q, true", cmd, ok, "ls -l")
-	}
-
-	cmd, ok = h.PreviousMatch()
-	if !ok || cmd != "ls Documents" {
-		t.Errorf("PreviousMatch() = %q, %v, want %q, true", cmd, ok, "ls Documents")
-	}
-
-	cmd, ok = h.PreviousMatch()
-	if !ok || cmd != "ls -la" {
-		t.Errorf("PreviousMatch() = %q, %v, want %q, true", cmd, ok, "ls -la")
-	}
-
-	// No more matches
-	cmd, ok = h.PreviousMatch()
-	if ok {
-		t.Errorf("PreviousMatch() should return false at end, got %q", cmd)
-	}
-
-	// Go forward
-	cmd, ok = h.NextMatch()
-	if !ok || cmd != "ls Documents" {
-		t.Errorf("NextMatch() = %q, %v, want %q, true", cmd, ok, "ls Documents")
-	}
-
-	// End search
-	h.EndSearch()
-}
-
 func TestHistoryClear(t *testing.T) {
 	h := New(100)
 	h.Add("cmd1")
@@ -354,3 +311,29 @@ func TestHistoryLast(t *testing.T) {
 		t.Errorf("Last().Command = %q, want %q", entry.Command, "cmd3")
 	}
 }
+
+func TestHistoryComplete(t *testing.T) {
+	h := New(100)
+	h.SetIgnoreDuplicates(true)
+	h.SetSession("host", "id")
+
+	h.Add("make")
+	h.Complete("/src", 2, time.Second)
+	if e, _ := h.Last(); e.Dir != "/src" || e.ExitCode != 2 || e.Duration != time.Second || e.Hostname != "host" || e.SessionID != "id" {
+		t.Errorf("Last() = %+v", e)
+	}
+
+	// A duplicate records the outcome of the new run
+	h.Add("make")
+	h.Complete("/src", 0, 2*time.Second)
+	if e, _ := h.Last(); h.Len() != 1 || e.ExitCode != 0 || e.Duration != 2*time.Second {
+		t.Errorf("after duplicate: Len() = %d, Last() = %+v", h.Len(), e)
+	}
+
+	// A command left out of the history does not change the last entry
+	h.Add("")
+	h.Complete("/tmp", 1, 0)
+	if e, _ := h.Last(); e.Dir != "/src" || e.ExitCode != 0 {
+		t.Errorf("after skipped command: Last() = %+v", e)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package history
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lock takes an advisory lock on a file, waiting until it is available.
+func lock(f *os.File, exclusive bool) error {
+	how := unix.LOCK_SH
+	if exclusive {
+		how = unix.LOCK_EX
+	}
+	for {
+		if err := unix.Flock(int(f.Fd()), how); err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// unlock releases the lock on a file.
+func unlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
@@ -1,8 +1,12 @@
 package history
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -311,3 +315,304 @@ func TestHistoryFileFormat(t *testing.T) {
 		t.Error("File is empty")
 	}
 }
+
+func TestHistoryDetailsRoundTrip(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "details_history")
+
+	h := New(100)
+	h.SetSession("host1", "session1")
+	h.Add("make test")
+	h.Complete("/src/app", 2, 1500*time.Millisecond)
+	h.Add(`echo "a
+b"`)
+	h.Complete("/src", 0, 0)
+	if err := h.Save(histFile); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	h2 := New(100)
+	if err := h2.Load(histFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	entries := h2.All()
+	if len(entries) != 2 {
+		t.Fatalf("Len() = %d, want 2", len(entries))
+	}
+	got := entries[0]
+	if got.Command != "make test" || got.Dir != "/src/app" || got.ExitCode != 2 ||
+		got.Duration != 1500*time.Millisecond || got.Hostname != "host1" || got.SessionID != "session1" {
+		t.Errorf("entry = %+v", got)
+	}
+	if !got.Timestamp.Equal(h.All()[0].Timestamp) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, h.All()[0].Timestamp)
+	}
+	if entries[1].Command != "echo \"a\nb\"" {
+		t.Errorf("multi-line command = %q", entries[1].Command)
+	}
+}
+
+func TestHistoryLoadLegacyFormat(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "legacy_history")
+	content := "1700000000:ls -la\n1700000060:echo a\\nb\n{ echo braces; }\n" +
+		`{"v":1,"time":"2026-01-02T03:04:05Z","cmd":"pwd","cwd":"/tmp","exit":1,"duration_ms":20}` + "\n"
+	if err := os.WriteFile(histFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(100)
+	if err := h.Load(histFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	entries := h.All()
+	if len(entries) != 4 {
+		t.Fatalf("Len() = %d, want 4", len(entries))
+	}
+	if entries[0].Command != "ls -la" || entries[0].Timestamp.Unix() != 1700000000 || entries[0].HasDetails() {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if entries[1].Command != "echo a\nb" {
+		t.Errorf("entries[1].Command = %q", entries[1].Command)
+	}
+	if entries[2].Command != "{ echo braces; }" {
+		t.Errorf("entries[2].Command = %q", entries[2].Command)
+	}
+	if e := entries[3]; e.Command != "pwd" || e.Dir != "/tmp" || e.ExitCode != 1 || e.Duration != 20*time.Millisecond || !e.HasDetails() {
+		t.Errorf("entries[3] = %+v", e)
+	}
+}
+
+// runCommand adds a command and records its outcome, as the shell does.
+func runCommand(h *History, command string) {
+	h.Add(command)
+	h.Complete("/src", 0, time.Millisecond)
+}
+
+func TestHistoryFlush(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "flush_history")
+
+	// Sessions append their commands as they complete
+	a, b := New(100), New(100)
+	a.SetSession("host", "a")
+	b.SetSession("host", "b")
+	runCommand(a, "a1")
+	if err := a.Flush(histFile); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	runCommand(b, "b1")
+	b.Flush(histFile)
+	a.Add("a2") // Still running: not written
+	a.Flush(histFile)
+
+	h := New(100)
+	if err := h.Load(histFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got := commands(h); got != "a1 b1" {
+		t.Errorf("file commands = %q, want %q", got, "a1 b1")
+	}
+
+	a.Complete("/src", 1, 0)
+	a.Flush(histFile)
+	h.Load(histFile)
+	if got := commands(h); got != "a1 b1 a2" {
+		t.Errorf("file commands = %q, want %q", got, "a1 b1 a2")
+	}
+	// Without share_history, sessions keep their own commands
+	if got := commands(a); got != "a1 a2" {
+		t.Errorf("session commands = %q, want %q", got, "a1 a2")
+	}
+}
+
+func TestHistoryShare(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "share_history")
+
+	a, b := New(100), New(100)
+	a.SetSession("host", "a")
+	a.SetShareHistory(true)
+	b.SetSession("host", "b")
+
+	runCommand(a, "a1")
+	a.Flush(histFile)
+	runCommand(b, "b1")
+	runCommand(b, "b2")
+	b.Flush(histFile)
+
+	if err := a.Sync(histFile); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	if got := commands(a); got != "a1 b1 b2" {
+		t.Errorf("after Sync: %q, want %q", got, "a1 b1 b2")
+	}
+
+	// Commands of other sessions written meanwhile come before the new one
+	runCommand(b, "b3")
+	b.Flush(histFile)
+	runCommand(a, "a2")
+	a.Flush(histFile)
+	if got := commands(a); got != "a1 b1 b2 b3 a2" {
+		t.Errorf("after Flush: %q, want %q", got, "a1 b1 b2 b3 a2")
+	}
+
+	// A session replacing the file rebuilds the history from it
+	b.Clear()
+	runCommand(b, "b4")
+	b.Save(histFile)
+	a.Sync(histFile)
+	if got := commands(a); got != "b4" {
+		t.Errorf("after replaced file: %q, want %q", got, "b4")
+	}
+}
+
+func TestHistoryCompact(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "compact_history")
+
+	h := New(4)
+	h.SetSession("host", "a")
+	for _, cmd := range []string{"c1", "c2", "c3", "c4", "c5", "c6", "c7"} {
+		runCommand(h, cmd)
+		if err := h.Flush(histFile); err != nil {
+			t.Fatalf("Flush() error: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines > compactThreshold(4) {
+		t.Errorf("file has %d records, want at most %d", lines, compactThreshold(4))
+	}
+	h2 := New(4)
+	h2.Load(histFile)
+	if got := commands(h2); got != "c4 c5 c6 c7" {
+		t.Errorf("loaded %q, want %q", got, "c4 c5 c6 c7")
+	}
+}
+
+func TestHistoryConcurrentFlush(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "concurrent_history")
+
+	const sessions, count = 8, 25
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h := New(1000)
+			h.SetSession("host", fmt.Sprint(i))
+			for j := range count {
+				runCommand(h, fmt.Sprintf("cmd %d %d", i, j))
+				if err := h.Flush(histFile); err != nil {
+					t.Errorf("Flush() error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	h := New(1000)
+	if err := h.Load(histFile); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if h.Len() != sessions*count {
+		t.Errorf("Len() = %d, want %d", h.Len(), sessions*count)
+	}
+	for _, entry := range h.All() {
+		if !entry.HasDetails() {
+			t.Errorf("entry %q was not read as a record", entry.Command)
+		}
+	}
+}
+
+func TestHistoryDeleteImport(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "rewrite_history")
+
+	h := New(5)
+	h.SetSession("host", "a")
+	for _, cmd := range []string{"c1", "c2", "c3", "c4"} {
+		runCommand(h, cmd)
+		h.Flush(histFile)
+	}
+
+	for _, r := range [][2]int{{0, 1}, {3, 2}, {2, 5}} {
+		if err := h.Delete(r[0], r[1]); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("Delete(%d, %d) error = %v, want ErrOutOfBounds", r[0], r[1], err)
+		}
+	}
+
+	// The running command is written once, when it completes
+	h.Add("history delete 2-3")
+	if err := h.Delete(2, 3); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	h.Complete("/src", 0, 0)
+	if err := h.Flush(histFile); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	loaded := New(5)
+	loaded.Load(histFile)
+	if got, want := commands(loaded), "c1 c4 history delete 2-3"; got != want {
+		t.Errorf("after Delete: %q, want %q", got, want)
+	}
+
+	// Imported commands come first, the oldest dropped beyond the maximum
+	h.Add("history import")
+	n := h.Import([]HistoryEntry{{Command: "old1"}, {Command: "old2"}, {Command: "old3"}})
+	if n != 1 {
+		t.Errorf("Import() = %d, want 1", n)
+	}
+	h.Complete("/src", 0, 0)
+	h.Flush(histFile)
+	loaded.Load(histFile)
+	if got, want := commands(loaded), "old3 c1 c4 history delete 2-3 history import"; got != want {
+		t.Errorf("after Import: %q, want %q", got, want)
+	}
+}
+
+// commands returns the commands of the history separated by spaces.
+func commands(h *History) string {
+	var cmds []string
+	for _, entry := range h.All() {
+		cmds = append(cmds, entry.Command)
+	}
+	return strings.Join(cmds, " ")
+}
+
+func TestHistoryRewriteKeepsOtherSessions(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "rewrite_sessions_history")
+
+	// Without share_history, a session only has its own commands
+	a, b := New(100), New(100)
+	a.SetSession("host", "a")
+	b.SetSession("host", "b")
+	runCommand(a, "a1")
+	a.Flush(histFile)
+	runCommand(b, "b1")
+	b.Flush(histFile)
+	runCommand(a, "a2")
+	a.Flush(histFile)
+
+	if err := a.Delete(1, 1); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := a.Flush(histFile); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	loaded := New(100)
+	loaded.Load(histFile)
+	if got, want := commands(loaded), "b1 a2"; got != want {
+		t.Errorf("after Delete: %q, want %q", got, want)
+	}
+
+	runCommand(b, "b2")
+	b.Flush(histFile)
+	a.Import([]HistoryEntry{{Command: "old"}})
+	runCommand(a, "a3")
+	if err := a.Flush(histFile); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+	loaded.Load(histFile)
+	if got, want := commands(loaded), "old b1 a2 b2 a3"; got != want {
+		t.Errorf("after Import: %q, want %q", got, want)
+	}
+}
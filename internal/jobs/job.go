@@ -0,0 +1,337 @@
+// Package jobs tracks the jobs of the shell: commands running in the
+// background, and commands stopped with Ctrl+Z.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// State is the state of a job.
+type State int
+
+const (
+	Running State = iota // Commands of the job are running
+	Stopped              // A process of the job is stopped (e.g., by Ctrl+Z)
+	Done                 // All commands of the job have finished
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "Running"
+	case Stopped:
+		return "Stopped"
+	case Done:
+		return "Done"
+	default:
+		return "Unknown"
+	}
+}
+
+// Job is a command run by the shell as a unit. The external processes it
+// starts share a process group, so that they can be stopped, continued and
+// signaled together.
+type Job struct {
+	ID      int    // Job number, 0 until the job is added to a table
+	Command string // Source text of the command
+
+	table  *Table
+	cancel context.CancelFunc // Stops the commands run by the shell itself
+
+	startMu sync.Mutex // Serializes process starts, so that they join one group
+
+	mu         sync.Mutex
+	finished   bool
+	code       int
+	pgid       int          // Process group of the running processes, 0 if none
+	pid        int          // First process started by the job, 0 if none
+	procs      map[int]bool // Running processes, by pid; true if stopped
+	foreground bool         // Processes get the terminal
+	reported   bool         // Current state was reported to the user
+	seq        uint64       // Order of the job, for the current job
+	signaled   os.Signal    // Signal that ended the job, sent with Signal
+	changed    chan struct{}
+}
+
+// StartProcess starts an external command as a process of the job.
+// newCmd creates the command; it may be called again if the process cannot
+// join the group of the job because its other processes just finished.
+func (j *Job) StartProcess(newCmd func() *exec.Cmd) (*exec.Cmd, error) {
+	j.startMu.Lock()
+	defer j.startMu.Unlock()
+
+	j.mu.Lock()
+	pgid, foreground := j.pgid, j.foreground
+	j.mu.Unlock()
+
+	cmd := j.newProcess(newCmd, pgid, foreground)
+	err := cmd.Start()
+	if err != nil && pgid != 0 {
+		// The group vanished with its last process: start a new one
+		pgid = 0
+		cmd = j.newProcess(newCmd, pgid, foreground)
+		err = cmd.Start()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if pgid == 0 {
+		j.pgid = cmd.Process.Pid
+	}
+	if j.pid == 0 {
+		j.pid = cmd.Process.Pid
+	}
+	j.procs[cmd.Process.Pid] = false
+	j.notifyLocked()
+	return cmd, nil
+}
+
+// newProcess creates a command with newCmd to run in the process group pgid.
+// A process that was sent a signal with Signal handles it itself: it is not
+// killed as well when the commands of the job are canceled.
+func (j *Job) newProcess(newCmd func() *exec.Cmd, pgid int, foreground bool) *exec.Cmd {
+	cmd := newCmd()
+	setProcessGroup(cmd, pgid, foreground, j.table.terminal())
+	if kill := cmd.Cancel; kill != nil {
+		cmd.Cancel = func() error {
+			if j.Signaled() != nil {
+				return nil
+			}
+			return kill()
+		}
+	}
+	return cmd
+}
+
+// WaitProcess waits for a process started with StartProcess to finish,
+// following it as it is stopped and continued. Returns its exit status; a
+// process killed by a signal has status 128 + the signal number.
+func (j *Job) WaitProcess(cmd *exec.Cmd) (int, error) {
+	return waitProcess(j, cmd)
+}
+
+// Finish marks the job as done with the given exit status.
+func (j *Job) Finish(code int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = true
+	j.code = code
+	j.reported = false
+	j.notifyLocked()
+}
+
+// State returns the state of the job.
+func (j *Job) State() State {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.stateLocked()
+}
+
+func (j *Job) stateLocked() State {
+	if j.finished {
+		return Done
+	}
+	for _, stopped := range j.procs {
+		if stopped {
+			return Stopped
+		}
+	}
+	return Running
+}
+
+// ExitCode returns the exit status of a finished job.
+func (j *Job) ExitCode() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.code
+}
+
+// Pgid returns the process group of the job's processes, 0 if none is running.
+func (j *Job) Pgid() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pgid
+}
+
+// Pid returns the process ID of the first process started by the job,
+// even if it has finished, or 0 if the job has not started any.
+func (j *Job) Pid() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.pid
+}
+
+// HasProcess returns true if pid is a running process of the job.
+func (j *Job) HasProcess(pid int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.procs[pid]
+	return ok
+}
+
+// Changed returns a channel closed at the next change of state of the job.
+func (j *Job) Changed() <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.changed
+}
+
+// Wait blocks until the job is done or stopped, or until ctx is canceled.
+// Returns the state of the job.
+func (j *Job) Wait(ctx context.Context) State {
+	for {
+		ch := j.Changed()
+		if state := j.State(); state != Running {
+			return state
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return j.State()
+		}
+	}
+}
+
+// Continue resumes the stopped processes of the job.
+// In the foreground, the job gets the terminal.
+func (j *Job) Continue(foreground bool) error {
+	j.mu.Lock()
+	j.foreground = foreground
+	pgid := j.pgid
+	for pid := range j.procs {
+		j.procs[pid] = false
+	}
+	j.reported = false
+	j.notifyLocked()
+	j.mu.Unlock()
+
+	if pgid == 0 {
+		return nil
+	}
+	if foreground {
+		giveTerminal(j.table.terminal(), pgid)
+	}
+	return continueGroup(pgid)
+}
+
+// Signal sends a signal to the processes of the job. Signals that terminate
+// processes also stop the commands the shell runs itself, such as loops,
+// once the processes got the signal.
+func (j *Job) Signal(sig os.Signal) error {
+	j.mu.Lock()
+	pgid, state := j.pgid, j.stateLocked()
+	j.mu.Unlock()
+
+	var err error
+	if pgid != 0 {
+		err = signalGroup(pgid, sig)
+		// A stopped process only handles the signal once continued
+		if err == nil && state == Stopped && terminates(sig) {
+			err = continueGroup(pgid)
+		}
+	}
+
+	if terminates(sig) {
+		j.mu.Lock()
+		j.signaled = sig
+		j.mu.Unlock()
+		j.cancel()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", j.Command, err)
+	}
+	return nil
+}
+
+// Signaled returns the terminating signal sent to the job with Signal, or
+// nil if none was.
+func (j *Job) Signaled() os.Signal {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.signaled
+}
+
+// SignalStatus returns the exit status of a command ended by a signal:
+// 128 + the signal number.
+func SignalStatus(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// RunForeground waits for the job in the foreground, giving the terminal to
+// its processes. Canceling ctx (e.g., Ctrl+C while the shell runs a command
+// itself) interrupts the job. Returns the state of the job: Done, or Stopped
+// if it was suspended with Ctrl+Z.
+func (j *Job) RunForeground(ctx context.Context) State {
+	j.table.setForeground(j)
+	defer j.table.setForeground(nil)
+	defer takeTerminal(j.table.terminal())
+
+	for {
+		state := j.Wait(ctx)
+		if state != Running {
+			j.mu.Lock()
+			j.foreground = false
+			j.mu.Unlock()
+			return state
+		}
+		// Interrupted: stop the job and wait for it to finish
+		j.cancel()
+		ctx = context.Background()
+	}
+}
+
+// processStopped records that a process of the job was stopped or continued.
+func (j *Job) processStopped(pid int, stopped bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.procs[pid]; !ok {
+		return
+	}
+	j.procs[pid] = stopped
+	if stopped {
+		j.foreground = false
+		j.seq = j.table.nextSeq()
+	}
+	j.reported = false
+	j.notifyLocked()
+}
+
+// processExited records that a process of the job has finished.
+// A foreground process killed by Ctrl+C interrupts the whole job.
+func (j *Job) processExited(pid int, interrupted bool) {
+	j.mu.Lock()
+	delete(j.procs, pid)
+	last := len(j.procs) == 0
+	if last {
+		j.pgid = 0
+	}
+	foreground := j.foreground
+	j.notifyLocked()
+	j.mu.Unlock()
+
+	// The shell gets the terminal back until the next process starts
+	if last && foreground {
+		takeTerminal(j.table.terminal())
+	}
+	if interrupted && foreground {
+		j.cancel()
+	}
+}
+
+// notifyLocked wakes up the waiters for a change of state.
+func (j *Job) notifyLocked() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
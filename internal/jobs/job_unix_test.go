@@ -0,0 +1,96 @@
+//go:build unix
+
+package jobs
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startSleep starts "sleep 10" as a process of a new job.
+func startSleep(t *testing.T) (*Job, *exec.Cmd) {
+	t.Helper()
+	path, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+
+	table := NewTable()
+	j := table.NewJob("sleep 10", func() {}, false)
+	table.Add(j)
+	cmd, err := j.StartProcess(func() *exec.Cmd { return exec.Command(path, "10") })
+	if err != nil {
+		t.Fatalf("StartProcess error: %v", err)
+	}
+	return j, cmd
+}
+
+// waitState waits for the job to reach the given state.
+func waitState(t *testing.T, j *Job, want State) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for j.State() != want {
+		select {
+		case <-j.Changed():
+		case <-ctx.Done():
+			t.Fatalf("State() = %v, want %v", j.State(), want)
+		}
+	}
+}
+
+func TestJobProcessGroup(t *testing.T) {
+	j, cmd := startSleep(t)
+	defer cmd.Process.Kill()
+
+	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	if err != nil {
+		t.Fatalf("Getpgid error: %v", err)
+	}
+	if pgid != cmd.Process.Pid || j.Pgid() != pgid {
+		t.Errorf("process group = %d, job pgid = %d, want %d", pgid, j.Pgid(), cmd.Process.Pid)
+	}
+	if !j.HasProcess(cmd.Process.Pid) {
+		t.Error("HasProcess should report the started process")
+	}
+}
+
+func TestJobStopContinueKill(t *testing.T) {
+	j, cmd := startSleep(t)
+
+	result := make(chan int, 1)
+	go func() {
+		code, _ := j.WaitProcess(cmd)
+		result <- code
+	}()
+
+	if err := j.Signal(syscall.SIGSTOP); err != nil {
+		t.Fatalf("Signal(STOP) error: %v", err)
+	}
+	waitState(t, j, Stopped)
+
+	if err := j.Continue(false); err != nil {
+		t.Fatalf("Continue error: %v", err)
+	}
+	if j.State() != Running {
+		t.Errorf("State() = %v after Continue, want Running", j.State())
+	}
+
+	if err := j.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Signal(TERM) error: %v", err)
+	}
+	select {
+	case code := <-result:
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("exit status = %d, want %d", code, 128+int(syscall.SIGTERM))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("process did not exit")
+	}
+	if j.Pgid() != 0 || j.HasProcess(cmd.Process.Pid) {
+		t.Error("finished process should be removed from the job")
+	}
+}
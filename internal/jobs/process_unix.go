@@ -0,0 +1,105 @@
+//go:build unix
+
+package jobs
+
+import (
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup makes the process join the process group pgid, or start
+// a new one if pgid is 0. A foreground process gets the terminal tty.
+func setProcessGroup(cmd *exec.Cmd, pgid int, foreground bool, tty int) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Pgid:       pgid,
+		Foreground: foreground && tty >= 0,
+		Ctty:       tty,
+	}
+}
+
+// waitProcess waits for a process of the job to finish, recording when it
+// is stopped and continued.
+func waitProcess(j *Job, cmd *exec.Cmd) (int, error) {
+	pid := cmd.Process.Pid
+	for {
+		var status syscall.WaitStatus
+		_, err := syscall.Wait4(pid, &status, syscall.WUNTRACED|syscall.WCONTINUED, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			j.processExited(pid, false)
+			return exitStatus(cmd.Wait())
+		}
+
+		switch {
+		case status.Stopped():
+			j.processStopped(pid, true)
+			continue
+		case status.Continued():
+			j.processStopped(pid, false)
+			continue
+		}
+
+		j.processExited(pid, status.Signaled() && status.Signal() == syscall.SIGINT)
+
+		// The process is reaped; Wait still copies its remaining output
+		cmd.Wait()
+
+		if status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return status.ExitStatus(), nil
+	}
+}
+
+// exitStatus returns the exit status reported by exec.Cmd.Wait.
+func exitStatus(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 1, err
+}
+
+// ownsTerminal returns true if the shell is in the foreground of the terminal.
+func ownsTerminal(tty int) bool {
+	pgid, err := unix.IoctlGetInt(tty, unix.TIOCGPGRP)
+	return err == nil && pgid == unix.Getpgrp()
+}
+
+// giveTerminal puts the process group pgid in the foreground of the terminal.
+func giveTerminal(tty, pgid int) {
+	if tty >= 0 {
+		unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, pgid)
+	}
+}
+
+// takeTerminal puts the shell back in the foreground of the terminal.
+func takeTerminal(tty int) {
+	if tty < 0 {
+		return
+	}
+	// The shell is in the background until then: changing the foreground
+	// group would stop it with SIGTTOU
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, unix.Getpgrp())
+}
+
+// continueGroup resumes the stopped processes of a process group.
+func continueGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGCONT)
+}
+
+// StoppedStatus is the exit status of a command stopped with Ctrl+Z.
+var StoppedStatus = 128 + int(syscall.SIGTSTP)
@@ -0,0 +1,42 @@
+//go:build windows
+
+package jobs
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing: Windows has no process groups to join.
+func setProcessGroup(cmd *exec.Cmd, pgid int, foreground bool, tty int) {}
+
+// waitProcess waits for a process of the job to finish.
+// Processes cannot be stopped on Windows.
+func waitProcess(j *Job, cmd *exec.Cmd) (int, error) {
+	err := cmd.Wait()
+	j.processExited(cmd.Process.Pid, false)
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// ownsTerminal returns false: job control is not available on Windows.
+func ownsTerminal(tty int) bool {
+	return false
+}
+
+func giveTerminal(tty, pgid int) {}
+
+func takeTerminal(tty int) {}
+
+// continueGroup does nothing: processes cannot be stopped on Windows.
+func continueGroup(pgid int) error {
+	return nil
+}
+
+// StoppedStatus is the exit status of a stopped command, as on Unix.
+// Processes cannot be stopped on Windows.
+var StoppedStatus = 148
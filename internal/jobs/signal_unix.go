@@ -0,0 +1,83 @@
+//go:build unix
+
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signals are the signals known by name, in the order of SignalNames.
+var signals = []struct {
+	name string
+	sig  syscall.Signal
+}{
+	{"HUP", syscall.SIGHUP},
+	{"INT", syscall.SIGINT},
+	{"QUIT", syscall.SIGQUIT},
+	{"KILL", syscall.SIGKILL},
+	{"USR1", syscall.SIGUSR1},
+	{"USR2", syscall.SIGUSR2},
+	{"PIPE", syscall.SIGPIPE},
+	{"ALRM", syscall.SIGALRM},
+	{"TERM", syscall.SIGTERM},
+	{"CHLD", syscall.SIGCHLD},
+	{"CONT", syscall.SIGCONT},
+	{"STOP", syscall.SIGSTOP},
+	{"TSTP", syscall.SIGTSTP},
+	{"TTIN", syscall.SIGTTIN},
+	{"TTOU", syscall.SIGTTOU},
+	{"WINCH", syscall.SIGWINCH},
+}
+
+// ParseSignal returns the signal with the given name or number.
+// Names are case-insensitive and may start with SIG (TERM, SIGTERM, 15).
+func ParseSignal(name string) (os.Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	upper := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	for _, s := range signals {
+		if s.name == upper {
+			return s.sig, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: invalid signal specification", name)
+}
+
+// SignalNames returns the names of the signals known by ParseSignal.
+func SignalNames() []string {
+	names := make([]string, len(signals))
+	for i, s := range signals {
+		names[i] = s.name
+	}
+	return names
+}
+
+// Kill sends a signal to a process.
+func Kill(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("%v: unsupported signal", sig)
+	}
+	return syscall.Kill(pid, s)
+}
+
+// signalGroup sends a signal to a process group.
+func signalGroup(pgid int, sig os.Signal) error {
+	return Kill(-pgid, sig)
+}
+
+// terminates returns true if the default action of the signal ends processes.
+func terminates(sig os.Signal) bool {
+	switch sig {
+	case syscall.SIGCONT, syscall.SIGSTOP, syscall.SIGTSTP, syscall.SIGTTIN,
+		syscall.SIGTTOU, syscall.SIGCHLD, syscall.SIGWINCH, syscall.SIGURG:
+		return false
+	default:
+		return true
+	}
+}
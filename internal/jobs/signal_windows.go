@@ -0,0 +1,43 @@
+//go:build windows
+
+package jobs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseSignal returns the signal with the given name or number.
+// Only KILL and TERM are supported on Windows, and both kill the process.
+func ParseSignal(name string) (os.Signal, error) {
+	switch strings.TrimPrefix(strings.ToUpper(name), "SIG") {
+	case "KILL", "9", "TERM", "15":
+		return os.Kill, nil
+	}
+	return nil, fmt.Errorf("%s: invalid signal specification", name)
+}
+
+// SignalNames returns the names of the signals known by ParseSignal.
+func SignalNames() []string {
+	return []string{"KILL", "TERM"}
+}
+
+// Kill sends a signal to a process.
+func Kill(pid int, sig os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
+
+// signalGroup sends a signal to the first process of a job.
+func signalGroup(pgid int, sig os.Signal) error {
+	return Kill(pgid, sig)
+}
+
+// terminates returns true: the only supported signals kill processes.
+func terminates(sig os.Signal) bool {
+	return true
+}
@@ -0,0 +1,243 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+)
+
+// Table holds the jobs of the shell that run in the background or are stopped.
+type Table struct {
+	mu         sync.Mutex
+	jobs       []*Job // By job number
+	seq        uint64
+	tty        int  // Terminal given to foreground jobs, -1 without job control
+	foreground *Job // Job running in the foreground, if any
+}
+
+// NewTable creates an empty job table, without job control.
+func NewTable() *Table {
+	return &Table{tty: -1}
+}
+
+// SetTerminal enables job control on the terminal with the given file
+// descriptor: foreground jobs get the terminal, so that Ctrl+C and Ctrl+Z
+// reach their processes rather than the shell. It has no effect if the
+// shell is not in the foreground of the terminal.
+func (t *Table) SetTerminal(fd int) {
+	if fd >= 0 && !ownsTerminal(fd) {
+		fd = -1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tty = fd
+}
+
+// JobControl returns true if job control is enabled.
+func (t *Table) JobControl() bool {
+	return t.terminal() >= 0
+}
+
+func (t *Table) terminal() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tty
+}
+
+// NewJob creates a job for the given command, not yet added to the table.
+// cancel stops the commands of the job that the shell runs itself.
+// Processes of a foreground job get the terminal.
+func (t *Table) NewJob(command string, cancel context.CancelFunc, foreground bool) *Job {
+	return &Job{
+		Command:    command,
+		table:      t,
+		cancel:     cancel,
+		procs:      make(map[int]bool),
+		foreground: foreground,
+		seq:        t.nextSeq(),
+		changed:    make(chan struct{}),
+	}
+}
+
+// Add adds a job to the table and gives it the next job number.
+// The job becomes the current job.
+func (t *Table) Add(j *Job) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	j.ID = 1
+	if n := len(t.jobs); n > 0 {
+		j.ID = t.jobs[n-1].ID + 1
+	}
+	t.jobs = append(t.jobs, j)
+
+	t.seq++
+	j.mu.Lock()
+	j.seq = t.seq
+	j.mu.Unlock()
+	return j.ID
+}
+
+// Remove removes a job from the table.
+func (t *Table) Remove(j *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, job := range t.jobs {
+		if job == j {
+			t.jobs = append(t.jobs[:i], t.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Jobs returns the jobs of the table, by job number.
+func (t *Table) Jobs() []*Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Job(nil), t.jobs...)
+}
+
+// Foreground returns the job running in the foreground, or nil.
+func (t *Table) Foreground() *Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.foreground
+}
+
+func (t *Table) setForeground(j *Job) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.foreground = j
+}
+
+func (t *Table) nextSeq() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	return t.seq
+}
+
+// ranked returns the jobs from the most to the least recent: stopped jobs
+// first, by time they were stopped, then the others by time they started.
+// The first is the current job (%+), the second the previous job (%-).
+func (t *Table) ranked() []*Job {
+	jobs := t.Jobs()
+	type rank struct {
+		stopped bool
+		seq     uint64
+	}
+	ranks := make(map[*Job]rank, len(jobs))
+	for _, j := range jobs {
+		j.mu.Lock()
+		ranks[j] = rank{j.stateLocked() == Stopped, j.seq}
+		j.mu.Unlock()
+	}
+	sort.SliceStable(jobs, func(a, b int) bool {
+		ra, rb := ranks[jobs[a]], ranks[jobs[b]]
+		if ra.stopped != rb.stopped {
+			return ra.stopped
+		}
+		return ra.seq > rb.seq
+	})
+	return jobs
+}
+
+// Find returns the job designated by a job specification: %n or n for job
+// number n, %+, %% or an empty spec for the current job, %- for the previous
+// job, and %prefix for the job whose command starts with prefix.
+func (t *Table) Find(spec string) (*Job, error) {
+	ranked := t.ranked()
+	name := strings.TrimPrefix(spec, "%")
+	if spec == "" {
+		spec = "%+"
+	}
+
+	switch name {
+	case "", "+", "%":
+		if len(ranked) > 0 {
+			return ranked[0], nil
+		}
+	case "-":
+		if len(ranked) > 1 {
+			return ranked[1], nil
+		}
+	default:
+		if id, err := strconv.Atoi(name); err == nil {
+			for _, j := range ranked {
+				if j.ID == id {
+					return j, nil
+				}
+			}
+			break
+		}
+		if !strings.HasPrefix(spec, "%") {
+			break
+		}
+		var found *Job
+		for _, j := range ranked {
+			if strings.HasPrefix(j.Command, name) {
+				if found != nil {
+					return nil, fmt.Errorf("%w: %s", errors.ErrAmbiguousJob, spec)
+				}
+				found = j
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errors.ErrNoSuchJob, spec)
+}
+
+// Format returns the line describing a job in the jobs list and in
+// notifications, such as "[1]+  Running                 make &".
+func (t *Table) Format(j *Job) string {
+	marker := ' '
+	if ranked := t.ranked(); len(ranked) > 0 && ranked[0] == j {
+		marker = '+'
+	} else if len(ranked) > 1 && ranked[1] == j {
+		marker = '-'
+	}
+
+	status := j.State().String()
+	command := j.Command
+	switch j.State() {
+	case Running:
+		command += " &"
+	case Done:
+		if sig := j.Signaled(); sig != nil && j.ExitCode() == SignalStatus(sig) {
+			// Like strsignal: "Terminated", "Killed", "Hangup"
+			name := sig.String()
+			status = strings.ToUpper(name[:1]) + name[1:]
+		} else if code := j.ExitCode(); code != 0 {
+			status = fmt.Sprintf("Exit %d", code)
+		}
+	}
+	return fmt.Sprintf("[%d]%c  %-24s%s", j.ID, marker, status, command)
+}
+
+// Notifications returns the lines reporting the jobs that finished or were
+// stopped since the last call. Finished jobs are removed from the table.
+func (t *Table) Notifications() []string {
+	var lines []string
+	for _, j := range t.Jobs() {
+		j.mu.Lock()
+		state, reported := j.stateLocked(), j.reported
+		j.reported = true
+		j.mu.Unlock()
+
+		if reported || state == Running {
+			continue
+		}
+		lines = append(lines, t.Format(j))
+		if state == Done {
+			t.Remove(j)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+// addJob adds a job running the given command to the table.
+func addJob(t *Table, command string) *Job {
+	j := t.NewJob(command, func() {}, false)
+	t.Add(j)
+	return j
+}
+
+func TestTableAdd(t *testing.T) {
+	table := NewTable()
+	a := addJob(table, "make")
+	b := addJob(table, "make test")
+	if a.ID != 1 || b.ID != 2 {
+		t.Errorf("IDs = %d, %d, want 1, 2", a.ID, b.ID)
+	}
+
+	table.Remove(a)
+	if c := addJob(table, "sleep 1"); c.ID != 3 {
+		t.Errorf("ID = %d, want 3", c.ID)
+	}
+	table.Remove(b)
+	if jobs := table.Jobs(); len(jobs) != 1 || jobs[0].Command != "sleep 1" {
+		t.Errorf("Jobs() = %v", jobs)
+	}
+}
+
+func TestTableFind(t *testing.T) {
+	table := NewTable()
+	makeJob := addJob(table, "make all")
+	vim := addJob(table, "vim notes")
+	sleep := addJob(table, "sleep 10")
+
+	tests := []struct {
+		spec string
+		want *Job
+		err  error
+	}{
+		{"", sleep, nil},
+		{"%+", sleep, nil},
+		{"%%", sleep, nil},
+		{"%-", vim, nil},
+		{"%1", makeJob, nil},
+		{"2", vim, nil},
+		{"%vim", vim, nil},
+		{"%m", makeJob, nil},
+		{"%7", nil, shellerrors.ErrNoSuchJob},
+		{"%emacs", nil, shellerrors.ErrNoSuchJob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := table.Find(tt.spec)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Find(%q) error = %v, want %v", tt.spec, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Find(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+
+	addJob(table, "vim todo")
+	if _, err := table.Find("%vim"); !errors.Is(err, shellerrors.ErrAmbiguousJob) {
+		t.Errorf("Find(%%vim) error = %v, want ErrAmbiguousJob", err)
+	}
+}
+
+func TestTableCurrentJobIsStopped(t *testing.T) {
+	table := NewTable()
+	stopped := addJob(table, "vim")
+	addJob(table, "make")
+
+	// A stopped job becomes the current job
+	stopped.procs[100] = true
+	if got, _ := table.Find("%+"); got != stopped {
+		t.Errorf("current job = %v, want the stopped job", got.Command)
+	}
+}
+
+func TestTableFormatAndNotifications(t *testing.T) {
+	table := NewTable()
+	ok := addJob(table, "true")
+	failed := addJob(table, "false")
+	running := addJob(table, "sleep 10")
+
+	if line := table.Format(running); line != "[3]+  Running                 sleep 10 &" {
+		t.Errorf("Format(running) = %q", line)
+	}
+
+	ok.Finish(0)
+	failed.Finish(1)
+	lines := table.Notifications()
+	want := []string{
+		"[1]   Done                    true",
+		"[2]-  Exit 1                  false",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Notifications() = %q, want %q", lines, want)
+	}
+
+	// Finished jobs are reported once and removed
+	if lines := table.Notifications(); len(lines) != 0 {
+		t.Errorf("second Notifications() = %q, want none", lines)
+	}
+	if jobs := table.Jobs(); len(jobs) != 1 || jobs[0] != running {
+		t.Errorf("Jobs() = %v, want only the running job", jobs)
+	}
+}
+
+func TestJobWait(t *testing.T) {
+	table := NewTable()
+	j := addJob(table, "true")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if state := j.Wait(ctx); state != Running {
+		t.Errorf("Wait with canceled context = %v, want Running", state)
+	}
+
+	go j.Finish(3)
+	if state := j.Wait(context.Background()); state != Done || j.ExitCode() != 3 {
+		t.Errorf("Wait = %v with status %d, want Done with 3", state, j.ExitCode())
+	}
+}
+
+func TestJobSignalCancelsCommands(t *testing.T) {
+	table := NewTable()
+	ctx, cancel := context.WithCancel(context.Background())
+	j := table.NewJob("while true; do true; done", cancel, false)
+
+	sig, err := ParseSignal("TERM")
+	if err != nil {
+		t.Fatalf("ParseSignal error: %v", err)
+	}
+	if err := j.Signal(sig); err != nil {
+		t.Fatalf("Signal error: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("terminating a job should cancel its commands")
+	}
+
+	// The job is reported as ended by the signal
+	j.Finish(SignalStatus(sig))
+	if line := table.Format(j); !strings.Contains(line, "Terminated") {
+		t.Errorf("Format() = %q, want Terminated", line)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"TERM", "sigterm", "SIGTERM", "KILL"} {
+		if _, err := ParseSignal(name); err != nil {
+			t.Errorf("ParseSignal(%q) error: %v", name, err)
+		}
+	}
+	if _, err := ParseSignal("NOPE"); err == nil {
+		t.Error("ParseSignal(NOPE) should fail")
+	}
+}
@@ -14,6 +14,8 @@ type Lexer struct {
 	ch      rune // Current character
 	line    int  // Current line number (1-indexed)
 	col     int  // Current column number (1-indexed)
+
+	hereDocEnd int // End of the here-document bodies of the current line, 0 if none
 }
 
 // New creates a new Lexer for the given input.
@@ -29,6 +31,7 @@ func New(input string) *Lexer {
 
 // readChar advances to the next character.
 func (l *Lexer) readChar() {
+	prev := l.ch
 	if l.readPos >= len(l.input) {
 		l.ch = 0 // EOF
 	} else {
@@ -40,10 +43,10 @@ func (l *Lexer) readChar() {
 		l.readPos = len(l.input) + 1 // Prevent further reading
 	}
 
-	// Track position
-	if l.ch == '\n' {
+	// Track position: a newline belongs to the line it ends
+	if prev == '\n' {
 		l.line++
-		l.col = 0
+		l.col = 1
 	} else {
 		l.col++
 	}
@@ -68,24 +71,89 @@ func (l *Lexer) NextToken() Token {
 
 	case l.ch == '\n':
 		l.readChar()
+		// The bodies of the here-documents of the line were already read
+		if l.hereDocEnd > 0 {
+			l.skip(l.hereDocEnd - l.pos)
+			l.hereDocEnd = 0
+		}
 		return Token{Type: TokenNewline, Value: "\n", Literal: "\n", Pos: startPos}
 
 	case unicode.IsSpace(l.ch):
 		return l.readWhitespace(startPos)
 
+	case l.ch == '\\' && l.peekChar() == '\n':
+		// Line continuation: a backslash ending the line joins it with the
+		// next one. Only a backslash starting a word continues the line, so
+		// that paths such as C:\ are left alone.
+		l.readChar()
+		l.readChar()
+		return Token{Type: TokenWhitespace, Value: "\\\n", Literal: " ", Pos: startPos}
+
 	case l.ch == '"':
 		return l.readDoubleQuotedString(startPos)
 
 	case l.ch == '\'':
 		return l.readSingleQuotedString(startPos)
 
+	case (l.ch == '$' && l.peekChar() == '(') || l.ch == '`':
+		return l.readSubstitution(startPos)
+
 	case l.ch == '$':
 		return l.readVariable(startPos)
 
+	case l.ch == '#':
+		// Comment: skip to end of line
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return l.NextToken()
+
 	case l.ch == '=':
 		l.readChar()
 		return Token{Type: TokenEquals, Value: "=", Literal: "=", Pos: startPos}
 
+	case l.ch == '|':
+		l.readChar()
+		if l.ch == '|' {
+			l.readChar()
+			return Token{Type: TokenOr, Value: "||", Literal: "||", Pos: startPos}
+		}
+		return Token{Type: TokenPipe, Value: "|", Literal: "|", Pos: startPos}
+
+	case l.ch == ';':
+		l.readChar()
+		if l.ch == ';' {
+			l.readChar()
+			return Token{Type: TokenCaseEnd, Value: ";;", Literal: ";;", Pos: startPos}
+		}
+		return Token{Type: TokenSemicolon, Value: ";", Literal: ";", Pos: startPos}
+
+	case l.ch == '(':
+		l.readChar()
+		return Token{Type: TokenLParen, Value: "(", Literal: "(", Pos: startPos}
+
+	case l.ch == ')':
+		l.readChar()
+		return Token{Type: TokenRParen, Value: ")", Literal: ")", Pos: startPos}
+
+	case l.ch == '&' && l.peekChar() == '&':
+		l.readChar()
+		l.readChar()
+		return Token{Type: TokenAnd, Value: "&&", Literal: "&&", Pos: startPos}
+
+	case l.atBackgroundOperator():
+		l.readChar()
+		return Token{Type: TokenBackground, Value: "&", Literal: "&", Pos: startPos}
+
+	case l.ch == '>' || l.ch == '<':
+		return l.readRedirect(startPos)
+
+	case l.ch == '&' && l.peekChar() == '>':
+		return l.readRedirect(startPos)
+
+	case unicode.IsDigit(l.ch) && (l.peekChar() == '>' || l.peekChar() == '<'):
+		return l.readRedirect(startPos)
+
 	case l.ch == '-':
 		return l.readOption(startPos)
 
@@ -118,7 +186,7 @@ func (l *Lexer) readWhitespace(startPos Position) Token {
 }
 
 // readDoubleQuotedString reads a double-quoted string.
-// Supports escape sequences: \\, \", \n, \t, \$
+// Supports escape sequences: \\, \", \n, \t, \$, \` and line continuation.
 func (l *Lexer) readDoubleQuotedString(startPos Position) Token {
 	l.readChar() // Skip opening quote
 	var literal strings.Builder
@@ -144,8 +212,12 @@ func (l *Lexer) readDoubleQuotedString(startPos Position) Token {
 				literal.WriteRune('\t')
 				l.readChar()
 				l.readChar()
-			case '$':
-				literal.WriteRune('$')
+			case '$', '`':
+				literal.WriteRune(next)
+				l.readChar()
+				l.readChar()
+			case '\n':
+				// Line continuation
 				l.readChar()
 				l.readChar()
 			default:
@@ -153,6 +225,14 @@ func (l *Lexer) readDoubleQuotedString(startPos Position) Token {
 				literal.WriteRune(l.ch)
 				l.readChar()
 			}
+		} else if (l.ch == '$' && l.peekChar() == '(') || l.ch == '`' {
+			// Quotes inside a command substitution do not end the string
+			n := SubstitutionLength(l.input[l.pos:])
+			if n < 0 {
+				return l.unterminatedSubstitution(start-1, startPos)
+			}
+			literal.WriteString(l.input[l.pos : l.pos+n])
+			l.skip(n)
 		} else {
 			literal.WriteRune(l.ch)
 			l.readChar()
@@ -212,8 +292,12 @@ func (l *Lexer) readVariable(startPos Position) Token {
 		l.readChar() // Skip {
 		varStart := l.pos
 
-		for isIdentChar(l.ch) {
+		if isSpecialParam(l.ch) {
 			l.readChar()
+		} else {
+			for isIdentChar(l.ch) {
+				l.readChar()
+			}
 		}
 
 		if l.ch != '}' {
@@ -232,9 +316,13 @@ func (l *Lexer) readVariable(startPos Position) Token {
 		return Token{Type: TokenVariable, Value: value, Literal: varName, Pos: startPos}
 	}
 
-	// $VAR form
-	for isIdentChar(l.ch) {
+	// $VAR form; positional ($1) and special ($#, $?, ...) parameters are a single character
+	if isSpecialParam(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
+	} else {
+		for isIdentChar(l.ch) {
+			l.readChar()
+		}
 	}
 
 	value := l.input[start:l.pos]
@@ -248,6 +336,226 @@ func (l *Lexer) readVariable(startPos Position) Token {
 	return Token{Type: TokenVariable, Value: value, Literal: varName, Pos: startPos}
 }
 
+// readSubstitution reads a command substitution ($(cmd) or `cmd`).
+// The literal is the command to run.
+func (l *Lexer) readSubstitution(startPos Position) Token {
+	start := l.pos
+	n := SubstitutionLength(l.input[start:])
+	if n < 0 {
+		return l.unterminatedSubstitution(start, startPos)
+	}
+	l.skip(n)
+
+	value := l.input[start:l.pos]
+	if expr, ok := ArithmeticExpr(value); ok {
+		return Token{Type: TokenArithmetic, Value: value, Literal: expr, Pos: startPos}
+	}
+	command := value[1 : len(value)-1] // `cmd`
+	if value[0] == '$' {
+		command = value[2 : len(value)-1] // $(cmd)
+	}
+	return Token{Type: TokenSubstitution, Value: value, Literal: command, Pos: startPos}
+}
+
+// unterminatedSubstitution consumes the rest of the input and returns
+// an error token for a command substitution starting at start.
+func (l *Lexer) unterminatedSubstitution(start int, startPos Position) Token {
+	for l.ch != 0 {
+		l.readChar()
+	}
+	return Token{
+		Type:    TokenError,
+		Value:   l.input[start:],
+		Literal: "unterminated command substitution",
+		Pos:     startPos,
+	}
+}
+
+// ArithmeticExpr returns the expression of an arithmetic expansion
+// ($((expr))). Returns false if value is a command substitution, such as
+// $((cd dir) && ls).
+func ArithmeticExpr(value string) (string, bool) {
+	if !strings.HasPrefix(value, "$((") || !strings.HasSuffix(value, "))") {
+		return "", false
+	}
+	expr := value[3 : len(value)-2]
+	depth := 0
+	for _, c := range expr {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return "", false
+			}
+		}
+	}
+	return expr, depth == 0
+}
+
+// ContinuesLine returns true if input ends with a backslash that continues
+// the command on the next line, as in "echo a \\".
+func ContinuesLine(input string) bool {
+	l := New(strings.TrimSuffix(input, "\n") + "\n")
+	var last Token
+	for tok := l.NextToken(); tok.Type != TokenEOF && tok.Type != TokenError; tok = l.NextToken() {
+		last = tok
+	}
+	return last.Type == TokenWhitespace && last.Value == "\\\n"
+}
+
+// skip advances over the next n bytes of input.
+func (l *Lexer) skip(n int) {
+	end := l.pos + n
+	for l.pos < end && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// SubstitutionLength returns the length in bytes of the command substitution
+// ($(cmd) or `cmd`) at the start of s, or -1 if it is not terminated.
+// Parentheses and quotes are balanced, so $(echo ")") is a single substitution,
+// and the ) closing a pattern of a case command does not end it.
+func SubstitutionLength(s string) int {
+	if strings.HasPrefix(s, "`") {
+		end := strings.IndexByte(s[1:], '`')
+		if end < 0 {
+			return -1
+		}
+		return end + 2
+	}
+
+	depth := 0
+	var cases []int  // Stages of the case commands being read, innermost last
+	cmdStart := true // At the start of a command, where case and esac are words
+	for i := 1; i < len(s); {
+		stage := -1
+		if len(cases) > 0 {
+			stage = cases[len(cases)-1]
+		}
+
+		switch c := s[i]; {
+		case c == '(':
+			// A pattern may start with (
+			if stage != casePattern {
+				depth++
+			}
+			cmdStart = true
+		case c == ')':
+			if stage == casePattern {
+				cases[len(cases)-1] = caseBody
+				cmdStart = true
+				break
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case c == ';':
+			// ;; ;& and ;;& end the commands of a pattern
+			if stage == caseBody && (strings.HasPrefix(s[i:], ";;") || strings.HasPrefix(s[i:], ";&")) {
+				cases[len(cases)-1] = casePattern
+				i += len(";;")
+				if strings.HasPrefix(s[i:], "&") {
+					i++
+				}
+				continue
+			}
+			cmdStart = true
+		case c == '&' || c == '|' || c == '\n':
+			cmdStart = true
+		case c == ' ' || c == '\t':
+		default:
+			n := wordLength(s[i:])
+			if n < 0 {
+				return -1
+			}
+			word := s[i : i+n]
+			switch {
+			case stage == caseSubject:
+				cases[len(cases)-1] = caseIn
+			case stage == caseIn && word == "in":
+				cases[len(cases)-1] = casePattern
+			case word == "esac" && (stage == casePattern || stage == caseBody && cmdStart):
+				cases = cases[:len(cases)-1]
+			case word == "case" && cmdStart:
+				cases = append(cases, caseSubject)
+			}
+			cmdStart = commandKeywords[word]
+			i += n
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// Stages of a case command read by SubstitutionLength.
+const (
+	caseSubject = iota // The word after case
+	caseIn             // The in keyword
+	casePattern        // Patterns, up to )
+	caseBody           // Commands, up to ;; or esac
+)
+
+// commandKeywords are the reserved words followed by a command.
+var commandKeywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "while": true,
+	"until": true, "do": true, "{": true, "!": true,
+}
+
+// wordLength returns the length of the word at the start of s, up to an
+// operator or blank, with its quotes and nested substitutions, or -1 if
+// one of them is not terminated.
+func wordLength(s string) int {
+	i := 0
+	for i < len(s) && !strings.ContainsRune(" \t\n;&|()", rune(s[i])) {
+		n := 1
+		switch {
+		case s[i] == '\\':
+			n = 2
+		case s[i] == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return -1
+			}
+			n = end + 2
+		case s[i] == '"':
+			n = quotedLength(s[i:])
+		case s[i] == '`' || strings.HasPrefix(s[i:], "$("):
+			n = SubstitutionLength(s[i:])
+		}
+		if n < 0 {
+			return -1
+		}
+		i += n
+	}
+	return min(i, len(s))
+}
+
+// quotedLength returns the length of the double-quoted string at the start
+// of s, including nested command substitutions, or -1 if it is not terminated.
+func quotedLength(s string) int {
+	for i := 1; i < len(s); {
+		switch {
+		case s[i] == '\\':
+			i += 2
+		case s[i] == '"':
+			return i + 1
+		case s[i] == '`' || strings.HasPrefix(s[i:], "$("):
+			n := SubstitutionLength(s[i:])
+			if n < 0 {
+				return -1
+			}
+			i += n
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
 // readOption reads an option (--flag or -abc for combined short options).
 func (l *Lexer) readOption(startPos Position) Token {
 	start := l.pos
@@ -274,15 +582,187 @@ func (l *Lexer) readOption(startPos Position) Token {
 	return Token{Type: TokenOption, Value: value, Literal: value, Pos: startPos}
 }
 
+// readRedirect reads a redirection operator: [n]>, [n]>>, [n]<, [n]>&m, &>,
+// &>>, [n]<<< and here-documents.
+func (l *Lexer) readRedirect(startPos Position) Token {
+	start := l.pos
+
+	// Optional file descriptor number or & (both stdout and stderr)
+	if unicode.IsDigit(l.ch) || l.ch == '&' {
+		l.readChar()
+	}
+
+	if l.ch == '<' {
+		l.readChar()
+		if l.ch == '<' {
+			l.readChar()
+			if l.ch != '<' {
+				return l.readHereDoc(start, startPos)
+			}
+			l.readChar() // Here-string
+		}
+	} else {
+		l.readChar() // Skip >
+		if l.ch == '>' {
+			l.readChar() // Append
+		} else if l.ch == '&' && unicode.IsDigit(l.peekChar()) && l.input[start] != '&' {
+			l.readChar() // Skip &
+			l.readChar() // Target descriptor
+		}
+	}
+
+	value := l.input[start:l.pos]
+	return Token{Type: TokenRedirect, Value: value, Literal: value, Pos: startPos}
+}
+
+// readHereDoc reads a here-document: the << or <<- operator, already
+// partly read, and its delimiter. The body, from the line following the
+// operator up to the delimiter line, is read at once and becomes the literal
+// of the token; the lexer skips it when it reaches the end of the line.
+// With <<-, leading tabs are removed from the body and delimiter lines.
+func (l *Lexer) readHereDoc(start int, startPos Position) Token {
+	strip := false
+	if l.ch == '-' {
+		strip = true
+		l.readChar()
+	}
+	for l.ch == ' ' || l.ch == '\t' {
+		l.readChar()
+	}
+
+	delim, ok := l.readDelimiter()
+	value := l.input[start:l.pos]
+	if !ok {
+		return Token{Type: TokenError, Value: value, Literal: "missing here-document delimiter", Pos: startPos}
+	}
+
+	// Bodies of several here-documents on one line follow each other
+	bodyStart := l.hereDocEnd
+	if bodyStart == 0 {
+		end := lineEnd(l.input[l.pos:])
+		if end < 0 {
+			return Token{Type: TokenError, Value: value, Literal: "unterminated here-document", Pos: startPos}
+		}
+		bodyStart = l.pos + end + 1
+	}
+
+	body, n, ok := hereDocBody(l.input[bodyStart:], delim, strip)
+	if !ok {
+		return Token{Type: TokenError, Value: value, Literal: "unterminated here-document", Pos: startPos}
+	}
+	l.hereDocEnd = bodyStart + n
+
+	return Token{Type: TokenHereDoc, Value: value, Literal: body, Pos: startPos}
+}
+
+// readDelimiter reads the delimiter word of a here-document, removing quotes
+// and backslashes. Returns false if there is no delimiter.
+func (l *Lexer) readDelimiter() (string, bool) {
+	start := l.pos
+	var sb strings.Builder
+	for l.ch != 0 && !unicode.IsSpace(l.ch) && !isOperatorChar(l.ch) && l.ch != '&' {
+		switch l.ch {
+		case '\'', '"':
+			quote := l.ch
+			l.readChar()
+			for l.ch != quote {
+				if l.ch == 0 {
+					return "", false
+				}
+				sb.WriteRune(l.ch)
+				l.readChar()
+			}
+			l.readChar()
+		case '\\':
+			l.readChar()
+			if l.ch != 0 {
+				sb.WriteRune(l.ch)
+				l.readChar()
+			}
+		default:
+			sb.WriteRune(l.ch)
+			l.readChar()
+		}
+	}
+	return sb.String(), l.pos > start
+}
+
+// lineEnd returns the index in s of the newline ending the current line,
+// skipping over quoted strings, command substitutions and comments,
+// or -1 if the line is not complete.
+func lineEnd(s string) int {
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\n':
+			return i
+		case s[i] == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return -1
+			}
+			i += end + 2
+		case s[i] == '"':
+			n := quotedLength(s[i:])
+			if n < 0 {
+				return -1
+			}
+			i += n
+		case s[i] == '`' || strings.HasPrefix(s[i:], "$("):
+			n := SubstitutionLength(s[i:])
+			if n < 0 {
+				return -1
+			}
+			i += n
+		case s[i] == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t'):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return -1
+			}
+			return i + end
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// hereDocBody returns the body of a here-document at the start of s, up to
+// the line holding only the delimiter, and the length of s it spans
+// including the delimiter line. Returns false if there is no delimiter line.
+func hereDocBody(s, delim string, strip bool) (string, int, bool) {
+	var body strings.Builder
+	for pos := 0; pos < len(s); {
+		line, next := s[pos:], len(s)
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line, next = line[:end], pos+end+1
+		}
+		if strip {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if strings.TrimSuffix(line, "\r") == delim {
+			return body.String(), next, true
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+		pos = next
+	}
+	return "", 0, false
+}
+
 // readWord reads a word (command name or argument).
 func (l *Lexer) readWord(startPos Position) Token {
 	start := l.pos
 
-	for l.ch != 0 && !isWordTerminator(l.ch) {
+	for l.ch != 0 && !isWordTerminator(l.ch) && !l.atAmpersandOperator() {
 		// All characters including backslash are kept literally.
 		// Backslash is NOT an escape character in unquoted words.
 		// This ensures Windows paths like C:\Users\name work correctly.
 		// For filenames with spaces, use quotes: "file name" or 'file name'
+		if n := extGlobLength(l.input[l.pos:]); n > 0 {
+			// The | of an extended glob such as +(a|b) is not a pipe
+			l.skip(n)
+			continue
+		}
 		l.readChar()
 	}
 
@@ -290,11 +770,54 @@ func (l *Lexer) readWord(startPos Position) Token {
 	return Token{Type: TokenWord, Value: value, Literal: value, Pos: startPos}
 }
 
+// extGlobLength returns the length of the extended glob pattern, such as
+// @(a|b) or !(*.go), at the start of s, or 0 if there is none.
+func extGlobLength(s string) int {
+	if len(s) < 2 || s[1] != '(' || !strings.ContainsRune("?*+@!", rune(s[0])) {
+		return 0
+	}
+	depth := 0
+	for i := 2; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i + 1
+			}
+			depth--
+		case '\n', ' ', '\t', '"', '\'', '`', '$', ';', '<', '>':
+			return 0
+		}
+	}
+	return 0
+}
+
+// atAmpersandOperator returns true if the current & starts &&, &> or
+// the background operator. Any other & remains part of the word.
+func (l *Lexer) atAmpersandOperator() bool {
+	return l.ch == '&' && (l.peekChar() == '&' || l.peekChar() == '>') || l.atBackgroundOperator()
+}
+
+// atBackgroundOperator returns true if the current & runs the command
+// before it in the background: the & must end the word, so that words
+// such as a&b are left alone.
+func (l *Lexer) atBackgroundOperator() bool {
+	next := l.peekChar()
+	return l.ch == '&' && (next == 0 || unicode.IsSpace(next))
+}
+
 // isIdentChar returns true if ch is a valid identifier character.
 func isIdentChar(ch rune) bool {
 	return isLetter(ch) || unicode.IsDigit(ch) || ch == '_'
 }
 
+// isSpecialParam returns true if ch names a special parameter
+// ($#, $@, $*, $?, $$, $!).
+func isSpecialParam(ch rune) bool {
+	return ch == '#' || ch == '@' || ch == '*' || ch == '?' || ch == '$' || ch == '!'
+}
+
 // isLetter returns true if ch is a letter.
 func isLetter(ch rune) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
@@ -307,5 +830,10 @@ func isOptionChar(ch rune) bool {
 
 // isWordTerminator returns true if ch terminates a word.
 func isWordTerminator(ch rune) bool {
-	return unicode.IsSpace(ch) || ch == '"' || ch == '\'' || ch == '$' || ch == '='
+	return unicode.IsSpace(ch) || ch == '"' || ch == '\'' || ch == '`' || ch == '$' || ch == '=' || isOperatorChar(ch)
+}
+
+// isOperatorChar returns true if ch starts a control operator.
+func isOperatorChar(ch rune) bool {
+	return ch == '|' || ch == '>' || ch == '<' || ch == ';' || ch == '(' || ch == ')'
 }
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -14,8 +16,16 @@ func TestTokenTypeString(t *testing.T) {
 		{TokenOption, "OPTION"},
 		{TokenEquals, "EQUALS"},
 		{TokenVariable, "VARIABLE"},
+		{TokenSubstitution, "SUBSTITUTION"},
+		{TokenArithmetic, "ARITHMETIC"},
 		{TokenWhitespace, "WHITESPACE"},
 		{TokenNewline, "NEWLINE"},
+		{TokenPipe, "PIPE"},
+		{TokenRedirect, "REDIRECT"},
+		{TokenSemicolon, "SEMICOLON"},
+		{TokenAnd, "AND"},
+		{TokenOr, "OR"},
+		{TokenCaseEnd, "CASE_END"},
 		{TokenEOF, "EOF"},
 		{TokenError, "ERROR"},
 		{TokenType(999), "UNKNOWN"},
@@ -357,8 +367,13 @@ func TestLexerMultiLine(t *testing.T) {
 		}
 	}
 
-	if defTok.Pos.Line != 2 {
-		t.Errorf("'def' token line = %d, want 2", defTok.Pos.Line)
+	if defTok.Pos.Line != 2 || defTok.Pos.Column != 1 {
+		t.Errorf("'def' token pos = %d:%d, want 2:1", defTok.Pos.Line, defTok.Pos.Column)
+	}
+
+	// The newline ends the first line
+	if nl := tokens[1]; nl.Type != TokenNewline || nl.Pos.Line != 1 || nl.Pos.Column != 4 {
+		t.Errorf("newline token = %v at %d:%d, want NEWLINE at 1:4", nl.Type, nl.Pos.Line, nl.Pos.Column)
 	}
 }
 
@@ -438,3 +453,421 @@ func TestLexerBackslashInWord(t *testing.T) {
 		})
 	}
 }
+
+func TestLexerPipe(t *testing.T) {
+	tests := []struct {
+		input string
+		types []TokenType
+	}{
+		{"ls | grep go", []TokenType{TokenWord, TokenWhitespace, TokenPipe, TokenWhitespace, TokenWord, TokenWhitespace, TokenWord, TokenEOF}},
+		{"ls|wc", []TokenType{TokenWord, TokenPipe, TokenWord, TokenEOF}},
+		{"ls -a|wc", []TokenType{TokenWord, TokenWhitespace, TokenOption, TokenPipe, TokenWord, TokenEOF}},
+		{`echo "a|b"`, []TokenType{TokenWord, TokenWhitespace, TokenString, TokenEOF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens := New(tt.input).Tokens()
+			if len(tokens) != len(tt.types) {
+				t.Fatalf("got %d tokens %v, want %d", len(tokens), tokens, len(tt.types))
+			}
+			for i, want := range tt.types {
+				if tokens[i].Type != want {
+					t.Errorf("token[%d].Type = %v, want %v", i, tokens[i].Type, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLexerRedirect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string // Values of redirect tokens
+	}{
+		{"ls > out", []string{">"}},
+		{"ls >> out", []string{">>"}},
+		{"sort < in", []string{"<"}},
+		{"search . 2> errs.log", []string{"2>"}},
+		{"make >out 2>&1", []string{">", "2>&1"}},
+		{"make &> all.log", []string{"&>"}},
+		{"make &>> all.log", []string{"&>>"}},
+		{"echo err >&2", []string{">&2"}},
+		{"echo a>b", []string{">"}},
+		{"cat <<< word", []string{"<<<"}},
+		{`echo "a > b"`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got []string
+			for _, tok := range New(tt.input).Tokens() {
+				if tok.Type == TokenRedirect {
+					got = append(got, tok.Value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("redirects = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("redirect[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerExtGlob(t *testing.T) {
+	tests := []struct {
+		input string
+		words []string
+		pipe  bool
+	}{
+		{"ls +(a|b).go", []string{"ls", "+(a|b).go"}, false},
+		{"ls src/!(*_test|x).go | wc", []string{"ls", "src/!(*_test|x).go", "wc"}, true},
+		{"ls @(a|@(b|c))", []string{"ls", "@(a|@(b|c))"}, false},
+		{"echo (a|b)", []string{"echo", "a", "b"}, true},
+		{"echo +(a| b)", []string{"echo", "+", "a", "b"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var words []string
+			pipe := false
+			for _, tok := range New(tt.input).Tokens() {
+				switch tok.Type {
+				case TokenWord:
+					words = append(words, tok.Value)
+				case TokenPipe:
+					pipe = true
+				}
+			}
+			if strings.Join(words, " ") != strings.Join(tt.words, " ") || pipe != tt.pipe {
+				t.Errorf("words = %q (pipe %v), want %q (pipe %v)", words, pipe, tt.words, tt.pipe)
+			}
+		})
+	}
+}
+
+func TestLexerHereDoc(t *testing.T) {
+	tests := []struct {
+		input  string
+		values []string // Values of here-document tokens
+		bodies []string // Bodies of here-document tokens
+		next   string   // Value of the first word after the here-documents
+	}{
+		{"cat <<EOF\nhello\nEOF\necho", []string{"<<EOF"}, []string{"hello\n"}, "echo"},
+		{"cat << 'END' | wc\n$x\nEND\n", []string{"<< 'END'"}, []string{"$x\n"}, "wc"},
+		{"cat <<-EOF\n\tindented\n\tEOF\necho", []string{"<<-EOF"}, []string{"indented\n"}, "echo"},
+		{"cat <<A <<B\na\nA\nb\nB\necho", []string{"<<A", "<<B"}, []string{"a\n", "b\n"}, "echo"},
+		{"cat <<EOF\nEOF\necho", []string{"<<EOF"}, []string{""}, "echo"},
+		{"cat <<\"E F\"\nx\nE F\necho", []string{`<<"E F"`}, []string{"x\n"}, "echo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var values, bodies []string
+			next := ""
+			for _, tok := range New(tt.input).Tokens() {
+				switch {
+				case tok.Type == TokenHereDoc:
+					values = append(values, tok.Value)
+					bodies = append(bodies, tok.Literal)
+				case tok.Type == TokenWord && len(values) > 0 && next == "":
+					next = tok.Value
+				case tok.Type == TokenError:
+					t.Fatalf("unexpected error token %q", tok.Literal)
+				}
+			}
+			if len(values) != len(tt.values) {
+				t.Fatalf("here-documents = %q, want %q", values, tt.values)
+			}
+			for i := range values {
+				if values[i] != tt.values[i] || bodies[i] != tt.bodies[i] {
+					t.Errorf("here-document[%d] = %q %q, want %q %q", i, values[i], bodies[i], tt.values[i], tt.bodies[i])
+				}
+			}
+			if next != tt.next {
+				t.Errorf("word after here-documents = %q, want %q", next, tt.next)
+			}
+		})
+	}
+}
+
+func TestLexerHereDocErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"cat <<EOF\nhello", "unterminated here-document"},
+		{"cat <<EOF", "unterminated here-document"},
+		{"cat <<", "missing here-document delimiter"},
+		{"cat << | wc", "missing here-document delimiter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens := New(tt.input).Tokens()
+			last := tokens[len(tokens)-1]
+			if last.Type != TokenError || last.Literal != tt.want {
+				t.Errorf("last token = %v (%q), want %q", last, last.Literal, tt.want)
+			}
+		})
+	}
+}
+
+func TestLexerListOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		types []TokenType
+	}{
+		{"a;b", []TokenType{TokenWord, TokenSemicolon, TokenWord, TokenEOF}},
+		{"a && b", []TokenType{TokenWord, TokenWhitespace, TokenAnd, TokenWhitespace, TokenWord, TokenEOF}},
+		{"a&&b", []TokenType{TokenWord, TokenAnd, TokenWord, TokenEOF}},
+		{"a||b|c", []TokenType{TokenWord, TokenOr, TokenWord, TokenPipe, TokenWord, TokenEOF}},
+		{"a&b", []TokenType{TokenWord, TokenEOF}},
+		{"a &", []TokenType{TokenWord, TokenWhitespace, TokenBackground, TokenEOF}},
+		{"sleep 5& b", []TokenType{TokenWord, TokenWhitespace, TokenWord, TokenBackground, TokenWhitespace, TokenWord, TokenEOF}},
+		{"a &\nb", []TokenType{TokenWord, TokenWhitespace, TokenBackground, TokenNewline, TokenWord, TokenEOF}},
+		{"a) b;;", []TokenType{TokenWord, TokenRParen, TokenWhitespace, TokenWord, TokenCaseEnd, TokenEOF}},
+		{"(cd a&&b)", []TokenType{TokenLParen, TokenWord, TokenWhitespace, TokenWord, TokenAnd, TokenWord, TokenRParen, TokenEOF}},
+		{"f() {", []TokenType{TokenWord, TokenLParen, TokenRParen, TokenWhitespace, TokenWord, TokenEOF}},
+		{`echo "a;b"`, []TokenType{TokenWord, TokenWhitespace, TokenString, TokenEOF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens := New(tt.input).Tokens()
+			if len(tokens) != len(tt.types) {
+				t.Fatalf("got %d tokens %v, want %d", len(tokens), tokens, len(tt.types))
+			}
+			for i, want := range tt.types {
+				if tokens[i].Type != want {
+					t.Errorf("token[%d].Type = %v, want %v", i, tokens[i].Type, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLexerSpecialParameters(t *testing.T) {
+	tests := []struct {
+		input   string
+		literal string
+		next    string // Value of the following token, if any
+	}{
+		{"$1", "1", ""},
+		{"$10", "1", "0"},
+		{"${10}", "10", ""},
+		{"$#", "#", ""},
+		{"$@", "@", ""},
+		{"$*", "*", ""},
+		{"${#}", "#", ""},
+		{"$0", "0", ""},
+		{"$?", "?", ""},
+		{"$??", "?", "?"},
+		{"$$", "$", ""},
+		{"$!", "!", ""},
+		{"${?}", "?", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := New(tt.input)
+			tok := l.NextToken()
+			if tok.Type != TokenVariable {
+				t.Fatalf("Type = %v, want TokenVariable", tok.Type)
+			}
+			if tok.Literal != tt.literal {
+				t.Errorf("Literal = %q, want %q", tok.Literal, tt.literal)
+			}
+			if next := l.NextToken(); next.Value != tt.next {
+				t.Errorf("next token = %q, want %q", next.Value, tt.next)
+			}
+		})
+	}
+}
+
+func TestLexerCommandSubstitution(t *testing.T) {
+	tests := []struct {
+		input   string
+		value   string
+		literal string
+	}{
+		{"$(date)", "$(date)", "date"},
+		{"`date`", "`date`", "date"},
+		{"$(ls | head -1)", "$(ls | head -1)", "ls | head -1"},
+		{"$(echo $(pwd))x", "$(echo $(pwd))", "echo $(pwd)"},
+		{`$(echo ")")`, `$(echo ")")`, `echo ")"`},
+		{`$(echo '(')`, `$(echo '(')`, `echo '('`},
+		{"$(echo `pwd`)", "$(echo `pwd`)", "echo `pwd`"},
+		{`$(\))`, `$(\))`, `\)`},
+		{"$(case x in x) echo cx;; esac)", "$(case x in x) echo cx;; esac)", "case x in x) echo cx;; esac"},
+		{"$(case $1 in (a|b) echo ab;; *) (echo x);; esac; echo y)z", "$(case $1 in (a|b) echo ab;; *) (echo x);; esac; echo y)", "case $1 in (a|b) echo ab;; *) (echo x);; esac; echo y"},
+		{"$(if true; then case x in x) echo;; esac; fi)", "$(if true; then case x in x) echo;; esac; fi)", "if true; then case x in x) echo;; esac; fi"},
+		{"$(echo case esac)", "$(echo case esac)", "echo case esac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tok := New(tt.input).NextToken()
+			if tok.Type != TokenSubstitution {
+				t.Fatalf("Type = %v, want TokenSubstitution", tok.Type)
+			}
+			if tok.Value != tt.value {
+				t.Errorf("Value = %q, want %q", tok.Value, tt.value)
+			}
+			if tok.Literal != tt.literal {
+				t.Errorf("Literal = %q, want %q", tok.Literal, tt.literal)
+			}
+		})
+	}
+}
+
+func TestLexerSubstitutionInWord(t *testing.T) {
+	tokens := New("a$(b)c`d`").Tokens()
+	want := []TokenType{TokenWord, TokenSubstitution, TokenWord, TokenSubstitution, TokenEOF}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %v", len(tokens), len(want), tokens)
+	}
+	for i, typ := range want {
+		if tokens[i].Type != typ {
+			t.Errorf("token[%d].Type = %v, want %v", i, tokens[i].Type, typ)
+		}
+	}
+}
+
+func TestLexerSubstitutionInString(t *testing.T) {
+	tokens := New(`"built $(date "+%F") at \$(x)" next`).Tokens()
+	if tokens[0].Type != TokenString {
+		t.Fatalf("Type = %v, want TokenString", tokens[0].Type)
+	}
+	if tokens[0].Value != `"built $(date "+%F") at \$(x)"` {
+		t.Errorf("Value = %q", tokens[0].Value)
+	}
+	if tokens[2].Value != "next" {
+		t.Errorf("next token = %q, want %q", tokens[2].Value, "next")
+	}
+}
+
+func TestLexerArithmetic(t *testing.T) {
+	tests := []struct {
+		input   string
+		typ     TokenType
+		literal string
+	}{
+		{"$((1 + 2))", TokenArithmetic, "1 + 2"},
+		{"$(( (a + 1) * 2 ))", TokenArithmetic, " (a + 1) * 2 "},
+		{"$((1 << 2 | 1))", TokenArithmetic, "1 << 2 | 1"},
+		{"$((cd /tmp) && pwd)", TokenSubstitution, "(cd /tmp) && pwd"},
+		{"$((a) + (b))", TokenSubstitution, "(a) + (b)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tok := New(tt.input).NextToken()
+			if tok.Type != tt.typ {
+				t.Fatalf("Type = %v, want %v", tok.Type, tt.typ)
+			}
+			if tok.Value != tt.input {
+				t.Errorf("Value = %q, want %q", tok.Value, tt.input)
+			}
+			if tok.Literal != tt.literal {
+				t.Errorf("Literal = %q, want %q", tok.Literal, tt.literal)
+			}
+		})
+	}
+}
+
+func TestLexerUnterminatedSubstitution(t *testing.T) {
+	for _, input := range []string{"echo $(date", "echo `date", `echo "$(date"`, `echo $(echo ")`} {
+		t.Run(input, func(t *testing.T) {
+			tokens := New(input).Tokens()
+			last := tokens[len(tokens)-1]
+			if last.Type != TokenError || last.Literal != "unterminated command substitution" {
+				t.Errorf("last token = %v (%q), want unterminated command substitution", last, last.Literal)
+			}
+		})
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	tests := []struct {
+		input string
+		words []string
+	}{
+		{"# only a comment", nil},
+		{"#!/usr/bin/env jsishell", nil},
+		{"echo hi # trailing comment", []string{"echo", "hi"}},
+		{"echo a#b", []string{"echo", "a#b"}},
+		{"# first\necho second", []string{"echo", "second"}},
+		{`echo "# not a comment"`, []string{"echo", "# not a comment"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var words []string
+			for _, tok := range New(tt.input).Tokens() {
+				if tok.IsWord() {
+					words = append(words, tok.Literal)
+				}
+			}
+			if len(words) != len(tt.words) {
+				t.Fatalf("words = %v, want %v", words, tt.words)
+			}
+			for i := range words {
+				if words[i] != tt.words[i] {
+					t.Errorf("word[%d] = %q, want %q", i, words[i], tt.words[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerLineContinuation(t *testing.T) {
+	tests := []struct {
+		input string
+		words []string
+	}{
+		{"echo a \\\nb", []string{"echo", "a", "b"}},
+		{"echo \"a\\\nb\"", []string{"echo", `"a\` + "\nb\""}},
+		{"cd C:\\\necho", []string{"cd", "C:\\", "echo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var words []string
+			for _, tok := range New(tt.input).Tokens() {
+				if tok.Type == TokenWord || tok.Type == TokenString {
+					words = append(words, tok.Value)
+				}
+			}
+			if !reflect.DeepEqual(words, tt.words) {
+				t.Errorf("words = %q, want %q", words, tt.words)
+			}
+		})
+	}
+}
+
+func TestContinuesLine(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"echo a \\", true},
+		{"echo a \\\n", true},
+		{"echo a", false},
+		{"cd C:\\", false},
+		{"echo a # comment \\", false},
+		{"echo 'a \\", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ContinuesLine(tt.input); got != tt.want {
+				t.Errorf("ContinuesLine(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
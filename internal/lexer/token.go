@@ -5,15 +5,27 @@ package lexer
 type TokenType int
 
 const (
-	TokenWord       TokenType = iota // Command or argument word
-	TokenString                      // Quoted string ("..." or '...')
-	TokenOption                      // Option (--flag or -f)
-	TokenEquals                      // Assignment operator (=)
-	TokenVariable                    // Variable reference ($VAR or ${VAR})
-	TokenWhitespace                  // Whitespace (space or tab)
-	TokenNewline                     // Newline character
-	TokenEOF                         // End of input
-	TokenError                       // Lexer error
+	TokenWord         TokenType = iota // Command or argument word
+	TokenString                        // Quoted string ("..." or '...')
+	TokenOption                        // Option (--flag or -f)
+	TokenEquals                        // Assignment operator (=)
+	TokenVariable                      // Variable reference ($VAR or ${VAR})
+	TokenSubstitution                  // Command substitution ($(cmd) or `cmd`)
+	TokenArithmetic                    // Arithmetic expansion ($((expr)))
+	TokenWhitespace                    // Whitespace (space or tab)
+	TokenNewline                       // Newline character
+	TokenPipe                          // Pipe operator (|)
+	TokenRedirect                      // Redirection operator (>, >>, <, 2>, 2>&1, &>, <<<)
+	TokenHereDoc                       // Here-document (<<EOF or <<-EOF) with its body
+	TokenSemicolon                     // Command separator (;)
+	TokenAnd                           // Logical AND operator (&&)
+	TokenOr                            // Logical OR operator (||)
+	TokenBackground                    // Background operator (&)
+	TokenCaseEnd                       // End of a case item (;;)
+	TokenLParen                        // Opening parenthesis of a subshell or case pattern (()
+	TokenRParen                        // Closing parenthesis ())
+	TokenEOF                           // End of input
+	TokenError                         // Lexer error
 )
 
 // String returns the string representation of a TokenType.
@@ -29,10 +41,34 @@ func (t TokenType) String() string {
 		return "EQUALS"
 	case TokenVariable:
 		return "VARIABLE"
+	case TokenSubstitution:
+		return "SUBSTITUTION"
+	case TokenArithmetic:
+		return "ARITHMETIC"
 	case TokenWhitespace:
 		return "WHITESPACE"
 	case TokenNewline:
 		return "NEWLINE"
+	case TokenPipe:
+		return "PIPE"
+	case TokenRedirect:
+		return "REDIRECT"
+	case TokenHereDoc:
+		return "HEREDOC"
+	case TokenSemicolon:
+		return "SEMICOLON"
+	case TokenAnd:
+		return "AND"
+	case TokenOr:
+		return "OR"
+	case TokenBackground:
+		return "BACKGROUND"
+	case TokenCaseEnd:
+		return "CASE_END"
+	case TokenLParen:
+		return "LPAREN"
+	case TokenRParen:
+		return "RPAREN"
 	case TokenEOF:
 		return "EOF"
 	case TokenError:
@@ -76,3 +112,18 @@ func (t Token) IsOption() bool {
 func (t Token) IsWhitespace() bool {
 	return t.Type == TokenWhitespace || t.Type == TokenNewline
 }
+
+// IsRedirect returns true if the token is a redirection operator or a here-document.
+func (t Token) IsRedirect() bool {
+	return t.Type == TokenRedirect || t.Type == TokenHereDoc
+}
+
+// IsOperator returns true if the token is a control operator that separates commands.
+func (t Token) IsOperator() bool {
+	switch t.Type {
+	case TokenPipe, TokenSemicolon, TokenAnd, TokenOr, TokenBackground, TokenCaseEnd, TokenLParen, TokenRParen:
+		return true
+	default:
+		return false
+	}
+}
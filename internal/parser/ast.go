@@ -1,14 +1,25 @@
 // Package parser parses lexer tokens into a command AST.
 package parser
 
+import "github.com/sdejongh/jsishell/internal/lexer"
+
 // Arg represents a command argument with quoting information.
 type Arg struct {
 	Value  string // The argument value
 	Quoted bool   // True if the argument was quoted (single or double quotes)
 }
 
+// Assignment is a NAME=value word at the start of a command. Without a
+// command name, it sets a shell variable; otherwise it is exported to the
+// command only.
+type Assignment struct {
+	Name  string // Variable name
+	Value string // Expanded value
+}
+
 // Command represents a parsed shell command.
 type Command struct {
+	Assignments  []Assignment        // Leading NAME=value words
 	Name         string              // Command name (may be abbreviated)
 	Resolved     string              // Resolved full command name (set by executor)
 	Args         []string            // Positional arguments (for backward compatibility)
@@ -16,6 +27,7 @@ type Command struct {
 	Options      map[string]string   // Named options (--key=value or --key value)
 	MultiOptions map[string][]string // Options that can be specified multiple times
 	Flags        map[string]bool     // Boolean flags (--verbose, -v)
+	Words        []string            // All arguments in source order, options as typed
 	RawInput     string              // Original input string
 }
 
@@ -72,6 +84,44 @@ func (c *Command) GetOptions(names ...string) []string {
 	return result
 }
 
+// BindValue makes the argument following each occurrence of a flag its
+// value, for an option that takes its value as a separate word, as in
+// --format json. Words are left as typed. Returns false if the flag is not
+// set or not followed by an argument.
+func (c *Command) BindValue(name string) bool {
+	if !c.Flags[name] {
+		return false
+	}
+
+	bound := false
+	arg := 0
+	for i := 0; i < len(c.Words); i++ {
+		// Arguments appear in Words in order, among the options
+		if arg < len(c.Args) && c.Words[i] == c.Args[arg] {
+			arg++
+			continue
+		}
+		if c.Words[i] != name || i+1 >= len(c.Words) || arg >= len(c.Args) || c.Words[i+1] != c.Args[arg] {
+			continue
+		}
+
+		value := c.Args[arg]
+		c.Options[name] = value
+		c.MultiOptions[name] = append(c.MultiOptions[name], value)
+		c.Args = append(c.Args[:arg:arg], c.Args[arg+1:]...)
+		if arg < len(c.ArgsWithInfo) {
+			c.ArgsWithInfo = append(c.ArgsWithInfo[:arg:arg], c.ArgsWithInfo[arg+1:]...)
+		}
+		bound = true
+		i++ // Skip the value
+	}
+
+	if bound {
+		delete(c.Flags, name)
+	}
+	return bound
+}
+
 // ArgCount returns the number of positional arguments.
 func (c *Command) ArgCount() int {
 	return len(c.Args)
@@ -123,3 +173,146 @@ func (c *Command) AllArgs() []string {
 
 	return result
 }
+
+// Node is a node of the syntax tree produced by ParseScript.
+type Node interface {
+	node()
+}
+
+// SimpleCommand is a single command in the syntax tree.
+// Its tokens are kept unexpanded so that variables and globs are expanded
+// when the command is executed rather than when it is parsed.
+type SimpleCommand struct {
+	Tokens    []lexer.Token // Tokens making up the command
+	Redirects []Redirect    // I/O redirections, in source order
+	RawInput  string        // Original source text of the command
+}
+
+// RedirectOp identifies the kind of an I/O redirection.
+type RedirectOp int
+
+const (
+	RedirectInput      RedirectOp = iota // [n]< file
+	RedirectOutput                       // [n]> file
+	RedirectAppend                       // [n]>> file
+	RedirectDup                          // [n]>&m
+	RedirectHereDoc                      // <<DELIM or <<-DELIM, with the body on the next lines
+	RedirectHereString                   // <<< word
+)
+
+// Redirect represents an I/O redirection of a command.
+type Redirect struct {
+	Op     RedirectOp    // Kind of redirection
+	FD     int           // Redirected descriptor (0 stdin, 1 stdout, 2 stderr)
+	Target []lexer.Token // Target file name or here-string word, expanded at execution time
+	DupFD  int           // Descriptor duplicated by RedirectDup
+	Body   string        // Body of a here-document
+	Quoted bool          // The here-document delimiter is quoted: the body is not expanded
+}
+
+// Pipeline represents commands connected by pipes (cmd1 | cmd2 | ...).
+// The standard output of each command feeds the standard input of the next.
+type Pipeline struct {
+	Commands []Node // Commands in the pipeline, in order
+	RawInput string // Original source text of the pipeline
+}
+
+// ListOp identifies the operator joining an item to the previous one in a List.
+type ListOp int
+
+const (
+	ListSeq ListOp = iota // ; or newline: always run
+	ListAnd               // &&: run if the previous command succeeded
+	ListOr                // ||: run if the previous command failed
+)
+
+// ListItem is a pipeline within a List together with its joining operator.
+type ListItem struct {
+	Op   ListOp // Operator before this item (ListSeq for the first item)
+	Node Node   // Pipeline to run
+}
+
+// List is a sequence of pipelines joined by ;, newlines, && and ||.
+// && and || have equal precedence and are evaluated left to right.
+type List struct {
+	Items []ListItem
+}
+
+// Background runs commands asynchronously as a job, as in "make &".
+// The commands are the pipelines joined by && and || before the &.
+type Background struct {
+	Node     Node   // *Pipeline or *List to run in the background
+	RawInput string // Original source text of the commands, without the &
+}
+
+// IfClause represents if COND; then BODY; [elif ...;] [else ...;] fi.
+type IfClause struct {
+	Cond *List // Condition; the Then branch runs if its status is zero
+	Then *List // Commands run when the condition succeeds
+	Else Node  // *IfClause for elif, *List for else, or nil
+}
+
+// WhileClause represents while COND; do BODY; done and its until variant.
+type WhileClause struct {
+	Cond  *List // Condition evaluated before each iteration
+	Body  *List // Loop body
+	Until bool  // Loop while the condition fails instead of while it succeeds
+}
+
+// ForClause represents for NAME [in WORDS]; do BODY; done.
+type ForClause struct {
+	Var   string        // Loop variable
+	Words []lexer.Token // Words to iterate over, expanded at execution time
+	HasIn bool          // False if "in WORDS" was omitted: iterate over "$@"
+	Body  *List         // Loop body
+}
+
+// CaseItem is a pattern list and its commands within a CaseClause.
+type CaseItem struct {
+	Patterns [][]lexer.Token // Alternative patterns (pat1 | pat2)
+	Body     *List           // Commands to run, nil if empty
+}
+
+// CaseClause represents case WORD in PATTERN) BODY;; ... esac.
+type CaseClause struct {
+	Word  []lexer.Token // Word matched against the patterns
+	Items []CaseItem    // Items, tried in order
+}
+
+// Subshell represents ( LIST ): the commands run in a copy of the shell
+// state, so that variables and the working directory do not leak out.
+type Subshell struct {
+	Body *List // Commands to run
+}
+
+// BraceGroup represents { LIST; }: the commands share the shell state and
+// redirections apply to the whole group.
+type BraceGroup struct {
+	Body *List // Commands to run
+}
+
+// RedirectedCommand applies redirections to a compound command,
+// as in "done > out.txt".
+type RedirectedCommand struct {
+	Node      Node       // Compound command
+	Redirects []Redirect // I/O redirections, in source order
+}
+
+// FunctionDef represents a function definition: name() { BODY; }.
+type FunctionDef struct {
+	Name string // Function name
+	Body Node   // Function body, with redirections applied on each call
+}
+
+func (*SimpleCommand) node()     {}
+func (*Pipeline) node()          {}
+func (*List) node()              {}
+func (*Background) node()        {}
+func (*IfClause) node()          {}
+func (*WhileClause) node()       {}
+func (*ForClause) node()         {}
+func (*CaseClause) node()        {}
+func (*Subshell) node()          {}
+func (*BraceGroup) node()        {}
+func (*RedirectedCommand) node() {}
+func (*FunctionDef) node()       {}
@@ -0,0 +1,441 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/lexer"
+)
+
+// reservedWords are the words that start or end compound commands.
+// They are only recognized unquoted and in command position.
+var reservedWords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+	"for": true, "case": true, "esac": true,
+	"{": true, "}": true, "function": true,
+}
+
+// reservedWord returns the reserved word at the current position, or "" if
+// the current token is not a reserved word.
+func (p *Parser) reservedWord() string {
+	if word := p.current().Value; reservedWords[word] && p.atKeyword(word) {
+		return word
+	}
+	return ""
+}
+
+// atKeyword returns true if the current token is the unquoted word keyword
+// standing on its own (so "done2" or "fi=1" do not match).
+func (p *Parser) atKeyword(keyword string) bool {
+	tok := p.current()
+	if tok.Type != lexer.TokenWord || tok.Value != keyword {
+		return false
+	}
+	next := p.peek()
+	return !(isWordPart(next) || next.Type == lexer.TokenOption) || !adjacent(tok, next)
+}
+
+// expect consumes the given keyword or returns a syntax error.
+func (p *Parser) expect(keyword string) error {
+	if !p.atKeyword(keyword) {
+		return p.unexpected(p.current())
+	}
+	p.advance()
+	return nil
+}
+
+// parseCompoundList parses a non-empty list ending at one of the terminators.
+func (p *Parser) parseCompoundList(terminators ...string) (*List, error) {
+	list, err := p.parseList(terminators...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, p.unexpected(p.current())
+	}
+	return list, nil
+}
+
+// parseIf parses if COND; then BODY; [elif COND; then BODY;]... [else BODY;] fi.
+// It is also used for elif, which is parsed as a nested IfClause.
+func (p *Parser) parseIf() (*IfClause, error) {
+	p.openBlock("fi")
+	defer p.closeBlock()
+	p.advance() // Skip if or elif
+
+	cond, err := p.parseCompoundList("then")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parseCompoundList("elif", "else", "fi")
+	if err != nil {
+		return nil, err
+	}
+
+	clause := &IfClause{Cond: cond, Then: then}
+	switch p.reservedWord() {
+	case "elif":
+		// The nested clause consumes the closing fi
+		elif, err := p.parseIf()
+		if err != nil {
+			return nil, err
+		}
+		clause.Else = elif
+		return clause, nil
+
+	case "else":
+		p.advance()
+		elseList, err := p.parseCompoundList("fi")
+		if err != nil {
+			return nil, err
+		}
+		clause.Else = elseList
+	}
+
+	if err := p.expect("fi"); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// parseWhile parses while COND; do BODY; done and until COND; do BODY; done.
+func (p *Parser) parseWhile() (*WhileClause, error) {
+	p.openBlock("done")
+	defer p.closeBlock()
+	clause := &WhileClause{Until: p.current().Value == "until"}
+	p.advance()
+
+	cond, err := p.parseCompoundList("do")
+	if err != nil {
+		return nil, err
+	}
+	clause.Cond = cond
+
+	if clause.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// parseFor parses for NAME [in WORDS]; do BODY; done.
+func (p *Parser) parseFor() (*ForClause, error) {
+	p.openBlock("done")
+	defer p.closeBlock()
+	p.advance() // Skip for
+	p.skipBlanks()
+
+	tok := p.current()
+	if tok.Type != lexer.TokenWord {
+		return nil, p.unexpected(tok)
+	}
+	if !isName(tok.Value) || !p.atKeyword(tok.Value) {
+		return nil, p.errorAt(tok, "", fmt.Errorf("%w: invalid loop variable name %q", errors.ErrInvalidSyntax, tok.Value))
+	}
+	p.advance()
+
+	clause := &ForClause{Var: tok.Value}
+	p.skipWhitespace()
+
+	if p.atKeyword("in") {
+		p.advance()
+		clause.HasIn = true
+
+		// Words extend to the end of the line or the next ;
+		for {
+			tok := p.current()
+			if tok.Type == lexer.TokenSemicolon || tok.Type == lexer.TokenNewline {
+				break
+			}
+			if tok.Type == lexer.TokenEOF || tok.Type == lexer.TokenError ||
+				tok.IsRedirect() || tok.IsOperator() {
+				return nil, p.unexpected(tok)
+			}
+			clause.Words = append(clause.Words, tok)
+			p.advance()
+		}
+		clause.Words = trimWhitespace(clause.Words)
+		p.advance() // Skip separator
+	} else if p.current().Type == lexer.TokenSemicolon {
+		p.advance()
+	}
+
+	p.skipWhitespace()
+	body, err := p.parseDoGroup()
+	if err != nil {
+		return nil, err
+	}
+	clause.Body = body
+	return clause, nil
+}
+
+// parseDoGroup parses do BODY; done.
+func (p *Parser) parseDoGroup() (*List, error) {
+	if err := p.expect("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList("done")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("done"); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseCase parses case WORD in [(]PATTERN[|PATTERN]...) BODY;; ... esac.
+// The last item does not need to be terminated by ;;.
+func (p *Parser) parseCase() (*CaseClause, error) {
+	p.openBlock("esac")
+	defer p.closeBlock()
+	p.advance() // Skip case
+	p.skipBlanks()
+
+	if !isWordPart(p.current()) {
+		return nil, p.unexpected(p.current())
+	}
+	clause := &CaseClause{Word: p.collectWord()}
+
+	p.skipWhitespace()
+	if err := p.expect("in"); err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipWhitespace()
+		if p.atKeyword("esac") {
+			p.advance()
+			return clause, nil
+		}
+
+		item, err := p.parseCaseItem()
+		if err != nil {
+			return nil, err
+		}
+		clause.Items = append(clause.Items, item)
+	}
+}
+
+// parseCaseItem parses the patterns and commands of a case item,
+// including the ;; terminating it.
+func (p *Parser) parseCaseItem() (CaseItem, error) {
+	var item CaseItem
+
+	// Optional opening parenthesis: (pattern)
+	if p.current().Type == lexer.TokenLParen {
+		p.advance()
+	}
+
+	for {
+		p.skipBlanks()
+		pattern, closed, err := p.parsePattern()
+		if err != nil {
+			return item, err
+		}
+		if len(pattern) == 0 {
+			return item, p.unexpected(p.current())
+		}
+		item.Patterns = append(item.Patterns, pattern)
+		if closed {
+			break
+		}
+
+		// Alternatives are separated by |
+		p.skipBlanks()
+		if p.current().Type != lexer.TokenPipe {
+			return item, p.unexpected(p.current())
+		}
+		p.advance()
+	}
+
+	body, err := p.parseList("esac")
+	if err != nil {
+		return item, err
+	}
+	if len(body.Items) > 0 {
+		item.Body = body
+	}
+
+	switch {
+	case p.current().Type == lexer.TokenCaseEnd:
+		p.advance()
+	case p.atKeyword("esac"):
+		// Last item
+	default:
+		return item, p.unexpected(p.current())
+	}
+	return item, nil
+}
+
+// parsePattern collects the tokens of a case pattern up to | or ).
+// closed is true if the pattern was terminated by the closing parenthesis.
+func (p *Parser) parsePattern() (pattern []lexer.Token, closed bool, err error) {
+	for {
+		tok := p.current()
+		if tok.Type == lexer.TokenError {
+			return nil, false, p.unexpected(tok)
+		}
+		if tok.Type == lexer.TokenRParen {
+			p.advance()
+			return pattern, true, nil
+		}
+		if !isWordPart(tok) && tok.Type != lexer.TokenOption {
+			return pattern, false, nil
+		}
+		if len(pattern) > 0 && !adjacent(pattern[len(pattern)-1], tok) {
+			return pattern, false, nil
+		}
+
+		pattern = append(pattern, tok)
+		p.advance()
+	}
+}
+
+// functionName returns the name of the function defined at the current
+// position by "name()" or "name ()", or "" if this is not a definition.
+func (p *Parser) functionName() string {
+	tok := p.current()
+	if tok.Type != lexer.TokenWord || !isName(tok.Value) || !p.atKeyword(tok.Value) {
+		return ""
+	}
+	if p.parensEnd(p.pos+1) < 0 {
+		return ""
+	}
+	return tok.Value
+}
+
+// parensEnd returns the position after the empty parentheses of a function
+// definition starting at token i, which may be preceded and separated by
+// blanks, or -1 if there are none.
+func (p *Parser) parensEnd(i int) int {
+	skip := func() {
+		for i < len(p.tokens) && p.tokens[i].Type == lexer.TokenWhitespace {
+			i++
+		}
+	}
+	skip()
+	if i >= len(p.tokens) || p.tokens[i].Type != lexer.TokenLParen {
+		return -1
+	}
+	i++
+	skip()
+	if i >= len(p.tokens) || p.tokens[i].Type != lexer.TokenRParen {
+		return -1
+	}
+	return i + 1
+}
+
+// parseFunction parses a function definition: name() { BODY; } or
+// function name [()] { BODY; }. Redirections after the closing brace
+// apply each time the function is called.
+func (p *Parser) parseFunction() (*FunctionDef, error) {
+	if p.atKeyword("function") {
+		p.advance()
+		p.skipBlanks()
+		if tok := p.current(); tok.Type != lexer.TokenWord || !isName(tok.Value) {
+			if tok.Type == lexer.TokenWord {
+				return nil, p.errorAt(tok, "", fmt.Errorf("%w: invalid function name %q", errors.ErrInvalidSyntax, tok.Value))
+			}
+			return nil, p.unexpected(tok)
+		}
+	}
+
+	fn := &FunctionDef{Name: p.current().Value}
+	p.openBlock("}")
+	defer p.closeBlock()
+	p.advance()
+
+	// Optional parentheses
+	if end := p.parensEnd(p.pos); end >= 0 {
+		p.pos = end
+	}
+
+	// The body may start on the next line
+	p.skipWhitespace()
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList("}")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	if fn.Body, err = p.parseCompoundRedirects(body); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// parseSubshell parses ( LIST ).
+func (p *Parser) parseSubshell() (*Subshell, error) {
+	p.openBlock(")")
+	defer p.closeBlock()
+	p.advance() // Skip (
+
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if p.current().Type != lexer.TokenRParen {
+		return nil, p.unexpected(p.current())
+	}
+	p.advance()
+	return &Subshell{Body: body}, nil
+}
+
+// parseBraceGroup parses { LIST; }.
+func (p *Parser) parseBraceGroup() (*BraceGroup, error) {
+	p.openBlock("}")
+	defer p.closeBlock()
+	p.advance() // Skip {
+
+	body, err := p.parseCompoundList("}")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return &BraceGroup{Body: body}, nil
+}
+
+// parseCompoundRedirects parses the redirections following a compound command.
+func (p *Parser) parseCompoundRedirects(node Node) (Node, error) {
+	var redirects []Redirect
+	for {
+		p.skipBlanks()
+		if !p.current().IsRedirect() {
+			break
+		}
+		r, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		redirects = append(redirects, r...)
+	}
+
+	if len(redirects) == 0 {
+		return node, nil
+	}
+	return &RedirectedCommand{Node: node, Redirects: redirects}, nil
+}
+
+// isName returns true if s is a valid variable name.
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
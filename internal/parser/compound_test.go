@@ -0,0 +1,350 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+// parseSingleCommand parses input that must consist of exactly one command.
+func parseSingleCommand(t *testing.T, input string) Node {
+	t.Helper()
+	pipeline := parseSinglePipeline(t, input)
+	if len(pipeline.Commands) != 1 {
+		t.Fatalf("len(Commands) = %d, want 1", len(pipeline.Commands))
+	}
+	return pipeline.Commands[0]
+}
+
+func TestParseIf(t *testing.T) {
+	node := parseSingleCommand(t, "if true; then echo yes; elif false; then echo maybe; else echo no; fi")
+
+	clause, ok := node.(*IfClause)
+	if !ok {
+		t.Fatalf("node = %T, want *IfClause", node)
+	}
+	if len(clause.Cond.Items) != 1 || len(clause.Then.Items) != 1 {
+		t.Errorf("Cond = %d items, Then = %d items, want 1 and 1", len(clause.Cond.Items), len(clause.Then.Items))
+	}
+
+	elif, ok := clause.Else.(*IfClause)
+	if !ok {
+		t.Fatalf("Else = %T, want *IfClause", clause.Else)
+	}
+	if _, ok := elif.Else.(*List); !ok {
+		t.Errorf("elif Else = %T, want *List", elif.Else)
+	}
+}
+
+func TestParseIfMultiLine(t *testing.T) {
+	node := parseSingleCommand(t, "if true\nthen\n  echo a\n  echo b\nfi")
+
+	clause, ok := node.(*IfClause)
+	if !ok {
+		t.Fatalf("node = %T, want *IfClause", node)
+	}
+	if len(clause.Then.Items) != 2 {
+		t.Errorf("Then = %d items, want 2", len(clause.Then.Items))
+	}
+	if clause.Else != nil {
+		t.Errorf("Else = %#v, want nil", clause.Else)
+	}
+}
+
+func TestParseWhile(t *testing.T) {
+	tests := []struct {
+		input string
+		until bool
+	}{
+		{"while true; do echo x; done", false},
+		{"until false\ndo\n echo x\ndone", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node := parseSingleCommand(t, tt.input)
+			clause, ok := node.(*WhileClause)
+			if !ok {
+				t.Fatalf("node = %T, want *WhileClause", node)
+			}
+			if clause.Until != tt.until {
+				t.Errorf("Until = %v, want %v", clause.Until, tt.until)
+			}
+			if len(clause.Body.Items) != 1 {
+				t.Errorf("Body = %d items, want 1", len(clause.Body.Items))
+			}
+		})
+	}
+}
+
+func TestParseFor(t *testing.T) {
+	tests := []struct {
+		input string
+		words string
+		hasIn bool
+	}{
+		{"for f in *.go; do echo $f; done", "*.go", true},
+		{"for f in a \"b c\" $x\ndo echo $f\ndone", `a "b c" $x`, true},
+		{"for arg; do echo $arg; done", "", false},
+		{"for arg\ndo echo $arg; done", "", false},
+		{"for f in; do echo $f; done", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node := parseSingleCommand(t, tt.input)
+			clause, ok := node.(*ForClause)
+			if !ok {
+				t.Fatalf("node = %T, want *ForClause", node)
+			}
+			if got := JoinTokens(clause.Words); got != tt.words {
+				t.Errorf("Words = %q, want %q", got, tt.words)
+			}
+			if clause.HasIn != tt.hasIn {
+				t.Errorf("HasIn = %v, want %v", clause.HasIn, tt.hasIn)
+			}
+		})
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	node := parseSingleCommand(t, "case $x in\n  a|b) echo ab;;\n  (\"*\") echo star ;;\n  *.go)echo go\n  ;;\n  -h|--help) ;;\n  @(x|y).+(c|h)|z) ;;\n  *) echo other\nesac")
+
+	clause, ok := node.(*CaseClause)
+	if !ok {
+		t.Fatalf("node = %T, want *CaseClause", node)
+	}
+
+	want := [][]string{{"a", "b"}, {`"*"`}, {"*.go"}, {"-h", "--help"}, {"@(x|y).+(c|h)", "z"}, {"*"}}
+	if len(clause.Items) != len(want) {
+		t.Fatalf("len(Items) = %d, want %d", len(clause.Items), len(want))
+	}
+	for i, item := range clause.Items {
+		if len(item.Patterns) != len(want[i]) {
+			t.Errorf("item %d: %d patterns, want %d", i, len(item.Patterns), len(want[i]))
+			continue
+		}
+		for j, pattern := range item.Patterns {
+			if got := JoinTokens(pattern); got != want[i][j] {
+				t.Errorf("item %d pattern %d = %q, want %q", i, j, got, want[i][j])
+			}
+		}
+	}
+
+	if clause.Items[3].Body != nil {
+		t.Errorf("empty item Body = %#v, want nil", clause.Items[3].Body)
+	}
+	if clause.Items[2].Body == nil {
+		t.Error("item *.go should have a body")
+	}
+}
+
+func TestParseCompoundRedirect(t *testing.T) {
+	node := parseSingleCommand(t, "for f in a b; do echo $f; done > out.txt")
+
+	redirected, ok := node.(*RedirectedCommand)
+	if !ok {
+		t.Fatalf("node = %T, want *RedirectedCommand", node)
+	}
+	if _, ok := redirected.Node.(*ForClause); !ok {
+		t.Errorf("Node = %T, want *ForClause", redirected.Node)
+	}
+	if len(redirected.Redirects) != 1 || redirected.Redirects[0].Op != RedirectOutput {
+		t.Errorf("Redirects = %#v, want one output redirection", redirected.Redirects)
+	}
+}
+
+func TestParseCompoundInPipeline(t *testing.T) {
+	pipeline := parseSinglePipeline(t, "for f in a b; do echo $f; done | sort")
+	if len(pipeline.Commands) != 2 {
+		t.Fatalf("len(Commands) = %d, want 2", len(pipeline.Commands))
+	}
+	if _, ok := pipeline.Commands[0].(*ForClause); !ok {
+		t.Errorf("Commands[0] = %T, want *ForClause", pipeline.Commands[0])
+	}
+}
+
+func TestParseReservedWordsAsArguments(t *testing.T) {
+	tests := []string{
+		"echo if then fi",
+		"echo done",
+		"done2",
+		"echo for; echo case in",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			node, err := ParseScriptInput(input)
+			if err != nil {
+				t.Fatalf("ParseScriptInput error: %v", err)
+			}
+			list := node.(*List)
+			for _, item := range list.Items {
+				for _, cmd := range item.Node.(*Pipeline).Commands {
+					if _, ok := cmd.(*SimpleCommand); !ok {
+						t.Errorf("command = %T, want *SimpleCommand", cmd)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseCompoundIncomplete(t *testing.T) {
+	tests := []string{
+		"if true",
+		"if true; then",
+		"if true; then echo a",
+		"if true; then echo a; else",
+		"while true; do",
+		"for f in a b",
+		"for f in a b; do echo $f",
+		"case x in",
+		"case x in a) echo a;;",
+		"echo \"unterminated",
+		"echo a &&",
+		"echo a |",
+		"f() {",
+		"echo $(date",
+		"echo \"$(date",
+		"function f {\n echo a",
+		"(echo a",
+		"(cd a && make\n",
+		"{ echo a",
+		"{ echo a }",
+		"{ echo a; }; (",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrIncompleteInput) {
+				t.Errorf("error = %v, want ErrIncompleteInput", err)
+			}
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+		})
+	}
+}
+
+func TestParseCompoundErrors(t *testing.T) {
+	tests := []string{
+		"fi",
+		"done",
+		"if; then echo a; fi",
+		"if true; then fi",
+		"if true; then echo a; fi extra",
+		"while true; do done",
+		"for 1x in a; do echo; done",
+		"for f in a | b; do echo; done",
+		"case x in a echo;; esac",
+		"echo a;;",
+		"then echo a",
+		"f() echo a",
+		"f() { }",
+		"function 1f { echo a; }",
+		"}",
+		")",
+		"()",
+		"echo (a)",
+		"(echo a))",
+		"{ echo a; )",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+			if errors.Is(err, shellerrors.ErrIncompleteInput) {
+				t.Errorf("error = %v, should not be ErrIncompleteInput", err)
+			}
+		})
+	}
+}
+
+func TestParseSubshell(t *testing.T) {
+	node := parseSingleCommand(t, "(cd build && make) > log.txt")
+
+	redirected, ok := node.(*RedirectedCommand)
+	if !ok {
+		t.Fatalf("node = %T, want *RedirectedCommand", node)
+	}
+	sub, ok := redirected.Node.(*Subshell)
+	if !ok {
+		t.Fatalf("Node = %T, want *Subshell", redirected.Node)
+	}
+	if len(sub.Body.Items) != 2 || sub.Body.Items[1].Op != ListAnd {
+		t.Errorf("Body = %#v, want cd && make", sub.Body.Items)
+	}
+}
+
+func TestParseBraceGroup(t *testing.T) {
+	tests := []struct {
+		input string
+		items int
+	}{
+		{"{ echo a; }", 1},
+		{"{ echo a; echo b; }", 2},
+		{"{\n  echo a\n  echo b\n}", 2},
+		{"{ (echo a); { echo b; }; }", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node := parseSingleCommand(t, tt.input)
+			group, ok := node.(*BraceGroup)
+			if !ok {
+				t.Fatalf("node = %T, want *BraceGroup", node)
+			}
+			if len(group.Body.Items) != tt.items {
+				t.Errorf("len(Body.Items) = %d, want %d", len(group.Body.Items), tt.items)
+			}
+		})
+	}
+}
+
+func TestParseFunction(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+	}{
+		{"greet() { echo hello; }", "greet"},
+		{"greet () { echo hello; }", "greet"},
+		{"function greet { echo hello; }", "greet"},
+		{"function greet() { echo hello; }", "greet"},
+		{"greet()\n{\n  echo hello\n}", "greet"},
+		{"greet ( ) { echo hello; }", "greet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node := parseSingleCommand(t, tt.input)
+			fn, ok := node.(*FunctionDef)
+			if !ok {
+				t.Fatalf("node = %T, want *FunctionDef", node)
+			}
+			if fn.Name != tt.name {
+				t.Errorf("Name = %q, want %q", fn.Name, tt.name)
+			}
+			if _, ok := fn.Body.(*List); !ok {
+				t.Errorf("Body = %T, want *List", fn.Body)
+			}
+		})
+	}
+}
+
+func TestParseFunctionRedirect(t *testing.T) {
+	node := parseSingleCommand(t, "log() { echo \"$@\"; } >> log.txt")
+
+	fn, ok := node.(*FunctionDef)
+	if !ok {
+		t.Fatalf("node = %T, want *FunctionDef", node)
+	}
+	if _, ok := fn.Body.(*RedirectedCommand); !ok {
+		t.Errorf("Body = %T, want *RedirectedCommand", fn.Body)
+	}
+}
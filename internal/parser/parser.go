@@ -3,11 +3,14 @@ package parser
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/sdejongh/jsishell/internal/arith"
 	"github.com/sdejongh/jsishell/internal/env"
 	"github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/glob"
 	"github.com/sdejongh/jsishell/internal/lexer"
 )
 
@@ -16,6 +19,10 @@ type Parser struct {
 	tokens []lexer.Token
 	pos    int
 	env    *env.Environment
+	subst  func(command string) string // Runs command substitutions
+	dir    string                      // Directory relative globs are resolved against
+	err    error                       // First expansion error, such as a failed glob
+	blocks []block                     // Compound commands being parsed, innermost last
 }
 
 // New creates a new Parser for the given tokens.
@@ -35,8 +42,38 @@ func NewWithEnv(tokens []lexer.Token, e *env.Environment) *Parser {
 	}
 }
 
+// WithSubstitution sets the function that runs the command of a $(...) or
+// `...` substitution and returns its output. Without it, substitutions
+// expand to nothing.
+func (p *Parser) WithSubstitution(run func(command string) string) *Parser {
+	p.subst = run
+	return p
+}
+
+// WithDir sets the directory that relative glob patterns are resolved
+// against. Without it, the current directory is used.
+func (p *Parser) WithDir(dir string) *Parser {
+	p.dir = dir
+	return p
+}
+
+// Err returns the first error met while expanding words, such as a glob
+// matching no file when the failglob option is on.
+func (p *Parser) Err() error {
+	return p.err
+}
+
 // Parse parses the tokens into a Command.
 func (p *Parser) Parse() (*Command, error) {
+	cmd, err := p.parse()
+	if err == nil && p.err != nil {
+		return nil, p.err
+	}
+	return cmd, err
+}
+
+// parse parses the tokens into a Command, without checking expansion errors.
+func (p *Parser) parse() (*Command, error) {
 	cmd := NewCommand()
 
 	// Skip leading whitespace
@@ -47,14 +84,33 @@ func (p *Parser) Parse() (*Command, error) {
 		return nil, nil // Empty command
 	}
 
+	// Leading NAME=value words are assignments, not the command name
+	for p.atAssignment() {
+		cmd.Assignments = append(cmd.Assignments, p.readAssignment())
+		p.skipWhitespace()
+	}
+	switch p.current().Type {
+	case lexer.TokenEOF, lexer.TokenNewline, lexer.TokenPipe:
+		return cmd, nil
+	}
+
 	// First non-whitespace token should be the command name
 	nameTok := p.current()
 	if nameTok.Type == lexer.TokenError {
-		return nil, fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, nameTok.Literal)
+		return nil, p.errorAt(nameTok, tokenHint(nameTok), fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, nameTok.Literal))
 	}
 
-	cmd.Name = p.expandValue(nameTok)
-	p.advance()
+	if isWordPart(nameTok) {
+		// A glob or $@ may expand to several words: the first is the command
+		words, _ := p.readWord()
+		if len(words) > 0 {
+			cmd.Name = words[0]
+			p.appendArgs(cmd, words[1:], false)
+		}
+	} else {
+		cmd.Name = p.expandValue(nameTok)
+		p.advance()
+	}
 
 	// Parse remaining tokens as arguments, options, and flags
 	for {
@@ -62,48 +118,20 @@ func (p *Parser) Parse() (*Command, error) {
 		tok := p.current()
 
 		switch tok.Type {
-		case lexer.TokenEOF, lexer.TokenNewline:
+		case lexer.TokenEOF, lexer.TokenNewline, lexer.TokenPipe:
 			return cmd, nil
 
 		case lexer.TokenError:
-			return nil, fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, tok.Literal)
+			return nil, p.errorAt(tok, tokenHint(tok), fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, tok.Literal))
 
 		case lexer.TokenOption:
 			if err := p.parseOption(cmd, tok); err != nil {
 				return nil, err
 			}
 
-		case lexer.TokenWord, lexer.TokenString, lexer.TokenVariable:
-			value := p.expandValue(tok)
-			isQuoted := tok.Type == lexer.TokenString
-
-			// Expand globs for unquoted arguments containing wildcards
-			if !isQuoted && containsGlobPattern(value) {
-				expanded := expandGlob(value)
-				for _, exp := range expanded {
-					cmd.Args = append(cmd.Args, exp)
-					cmd.ArgsWithInfo = append(cmd.ArgsWithInfo, Arg{
-						Value:  exp,
-						Quoted: false,
-					})
-				}
-			} else {
-				cmd.Args = append(cmd.Args, value)
-				cmd.ArgsWithInfo = append(cmd.ArgsWithInfo, Arg{
-					Value:  value,
-					Quoted: isQuoted,
-				})
-			}
-			p.advance()
-
-		case lexer.TokenEquals:
-			// Unexpected equals - treat as argument
-			cmd.Args = append(cmd.Args, tok.Literal)
-			cmd.ArgsWithInfo = append(cmd.ArgsWithInfo, Arg{
-				Value:  tok.Literal,
-				Quoted: false,
-			})
-			p.advance()
+		case lexer.TokenWord, lexer.TokenString, lexer.TokenVariable, lexer.TokenSubstitution, lexer.TokenArithmetic, lexer.TokenEquals:
+			words, quoted := p.readWord()
+			p.appendArgs(cmd, words, quoted)
 
 		default:
 			p.advance() // Skip unknown tokens
@@ -117,6 +145,7 @@ func (p *Parser) parseOption(cmd *Command, tok lexer.Token) error {
 
 	// Check if this is --key=value format
 	if idx := strings.Index(optName, "="); idx != -1 {
+		cmd.Words = append(cmd.Words, optName)
 		key := optName[:idx]
 		value := optName[idx+1:]
 		cmd.Options[key] = value
@@ -142,19 +171,23 @@ func (p *Parser) parseOption(cmd *Command, tok lexer.Token) error {
 		p.skipWhitespace()
 		valueTok := p.current()
 
-		if valueTok.Type == lexer.TokenWord || valueTok.Type == lexer.TokenString || valueTok.Type == lexer.TokenVariable {
-			value := p.expandValue(valueTok)
+		if isWordPart(valueTok) && valueTok.Type != lexer.TokenEquals {
+			words, _ := p.readWord()
+			value := strings.Join(words, " ")
 			cmd.Options[optName] = value
 			cmd.MultiOptions[optName] = append(cmd.MultiOptions[optName], value)
-			p.advance()
+			cmd.Words = append(cmd.Words, optName+"="+value)
 		} else {
 			// -e= with no value
 			cmd.Options[optName] = ""
 			cmd.MultiOptions[optName] = append(cmd.MultiOptions[optName], "")
+			cmd.Words = append(cmd.Words, optName+"=")
 		}
 		return nil
 	}
 
+	cmd.Words = append(cmd.Words, optName)
+
 	// Short option(s): -a or -abc (combined)
 	// Expand combined short options into individual flags
 	// e.g., -al becomes -a and -l
@@ -184,33 +217,396 @@ func (p *Parser) parseLongOption(cmd *Command, optName string) error {
 		p.skipWhitespace()
 		valueTok := p.current()
 
-		if valueTok.Type == lexer.TokenWord || valueTok.Type == lexer.TokenString || valueTok.Type == lexer.TokenVariable {
-			value := p.expandValue(valueTok)
+		if isWordPart(valueTok) && valueTok.Type != lexer.TokenEquals {
+			words, _ := p.readWord()
+			value := strings.Join(words, " ")
 			cmd.Options[optName] = value
 			cmd.MultiOptions[optName] = append(cmd.MultiOptions[optName], value)
-			p.advance()
+			cmd.Words = append(cmd.Words, optName+"="+value)
 		} else {
 			// --key= with no value
 			cmd.Options[optName] = ""
 			cmd.MultiOptions[optName] = append(cmd.MultiOptions[optName], "")
+			cmd.Words = append(cmd.Words, optName+"=")
 		}
 		return nil
 	}
 
 	// Treat as a flag
 	cmd.Flags[optName] = true
+	cmd.Words = append(cmd.Words, optName)
 	return nil
 }
 
+// atAssignment returns true if the current word is NAME=value.
+func (p *Parser) atAssignment() bool {
+	tok := p.current()
+	return tok.Type == lexer.TokenWord && env.ValidName(tok.Literal) &&
+		p.peek().Type == lexer.TokenEquals && adjacent(tok, p.peek())
+}
+
+// readAssignment consumes a NAME=value word. The value is expanded into a
+// single word: it is neither split at whitespace nor globbed.
+func (p *Parser) readAssignment() Assignment {
+	a := Assignment{Name: p.current().Literal}
+	p.advance()
+	prev := p.current() // The = sign
+	p.advance()
+
+	var parts []lexer.Token
+	for tok := p.current(); isWordPart(tok) || tok.Type == lexer.TokenOption; tok = p.current() {
+		if !adjacent(prev, tok) {
+			break
+		}
+		parts = append(parts, tok)
+		prev = tok
+		p.advance()
+	}
+
+	a.Value = p.expandJoined(parts)
+	return a
+}
+
+// ExpandString expands all tokens into a single string, as the word of a
+// here-string: variables, substitutions and tilde are expanded, but the
+// result is neither split at whitespace nor globbed.
+func (p *Parser) ExpandString() string {
+	return p.expandJoined(trimWhitespace(p.tokens))
+}
+
+// expandJoined expands tokens into a single string without word splitting
+// or globbing. Whitespace between tokens is kept.
+func (p *Parser) expandJoined(tokens []lexer.Token) string {
+	var sb strings.Builder
+	for i, tok := range tokens {
+		switch {
+		case isDoubleQuoted(tok):
+			sb.WriteString(strings.Join(p.expandDoubleQuoted(tok.Value), " "))
+		case tok.Type == lexer.TokenVariable:
+			sb.WriteString(p.lookupVar(tok.Literal))
+		case tok.Type == lexer.TokenSubstitution:
+			sb.WriteString(p.substitute(tok.Literal))
+		case tok.Type == lexer.TokenArithmetic:
+			sb.WriteString(p.arithmetic(tok.Literal))
+		case tok.Type == lexer.TokenWord && i == 0:
+			sb.WriteString(p.expandTilde(tok.Literal))
+		case tok.Type == lexer.TokenEOF:
+		default:
+			sb.WriteString(tok.Literal)
+		}
+	}
+	return sb.String()
+}
+
+// appendArgs appends positional arguments to the command.
+func (p *Parser) appendArgs(cmd *Command, words []string, quoted bool) {
+	for _, value := range words {
+		cmd.Args = append(cmd.Args, value)
+		cmd.Words = append(cmd.Words, value)
+		cmd.ArgsWithInfo = append(cmd.ArgsWithInfo, Arg{
+			Value:  value,
+			Quoted: quoted,
+		})
+	}
+}
+
+// ExpandWords expands all word tokens into a list of words,
+// applying variable, tilde and glob expansion. Options are kept as words.
+func (p *Parser) ExpandWords() []string {
+	var words []string
+	for p.current().Type != lexer.TokenEOF {
+		if tok := p.current(); isWordPart(tok) || tok.Type == lexer.TokenOption {
+			expanded, _ := p.readWord()
+			words = append(words, expanded...)
+			continue
+		}
+		p.advance()
+	}
+	return words
+}
+
+// ExpandPattern expands the tokens of a pattern into a single string.
+// Variables and tilde are expanded but globs are not; wildcards in quoted
+// parts are escaped with a backslash so that they match literally.
+func (p *Parser) ExpandPattern() string {
+	var sb strings.Builder
+	for i, tok := range p.tokens {
+		switch tok.Type {
+		case lexer.TokenString:
+			value := tok.Literal
+			if isDoubleQuoted(tok) {
+				value = strings.Join(p.expandDoubleQuoted(tok.Value), " ")
+			}
+			sb.WriteString(escapePattern(value))
+		case lexer.TokenVariable:
+			sb.WriteString(p.lookupVar(tok.Literal))
+		case lexer.TokenSubstitution:
+			sb.WriteString(p.substitute(tok.Literal))
+		case lexer.TokenArithmetic:
+			sb.WriteString(p.arithmetic(tok.Literal))
+		case lexer.TokenWord:
+			if i == 0 {
+				sb.WriteString(p.expandTilde(tok.Literal))
+			} else {
+				sb.WriteString(tok.Literal)
+			}
+		case lexer.TokenOption, lexer.TokenEquals:
+			sb.WriteString(tok.Literal)
+		}
+	}
+	return sb.String()
+}
+
+// escapePattern escapes the wildcard characters of s with a backslash.
+func escapePattern(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// readWord consumes the current token together with the tokens directly
+// attached to it (as in $HOME/bin, NAME=value or "$dir"/*.go) and expands
+// them into words, starting with brace expansion. quoted is true if any
+// part of the word was quoted.
+func (p *Parser) readWord() (words []string, quoted bool) {
+	parts := p.collectWord()
+	for _, part := range parts {
+		if part.Type == lexer.TokenString {
+			quoted = true
+		}
+	}
+	for _, alt := range expandBraces(parts) {
+		words = append(words, p.expandParts(alt)...)
+	}
+	return words, quoted
+}
+
+// expandBraces performs brace expansion on the parts of a word. Only braces
+// and commas in unquoted text count; other parts are kept as they are.
+func expandBraces(parts []lexer.Token) [][]lexer.Token {
+	braces := false
+	for _, part := range parts {
+		if part.Type == lexer.TokenWord && strings.ContainsRune(part.Literal, '{') {
+			braces = true
+		}
+	}
+	if !braces {
+		return [][]lexer.Token{parts}
+	}
+
+	// Other parts are replaced with a placeholder from the private use
+	// area of Unicode, and put back into each alternative
+	var sb strings.Builder
+	for i, part := range parts {
+		if part.Type == lexer.TokenWord {
+			sb.WriteString(part.Literal)
+		} else {
+			sb.WriteRune(placeholder + rune(i))
+		}
+	}
+
+	var alts [][]lexer.Token
+	for _, text := range glob.ExpandBraces(sb.String()) {
+		if text == "" {
+			continue // Empty unquoted words are removed
+		}
+		var alt []lexer.Token
+		start := 0
+		for i, c := range text {
+			if c < placeholder || c >= placeholder+rune(len(parts)) {
+				continue
+			}
+			if i > start {
+				alt = append(alt, wordToken(text[start:i]))
+			}
+			alt = append(alt, parts[c-placeholder])
+			start = i + utf8.RuneLen(c)
+		}
+		if start < len(text) || len(alt) == 0 {
+			alt = append(alt, wordToken(text[start:]))
+		}
+		alts = append(alts, alt)
+	}
+	return alts
+}
+
+// placeholder stands for the first part of a word during brace expansion,
+// placeholder+1 for the second and so on.
+const placeholder = '\uE000'
+
+// wordToken returns an unquoted word token holding text.
+func wordToken(text string) lexer.Token {
+	return lexer.Token{Type: lexer.TokenWord, Value: text, Literal: text}
+}
+
+// expandParts expands the parts of a word into words.
+func (p *Parser) expandParts(parts []lexer.Token) (words []string) {
+	if len(parts) == 1 {
+		return p.expandWord(parts[0])
+	}
+
+	var sb strings.Builder
+	wildcard := false
+	started := false // A word is being built, even if still empty
+
+	// flush ends the current word
+	flush := func() {
+		if wildcard {
+			words = append(words, p.expandGlob(sb.String())...)
+		} else {
+			words = append(words, sb.String())
+		}
+		sb.Reset()
+		wildcard = false
+		started = false
+	}
+
+	for i, part := range parts {
+		if part.Type != lexer.TokenSubstitution {
+			started = true
+		}
+		switch part.Type {
+		case lexer.TokenString:
+			if isDoubleQuoted(part) {
+				sb.WriteString(strings.Join(p.expandDoubleQuoted(part.Value), " "))
+			} else {
+				sb.WriteString(part.Literal)
+			}
+		case lexer.TokenVariable:
+			sb.WriteString(p.lookupVar(part.Literal))
+		case lexer.TokenArithmetic:
+			sb.WriteString(p.arithmetic(part.Literal))
+		case lexer.TokenSubstitution:
+			// Whitespace in the output separates words
+			output := p.substitute(part.Literal)
+			for j, field := range strings.Fields(output) {
+				if j > 0 || (started && startsWithSpace(output)) {
+					flush()
+				}
+				if glob.HasMeta(field) {
+					wildcard = true
+				}
+				sb.WriteString(field)
+				started = true
+			}
+			if started && endsWithSpace(output) {
+				flush()
+			}
+		default:
+			value := part.Literal
+			if i == 0 {
+				value = p.expandTilde(value)
+			}
+			if glob.HasMeta(value) {
+				wildcard = true
+			}
+			sb.WriteString(value)
+		}
+	}
+
+	if started {
+		flush()
+	}
+	return words
+}
+
+// startsWithSpace returns true if s starts with whitespace.
+func startsWithSpace(s string) bool {
+	return strings.TrimLeft(s, " \t\n") != s
+}
+
+// endsWithSpace returns true if s ends with whitespace.
+func endsWithSpace(s string) bool {
+	return strings.TrimRight(s, " \t\n") != s
+}
+
+// collectWord consumes the current token and the tokens directly attached to it.
+func (p *Parser) collectWord() []lexer.Token {
+	parts := []lexer.Token{p.current()}
+	p.advance()
+	for next := p.current(); isWordPart(next) || next.Type == lexer.TokenOption; next = p.current() {
+		if !adjacent(parts[len(parts)-1], next) {
+			break
+		}
+		parts = append(parts, next)
+		p.advance()
+	}
+	return parts
+}
+
+// isWordPart returns true if the token can be part of a word.
+func isWordPart(tok lexer.Token) bool {
+	switch tok.Type {
+	case lexer.TokenWord, lexer.TokenString, lexer.TokenVariable, lexer.TokenSubstitution, lexer.TokenArithmetic, lexer.TokenEquals:
+		return true
+	default:
+		return false
+	}
+}
+
+// adjacent returns true if b directly follows a in the source, without whitespace.
+func adjacent(a, b lexer.Token) bool {
+	return a.Value != "" && a.Pos.Offset+len(a.Value) == b.Pos.Offset
+}
+
+// expandWord expands a word token into one or more words.
+// Unquoted words containing wildcards are expanded to matching paths,
+// $@ and $* produce one word per positional parameter, and the output of
+// an unquoted command substitution is split at whitespace.
+func (p *Parser) expandWord(tok lexer.Token) []string {
+	switch {
+	case tok.Type == lexer.TokenVariable && (tok.Literal == "@" || tok.Literal == "*"):
+		if p.env == nil {
+			return nil
+		}
+		return p.env.Args()
+	case tok.Type == lexer.TokenSubstitution:
+		var words []string
+		for _, field := range strings.Fields(p.substitute(tok.Literal)) {
+			if glob.HasMeta(field) {
+				words = append(words, p.expandGlob(field)...)
+			} else {
+				words = append(words, field)
+			}
+		}
+		return words
+	case isDoubleQuoted(tok):
+		return p.expandDoubleQuoted(tok.Value)
+	}
+
+	value := p.expandValue(tok)
+
+	// Expand globs for unquoted arguments containing wildcards
+	if tok.Type != lexer.TokenString && glob.HasMeta(value) {
+		return p.expandGlob(value)
+	}
+	return []string{value}
+}
+
 // expandValue expands variables and tilde in a token value.
 func (p *Parser) expandValue(tok lexer.Token) string {
 	value := tok.Literal
 
 	if tok.Type == lexer.TokenVariable {
-		if p.env != nil {
-			return p.env.Get(tok.Literal)
-		}
-		return "" // No environment, return empty
+		return p.lookupVar(tok.Literal)
+	}
+
+	if tok.Type == lexer.TokenSubstitution {
+		return p.substitute(tok.Literal)
+	}
+
+	if tok.Type == lexer.TokenArithmetic {
+		return p.arithmetic(tok.Literal)
+	}
+
+	if isDoubleQuoted(tok) {
+		return strings.Join(p.expandDoubleQuoted(tok.Value), " ")
 	}
 
 	// Expand tilde to home directory
@@ -219,6 +615,186 @@ func (p *Parser) expandValue(tok lexer.Token) string {
 	return value
 }
 
+// lookupVar returns the value of a variable or parameter. With the nounset
+// option, expanding a variable that is not set is an error.
+func (p *Parser) lookupVar(name string) string {
+	if p.env == nil {
+		return "" // No environment, return empty
+	}
+	value, ok := p.env.Lookup(name)
+	if !ok && p.err == nil && p.env.Option("nounset") {
+		p.err = fmt.Errorf("%w: %s", errors.ErrUnboundVariable, name)
+	}
+	return value
+}
+
+// substitute runs the command of a command substitution and returns its
+// output without trailing newlines.
+func (p *Parser) substitute(command string) string {
+	if p.subst == nil {
+		return "" // No executor, substitute nothing
+	}
+	return strings.TrimRight(p.subst(command), "\r\n")
+}
+
+// arithmetic evaluates the expression of an arithmetic expansion, after
+// expanding the parameters and command substitutions it contains.
+// Errors are recorded and expand to nothing.
+func (p *Parser) arithmetic(expr string) string {
+	expanded := strings.Join(p.expandText(expr, true), " ")
+	var vars arith.Variables
+	if p.env != nil {
+		vars = p.env
+	}
+	n, err := arith.Eval(expanded, vars)
+	if err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// isDoubleQuoted returns true if the token is a double-quoted string.
+func isDoubleQuoted(tok lexer.Token) bool {
+	return tok.Type == lexer.TokenString && strings.HasPrefix(tok.Value, `"`)
+}
+
+// expandDoubleQuoted processes escape sequences and expands parameters in the
+// raw text of a double-quoted string (quotes included).
+// "$@" produces one word per positional parameter; everything else yields one word.
+func (p *Parser) expandDoubleQuoted(raw string) []string {
+	inner := raw[1 : len(raw)-1]
+	if inner == "$@" && p.env != nil && len(p.env.Args()) == 0 {
+		return nil
+	}
+	return p.expandText(inner, false)
+}
+
+// ExpandHereDoc expands parameters and command substitutions in the body
+// of a here-document. A backslash only escapes \\, $, ` and newline.
+func (p *Parser) ExpandHereDoc(body string) string {
+	return strings.Join(p.expandText(body, true), " ")
+}
+
+// unescape returns the text for a backslash followed by c in a double-quoted
+// string or, if hereDoc, in the body of a here-document.
+// Returns false if the backslash is kept as is.
+func unescape(c byte, hereDoc bool) (string, bool) {
+	switch {
+	case c == '\\' || c == '$' || c == '`':
+		return string(c), true
+	case c == '\n':
+		return "", true // Line continuation
+	case hereDoc:
+		return "", false
+	case c == '"':
+		return `"`, true
+	case c == 'n':
+		return "\n", true
+	case c == 't':
+		return "\t", true
+	}
+	return "", false
+}
+
+// expandText processes escape sequences and expands parameters and command
+// substitutions in the text of a double-quoted string or here-document.
+// $@ produces one word per positional parameter; everything else yields one word.
+func (p *Parser) expandText(inner string, hereDoc bool) []string {
+	var words []string
+	var sb strings.Builder
+	for i := 0; i < len(inner); {
+		c := inner[i]
+		switch {
+		case c == '\\' && i+1 < len(inner):
+			text, ok := unescape(inner[i+1], hereDoc)
+			if !ok {
+				// Unknown escape, keep backslash
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			sb.WriteString(text)
+			i += 2
+
+		case c == '`' || strings.HasPrefix(inner[i:], "$("):
+			n := lexer.SubstitutionLength(inner[i:])
+			if n < 0 {
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			if expr, ok := lexer.ArithmeticExpr(inner[i : i+n]); ok {
+				sb.WriteString(p.arithmetic(expr))
+				i += n
+				continue
+			}
+			command := inner[i+1 : i+n-1] // `cmd`
+			if c == '$' {
+				command = inner[i+2 : i+n-1] // $(cmd)
+			}
+			sb.WriteString(p.substitute(command))
+			i += n
+
+		case c == '$':
+			name, n := scanParamName(inner[i+1:])
+			if n == 0 {
+				sb.WriteByte(c)
+				i++
+				continue
+			}
+			i += 1 + n
+
+			if name == "@" && p.env != nil {
+				for j, arg := range p.env.Args() {
+					if j > 0 {
+						words = append(words, sb.String())
+						sb.Reset()
+					}
+					sb.WriteString(arg)
+				}
+				continue
+			}
+			sb.WriteString(p.lookupVar(name))
+
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+
+	return append(words, sb.String())
+}
+
+// scanParamName scans the parameter name following a $ in s.
+// Returns the name and the number of bytes consumed, or 0 if s does not
+// start with a parameter name.
+func scanParamName(s string) (string, int) {
+	if s == "" {
+		return "", 0
+	}
+
+	if s[0] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end <= 1 {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+
+	if strings.IndexByte("#@*?$!", s[0]) >= 0 || (s[0] >= '0' && s[0] <= '9') {
+		return s[:1], 1
+	}
+
+	n := 0
+	for n < len(s) && (s[n] == '_' || (s[n] >= 'a' && s[n] <= 'z') || (s[n] >= 'A' && s[n] <= 'Z') || (n > 0 && s[n] >= '0' && s[n] <= '9')) {
+		n++
+	}
+	return s[:n], n
+}
+
 // expandTilde expands ~ to the user's home directory.
 func (p *Parser) expandTilde(value string) string {
 	if len(value) == 0 {
@@ -266,6 +842,14 @@ func (p *Parser) current() lexer.Token {
 	return p.tokens[p.pos]
 }
 
+// peek returns the token after the current one.
+func (p *Parser) peek() lexer.Token {
+	if p.pos+1 >= len(p.tokens) {
+		return lexer.Token{Type: lexer.TokenEOF}
+	}
+	return p.tokens[p.pos+1]
+}
+
 // advance moves to the next token.
 func (p *Parser) advance() {
 	p.pos++
@@ -287,7 +871,7 @@ func ParseInput(input string) (*Command, error) {
 	if cmd != nil {
 		cmd.RawInput = input
 	}
-	return cmd, err
+	return cmd, errors.WithSource(err, input)
 }
 
 // ParseInputWithEnv parses input with environment for variable expansion.
@@ -299,27 +883,24 @@ func ParseInputWithEnv(input string, e *env.Environment) (*Command, error) {
 	if cmd != nil {
 		cmd.RawInput = input
 	}
-	return cmd, err
+	return cmd, errors.WithSource(err, input)
 }
 
-// containsGlobPattern checks if a string contains glob wildcards.
-func containsGlobPattern(s string) bool {
-	for _, c := range s {
-		switch c {
-		case '*', '?', '[':
-			return true
-		}
+// expandGlob expands a glob pattern to matching file paths. If no file
+// matches, the pattern is kept as is, unless the nullglob option removes it
+// or the failglob option makes it an error.
+func (p *Parser) expandGlob(pattern string) []string {
+	if matches := glob.Glob(pattern, p.dir); len(matches) > 0 {
+		return matches
 	}
-	return false
-}
-
-// expandGlob expands a glob pattern to matching file paths.
-// If no files match, returns the original pattern (bash behavior).
-func expandGlob(pattern string) []string {
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
-		// No matches or error: return original pattern
-		return []string{pattern}
+	switch {
+	case p.env != nil && p.env.Option("failglob"):
+		if p.err == nil {
+			p.err = fmt.Errorf("%w: %s", errors.ErrNoMatch, pattern)
+		}
+		return nil
+	case p.env != nil && p.env.Option("nullglob"):
+		return nil
 	}
-	return matches
+	return []string{pattern}
 }
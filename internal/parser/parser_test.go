@@ -1,9 +1,15 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sdejongh/jsishell/internal/env"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
 	"github.com/sdejongh/jsishell/internal/lexer"
 )
 
@@ -59,6 +65,40 @@ func TestCommandGetOption(t *testing.T) {
 	}
 }
 
+func TestCommandBindValue(t *testing.T) {
+	cmd := NewCommand()
+	cmd.Words = []string{"export", "-e", "a", "--format", "zsh", "-e", "b", "out"}
+	cmd.Args = []string{"export", "a", "zsh", "b", "out"}
+	cmd.ArgsWithInfo = []Arg{{Value: "export"}, {Value: "a"}, {Value: "zsh"}, {Value: "b"}, {Value: "out"}}
+	cmd.Flags["-e"] = true
+	cmd.Flags["--format"] = true
+
+	if !cmd.BindValue("--format") || !cmd.BindValue("-e") {
+		t.Fatal("BindValue() = false for a flag followed by an argument")
+	}
+	if got := cmd.GetOption("--format"); got != "zsh" {
+		t.Errorf("GetOption(--format) = %q, want %q", got, "zsh")
+	}
+	if got := cmd.GetOptions("-e"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("GetOptions(-e) = %q, want [a b]", got)
+	}
+	if len(cmd.Args) != 2 || cmd.Args[0] != "export" || cmd.Args[1] != "out" || len(cmd.ArgsWithInfo) != 2 {
+		t.Errorf("Args = %q, want [export out]", cmd.Args)
+	}
+	if cmd.HasFlag("--format", "-e") {
+		t.Error("bound options are still flags")
+	}
+
+	// A flag at the end has no value
+	cmd = NewCommand()
+	cmd.Words = []string{"out", "--format"}
+	cmd.Args = []string{"out"}
+	cmd.Flags["--format"] = true
+	if cmd.BindValue("--format") || !cmd.HasFlag("--format") || len(cmd.Args) != 1 {
+		t.Errorf("BindValue() bound %q to a flag at the end", cmd.Args)
+	}
+}
+
 func TestCommandGetOptionOr(t *testing.T) {
 	cmd := NewCommand()
 	cmd.Options["--output"] = "/tmp/file"
@@ -350,6 +390,12 @@ func TestParseCommandWithMixedArgs(t *testing.T) {
 			t.Errorf("Flag %q not set", flag)
 		}
 	}
+
+	// Words keep the source order
+	expectedWords := []string{"--recursive", "-v", "source dir", "/dest", "--force"}
+	if strings.Join(cmd.Words, "|") != strings.Join(expectedWords, "|") {
+		t.Errorf("Words = %q, want %q", cmd.Words, expectedWords)
+	}
 }
 
 func TestParserSkipsWhitespace(t *testing.T) {
@@ -370,3 +416,399 @@ func TestParserSkipsWhitespace(t *testing.T) {
 		t.Error("Flag -a not set")
 	}
 }
+
+func TestParseDoubleQuotedExpansion(t *testing.T) {
+	e := env.New()
+	e.Set("NAME", "John")
+	e.SetPositional("script", []string{"first", "second arg"})
+
+	tests := []struct {
+		input string
+		args  []string
+	}{
+		{`echo "hello $NAME"`, []string{"hello John"}},
+		{`echo "${NAME}ny"`, []string{"Johnny"}},
+		{`echo "cost: \$5"`, []string{"cost: $5"}},
+		{`echo 'no $NAME'`, []string{"no $NAME"}},
+		{`echo "$1 and $2"`, []string{"first and second arg"}},
+		{`echo "$#"`, []string{"2"}},
+		{`echo "$@"`, []string{"first", "second arg"}},
+		{`echo "<$@>"`, []string{"<first", "second arg>"}},
+		{`echo "$*"`, []string{"first second arg"}},
+		{`echo $@`, []string{"first", "second arg"}},
+		{`echo "$"`, []string{"$"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd, err := ParseInputWithEnv(tt.input, e)
+			if err != nil {
+				t.Fatalf("ParseInputWithEnv error: %v", err)
+			}
+			if len(cmd.Args) != len(tt.args) {
+				t.Fatalf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+			for i, want := range tt.args {
+				if cmd.Args[i] != want {
+					t.Errorf("Args[%d] = %q, want %q", i, cmd.Args[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandHereDoc(t *testing.T) {
+	e := env.New()
+	e.Set("NAME", "John")
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"hello $NAME\n", "hello John\n"},
+		{"${NAME}ny\n", "Johnny\n"},
+		{"cost: \\$5\n", "cost: $5\n"},
+		{`"quoted" 'single' \n` + "\n", `"quoted" 'single' \n` + "\n"},
+		{"one \\\ntwo\n", "one two\n"},
+		{"a\\\\b\n", "a\\b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			if got := NewWithEnv(nil, e).ExpandHereDoc(tt.body); got != tt.want {
+				t.Errorf("ExpandHereDoc(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmptyPositionalExpansion(t *testing.T) {
+	e := env.New()
+	e.SetPositional("script", nil)
+
+	cmd, err := ParseInputWithEnv(`echo "$@" $@`, e)
+	if err != nil {
+		t.Fatalf("ParseInputWithEnv error: %v", err)
+	}
+	if len(cmd.Args) != 0 {
+		t.Errorf("Args = %q, want none", cmd.Args)
+	}
+}
+
+func TestParseAdjacentTokensFormOneWord(t *testing.T) {
+	e := env.New()
+	e.Set("HOME", "/home/user")
+	e.Set("DIR", "my dir")
+	e.SetPositional("script", []string{"file"})
+
+	tests := []struct {
+		input string
+		name  string
+		args  []string
+	}{
+		{"ls $HOME/bin", "ls", []string{"/home/user/bin"}},
+		{"env FOO=bar", "env", []string{"FOO=bar"}},
+		{"env PATH=$HOME/bin", "env", []string{"PATH=/home/user/bin"}},
+		{`cp $1 $1.bak`, "cp", []string{"file", "file.bak"}},
+		{`echo "$DIR"/sub`, "echo", []string{"my dir/sub"}},
+		{`echo pre"mid"'post'`, "echo", []string{"premidpost"}},
+		{`echo $HOME-1.0`, "echo", []string{"/home/user-1.0"}},
+		{"$HOME/bin/tool arg", "/home/user/bin/tool", []string{"arg"}},
+		{"echo a = b", "echo", []string{"a", "=", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd, err := ParseInputWithEnv(tt.input, e)
+			if err != nil {
+				t.Fatalf("ParseInputWithEnv error: %v", err)
+			}
+			if cmd.Name != tt.name {
+				t.Errorf("Name = %q, want %q", cmd.Name, tt.name)
+			}
+			if len(cmd.Args) != len(tt.args) {
+				t.Fatalf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+			for i, want := range tt.args {
+				if cmd.Args[i] != want {
+					t.Errorf("Args[%d] = %q, want %q", i, cmd.Args[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBraceExpansion(t *testing.T) {
+	e := env.New()
+	e.Set("X", "x y")
+
+	tests := []struct {
+		input string
+		args  []string
+	}{
+		{"echo a{b,c}d", []string{"abd", "acd"}},
+		{"echo {1..3}", []string{"1", "2", "3"}},
+		{"echo f{01..03}.txt", []string{"f01.txt", "f02.txt", "f03.txt"}},
+		{`echo "{a,b}"`, []string{"{a,b}"}},
+		{`echo "pre"{a,b}`, []string{"prea", "preb"}},
+		{`echo {"a b",c}`, []string{"a b", "c"}},
+		{"echo {$X,z}", []string{"x y", "z"}},
+		{`echo {"$X",z}`, []string{"x y", "z"}},
+		{"echo {,x}", []string{"x"}},
+		{"echo {a}", []string{"{a}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd, err := ParseInputWithEnv(tt.input, e)
+			if err != nil {
+				t.Fatalf("ParseInputWithEnv error: %v", err)
+			}
+			if strings.Join(cmd.Args, "|") != strings.Join(tt.args, "|") {
+				t.Errorf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input   string
+		options []string
+		args    []string
+		err     error
+	}{
+		{"ls *.go", nil, []string{"a.go", "b.go"}, nil},
+		{"ls !(*.go)", nil, []string{"c.txt"}, nil},
+		{"ls *.{go,txt}", nil, []string{"a.go", "b.go", "c.txt"}, nil},
+		{"ls *.md", nil, []string{"*.md"}, nil},
+		{"ls *.md *.txt", []string{"nullglob"}, []string{"c.txt"}, nil},
+		{"ls *.md *.txt", []string{"failglob"}, nil, shellerrors.ErrNoMatch},
+		{`ls "*.go"`, []string{"failglob"}, []string{"*.go"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.input, tt.options), func(t *testing.T) {
+			e := env.New()
+			for _, name := range tt.options {
+				e.SetOption(name, true)
+			}
+
+			cmd, err := NewWithEnv(lexer.New(tt.input).Tokens(), e).WithDir(dir).Parse()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if strings.Join(cmd.Args, "|") != strings.Join(tt.args, "|") {
+				t.Errorf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseOptionValueWithVariable(t *testing.T) {
+	e := env.New()
+	e.Set("HOME", "/home/user")
+
+	cmd, err := ParseInputWithEnv("build --prefix=$HOME/local", e)
+	if err != nil {
+		t.Fatalf("ParseInputWithEnv error: %v", err)
+	}
+	if got := cmd.GetOption("--prefix"); got != "/home/user/local" {
+		t.Errorf("--prefix = %q, want %q", got, "/home/user/local")
+	}
+}
+
+func TestParseCommandSubstitution(t *testing.T) {
+	// Canned outputs, with trailing newlines like real command output
+	outputs := map[string]string{
+		"date":      "Mon Jan 1\n",
+		"ls":        "a.go\nb.go\n\n",
+		"pad":       " x ",
+		"none":      "",
+		"echo $(x)": "nested\n",
+	}
+	run := func(command string) string {
+		return outputs[command]
+	}
+
+	tests := []struct {
+		input string
+		args  []string
+	}{
+		{"echo $(date)", []string{"Mon", "Jan", "1"}},
+		{`echo "$(date)"`, []string{"Mon Jan 1"}},
+		{"echo `ls`", []string{"a.go", "b.go"}},
+		{`echo "files: $(ls)."`, []string{"files: a.go\nb.go."}},
+		{"echo $(none) end", []string{"end"}},
+		{`echo "$(none)"`, []string{""}},
+		{"echo a$(pad)b", []string{"a", "x", "b"}},
+		{"echo a$(date)b", []string{"aMon", "Jan", "1b"}},
+		{`echo "$(echo $(x))"`, []string{"nested"}},
+		{`echo "\$(date)"`, []string{"$(date)"}},
+		{`echo '$(date)'`, []string{"$(date)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens := lexer.New(tt.input).Tokens()
+			cmd, err := NewWithEnv(tokens, env.New()).WithSubstitution(run).Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if strings.Join(cmd.Args, "|") != strings.Join(tt.args, "|") || len(cmd.Args) != len(tt.args) {
+				t.Errorf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseCommandSubstitutionAsName(t *testing.T) {
+	tokens := lexer.New("$(which) -v").Tokens()
+	cmd, err := New(tokens).WithSubstitution(func(string) string { return "echo hi\n" }).Parse()
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if cmd.Name != "echo" || len(cmd.Args) != 1 || cmd.Args[0] != "hi" {
+		t.Errorf("Name = %q, Args = %q, want echo [hi]", cmd.Name, cmd.Args)
+	}
+}
+
+func TestParseArithmetic(t *testing.T) {
+	e := env.New()
+	e.Set("i", "4")
+	run := func(command string) string { return "3\n" }
+
+	tests := []struct {
+		input string
+		args  []string
+	}{
+		{"echo $((1 + 2 * 3))", []string{"7"}},
+		{"echo $((i * 2)) $(($i - 1))", []string{"8", "3"}},
+		{`echo "i+1=$((i + 1))"`, []string{"i+1=5"}},
+		{"echo x$((i))y", []string{"x4y"}},
+		{"echo $(($(n) * 2))", []string{"6"}},
+		{"echo $((i++)) $i", []string{"4", "5"}},
+		{"echo $((2 ** 3 > 7 ? 1 : 0))", []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			e.Set("i", "4")
+			tokens := lexer.New(tt.input).Tokens()
+			cmd, err := NewWithEnv(tokens, e).WithSubstitution(run).Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if strings.Join(cmd.Args, "|") != strings.Join(tt.args, "|") {
+				t.Errorf("Args = %q, want %q", cmd.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseArithmeticErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"echo $((1 / 0))", shellerrors.ErrDivisionByZero},
+		{"echo $((1 +))", shellerrors.ErrInvalidSyntax},
+		{`echo "$((1.5))"`, shellerrors.ErrInvalidSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseInputWithEnv(tt.input, env.New())
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	e := env.New()
+	e.Set("HOME", "/home/user")
+	e.Set("DIR", "a b")
+	run := func(command string) string { return "x  y\n" }
+
+	tests := []struct {
+		input       string
+		assignments []Assignment
+		name        string
+		args        []string
+	}{
+		{"x=1", []Assignment{{"x", "1"}}, "", nil},
+		{"x=", []Assignment{{"x", ""}}, "", nil},
+		{"a=1 b=2", []Assignment{{"a", "1"}, {"b", "2"}}, "", nil},
+		{"x=$DIR", []Assignment{{"x", "a b"}}, "", nil},
+		{`x="$DIR"/*`, []Assignment{{"x", "a b/*"}}, "", nil},
+		{"x=$(cmd)", []Assignment{{"x", "x  y"}}, "", nil},
+		{"x=~/bin:$HOME", []Assignment{{"x", "/home/user/bin:/home/user"}}, "", nil},
+		{"x=a=b", []Assignment{{"x", "a=b"}}, "", nil},
+		{"LANG=C sort -r f", []Assignment{{"LANG", "C"}}, "sort", []string{"f"}},
+		{"echo x=1", nil, "echo", []string{"x=1"}},
+		{"2x=1", nil, "2x=1", nil},
+		{"x =1", nil, "x", []string{"=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tokens := lexer.New(tt.input).Tokens()
+			cmd, err := NewWithEnv(tokens, e).WithSubstitution(run).Parse()
+			if err != nil {
+				t.Fatalf("Parse error: %v", err)
+			}
+			if len(cmd.Assignments) != len(tt.assignments) {
+				t.Fatalf("Assignments = %v, want %v", cmd.Assignments, tt.assignments)
+			}
+			for i, a := range tt.assignments {
+				if cmd.Assignments[i] != a {
+					t.Errorf("Assignments[%d] = %v, want %v", i, cmd.Assignments[i], a)
+				}
+			}
+			if cmd.Name != tt.name || strings.Join(cmd.Args, "|") != strings.Join(tt.args, "|") {
+				t.Errorf("Name = %q, Args = %q, want %q %q", cmd.Name, cmd.Args, tt.name, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseSpecialParameters(t *testing.T) {
+	e := env.New()
+	e.SetStatus(2)
+
+	tokens := lexer.New(`echo $? "${?}:$?" $$`).Tokens()
+	cmd, err := NewWithEnv(tokens, e).Parse()
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	want := []string{"2", "2:2", e.Get("$")}
+	if strings.Join(cmd.Args, "|") != strings.Join(want, "|") {
+		t.Errorf("Args = %q, want %q", cmd.Args, want)
+	}
+}
+
+func TestParseNounset(t *testing.T) {
+	e := env.New()
+	e.Set("EMPTY", "")
+	e.SetOption("nounset", true)
+
+	if _, err := NewWithEnv(lexer.New(`echo "$EMPTY" $?`).Tokens(), e).Parse(); err != nil {
+		t.Errorf("Parse error = %v, want nil for set variables", err)
+	}
+	_, err := NewWithEnv(lexer.New(`echo $JSI_UNSET_VAR`).Tokens(), e).Parse()
+	if !errors.Is(err, shellerrors.ErrUnboundVariable) || !strings.Contains(err.Error(), "JSI_UNSET_VAR") {
+		t.Errorf("Parse error = %v, want unbound variable JSI_UNSET_VAR", err)
+	}
+}
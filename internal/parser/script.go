@@ -0,0 +1,407 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/lexer"
+)
+
+// ParseScript parses the tokens into a syntax tree.
+// Returns nil for empty input.
+func (p *Parser) ParseScript() (Node, error) {
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.current(); tok.Type != lexer.TokenEOF {
+		return nil, p.unexpected(tok)
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	return list, nil
+}
+
+// parseList parses pipelines separated by ;, newlines, && and ||.
+// The list ends at end of input, at ;; or at one of the given reserved words,
+// which is left for the caller to consume.
+func (p *Parser) parseList(terminators ...string) (*List, error) {
+	list := &List{}
+	op := ListSeq
+
+	// Start of the pipelines joined by && and ||, which & runs in the background
+	first, start := 0, 0
+
+	p.skipWhitespace()
+	for !p.atListEnd(terminators) {
+		if op == ListSeq {
+			first, start = len(list.Items), p.pos
+		}
+
+		node, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, ListItem{Op: op, Node: node})
+
+		p.skipBlanks()
+		tok := p.current()
+		switch tok.Type {
+		case lexer.TokenEOF, lexer.TokenCaseEnd, lexer.TokenRParen:
+			return list, nil
+		case lexer.TokenSemicolon, lexer.TokenNewline:
+			op = ListSeq
+		case lexer.TokenAnd:
+			op = ListAnd
+		case lexer.TokenOr:
+			op = ListOr
+		case lexer.TokenBackground:
+			list.Items = append(list.Items[:first], ListItem{Op: ListSeq, Node: &Background{
+				Node:     andOrNode(list.Items[first:]),
+				RawInput: JoinTokens(trimWhitespace(p.tokens[start:p.pos])),
+			}})
+			op = ListSeq
+		default:
+			return nil, p.unexpected(tok)
+		}
+		p.advance()
+
+		// A line break may follow any separator
+		p.skipWhitespace()
+		if op != ListSeq && p.atListEnd(terminators) {
+			return nil, p.unexpected(p.current())
+		}
+	}
+
+	return list, nil
+}
+
+// andOrNode returns the node running the given list items, which are
+// pipelines joined by && and ||.
+func andOrNode(items []ListItem) Node {
+	if len(items) == 1 {
+		return items[0].Node
+	}
+	return &List{Items: append([]ListItem(nil), items...)}
+}
+
+// atListEnd returns true if the current token ends a list.
+func (p *Parser) atListEnd(terminators []string) bool {
+	switch p.current().Type {
+	case lexer.TokenEOF, lexer.TokenCaseEnd, lexer.TokenRParen:
+		return true
+	}
+	word := p.reservedWord()
+	for _, t := range terminators {
+		if word == t {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePipeline parses one or more commands separated by pipes.
+func (p *Parser) parsePipeline() (Node, error) {
+	start := p.pos
+	first, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, p.unexpected(p.current())
+	}
+
+	pipeline := &Pipeline{Commands: []Node{first}}
+	for {
+		p.skipBlanks()
+		if p.current().Type != lexer.TokenPipe {
+			break
+		}
+		p.advance()
+
+		// A pipe may be followed by a line break before the next command
+		p.skipWhitespace()
+
+		next, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, p.unexpected(p.current())
+		}
+		pipeline.Commands = append(pipeline.Commands, next)
+	}
+
+	pipeline.RawInput = JoinTokens(trimWhitespace(p.tokens[start:p.pos]))
+	return pipeline, nil
+}
+
+// parseCommand parses a compound or simple command.
+// Returns nil if there is no command.
+func (p *Parser) parseCommand() (Node, error) {
+	p.skipBlanks()
+
+	if p.functionName() != "" {
+		return p.parseFunction()
+	}
+
+	var node Node
+	var err error
+	if p.current().Type == lexer.TokenLParen {
+		if node, err = p.parseSubshell(); err != nil {
+			return nil, err
+		}
+		return p.parseCompoundRedirects(node)
+	}
+
+	switch p.reservedWord() {
+	case "function":
+		return p.parseFunction()
+	case "":
+		sc, err := p.parseSimpleCommand()
+		if err != nil || sc == nil {
+			return nil, err
+		}
+		return sc, nil
+	case "if":
+		node, err = p.parseIf()
+	case "while", "until":
+		node, err = p.parseWhile()
+	case "for":
+		node, err = p.parseFor()
+	case "case":
+		node, err = p.parseCase()
+	case "{":
+		node, err = p.parseBraceGroup()
+	default:
+		return nil, p.unexpected(p.current())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseCompoundRedirects(node)
+}
+
+// parseSimpleCommand collects the tokens of a single command up to the next
+// operator or end of line. Returns nil if there is no command.
+func (p *Parser) parseSimpleCommand() (*SimpleCommand, error) {
+	p.skipBlanks()
+
+	sc := &SimpleCommand{}
+	start := p.pos
+	for {
+		tok := p.current()
+		if tok.Type == lexer.TokenEOF || tok.Type == lexer.TokenNewline || tok.IsOperator() {
+			break
+		}
+		if tok.Type == lexer.TokenError {
+			return nil, p.unexpected(tok)
+		}
+		if tok.IsRedirect() {
+			redirects, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			sc.Redirects = append(sc.Redirects, redirects...)
+			continue
+		}
+		sc.Tokens = append(sc.Tokens, tok)
+		p.advance()
+	}
+
+	// Drop trailing whitespace
+	sc.Tokens = trimWhitespace(sc.Tokens)
+	if len(sc.Tokens) == 0 && len(sc.Redirects) == 0 {
+		return nil, nil
+	}
+
+	sc.RawInput = JoinTokens(trimWhitespace(p.tokens[start:p.pos]))
+	return sc, nil
+}
+
+// parseRedirect parses a redirection operator and its target.
+// &> and &>> expand to a stdout redirection followed by 2>&1.
+// Here-documents come with their body, read by the lexer.
+func (p *Parser) parseRedirect() ([]Redirect, error) {
+	opTok := p.current()
+	p.advance()
+
+	op := opTok.Value
+	r := Redirect{FD: 1}
+	both := false
+
+	switch {
+	case op[0] == '&':
+		both = true
+		op = op[1:]
+	case op[0] >= '0' && op[0] <= '9':
+		r.FD = int(op[0] - '0')
+		op = op[1:]
+	case op[0] == '<':
+		r.FD = 0
+	}
+	if r.FD > 2 {
+		return nil, p.errorAt(opTok, "", fmt.Errorf("%w: unsupported file descriptor %d", errors.ErrInvalidSyntax, r.FD))
+	}
+
+	switch {
+	case opTok.Type == lexer.TokenHereDoc:
+		r.Op = RedirectHereDoc
+		r.Body = opTok.Literal
+		r.Quoted = strings.ContainsAny(op, `'"\`)
+		return []Redirect{r}, nil
+	case op == "<":
+		r.Op = RedirectInput
+	case op == "<<<":
+		r.Op = RedirectHereString
+	case op == ">>":
+		r.Op = RedirectAppend
+	case strings.HasPrefix(op, ">&"):
+		r.Op = RedirectDup
+		r.DupFD = int(op[2] - '0')
+		if r.DupFD < 1 || r.DupFD > 2 {
+			return nil, p.errorAt(opTok, "", fmt.Errorf("%w: unsupported file descriptor %d", errors.ErrInvalidSyntax, r.DupFD))
+		}
+		return []Redirect{r}, nil
+	default:
+		r.Op = RedirectOutput
+	}
+
+	// The target file name follows, optionally after whitespace
+	p.skipBlanks()
+	target := p.current()
+	switch {
+	case isWordPart(target):
+		r.Target = p.collectWord()
+	case target.Type == lexer.TokenError:
+		return nil, p.unexpected(target)
+	default:
+		return nil, p.errorAt(target, "", fmt.Errorf("%w: missing file name after %s", errors.ErrInvalidSyntax, opTok.Value))
+	}
+
+	if both {
+		return []Redirect{r, {Op: RedirectDup, FD: 2, DupFD: 1}}, nil
+	}
+	return []Redirect{r}, nil
+}
+
+// trimWhitespace removes leading and trailing whitespace tokens.
+func trimWhitespace(tokens []lexer.Token) []lexer.Token {
+	for len(tokens) > 0 && tokens[0].IsWhitespace() {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsWhitespace() {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+// skipBlanks skips whitespace tokens but not newlines, which separate commands.
+func (p *Parser) skipBlanks() {
+	for p.current().Type == lexer.TokenWhitespace {
+		p.advance()
+	}
+}
+
+// unexpected returns a syntax error for an unexpected token.
+// Errors caused by the input ending too early also wrap ErrIncompleteInput,
+// so that callers can read more lines and try again.
+func (p *Parser) unexpected(tok lexer.Token) error {
+	switch tok.Type {
+	case lexer.TokenEOF:
+		err := fmt.Errorf("%w: %w", errors.ErrInvalidSyntax, errors.ErrIncompleteInput)
+		if len(p.blocks) > 0 {
+			// Point at the compound command that is not closed
+			b := p.blocks[len(p.blocks)-1]
+			return p.errorAt(b.start, fmt.Sprintf("%s started here is missing '%s'", b.name, b.end), err)
+		}
+		return p.errorAt(tok, "", err)
+	case lexer.TokenNewline:
+		return p.errorAt(tok, "", fmt.Errorf("%w: unexpected newline", errors.ErrInvalidSyntax))
+	case lexer.TokenError:
+		switch tok.Literal {
+		case "unterminated string", "unterminated command substitution", "unterminated here-document":
+			return p.errorAt(tok, tokenHint(tok), fmt.Errorf("%w: %s (%w)", errors.ErrInvalidSyntax, tok.Literal, errors.ErrIncompleteInput))
+		}
+		return p.errorAt(tok, tokenHint(tok), fmt.Errorf("%w: %s", errors.ErrInvalidSyntax, tok.Literal))
+	default:
+		hint := ""
+		if closesBlock[tok.Value] && len(p.blocks) > 0 {
+			b := p.blocks[len(p.blocks)-1]
+			hint = fmt.Sprintf("expected '%s' to close %s on line %d", b.end, b.name, b.start.Pos.Line)
+		}
+		return p.errorAt(tok, hint, fmt.Errorf("%w: unexpected token %q", errors.ErrInvalidSyntax, tok.Value))
+	}
+}
+
+// closesBlock are the reserved words that end a compound command.
+var closesBlock = map[string]bool{"fi": true, "done": true, "esac": true, "}": true, ")": true}
+
+// errorAt returns a diagnostic for err at the position of tok.
+func (p *Parser) errorAt(tok lexer.Token, hint string, err error) error {
+	return &errors.Diagnostic{Err: err, Line: tok.Pos.Line, Column: tok.Pos.Column, Hint: hint}
+}
+
+// tokenHint returns the hint shown at the position of a lexer error token.
+func tokenHint(tok lexer.Token) string {
+	switch tok.Literal {
+	case "unterminated string":
+		if strings.HasPrefix(tok.Value, "'") {
+			return "unterminated single quote started here"
+		}
+		return "unterminated double quote started here"
+	case "unterminated command substitution":
+		return "unterminated command substitution started here"
+	case "unterminated variable":
+		return "unterminated ${ started here"
+	case "unterminated here-document":
+		return "here-document started here never reaches its delimiter line"
+	case "missing here-document delimiter":
+		return "a delimiter word must follow here"
+	}
+	return ""
+}
+
+// block is a compound command being parsed.
+type block struct {
+	start lexer.Token // Token starting the command
+	name  string      // Name of the command in errors, such as 'if'
+	end   string      // Reserved word ending the command
+}
+
+// openBlock records the start of a compound command at the current token,
+// so that errors can point at it if its end is missing.
+func (p *Parser) openBlock(end string) {
+	tok := p.current()
+	name := "'" + tok.Value + "'"
+	if end == "}" && tok.Value != "{" {
+		name = "function " + tok.Value
+	}
+	p.blocks = append(p.blocks, block{start: tok, name: name, end: end})
+}
+
+// closeBlock forgets the innermost compound command.
+func (p *Parser) closeBlock() {
+	p.blocks = p.blocks[:len(p.blocks)-1]
+}
+
+// JoinTokens reconstructs the source text of a token sequence.
+func JoinTokens(tokens []lexer.Token) string {
+	var sb strings.Builder
+	for _, tok := range tokens {
+		sb.WriteString(tok.Value)
+	}
+	return sb.String()
+}
+
+// ParseScriptInput is a convenience function that lexes and parses input
+// into a syntax tree.
+func ParseScriptInput(input string) (Node, error) {
+	l := lexer.New(input)
+	node, err := New(l.Tokens()).ParseScript()
+	return node, errors.WithSource(err, input)
+}
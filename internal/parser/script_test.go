@@ -0,0 +1,335 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+// parseSinglePipeline parses input that must consist of exactly one pipeline.
+func parseSinglePipeline(t *testing.T, input string) *Pipeline {
+	t.Helper()
+	node, err := ParseScriptInput(input)
+	if err != nil {
+		t.Fatalf("ParseScriptInput error: %v", err)
+	}
+
+	list, ok := node.(*List)
+	if !ok || len(list.Items) != 1 {
+		t.Fatalf("node = %#v, want list with one item", node)
+	}
+	pipeline, ok := list.Items[0].Node.(*Pipeline)
+	if !ok {
+		t.Fatalf("item = %T, want *Pipeline", list.Items[0].Node)
+	}
+	return pipeline
+}
+
+func TestParseScriptEmpty(t *testing.T) {
+	for _, input := range []string{"", "   ", "\n"} {
+		node, err := ParseScriptInput(input)
+		if err != nil {
+			t.Errorf("ParseScriptInput(%q) error: %v", input, err)
+		}
+		if node != nil {
+			t.Errorf("ParseScriptInput(%q) = %#v, want nil", input, node)
+		}
+	}
+}
+
+func TestParseScriptPipeline(t *testing.T) {
+	tests := []struct {
+		input string
+		raw   []string
+	}{
+		{"ls", []string{"ls"}},
+		{"ls -l | grep go", []string{"ls -l", "grep go"}},
+		{"search . \"*.go\" -r|sort|head -1", []string{`search . "*.go" -r`, "sort", "head -1"}},
+		{"echo 'a | b' | cat", []string{"echo 'a | b'", "cat"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			pipeline := parseSinglePipeline(t, tt.input)
+			if len(pipeline.Commands) != len(tt.raw) {
+				t.Fatalf("len(Commands) = %d, want %d", len(pipeline.Commands), len(tt.raw))
+			}
+			for i, want := range tt.raw {
+				sc, ok := pipeline.Commands[i].(*SimpleCommand)
+				if !ok {
+					t.Fatalf("Commands[%d] = %T, want *SimpleCommand", i, pipeline.Commands[i])
+				}
+				if sc.RawInput != want {
+					t.Errorf("Commands[%d].RawInput = %q, want %q", i, sc.RawInput, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScriptPipelineErrors(t *testing.T) {
+	for _, input := range []string{"| grep", "ls |", "ls | | wc", `echo "open | wc`} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+		})
+	}
+}
+
+func TestParseScriptHereDoc(t *testing.T) {
+	tests := []struct {
+		input  string
+		args   string
+		body   string
+		quoted bool
+	}{
+		{"cat <<EOF\n$x\nEOF", "cat", "$x\n", false},
+		{"cat <<'EOF'\n$x\nEOF", "cat", "$x\n", true},
+		{"cat <<\\EOF\n$x\nEOF", "cat", "$x\n", true},
+		{"cat -n <<\"EOF\"\n$x\nEOF", "cat -n", "$x\n", true},
+		{"<<-EOF cat\n\t$x\n\tEOF", "cat", "$x\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sc := parseSinglePipeline(t, tt.input).Commands[0].(*SimpleCommand)
+			if got := JoinTokens(trimWhitespace(sc.Tokens)); got != tt.args {
+				t.Errorf("command tokens = %q, want %q", got, tt.args)
+			}
+			if len(sc.Redirects) != 1 {
+				t.Fatalf("len(Redirects) = %d, want 1", len(sc.Redirects))
+			}
+			r := sc.Redirects[0]
+			if r.Op != RedirectHereDoc || r.FD != 0 || r.Body != tt.body || r.Quoted != tt.quoted {
+				t.Errorf("Redirect = %+v, want here-document %q (quoted %v)", r, tt.body, tt.quoted)
+			}
+		})
+	}
+}
+
+func TestParseScriptHereDocIncomplete(t *testing.T) {
+	for _, input := range []string{"cat <<EOF", "cat <<EOF\nline", "if true; then cat <<EOF\nfi\n"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrIncompleteInput) {
+				t.Errorf("error = %v, want ErrIncompleteInput", err)
+			}
+		})
+	}
+}
+
+func TestParseSimpleCommandStopsAtPipe(t *testing.T) {
+	cmd, err := ParseInput("echo hello | wc")
+	if err != nil {
+		t.Fatalf("ParseInput error: %v", err)
+	}
+	if len(cmd.Args) != 1 || cmd.Args[0] != "hello" {
+		t.Errorf("Args = %v, want [hello]", cmd.Args)
+	}
+}
+
+func TestParseScriptRedirects(t *testing.T) {
+	tests := []struct {
+		input     string
+		args      string
+		redirects []Redirect
+	}{
+		{"ls -l > listing.txt", "ls -l", []Redirect{{Op: RedirectOutput, FD: 1}}},
+		{"cat>>log", "cat", []Redirect{{Op: RedirectAppend, FD: 1}}},
+		{"sort < in.txt", "sort", []Redirect{{Op: RedirectInput, FD: 0}}},
+		{"search . x 2> errs.log", "search . x", []Redirect{{Op: RedirectOutput, FD: 2}}},
+		{"make > out 2>&1", "make", []Redirect{{Op: RedirectOutput, FD: 1}, {Op: RedirectDup, FD: 2, DupFD: 1}}},
+		{"make &> out", "make", []Redirect{{Op: RedirectOutput, FD: 1}, {Op: RedirectDup, FD: 2, DupFD: 1}}},
+		{"> out echo hi", "echo hi", []Redirect{{Op: RedirectOutput, FD: 1}}},
+		{"> empty", "", []Redirect{{Op: RedirectOutput, FD: 1}}},
+		{"cat <<< $x", "cat", []Redirect{{Op: RedirectHereString, FD: 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sc := parseSinglePipeline(t, tt.input).Commands[0].(*SimpleCommand)
+			if got := JoinTokens(trimWhitespace(sc.Tokens)); got != tt.args {
+				t.Errorf("command tokens = %q, want %q", got, tt.args)
+			}
+			if sc.RawInput != tt.input {
+				t.Errorf("RawInput = %q, want %q", sc.RawInput, tt.input)
+			}
+			if len(sc.Redirects) != len(tt.redirects) {
+				t.Fatalf("len(Redirects) = %d, want %d", len(sc.Redirects), len(tt.redirects))
+			}
+			for i, want := range tt.redirects {
+				got := sc.Redirects[i]
+				if got.Op != want.Op || got.FD != want.FD || got.DupFD != want.DupFD {
+					t.Errorf("Redirects[%d] = %+v, want %+v", i, got, want)
+				}
+				if want.Op != RedirectDup && len(got.Target) != 1 {
+					t.Errorf("Redirects[%d] has no target", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScriptRedirectErrors(t *testing.T) {
+	for _, input := range []string{"ls >", "ls > | wc", "ls 3> out", "cat <"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+		})
+	}
+}
+
+func TestParseScriptList(t *testing.T) {
+	tests := []struct {
+		input string
+		ops   []ListOp
+		raw   []string
+	}{
+		{"a ; b", []ListOp{ListSeq, ListSeq}, []string{"a", "b"}},
+		{"a;b;", []ListOp{ListSeq, ListSeq}, []string{"a", "b"}},
+		{"mkdir -p out && cp -r src out", []ListOp{ListSeq, ListAnd}, []string{"mkdir -p out", "cp -r src out"}},
+		{"a || b && c", []ListOp{ListSeq, ListOr, ListAnd}, []string{"a", "b", "c"}},
+		{"a &&\nb", []ListOp{ListSeq, ListAnd}, []string{"a", "b"}},
+		{"a\nb\n\nc\n", []ListOp{ListSeq, ListSeq, ListSeq}, []string{"a", "b", "c"}},
+		{"a&&b||c", []ListOp{ListSeq, ListAnd, ListOr}, []string{"a", "b", "c"}},
+		{"echo a&b", []ListOp{ListSeq}, []string{"echo a&b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := ParseScriptInput(tt.input)
+			if err != nil {
+				t.Fatalf("ParseScriptInput error: %v", err)
+			}
+
+			list := node.(*List)
+			if len(list.Items) != len(tt.ops) {
+				t.Fatalf("len(Items) = %d, want %d", len(list.Items), len(tt.ops))
+			}
+			for i, item := range list.Items {
+				if item.Op != tt.ops[i] {
+					t.Errorf("Items[%d].Op = %v, want %v", i, item.Op, tt.ops[i])
+				}
+				sc := item.Node.(*Pipeline).Commands[0].(*SimpleCommand)
+				if sc.RawInput != tt.raw[i] {
+					t.Errorf("Items[%d].RawInput = %q, want %q", i, sc.RawInput, tt.raw[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseScriptBackground(t *testing.T) {
+	tests := []struct {
+		input string
+		raw   []string // RawInput of the background items, "" for others
+	}{
+		{"make &", []string{"make"}},
+		{"a & b", []string{"a", ""}},
+		{"a; b && c & d", []string{"", "b && c", ""}},
+		{"a | b &\nc &", []string{"a | b", "c"}},
+		{"while true; do sleep 1 & done", []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := ParseScriptInput(tt.input)
+			if err != nil {
+				t.Fatalf("ParseScriptInput error: %v", err)
+			}
+
+			list := node.(*List)
+			if len(list.Items) != len(tt.raw) {
+				t.Fatalf("len(Items) = %d, want %d", len(list.Items), len(tt.raw))
+			}
+			for i, item := range list.Items {
+				bg, ok := item.Node.(*Background)
+				if tt.raw[i] == "" {
+					if ok {
+						t.Errorf("Items[%d] should not run in the background", i)
+					}
+					continue
+				}
+				if !ok {
+					t.Fatalf("Items[%d] = %T, want *Background", i, item.Node)
+				}
+				if item.Op != ListSeq {
+					t.Errorf("Items[%d].Op = %v, want ListSeq", i, item.Op)
+				}
+				if bg.RawInput != tt.raw[i] {
+					t.Errorf("Items[%d].RawInput = %q, want %q", i, bg.RawInput, tt.raw[i])
+				}
+			}
+		})
+	}
+
+	// The commands joined by && run together in the background
+	node, _ := ParseScriptInput("a && b &")
+	bg := node.(*List).Items[0].Node.(*Background)
+	if inner, ok := bg.Node.(*List); !ok || len(inner.Items) != 2 || inner.Items[1].Op != ListAnd {
+		t.Errorf("Background.Node = %#v, want the list a && b", bg.Node)
+	}
+
+	// Background jobs within a loop body
+	node, _ = ParseScriptInput("while true; do sleep 1 & done")
+	loop := node.(*List).Items[0].Node.(*Pipeline).Commands[0].(*WhileClause)
+	if _, ok := loop.Body.Items[0].Node.(*Background); !ok {
+		t.Errorf("loop body = %T, want *Background", loop.Body.Items[0].Node)
+	}
+}
+
+func TestParseScriptListErrors(t *testing.T) {
+	for _, input := range []string{"; a", "a ;; b", "a &&", "|| b", "a && ; b", "& a", "a & & b", "a && &"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseScriptInput(input)
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+		})
+	}
+}
+
+func TestParseScriptDiagnostics(t *testing.T) {
+	tests := []struct {
+		input  string
+		line   int
+		column int
+		hint   string
+	}{
+		{"echo 'abc", 1, 6, "unterminated single quote started here"},
+		{"echo ok\necho \"abc", 2, 6, "unterminated double quote started here"},
+		{"echo $(date", 1, 6, "unterminated command substitution started here"},
+		{"echo a | | b", 1, 10, ""},
+		{"if true; then\n  echo x\n", 1, 1, "'if' started here is missing 'fi'"},
+		{"for x in a; do\n  if true; then echo\n  done", 3, 3, "expected 'fi' to close 'if' on line 2"},
+		{"f() {\n  echo", 1, 1, "function f started here is missing '}'"},
+		{"echo a; (cd b\n  make", 1, 9, "'(' started here is missing ')'"},
+		{"{ echo a; ) }", 1, 11, "expected '}' to close '{' on line 1"},
+		{"echo a >", 1, 9, ""},
+		{"for 1x in a; do echo; done", 1, 5, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseScriptInput(tt.input)
+			var d *shellerrors.Diagnostic
+			if !errors.As(err, &d) {
+				t.Fatalf("error = %v, want a diagnostic", err)
+			}
+			if !errors.Is(err, shellerrors.ErrInvalidSyntax) {
+				t.Errorf("error = %v, want ErrInvalidSyntax", err)
+			}
+			if d.Line != tt.line || d.Column != tt.column || d.Hint != tt.hint {
+				t.Errorf("diagnostic at %d:%d %q, want %d:%d %q", d.Line, d.Column, d.Hint, tt.line, tt.column, tt.hint)
+			}
+			if d.Source != tt.input {
+				t.Errorf("Source = %q, want the input", d.Source)
+			}
+		})
+	}
+}
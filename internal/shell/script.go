@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sdejongh/jsishell/internal/config"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+)
+
+// RunFile executes a script file with the given positional parameters.
+// $0 is set to the script path. The exit code of the last command is
+// available through ExitCode. Returns an error only if the script cannot be read.
+func (s *Shell) RunFile(path string, args []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", shellerrors.ErrFileNotFound, path)
+		}
+		if os.IsPermission(err) {
+			return fmt.Errorf("%w: %s", shellerrors.ErrPermissionDenied, path)
+		}
+		return err
+	}
+
+	s.env.SetPositional(path, args)
+	s.scriptName = path
+	s.runScript(string(data))
+	return nil
+}
+
+// RunCommand executes a command string as given to "jsishell -c".
+// name becomes $0 and args the positional parameters.
+func (s *Shell) RunCommand(command, name string, args []string) error {
+	s.env.SetPositional(name, args)
+	s.runScript(command)
+	return nil
+}
+
+// LoadStartupFiles runs the startup files in the shell's own state, so that
+// the aliases, functions and variables they define remain: the login
+// profiles if profile is true, then the rc scripts if rc is true. System-wide
+// files run before those of the configuration directory; missing files are
+// skipped. Returns false if a file ran exit, in which case the shell should
+// exit with ExitCode.
+func (s *Shell) LoadStartupFiles(profile, rc bool) bool {
+	var files []string
+	if profile {
+		files = append(files, config.StartupFiles(config.ProfileFile)...)
+	}
+	if rc {
+		files = append(files, config.StartupFiles(config.RCFile)...)
+	}
+
+	for _, path := range files {
+		if !s.runStartupFile(path) {
+			s.runExitTrap()
+			return false
+		}
+	}
+
+	// The files may have defined functions or aliases
+	if s.completer != nil {
+		s.completer.SetCommandDefs(s.commandDefs())
+	}
+	return true
+}
+
+// runStartupFile runs one startup file, naming it in syntax errors.
+// Returns false if the file ran exit.
+func (s *Shell) runStartupFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(s.stderr, "Warning: failed to read %s: %v\n", path, err)
+		}
+		return true
+	}
+
+	scriptName := s.scriptName
+	s.scriptName = path
+	defer func() { s.scriptName = scriptName }()
+	return s.runLine(string(data))
+}
+
+// runScript executes the complete source of a script, then its EXIT trap.
+// A leading "#!" line is a comment and is skipped by the lexer.
+// Signals without a trap end the script with status 128+signal.
+func (s *Shell) runScript(source string) {
+	s.running = true
+	s.inScript = true
+	defer func() { s.running, s.inScript = false, false }()
+
+	s.setupSignals()
+	defer s.cleanupSignals()
+
+	s.runLine(source)
+	s.runExitTrap()
+}
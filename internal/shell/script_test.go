@@ -0,0 +1,416 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sdejongh/jsishell/internal/builtins"
+	shellerrors "github.com/sdejongh/jsishell/internal/errors"
+	"github.com/sdejongh/jsishell/internal/executor"
+)
+
+// newScriptTestShell creates a shell with all builtins writing to the given buffers.
+func newScriptTestShell(stdin string, stdout, stderr *bytes.Buffer) *Shell {
+	reg := builtins.NewRegistry()
+	builtins.RegisterAll(reg)
+
+	s := New(
+		WithStdin(strings.NewReader(stdin)),
+		WithStdout(stdout),
+		WithStderr(stderr),
+		WithPrompt("PROMPT> "),
+	)
+	s.executor = executor.New(
+		executor.WithRegistry(reg),
+		executor.WithEnv(s.env),
+		executor.WithStdout(stdout),
+		executor.WithStderr(stderr),
+	)
+	return s
+}
+
+func TestShellRunFile(t *testing.T) {
+	script := `#!/usr/bin/env jsishell
+# Greets its arguments
+echo "$0 has $# args"
+echo first=$1 rest="$2"
+exit 7
+echo unreachable
+`
+	path := filepath.Join(t.TempDir(), "greet.jsi")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	if err := s.RunFile(path, []string{"a", "b c"}); err != nil {
+		t.Fatalf("RunFile error: %v", err)
+	}
+
+	want := path + " has 2 args\nfirst=a rest=b c\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if s.ExitCode() != 7 {
+		t.Errorf("ExitCode() = %d, want 7", s.ExitCode())
+	}
+}
+
+func TestShellRunFileNotFound(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	err := s.RunFile(filepath.Join(t.TempDir(), "missing.jsi"), nil)
+	if !errors.Is(err, shellerrors.ErrFileNotFound) {
+		t.Errorf("error = %v, want ErrFileNotFound", err)
+	}
+}
+
+func TestShellRunFileReportsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsi")
+	if err := os.WriteFile(path, []byte("nonexistentcommand12345\necho after\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	if err := s.RunFile(path, nil); err != nil {
+		t.Fatalf("RunFile error: %v", err)
+	}
+	if stdout.String() != "after\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "after\n")
+	}
+	if !strings.Contains(stderr.String(), "command not found") {
+		t.Errorf("stderr = %q, want command not found error", stderr.String())
+	}
+	if s.ExitCode() != 0 {
+		t.Errorf("ExitCode() = %d, want 0", s.ExitCode())
+	}
+}
+
+func TestShellRunFileNounset(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		stdout string
+		code   int
+	}{
+		{"command", "set -u\necho $NOPE\necho after\n", "", 1},
+		{"loop", "set -u\nfor x in a b; do echo $x $NOPE; done\necho after\n", "", 1},
+		{"condition", "set -u\nif [ -n \"$NOPE\" ]; then echo yes; fi\necho after\n", "", 1},
+		{"function", "set -u\nf() { echo $NOPE; echo in f; }\nf\necho after\n", "", 1},
+		{"subshell", "set -u\n(echo $NOPE; echo never)\necho after $?\n", "after 1\n", 0},
+		{"exit trap", "trap 'echo bye' EXIT\nset -u\necho $NOPE\n", "bye\n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nounset.jsi")
+			if err := os.WriteFile(path, []byte(tt.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			s := newScriptTestShell("", &stdout, &stderr)
+
+			if err := s.RunFile(path, nil); err != nil {
+				t.Fatalf("RunFile error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if !strings.Contains(stderr.String(), "unbound variable: NOPE") {
+				t.Errorf("stderr = %q, want unbound variable error", stderr.String())
+			}
+			if s.ExitCode() != tt.code {
+				t.Errorf("ExitCode() = %d, want %d", s.ExitCode(), tt.code)
+			}
+		})
+	}
+}
+
+func TestShellRunFileSyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.jsi")
+	script := "echo start\nfor x in a b; do\n  echo \"$x\ndone\n"
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	if err := s.RunFile(path, nil); err != nil {
+		t.Fatalf("RunFile error: %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("stdout = %q, want nothing to run", stdout.String())
+	}
+	want := "error: " + path + ":3:8: invalid syntax: unterminated string (unexpected end of input)\n" +
+		"  3 |   echo \"$x\n" +
+		"    |        ^ unterminated double quote started here\n"
+	if stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestShellRunCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	if err := s.RunCommand(`echo "$0:$1" && echo $#`, "myname", []string{"x", "y"}); err != nil {
+		t.Fatalf("RunCommand error: %v", err)
+	}
+	if stdout.String() != "myname:x\n2\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "myname:x\n2\n")
+	}
+}
+
+func TestShellExitTrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		stdout  string
+		code    int
+	}{
+		{"end of script", "trap 'echo bye' EXIT; echo hi", "hi\nbye\n", 0},
+		{"exit", "trap 'echo bye $?' EXIT; exit 4; echo never", "bye 4\n", 4},
+		{"exit in trap", "trap 'exit 3' EXIT; true", "", 3},
+		{"reset", "trap 'echo bye' EXIT; trap - EXIT", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			s := newScriptTestShell("", &stdout, &stderr)
+
+			if err := s.RunCommand(tt.command, "jsishell", nil); err != nil {
+				t.Fatalf("RunCommand error: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if s.ExitCode() != tt.code {
+				t.Errorf("ExitCode() = %d, want %d", s.ExitCode(), tt.code)
+			}
+		})
+	}
+}
+
+func TestShellRunHook(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+
+	if !s.runHook("chpwd", "") {
+		t.Error("runHook() = false for an unset hook")
+	}
+	if !s.runHook("preexec", `echo "> $1"`, "ls -l") || stdout.String() != "> ls -l\n" {
+		t.Errorf("preexec stdout = %q, want %q", stdout.String(), "> ls -l\n")
+	}
+	if s.runHook("precmd", "exit 2") || s.ExitCode() != 2 {
+		t.Errorf("runHook(exit 2) did not request exit, ExitCode() = %d", s.ExitCode())
+	}
+}
+
+func TestShellExpandHistory(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+	s.history.Add("echo one two")
+
+	// Only interactive shells turn histexpand on
+	if line, run := s.expandHistory("echo !$"); !run || line != "echo !$" {
+		t.Errorf("expandHistory() = %q, %v without histexpand", line, run)
+	}
+
+	s.env.SetOption("histexpand", true)
+	if line, run := s.expandHistory("echo !$"); !run || line != "echo two" || stdout.String() != "echo two\n" {
+		t.Errorf("expandHistory() = %q, %v, stdout %q, want the expanded line shown", line, run, stdout.String())
+	}
+
+	stdout.Reset()
+	if _, run := s.expandHistory("!!:s/one/1/:p"); run || stdout.String() != "echo 1 two\n" {
+		t.Errorf("expandHistory(:p) ran = %v, stdout %q", run, stdout.String())
+	}
+	if last, _ := s.history.Last(); last.Command != "echo 1 two" {
+		t.Errorf("history last = %q, want the printed line", last.Command)
+	}
+
+	if _, run := s.expandHistory("!nothing"); run || !strings.Contains(stderr.String(), "event not found: !nothing") {
+		t.Errorf("expandHistory(!nothing) ran = %v, stderr %q", run, stderr.String())
+	}
+}
+
+func TestShellNonInteractiveHasNoPrompt(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("echo one\necho two", &stdout, &stderr)
+	s.running = true
+
+	if err := s.runNonInteractive(); err != nil {
+		t.Fatalf("runNonInteractive error: %v", err)
+	}
+	if stdout.String() != "one\ntwo\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "one\ntwo\n")
+	}
+}
+
+func TestShellNonInteractiveMultiLine(t *testing.T) {
+	input := "for x in a b\ndo\n  if true; then\n    echo \"$x\n!\"\n  fi\ndone\necho after\n"
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell(input, &stdout, &stderr)
+	s.running = true
+
+	if err := s.runNonInteractive(); err != nil {
+		t.Fatalf("runNonInteractive error: %v", err)
+	}
+	if stdout.String() != "a\n!\nb\n!\nafter\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "a\n!\nb\n!\nafter\n")
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want empty", stderr.String())
+	}
+}
+
+func TestShellNonInteractiveIncompleteAtEOF(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("echo before\nif true; then\n  echo never\n", &stdout, &stderr)
+	s.running = true
+
+	if err := s.runNonInteractive(); err != nil {
+		t.Fatalf("runNonInteractive error: %v", err)
+	}
+	if stdout.String() != "before\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "before\n")
+	}
+	if !strings.Contains(stderr.String(), "unexpected end of input") {
+		t.Errorf("stderr = %q, want syntax error", stderr.String())
+	}
+}
+
+func TestShellNonInteractiveMultiLineSubstitution(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("echo \"[$(echo a\necho b)]\"\n", &stdout, &stderr)
+	s.running = true
+
+	if err := s.runNonInteractive(); err != nil {
+		t.Fatalf("runNonInteractive error: %v", err)
+	}
+	if stdout.String() != "[a\nb]\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "[a\nb]\n")
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want empty", stderr.String())
+	}
+}
+
+func TestShellNonInteractiveLineContinuation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("echo one \\\n  two \\\n  three\necho \"a\\\nb\"\n", &stdout, &stderr)
+	s.running = true
+
+	if err := s.runNonInteractive(); err != nil {
+		t.Fatalf("runNonInteractive error: %v", err)
+	}
+	if stdout.String() != "one two three\nab\n" {
+		t.Errorf("stdout = %q, want %q", stdout.String(), "one two three\nab\n")
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want empty", stderr.String())
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"echo hi", false},
+		{"echo 'hi", true},
+		{"if true; then", true},
+		{"echo a \\", true},
+		{"dir C:\\", false},
+		{"for x in a b\ndo\n  echo $x\ndone", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := isIncomplete(tt.input); got != tt.want {
+				t.Errorf("isIncomplete(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellLoadStartupFiles(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	dir := filepath.Join(configHome, "jsishell")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"profile.jsi": "TEAM=core\n",
+		"rc.jsi":      "alias hi='echo hi'\ngreet() { echo \"hello $TEAM\"; }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		profile, rc bool
+		want        string
+	}{
+		{"profile and rc", true, true, "hello core\nhi\n"},
+		{"rc only", false, true, "hello \nhi\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			s := newScriptTestShell("", &stdout, &stderr)
+			s.env.Unset("TEAM")
+
+			if !s.LoadStartupFiles(tt.profile, tt.rc) {
+				t.Fatal("LoadStartupFiles() = false, want true")
+			}
+			if err := s.RunCommand("greet; hi", "jsishell", nil); err != nil {
+				t.Fatalf("RunCommand error: %v", err)
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.want)
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("stderr = %q, want empty", stderr.String())
+			}
+		})
+	}
+}
+
+func TestShellLoadStartupFilesExit(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	dir := filepath.Join(configHome, "jsishell")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rc.jsi"), []byte("exit 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	s := newScriptTestShell("", &stdout, &stderr)
+	if s.LoadStartupFiles(false, true) {
+		t.Error("LoadStartupFiles() = true, want false after exit")
+	}
+	if s.ExitCode() != 4 {
+		t.Errorf("ExitCode() = %d, want 4", s.ExitCode())
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sdejongh/jsishell/internal/builtins"
 	"github.com/sdejongh/jsishell/internal/completion"
@@ -18,6 +20,9 @@ import (
 	shellerrors "github.com/sdejongh/jsishell/internal/errors"
 	"github.com/sdejongh/jsishell/internal/executor"
 	"github.com/sdejongh/jsishell/internal/history"
+	"github.com/sdejongh/jsishell/internal/jobs"
+	"github.com/sdejongh/jsishell/internal/lexer"
+	"github.com/sdejongh/jsishell/internal/parser"
 	"github.com/sdejongh/jsishell/internal/terminal"
 )
 
@@ -26,23 +31,33 @@ type Shell struct {
 	executor       *executor.Executor
 	terminal       *terminal.Terminal
 	lineEditor     *terminal.LineEditor
+	completer      *completion.Completer
 	env            *env.Environment
 	config         *config.Config
 	history        *history.History
+	jobs           *jobs.Table // Background and stopped jobs
 	stdin          io.Reader
 	stdout         io.Writer
 	stderr         io.Writer
 	promptExpander *terminal.PromptExpander
 
-	promptFormat string // Prompt format string (with %d, %u, etc.)
-	running      bool
-	exitCode     int
-	interactive  bool // true if using LineEditor
+	promptFormat       string // Prompt format string (with %d, %u, etc.)
+	continuationFormat string // Prompt format of the continuation lines of a multi-line command
+	running            bool
+	exitCode           int
+	interactive        bool   // true if using LineEditor
+	prompting          bool   // Reading commands at the prompt, with job control
+	historyErr         string // Last history file error reported, not repeated
+	inScript           bool   // Running a script or -c command, which signals end
+	scriptName         string // Script file being run, named in syntax errors
 
 	// Signal handling
 	sigChan chan os.Signal
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	cmdMu     sync.Mutex
+	cmdCancel context.CancelFunc // Cancels the running command on interrupt
 }
 
 // Option is a functional option for configuring the Shell.
@@ -50,7 +65,8 @@ type Option func(*Shell)
 
 // WithPrompt sets the shell prompt format string.
 // Supports variables: %d (cwd), %D (cwd basename), %~ (cwd with ~),
-// %u (username), %h (hostname), %t (time), %T (time with seconds), %n (newline), %% (literal %)
+// %u (username), %h (hostname), %t (time), %T (time with seconds), %n (newline),
+// %? (exit status of the last command), %% (literal %)
 func WithPrompt(prompt string) Option {
 	return func(s *Shell) {
 		s.promptFormat = prompt
@@ -104,16 +120,16 @@ func New(opts ...Option) *Shell {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &Shell{
-		stdin:          os.Stdin,
-		stdout:         os.Stdout,
-		stderr:         os.Stderr,
-		promptFormat:   "", // Will be set by config or options
-		promptExpander: terminal.NewPromptExpander(),
-		running:        false,
-		exitCode:       0,
-		sigChan:        make(chan os.Signal, 1),
-		ctx:            ctx,
-		cancel:         cancel,
+		stdin:              os.Stdin,
+		stdout:             os.Stdout,
+		stderr:             os.Stderr,
+		promptFormat:       "", // Will be set by config or options
+		continuationFormat: config.DefaultContinuationPrompt,
+		promptExpander:     terminal.NewPromptExpander(),
+		running:            false,
+		exitCode:           0,
+		ctx:                ctx,
+		cancel:             cancel,
 	}
 
 	// Load configuration first
@@ -153,6 +169,8 @@ func New(opts ...Option) *Shell {
 	if s.terminal.IsTerminal() {
 		s.lineEditor = terminal.NewLineEditor(s.terminal)
 		s.lineEditor.SetPrompt(s.expandedPrompt())
+		s.lineEditor.SetContinuationPrompt(s.expandPrompt(s.continuationFormat))
+		s.lineEditor.SetIncompleteFunc(isIncomplete)
 		s.interactive = true
 
 		// Setup color scheme for ghost text
@@ -191,6 +209,13 @@ func New(opts ...Option) *Shell {
 		)
 	}
 
+	s.jobs = s.executor.Jobs()
+
+	// Define the aliases of the configuration
+	if s.config != nil {
+		s.applyAliases(nil, s.config.Aliases)
+	}
+
 	// Setup reload callback
 	builtins.SetReloadCallback(s.onConfigReload)
 
@@ -199,8 +224,8 @@ func New(opts ...Option) *Shell {
 
 	// Setup completion if interactive
 	if s.interactive && s.lineEditor != nil {
-		completer := s.createCompleter()
-		s.lineEditor.SetCompleter(completer)
+		s.completer = s.createCompleter()
+		s.lineEditor.SetCompleter(s.completer)
 	}
 
 	// Initialize history
@@ -209,13 +234,30 @@ func New(opts ...Option) *Shell {
 	return s
 }
 
+// StartInteractive sets up what only applies to the commands typed at the
+// prompt, in an interactive shell: job control, foreground jobs getting the
+// terminal so that Ctrl+C and Ctrl+Z reach them, and the expansion of history
+// references such as !!. Scripts and -c commands run without them, so that
+// a signal ends the whole script. It should be called before the rc files
+// run, so that they can change the histexpand option; Run calls it otherwise.
+func (s *Shell) StartInteractive() {
+	if !s.interactive || s.prompting {
+		return
+	}
+	s.prompting = true
+	s.jobs.SetTerminal(s.terminal.Fd())
+	s.env.SetOption("histexpand", true)
+}
+
 // Run starts the shell REPL loop. Blocks until exit.
 func (s *Shell) Run() error {
 	s.running = true
+	s.StartInteractive()
 
 	// Setup signal handling
 	s.setupSignals()
 	defer s.cleanupSignals()
+	defer s.runExitTrap()
 
 	// Use interactive mode if available
 	if s.interactive {
@@ -230,13 +272,32 @@ func (s *Shell) runInteractive() error {
 	// Ensure history is saved on exit
 	defer s.saveHistory()
 
+	if !s.runHook("on_start", s.hooks().OnStart) {
+		return nil
+	}
+
 	for s.running {
 		// Update prompt before each read (to reflect cwd changes, time, etc.)
+		s.notifyJobs()
+		s.syncHistory()
+		if !s.runHook("precmd", s.hooks().Precmd) {
+			break
+		}
 		s.lineEditor.SetPrompt(s.expandedPrompt())
+		s.lineEditor.SetContinuationPrompt(s.expandPrompt(s.continuationFormat))
 
-		// Read line with editor
-		line, err := s.lineEditor.ReadLine()
+		// Read the command with the editor. Incomplete commands (unclosed
+		// quote or block, trailing backslash) continue on new lines of the
+		// editor.
+		input, err := s.lineEditor.ReadLine()
 		if err != nil {
+			if errors.Is(err, shellerrors.ErrInterrupted) {
+				// Ctrl+C abandons the command being typed and runs the INT trap
+				if s.executor.Traps().Catch("INT") && !s.runPendingTraps() {
+					break
+				}
+				continue
+			}
 			if err == io.EOF {
 				fmt.Fprintln(s.stdout)
 				break
@@ -245,53 +306,100 @@ func (s *Shell) runInteractive() error {
 		}
 
 		// Trim whitespace
-		line = strings.TrimSpace(line)
+		input = strings.TrimSpace(input)
 
 		// Skip empty lines
-		if line == "" {
+		if input == "" {
+			continue
+		}
+
+		input, run := s.expandHistory(input)
+		if !run {
 			continue
 		}
 
 		// Add to history before execution
 		if s.history != nil {
-			s.history.Add(line)
+			s.history.Add(input)
 		}
 
 		// Execute command
-		exitCode, err := s.Execute(line)
-		s.exitCode = exitCode
-
-		// Check for exit command
-		var exitErr builtins.ExitCode
-		if errors.As(err, &exitErr) {
-			s.exitCode = exitErr.Code
+		if !s.runHook("preexec", s.hooks().Preexec, input) {
+			break
+		}
+		workDir := s.executor.WorkDir()
+		start := time.Now()
+		ok := s.runLine(input)
+		if s.history != nil {
+			s.history.Complete(workDir, s.exitCode, time.Since(start))
+			s.saveHistory()
+		}
+		if !ok {
+			break
+		}
+		if s.executor.WorkDir() != workDir && !s.runHook("chpwd", s.hooks().Chpwd) {
 			break
 		}
 
-		// Display error if any (but not for exit)
-		if err != nil {
-			fmt.Fprintf(s.stderr, "error: %v\n", err)
+		// The command may have defined functions or aliases
+		if s.completer != nil {
+			s.completer.SetCommandDefs(s.commandDefs())
 		}
 	}
 
 	return nil
 }
 
+// expandHistory replaces the history references of a typed line, such as
+// !! or !$, with the histexpand option, and displays the expanded line.
+// Returns false if the line must not run: the expansion failed, or it
+// must be checked first (histverify option, :p modifier).
+func (s *Shell) expandHistory(input string) (string, bool) {
+	if s.history == nil || !s.env.Option("histexpand") {
+		return input, true
+	}
+	expansion, err := s.history.Expand(input)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "error: %v\n", err)
+		return "", false
+	}
+	if !expansion.Expanded {
+		return input, true
+	}
+
+	switch {
+	case expansion.PrintOnly:
+		fmt.Fprintln(s.stdout, expansion.Line)
+		s.history.Add(expansion.Line)
+		s.history.Complete(s.executor.WorkDir(), 0, 0)
+		s.saveHistory()
+		return "", false
+	case s.env.Option("histverify"):
+		// The expanded line is edited again before it runs
+		s.lineEditor.SetInitialText(expansion.Line)
+		return "", false
+	}
+	fmt.Fprintln(s.stdout, expansion.Line)
+	return expansion.Line, true
+}
+
 // runNonInteractive runs the shell without line editing (pipe/script mode).
 func (s *Shell) runNonInteractive() error {
 	// Create a line reader
 	reader := bufio.NewReader(s.stdin)
 
-	for s.running {
-		// Display prompt
-		fmt.Fprint(s.stdout, s.expandedPrompt())
+	// Lines of an incomplete command (unclosed quote or block)
+	pending := ""
 
-		// Read line
+	for s.running {
+		// Read line (no prompt: input does not come from a terminal)
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			if err == io.EOF {
-				// End of input (e.g., Ctrl+D)
-				fmt.Fprintln(s.stdout)
+				// End of input: run what is left, reporting incomplete commands
+				if input := pending + line; strings.TrimSpace(input) != "" {
+					s.runLine(input)
+				}
 				break
 			}
 			// Other error
@@ -302,37 +410,161 @@ func (s *Shell) runNonInteractive() error {
 			return err
 		}
 
+		input := pending + line
+		if isIncomplete(input) {
+			pending = input
+			continue
+		}
+		pending = ""
+
 		// Trim whitespace
-		line = strings.TrimSpace(line)
+		input = strings.TrimSpace(input)
 
 		// Skip empty lines
-		if line == "" {
+		if input == "" {
 			continue
 		}
 
-		// Execute command
-		exitCode, err := s.Execute(line)
-		s.exitCode = exitCode
-
-		// Check for exit command
-		var exitErr builtins.ExitCode
-		if errors.As(err, &exitErr) {
-			s.exitCode = exitErr.Code
+		if !s.runLine(input) {
 			break
 		}
-
-		// Display error if any (but not for exit)
-		if err != nil {
-			fmt.Fprintf(s.stderr, "error: %v\n", err)
-		}
 	}
 
 	return nil
 }
 
+// isIncomplete returns true if input ends inside a quote or compound command,
+// or with a backslash continuing the line, and more lines must be read
+// before it can run.
+func isIncomplete(input string) bool {
+	if lexer.ContinuesLine(input) {
+		return true
+	}
+	_, err := parser.ParseScriptInput(input)
+	return errors.Is(err, shellerrors.ErrIncompleteInput)
+}
+
+// runLine executes a line of input, records its exit code and reports errors.
+// Returns false if the shell should exit.
+func (s *Shell) runLine(line string) bool {
+	exitCode, err := s.Execute(line)
+	if s.ctx.Err() != nil {
+		// Ended by Exit, e.g. on a signal, which set the exit code
+		return false
+	}
+	s.exitCode = exitCode
+
+	// Check for exit command
+	var exitErr builtins.ExitCode
+	if errors.As(err, &exitErr) {
+		s.exitCode = exitErr.Code
+		return false
+	}
+
+	// Display error if any (but not for exit)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "error: %s\n", shellerrors.Format(err, s.scriptName))
+	}
+
+	// An unset variable with the nounset option ends a shell that is not
+	// reading commands at the prompt
+	if errors.Is(err, shellerrors.ErrUnboundVariable) && !s.prompting {
+		s.exitCode = 1
+		return false
+	}
+
+	// Traps of the signals caught at the end of the command
+	return s.runPendingTraps()
+}
+
+// runPendingTraps runs the traps of the signals caught so far.
+// Returns false if a trap ran exit.
+func (s *Shell) runPendingTraps() bool {
+	return !s.exitRequested(s.executor.RunPendingTraps(s.ctx))
+}
+
+// runExitTrap runs the EXIT trap, once, as the shell exits.
+// exit within the trap sets the exit code of the shell.
+func (s *Shell) runExitTrap() {
+	traps := s.executor.Traps()
+	if _, ok := traps.Get("EXIT"); !ok {
+		return
+	}
+	// The shell may exit because its context was canceled by a signal
+	err := s.executor.RunTrap(context.WithoutCancel(s.ctx), "EXIT")
+	traps.Remove("EXIT")
+	s.exitRequested(err)
+}
+
+// hooks returns the hooks of the configuration.
+func (s *Shell) hooks() config.HooksConfig {
+	if s.config == nil {
+		return config.HooksConfig{}
+	}
+	return s.config.Hooks
+}
+
+// runHook runs the code of a hook of the configuration, if set, with the
+// given positional parameters. Returns false if the hook ran exit.
+func (s *Shell) runHook(name, code string, args ...string) bool {
+	if code == "" {
+		return true
+	}
+	return !s.exitRequested(s.executor.RunHook(s.ctx, name, code, args))
+}
+
+// exitRequested returns true if err is a request to exit the shell, such as
+// exit run by a trap, and records its exit code.
+func (s *Shell) exitRequested(err error) bool {
+	var exitErr builtins.ExitCode
+	if errors.As(err, &exitErr) {
+		s.exitCode = exitErr.Code
+		return true
+	}
+	return false
+}
+
 // Execute runs a single command string and returns exit code.
 func (s *Shell) Execute(input string) (int, error) {
-	return s.executor.ExecuteInput(s.ctx, input)
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	s.cmdMu.Lock()
+	s.cmdCancel = cancel
+	s.cmdMu.Unlock()
+
+	defer func() {
+		s.cmdMu.Lock()
+		s.cmdCancel = nil
+		s.cmdMu.Unlock()
+	}()
+
+	return s.executor.ExecuteInput(ctx, input)
+}
+
+// interruptCommand cancels the running command, if any (e.g., on Ctrl+C).
+// Loops check for cancellation so that they can be interrupted.
+func (s *Shell) interruptCommand() {
+	s.cmdMu.Lock()
+	defer s.cmdMu.Unlock()
+	if s.cmdCancel != nil {
+		s.cmdCancel()
+	}
+}
+
+// suspendCommand stops the foreground job, if any (e.g., on Ctrl+Z).
+// The shell itself is never suspended.
+func (s *Shell) suspendCommand(sig os.Signal) {
+	if job := s.jobs.Foreground(); job != nil {
+		job.Signal(sig)
+	}
+}
+
+// notifyJobs reports the jobs that finished or were stopped since the last prompt.
+func (s *Shell) notifyJobs() {
+	for _, line := range s.jobs.Notifications() {
+		fmt.Fprintln(s.stderr, line)
+	}
 }
 
 // Exit terminates the shell with the given exit code.
@@ -352,6 +584,11 @@ func (s *Shell) IsRunning() bool {
 	return s.running
 }
 
+// IsInteractive returns true if the shell reads commands from a terminal.
+func (s *Shell) IsInteractive() bool {
+	return s.interactive
+}
+
 // Executor returns the shell's executor.
 func (s *Shell) Executor() *executor.Executor {
 	return s.executor
@@ -372,10 +609,21 @@ func (s *Shell) SetPrompt(prompt string) {
 
 // setupSignals sets up signal handling for the shell.
 func (s *Shell) setupSignals() {
-	setupPlatformSignals(s.sigChan)
+	sigChan := make(chan os.Signal, 1)
+	s.sigChan = sigChan
+	setupPlatformSignals(sigChan, s.jobs.JobControl())
 
 	go func() {
-		for sig := range s.sigChan {
+		for sig := range sigChan {
+			// Signals with a trap run it between commands
+			if name := trapName(sig); name != "" && s.executor.Traps().Catch(name) {
+				continue
+			}
+			// Other signals end scripts, after their EXIT trap
+			if s.inScript {
+				s.Exit(signalStatus(sig))
+				continue
+			}
 			shouldContinue := s.handlePlatformSignal(sig)
 			if shouldContinue {
 				// Print newline and prompt after interrupt
@@ -402,10 +650,18 @@ func (s *Shell) applyConfig() {
 	if s.config.Prompt != "" {
 		s.promptFormat = s.config.Prompt
 	}
+	if s.config.ContinuationPrompt != "" {
+		s.continuationFormat = s.config.ContinuationPrompt
+	}
 }
 
 // onConfigReload is called when the configuration is reloaded.
 func (s *Shell) onConfigReload(cfg *config.Config) {
+	var previousAliases map[string]string
+	if s.config != nil {
+		previousAliases = s.config.Aliases
+	}
+
 	s.config = cfg
 	s.applyConfig()
 
@@ -425,6 +681,7 @@ func (s *Shell) onConfigReload(cfg *config.Config) {
 	// Update line editor prompt if interactive
 	if s.lineEditor != nil {
 		s.lineEditor.SetPrompt(s.expandedPrompt())
+		s.lineEditor.SetContinuationPrompt(s.expandPrompt(s.continuationFormat))
 	}
 
 	// Update executor settings
@@ -434,20 +691,44 @@ func (s *Shell) onConfigReload(cfg *config.Config) {
 		// Update abbreviations setting
 		if cfg != nil {
 			s.executor.SetAbbreviations(cfg.Abbreviations.Enabled)
+			s.applyAliases(previousAliases, cfg.Aliases)
 		}
 	}
 }
 
+// applyAliases defines the aliases of the configuration. Aliases of the
+// previous configuration that it no longer defines are removed; aliases
+// defined with the alias command are kept.
+func (s *Shell) applyAliases(previous, current map[string]string) {
+	aliases := s.executor.Aliases()
+	for name, value := range previous {
+		if _, ok := current[name]; !ok {
+			if v, _ := aliases.Get(name); v == value {
+				aliases.Remove(name)
+			}
+		}
+	}
+	for name, value := range current {
+		aliases.Set(name, value)
+	}
+}
+
 // expandedPrompt returns the prompt with all variables expanded.
 func (s *Shell) expandedPrompt() string {
+	return s.expandPrompt(s.promptFormat)
+}
+
+// expandPrompt expands the variables and colors of a prompt format.
+func (s *Shell) expandPrompt(format string) string {
 	if s.promptExpander == nil {
-		return s.promptFormat
+		return format
 	}
 	// Update the working directory in the expander
 	if s.executor != nil {
 		s.promptExpander.SetWorkDir(s.executor.WorkDir())
 	}
-	return s.promptExpander.Expand(s.promptFormat)
+	s.promptExpander.SetExitCode(s.exitCode)
+	return s.promptExpander.Expand(format)
 }
 
 // Config returns the shell's configuration.
@@ -476,6 +757,11 @@ func (s *Shell) initHistory() {
 	s.history = history.New(maxSize)
 	s.history.SetIgnoreDuplicates(ignoreDuplicates)
 	s.history.SetIgnoreSpacePrefix(ignoreSpacePrefix)
+	if s.config != nil {
+		s.history.SetShareHistory(s.config.History.ShareHistory)
+	}
+	hostname, _ := os.Hostname()
+	s.history.SetSession(hostname, history.NewSessionID())
 
 	// Load history from file
 	if histFile != "" {
@@ -487,18 +773,51 @@ func (s *Shell) initHistory() {
 
 	// Connect history to line editor
 	if s.lineEditor != nil {
-		s.lineEditor.SetHistory(s.history)
+		s.lineEditor.SetHistory(searchAdapter{s.history})
+		s.lineEditor.SetWorkDirFunc(func() string { return s.executor.WorkDir() })
 	}
 
 	// Setup history provider for the history builtin command
 	builtins.SetHistoryProvider(func() builtins.HistoryProvider {
-		return &historyAdapter{h: s.history}
+		return &historyAdapter{h: s.history, file: s.historyFile(), stderr: s.stderr}
 	})
 }
 
+// searchAdapter adapts history.History to terminal.HistoryProvider.
+type searchAdapter struct {
+	*history.History
+}
+
+// FuzzySearch returns the best matches of query in the history, at most
+// limit.
+func (a searchAdapter) FuzzySearch(query string, filter terminal.SearchFilter, limit int) []terminal.SearchMatch {
+	status := history.AnyStatus
+	switch filter.Status {
+	case terminal.Succeeded:
+		status = history.Succeeded
+	case terminal.Failed:
+		status = history.Failed
+	}
+
+	matches := a.History.FuzzySearch(query, history.Filter{Dir: filter.Dir, Status: status}, limit)
+	result := make([]terminal.SearchMatch, len(matches))
+	for i, m := range matches {
+		result[i] = terminal.SearchMatch{
+			Command:   m.Entry.Command,
+			Timestamp: m.Entry.Timestamp,
+			ExitCode:  m.Entry.ExitCode,
+			Detailed:  m.Entry.HasDetails(),
+			Positions: m.Positions,
+		}
+	}
+	return result
+}
+
 // historyAdapter adapts history.History to builtins.HistoryProvider.
 type historyAdapter struct {
-	h *history.History
+	h      *history.History
+	file   string // History file, emptied on Clear
+	stderr io.Writer
 }
 
 func (a *historyAdapter) Len() int {
@@ -518,64 +837,173 @@ func (a *historyAdapter) All() []builtins.HistoryEntry {
 		result[i] = builtins.HistoryEntry{
 			Command:   e.Command,
 			Timestamp: e.Timestamp,
+			Dir:       e.Dir,
+			ExitCode:  e.ExitCode,
+			Duration:  e.Duration,
+			Detailed:  e.HasDetails(),
 		}
 	}
 	return result
 }
 
 func (a *historyAdapter) Clear() {
-	if a.h != nil {
-		a.h.Clear()
+	if a.h == nil {
+		return
+	}
+	a.h.Clear()
+	if a.file != "" {
+		if err := a.h.Save(a.file); err != nil {
+			fmt.Fprintf(a.stderr, "Warning: failed to save history: %v\n", err)
+		}
 	}
 }
 
-// saveHistory saves the command history to file.
+func (a *historyAdapter) Delete(first, last int) error {
+	if a.h == nil {
+		return history.ErrOutOfBounds
+	}
+	if err := a.h.Delete(first, last); err != nil {
+		return err
+	}
+	a.flush()
+	return nil
+}
+
+func (a *historyAdapter) Export(w io.Writer, format string) error {
+	if a.h == nil {
+		return nil
+	}
+	return history.Export(w, a.h.All(), format)
+}
+
+func (a *historyAdapter) Import(r io.Reader, format string) (int, error) {
+	if a.h == nil {
+		return 0, nil
+	}
+	entries, err := history.Parse(r, format)
+	if err != nil {
+		return 0, err
+	}
+	n := a.h.Import(entries)
+	a.flush()
+	return n, nil
+}
+
+// flush rewrites the history file after a change, without the command that
+// is running, which is written when it completes.
+func (a *historyAdapter) flush() {
+	if a.file == "" {
+		return
+	}
+	if err := a.h.Flush(a.file); err != nil {
+		fmt.Fprintf(a.stderr, "Warning: failed to save history: %v\n", err)
+	}
+}
+
+// historyFile returns the expanded path of the history file, or "" if
+// history is not saved.
+func (s *Shell) historyFile() string {
+	if s.config == nil || s.config.History.File == "" {
+		return ""
+	}
+	return config.ExpandPath(s.config.History.File)
+}
+
+// saveHistory appends the commands that completed since the last save to
+// the history file.
 func (s *Shell) saveHistory() {
-	if s.history == nil || s.config == nil {
+	if s.history == nil {
 		return
 	}
+	if histFile := s.historyFile(); histFile != "" {
+		s.reportHistoryError("save", s.history.Flush(histFile))
+	}
+}
 
-	histFile := s.config.History.File
-	if histFile == "" {
+// syncHistory merges the commands other sessions saved to the history file,
+// when the history is shared.
+func (s *Shell) syncHistory() {
+	if s.history == nil {
 		return
 	}
+	if histFile := s.historyFile(); histFile != "" {
+		s.reportHistoryError("read", s.history.Sync(histFile))
+	}
+}
 
-	histFile = config.ExpandPath(histFile)
-	if err := s.history.Save(histFile); err != nil {
-		fmt.Fprintf(s.stderr, "Warning: failed to save history: %v\n", err)
+// reportHistoryError warns about a history file error, unless it is the
+// same as the last one, which would repeat at each command.
+func (s *Shell) reportHistoryError(action string, err error) {
+	if err == nil {
+		s.historyErr = ""
+		return
+	}
+	if msg := err.Error(); msg != s.historyErr {
+		s.historyErr = msg
+		fmt.Fprintf(s.stderr, "Warning: failed to %s history: %v\n", action, err)
 	}
 }
 
 // createCompleter creates a completer with command definitions including options.
 func (s *Shell) createCompleter() *completion.Completer {
+	completer := completion.NewCompleterWithDefs(s.commandDefs())
+
+	// Enable PATH executable completion
+	// Use env.GetPathFrom to handle PATH vs Path (Windows)
+	pathEnv := env.GetPathFrom(s.env)
+	completer.EnablePathCompletion(pathEnv)
+
+	return completer
+}
+
+// commandDefs returns the completion definitions of the registered commands,
+// including shell functions and aliases.
+func (s *Shell) commandDefs() []completion.CommandDef {
 	allDefs := s.executor.Registry().All()
+	functions := s.executor.Functions()
+	for _, name := range functions.Names() {
+		allDefs[name], _ = functions.Get(name)
+	}
 
-	// Convert builtin definitions to completion definitions
+	// Convert builtin and function definitions to completion definitions
 	var defs []completion.CommandDef
 	for _, def := range allDefs {
-		cmdDef := completion.CommandDef{
+		defs = append(defs, completion.CommandDef{
 			Name:        def.Name,
 			Description: def.Description,
-			Options:     make([]completion.OptionDef, 0, len(def.Options)),
-		}
+			Options:     completionOptions(def.Options),
+		})
+	}
 
-		for _, opt := range def.Options {
-			cmdDef.Options = append(cmdDef.Options, completion.OptionDef{
-				Long:        opt.Long,
-				Short:       opt.Short,
-				Description: opt.Description,
-			})
+	// Aliases complete like commands, with the options of the command they run
+	aliases := s.executor.Aliases()
+	for _, name := range aliases.Names() {
+		if _, ok := allDefs[name]; ok {
+			continue
+		}
+		value, _ := aliases.Get(name)
+		cmdDef := completion.CommandDef{
+			Name:        name,
+			Description: "Alias for " + value,
+		}
+		if fields := strings.Fields(value); len(fields) > 0 {
+			cmdDef.Options = completionOptions(allDefs[fields[0]].Options)
 		}
-
 		defs = append(defs, cmdDef)
 	}
 
-	completer := completion.NewCompleterWithDefs(defs)
-
-	// Enable PATH executable completion
-	// Use env.GetPathFrom to handle PATH vs Path (Windows)
-	pathEnv := env.GetPathFrom(s.env)
-	completer.EnablePathCompletion(pathEnv)
+	return defs
+}
 
-	return completer
+// completionOptions converts builtin option definitions to completion definitions.
+func completionOptions(opts []builtins.OptionDef) []completion.OptionDef {
+	result := make([]completion.OptionDef, 0, len(opts))
+	for _, opt := range opts {
+		result = append(result, completion.OptionDef{
+			Long:        opt.Long,
+			Short:       opt.Short,
+			Description: opt.Description,
+		})
+	}
+	return result
 }
@@ -5,11 +5,15 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sdejongh/jsishell/internal/builtins"
+	"github.com/sdejongh/jsishell/internal/config"
 	"github.com/sdejongh/jsishell/internal/env"
 	"github.com/sdejongh/jsishell/internal/executor"
+	"github.com/sdejongh/jsishell/internal/history"
 	"github.com/sdejongh/jsishell/internal/parser"
+	"github.com/sdejongh/jsishell/internal/terminal"
 )
 
 func TestNewShell(t *testing.T) {
@@ -278,6 +282,77 @@ func TestShellSkipsEmptyLines(t *testing.T) {
 	}
 }
 
+func TestShellRunCommandList(t *testing.T) {
+	input := "echo one ; echo two && exit 3 || echo never\necho unreachable\n"
+	var stdout bytes.Buffer
+
+	reg := builtins.NewRegistry()
+	builtins.RegisterAll(reg)
+
+	exec := executor.New(
+		executor.WithRegistry(reg),
+		executor.WithStdout(&stdout),
+	)
+
+	s := New(
+		WithStdin(strings.NewReader(input)),
+		WithStdout(&stdout),
+		WithExecutor(exec),
+		WithPrompt(""),
+	)
+
+	if err := s.Run(); err != nil {
+		t.Errorf("Run error: %v", err)
+	}
+
+	if s.ExitCode() != 3 {
+		t.Errorf("ExitCode() = %d, want 3", s.ExitCode())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "one\ntwo\n") {
+		t.Errorf("stdout = %q, want one and two", out)
+	}
+	if strings.Contains(out, "never") || strings.Contains(out, "unreachable") {
+		t.Errorf("stdout = %q, commands after exit should not run", out)
+	}
+}
+
+func TestShellConfigAliases(t *testing.T) {
+	s := New(WithExecutor(executor.New()))
+	aliases := s.Executor().Aliases()
+	aliases.Clear()
+
+	s.onConfigReload(&config.Config{Aliases: map[string]string{"ll": "ls -l", "gs": "git status"}})
+	aliases.Set("mine", "echo mine")
+
+	// Aliases removed from the configuration are removed, others are kept
+	s.onConfigReload(&config.Config{Aliases: map[string]string{"ll": "ls -la"}})
+	if got := strings.Join(aliases.Names(), " "); got != "ll mine" {
+		t.Errorf("aliases = %q, want %q", got, "ll mine")
+	}
+	if value, _ := aliases.Get("ll"); value != "ls -la" {
+		t.Errorf("ll = %q, want %q", value, "ls -la")
+	}
+}
+
+func TestShellCommandDefsIncludeAliases(t *testing.T) {
+	reg := builtins.NewRegistry()
+	builtins.RegisterAll(reg)
+	s := New(WithExecutor(executor.New(executor.WithRegistry(reg))))
+	s.Executor().Aliases().Set("lsa", "ls -a")
+
+	for _, def := range s.commandDefs() {
+		if def.Name != "lsa" {
+			continue
+		}
+		if len(def.Options) == 0 {
+			t.Error("alias should complete the options of ls")
+		}
+		return
+	}
+	t.Error("commandDefs() should include aliases")
+}
+
 // ============================================================================
 // T125: Performance benchmark for startup time (<100ms target)
 // ============================================================================
@@ -326,6 +401,34 @@ func BenchmarkShellStartupWithAllBuiltins(b *testing.B) {
 	}
 }
 
+func TestSearchAdapter(t *testing.T) {
+	h := history.New(100)
+	for _, run := range []struct {
+		cmd    string
+		status int
+	}{
+		{"go test ./...", 1},
+		{"git status", 0},
+	} {
+		h.Add(run.cmd)
+		h.Complete("/src/app", run.status, time.Second)
+	}
+	a := searchAdapter{h}
+
+	matches := a.FuzzySearch("gt", terminal.SearchFilter{}, 10)
+	if len(matches) != 2 || matches[0].Command != "go test ./..." {
+		t.Fatalf("FuzzySearch(gt) = %+v, want go test first", matches)
+	}
+	if m := matches[0]; m.ExitCode != 1 || !m.Detailed || m.Timestamp.IsZero() || len(m.Positions) != 2 {
+		t.Errorf("match = %+v, want the details of the entry", m)
+	}
+
+	matches = a.FuzzySearch("", terminal.SearchFilter{Dir: "/src/app", Status: terminal.Succeeded}, 10)
+	if len(matches) != 1 || matches[0].Command != "git status" {
+		t.Errorf("FuzzySearch(succeeded) = %+v, want git status", matches)
+	}
+}
+
 // TestStartupTimeMeetsTarget verifies startup time is under 100ms.
 func TestStartupTimeMeetsTarget(t *testing.T) {
 	var stdout, stderr bytes.Buffer
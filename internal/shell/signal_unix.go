@@ -19,13 +19,19 @@ func platformSignals() []os.Signal {
 }
 
 // handlePlatformSignal handles platform-specific signals.
-// Returns true if the signal was handled and the shell should continue,
-// false if the shell should exit.
+// Returns true if the signal was handled and the shell should show a new
+// prompt, false if it should not (e.g., because the shell exits).
 func (s *Shell) handlePlatformSignal(sig os.Signal) bool {
 	switch sig {
 	case os.Interrupt:
 		// Ctrl+C - interrupt current operation, continue shell
+		s.interruptCommand()
 		return true
+	case syscall.SIGTSTP:
+		// Ctrl+Z while the shell runs a command itself - stop the
+		// processes of the foreground job, continue shell
+		s.suspendCommand(sig)
+		return false
 	case syscall.SIGTERM:
 		// Terminate gracefully
 		s.Exit(0)
@@ -45,6 +51,31 @@ func (s *Shell) handlePlatformSignal(sig os.Signal) bool {
 }
 
 // setupPlatformSignals performs platform-specific signal setup.
-func setupPlatformSignals(sigChan chan os.Signal) {
+// With job control, the shell handles Ctrl+Z instead of being suspended.
+func setupPlatformSignals(sigChan chan os.Signal, jobControl bool) {
 	signal.Notify(sigChan, platformSignals()...)
+	if jobControl {
+		signal.Notify(sigChan, syscall.SIGTSTP)
+	}
+}
+
+// trapName returns the name under which the trap builtin catches a
+// signal, or "" if the signal cannot be trapped.
+func trapName(sig os.Signal) string {
+	switch sig {
+	case os.Interrupt:
+		return "INT"
+	case syscall.SIGTERM:
+		return "TERM"
+	default:
+		return ""
+	}
+}
+
+// signalStatus returns the exit status of a script ended by a signal.
+func signalStatus(sig os.Signal) int {
+	if sysSig, ok := sig.(syscall.Signal); ok {
+		return 128 + int(sysSig)
+	}
+	return 1
 }
@@ -16,12 +16,13 @@ func platformSignals() []os.Signal {
 }
 
 // handlePlatformSignal handles platform-specific signals.
-// Returns true if the signal was handled and the shell should continue,
-// false if the shell should exit.
+// Returns true if the signal was handled and the shell should show a new
+// prompt, false if it should not (e.g., because the shell exits).
 func (s *Shell) handlePlatformSignal(sig os.Signal) bool {
 	switch sig {
 	case os.Interrupt:
 		// Ctrl+C - interrupt current operation, continue shell
+		s.interruptCommand()
 		return true
 	default:
 		return true
@@ -29,6 +30,22 @@ func (s *Shell) handlePlatformSignal(sig os.Signal) bool {
 }
 
 // setupPlatformSignals performs platform-specific signal setup.
-func setupPlatformSignals(sigChan chan os.Signal) {
+// Job control is not available on Windows.
+func setupPlatformSignals(sigChan chan os.Signal, jobControl bool) {
 	signal.Notify(sigChan, platformSignals()...)
 }
+
+// trapName returns the name under which the trap builtin catches a
+// signal, or "" if the signal cannot be trapped.
+func trapName(sig os.Signal) string {
+	if sig == os.Interrupt {
+		return "INT"
+	}
+	return ""
+}
+
+// signalStatus returns the exit status of a script ended by a signal.
+// Ctrl+C ends a script with the status of SIGINT, as on Unix.
+func signalStatus(sig os.Signal) int {
+	return 130
+}
@@ -2,9 +2,13 @@
 package terminal
 
 import (
+	"fmt"
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/sdejongh/jsishell/internal/errors"
 )
 
 // CompletionProvider provides completion suggestions.
@@ -21,11 +25,32 @@ type HistoryProvider interface {
 	ResetNavigation()
 	Previous() (string, bool)
 	Next() (string, bool)
-	// Search methods
-	StartSearch(query string)
-	PreviousMatch() (string, bool)
-	NextMatch() (string, bool)
-	EndSearch()
+	// FuzzySearch returns the best matches of query, at most limit
+	FuzzySearch(query string, filter SearchFilter, limit int) []SearchMatch
+}
+
+// SearchStatus selects the commands of a history search by exit status.
+type SearchStatus int
+
+const (
+	AnyStatus SearchStatus = iota
+	Succeeded              // Exit status 0
+	Failed                 // Non-zero exit status
+)
+
+// SearchFilter restricts the commands of a history search.
+type SearchFilter struct {
+	Dir    string // Directory the command ran in, if not empty
+	Status SearchStatus
+}
+
+// SearchMatch is a command found by a history search.
+type SearchMatch struct {
+	Command   string
+	Timestamp time.Time // Zero if unknown
+	ExitCode  int
+	Detailed  bool  // False if the exit status is unknown
+	Positions []int // Indices of the matched runes of the command
 }
 
 // LineEditor handles interactive line input with cursor movement and editing.
@@ -40,10 +65,25 @@ type LineEditor struct {
 	lastTab   time.Time          // Time of last Tab press for Tab-Tab detection
 	colors    *ColorScheme       // Color scheme for ghost text
 
+	// Multi-line input
+	continuationPrompt string            // Prompt of the continuation lines
+	incomplete         func(string) bool // Reports whether Enter continues the buffer on a new line
+	pasting            bool              // Inside a bracketed paste
+	initialText        string            // Text the next ReadLine starts with
+
+	// Screen rows of the last render, counted from its first row
+	cursorRow int // Row of the cursor
+	endRow    int // Row of the end of the text
+
 	// Search mode state
-	searchMode   bool   // Whether we're in search mode (Ctrl+R)
-	searchQuery  string // Current search query
-	searchPrompt string // Search prompt (e.g., "(reverse-i-search)`query':")
+	searchMode     bool          // Whether we're in search mode (Ctrl+R)
+	searchQuery    []rune        // Current search query
+	searchFilter   int           // Index of the filter in searchFilters
+	searchMatches  []SearchMatch // Candidates for the query, best first
+	searchSelected int           // Index of the selected candidate
+	searchTop      int           // Index of the first candidate shown
+	searchOriginal string        // Buffer before the search, restored by Escape
+	workDir        func() string // Current directory, for the directory filter
 }
 
 // NewLineEditor creates a new LineEditor.
@@ -63,6 +103,12 @@ func (e *LineEditor) SetBuffer(s string) {
 	}
 }
 
+// SetInitialText sets text that the next ReadLine starts with, as if it had
+// been typed, e.g. a history expansion to check before running it.
+func (e *LineEditor) SetInitialText(text string) {
+	e.initialText = text
+}
+
 // SetCursor sets the cursor position.
 func (e *LineEditor) SetCursor(pos int) {
 	if pos < 0 {
@@ -99,6 +145,24 @@ func (e *LineEditor) SetPrompt(prompt string) {
 	e.prompt = prompt
 }
 
+// ContinuationPrompt returns the prompt of the continuation lines.
+func (e *LineEditor) ContinuationPrompt() string {
+	return e.continuationPrompt
+}
+
+// SetContinuationPrompt sets the prompt shown at the start of the
+// continuation lines of a multi-line buffer.
+func (e *LineEditor) SetContinuationPrompt(prompt string) {
+	e.continuationPrompt = prompt
+}
+
+// SetIncompleteFunc sets the function that tells whether the buffer is an
+// incomplete command, such as an unclosed quote or block. Enter then opens
+// a continuation line instead of submitting the buffer.
+func (e *LineEditor) SetIncompleteFunc(incomplete func(string) bool) {
+	e.incomplete = incomplete
+}
+
 // GhostText returns the current ghost text suggestion.
 func (e *LineEditor) GhostText() string {
 	return e.ghostText
@@ -119,6 +183,12 @@ func (e *LineEditor) SetHistory(h HistoryProvider) {
 	e.history = h
 }
 
+// SetWorkDirFunc sets the function that returns the current directory,
+// which the history search can filter commands by.
+func (e *LineEditor) SetWorkDirFunc(workDir func() string) {
+	e.workDir = workDir
+}
+
 // SetColors sets the color scheme for ghost text.
 func (e *LineEditor) SetColors(colors *ColorScheme) {
 	e.colors = colors
@@ -151,12 +221,56 @@ func (e *LineEditor) MoveRight() {
 
 // MoveToStart moves the cursor to the start of the line (Home/Ctrl+A).
 func (e *LineEditor) MoveToStart() {
-	e.cursor = 0
+	e.cursor = e.lineStart(e.cursor)
 }
 
 // MoveToEnd moves the cursor to the end of the line (End/Ctrl+E).
 func (e *LineEditor) MoveToEnd() {
-	e.cursor = len(e.buffer)
+	e.cursor = e.lineEnd(e.cursor)
+}
+
+// MoveUp moves the cursor to the previous line of a multi-line buffer,
+// keeping its column if the line is long enough. Returns false on the
+// first line.
+func (e *LineEditor) MoveUp() bool {
+	start := e.lineStart(e.cursor)
+	if start == 0 {
+		return false
+	}
+	column := e.cursor - start
+	prev := e.lineStart(start - 1)
+	e.cursor = prev + min(column, start-1-prev)
+	return true
+}
+
+// MoveDown moves the cursor to the next line of a multi-line buffer,
+// keeping its column if the line is long enough. Returns false on the
+// last line.
+func (e *LineEditor) MoveDown() bool {
+	end := e.lineEnd(e.cursor)
+	if end == len(e.buffer) {
+		return false
+	}
+	column := e.cursor - e.lineStart(e.cursor)
+	next := end + 1
+	e.cursor = next + min(column, e.lineEnd(next)-next)
+	return true
+}
+
+// lineStart returns the position of the start of the line containing pos.
+func (e *LineEditor) lineStart(pos int) int {
+	for pos > 0 && e.buffer[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// lineEnd returns the position of the end of the line containing pos.
+func (e *LineEditor) lineEnd(pos int) int {
+	for pos < len(e.buffer) && e.buffer[pos] != '\n' {
+		pos++
+	}
+	return pos
 }
 
 // ============================================================================
@@ -204,13 +318,14 @@ func (e *LineEditor) Delete() {
 
 // DeleteToEnd deletes from cursor to end of line (Ctrl+K).
 func (e *LineEditor) DeleteToEnd() {
-	e.buffer = e.buffer[:e.cursor]
+	e.buffer = append(e.buffer[:e.cursor], e.buffer[e.lineEnd(e.cursor):]...)
 }
 
 // DeleteToStart deletes from start of line to cursor (Ctrl+U).
 func (e *LineEditor) DeleteToStart() {
-	e.buffer = e.buffer[e.cursor:]
-	e.cursor = 0
+	start := e.lineStart(e.cursor)
+	e.buffer = append(e.buffer[:start], e.buffer[e.cursor:]...)
+	e.cursor = start
 }
 
 // ============================================================================
@@ -346,26 +461,66 @@ func (e *LineEditor) historyNext() {
 // History Search (T112)
 // ============================================================================
 
-// startHistorySearch enters reverse search mode (Ctrl+R).
+// Size of the history search picker.
+const (
+	searchCandidates = 200 // Candidates kept for scrolling
+	searchRows       = 10  // Candidates shown below the query
+)
+
+// searchFilters are the filters of the history search, cycled with Ctrl+F.
+var searchFilters = []struct {
+	name   string
+	dir    bool // Only commands run in the current directory
+	status SearchStatus
+}{
+	{"all", false, AnyStatus},
+	{"directory", true, AnyStatus},
+	{"succeeded", false, Succeeded},
+	{"failed", false, Failed},
+}
+
+// startHistorySearch enters search mode (Ctrl+R), with the buffer as the
+// initial query.
 func (e *LineEditor) startHistorySearch() {
 	if e.history == nil {
 		return
 	}
 
 	e.searchMode = true
-	e.searchQuery = ""
-	e.searchPrompt = "(reverse-i-search)`':"
-	e.history.StartSearch("")
+	e.searchQuery = append([]rune(nil), e.buffer...)
+	e.searchFilter = 0
+	e.searchOriginal = string(e.buffer)
+	e.ghostText = ""
+	e.updateSearch()
 }
 
-// exitHistorySearch exits search mode.
-func (e *LineEditor) exitHistorySearch() {
-	e.searchMode = false
-	e.searchQuery = ""
-	e.searchPrompt = ""
-	if e.history != nil {
-		e.history.EndSearch()
+// updateSearch searches the history for the query and selects the best
+// match.
+func (e *LineEditor) updateSearch() {
+	f := searchFilters[e.searchFilter]
+	filter := SearchFilter{Status: f.status}
+	if f.dir && e.workDir != nil {
+		filter.Dir = e.workDir()
+	}
+	e.searchMatches = e.history.FuzzySearch(string(e.searchQuery), filter, searchCandidates)
+	e.searchSelected, e.searchTop = 0, 0
+}
+
+// exitHistorySearch exits search mode. With accept, the buffer gets the
+// selected command, otherwise the buffer from before the search.
+func (e *LineEditor) exitHistorySearch(accept bool) {
+	text := e.searchOriginal
+	if accept && e.searchSelected < len(e.searchMatches) {
+		text = e.searchMatches[e.searchSelected].Command
 	}
+	e.buffer = []rune(text)
+	e.cursor = len(e.buffer)
+
+	e.searchMode = false
+	e.searchQuery = nil
+	e.searchMatches = nil
+	e.searchSelected, e.searchTop = 0, 0
+	e.searchOriginal = ""
 }
 
 // handleSearchKey handles a key while in search mode.
@@ -373,130 +528,335 @@ func (e *LineEditor) exitHistorySearch() {
 func (e *LineEditor) handleSearchKey(key Key) bool {
 	switch key.Special {
 	case KeyEnter:
-		// Accept current result and exit search
-		e.exitHistorySearch()
+		// Run the selected command
+		if len(e.searchMatches) == 0 {
+			return false
+		}
+		e.exitHistorySearch(true)
 		return true
 
-	case KeyCtrlR:
-		// Search for next match
-		if e.history != nil {
-			cmd, ok := e.history.PreviousMatch()
-			if ok {
-				e.buffer = []rune(cmd)
-				e.cursor = len(e.buffer)
-			}
-		}
+	case KeyUp, KeyCtrlP:
+		e.moveSelection(-1)
 
-	case KeyCtrlC, KeyEscape:
-		// Cancel search
-		e.exitHistorySearch()
-		e.Clear()
-		if e.terminal != nil {
-			e.terminal.WriteString("^C\r\n")
+	case KeyDown, KeyCtrlN, KeyCtrlR:
+		e.moveSelection(1)
+
+	case KeyCtrlF:
+		// Next filter, skipping the directory without a way to know it
+		e.searchFilter = (e.searchFilter + 1) % len(searchFilters)
+		if searchFilters[e.searchFilter].dir && e.workDir == nil {
+			e.searchFilter++
 		}
+		e.updateSearch()
+
+	case KeyEscape:
+		e.exitHistorySearch(false)
+
+	case KeyCtrlC:
+		e.exitHistorySearch(false)
+		e.interrupt()
 
 	case KeyBackspace:
-		// Remove last char from search query
 		if len(e.searchQuery) > 0 {
 			e.searchQuery = e.searchQuery[:len(e.searchQuery)-1]
-			e.searchPrompt = "(reverse-i-search)`" + e.searchQuery + "':"
-			if e.history != nil {
-				e.history.StartSearch(e.searchQuery)
-				// Get first match
-				cmd, ok := e.history.PreviousMatch()
-				if ok {
-					e.buffer = []rune(cmd)
-					e.cursor = len(e.buffer)
-				}
-			}
+			e.updateSearch()
 		}
 
-	case KeyLeft, KeyRight, KeyHome, KeyEnd:
-		// Exit search mode and apply the key
-		e.exitHistorySearch()
-		return e.HandleKey(key)
+	case KeyTab, KeyLeft, KeyRight, KeyHome, KeyEnd:
+		// Edit the selected command: exit search mode and apply the key
+		e.exitHistorySearch(true)
+		if key.Special != KeyTab {
+			return e.HandleKey(key)
+		}
 
 	case KeyNone:
 		// Add character to search query
 		if key.Rune != 0 && !key.Ctrl && !key.Alt {
-			e.searchQuery += string(key.Rune)
-			e.searchPrompt = "(reverse-i-search)`" + e.searchQuery + "':"
-			if e.history != nil {
-				e.history.StartSearch(e.searchQuery)
-				// Get first match
-				cmd, ok := e.history.PreviousMatch()
-				if ok {
-					e.buffer = []rune(cmd)
-					e.cursor = len(e.buffer)
-				}
-			}
+			e.searchQuery = append(e.searchQuery, key.Rune)
+			e.updateSearch()
 		}
 	}
 
 	return false
 }
 
-// renderSearch renders the search prompt and buffer.
+// moveSelection moves the selection of the picker by delta candidates.
+func (e *LineEditor) moveSelection(delta int) {
+	e.searchSelected = max(0, min(e.searchSelected+delta, len(e.searchMatches)-1))
+}
+
+// renderSearch renders the query, followed by a picker with the candidates
+// around the selected one, one per row.
 func (e *LineEditor) renderSearch() {
 	if e.terminal == nil {
 		return
 	}
+	width, height, err := e.terminal.Size()
+	if err != nil {
+		width, height = 0, 0 // Unknown size: no limits
+	}
 
-	// Move to start of line and clear it
-	e.terminal.WriteString("\r")
-	e.terminal.WriteString("\033[K")
+	// Keep the query row and the picker on the screen
+	rows := searchRows
+	if height > 0 {
+		rows = max(1, min(rows, height-2))
+	}
+	if e.searchSelected < e.searchTop {
+		e.searchTop = e.searchSelected
+	}
+	if e.searchSelected >= e.searchTop+rows {
+		e.searchTop = e.searchSelected - rows + 1
+	}
+
+	var panel []string
+	for i := e.searchTop; i < len(e.searchMatches) && i < e.searchTop+rows; i++ {
+		panel = append(panel, e.searchRow(e.searchMatches[i], i == e.searchSelected, width))
+	}
+	count := ""
+	if len(e.searchMatches) == 0 {
+		panel = append(panel, e.styled("  no match", (*ColorScheme).Dim, "\033[2m"))
+	} else {
+		count = e.styled(fmt.Sprintf("  %d/%d", e.searchSelected+1, len(e.searchMatches)), (*ColorScheme).GhostText, "\033[2m")
+	}
 
-	// Write search prompt
-	e.terminal.WriteString(e.searchPrompt)
+	prompt := "(history: " + searchFilters[e.searchFilter].name + ") "
+	e.draw(prompt, e.searchQuery, len(e.searchQuery), count, panel)
+}
+
+// searchRow formats a candidate of the picker: a marker if it is selected,
+// the time of the command, its exit status if it failed, and the command
+// with the matched characters highlighted, cut to the width.
+func (e *LineEditor) searchRow(m SearchMatch, selected bool, width int) string {
+	var sb strings.Builder
+	if selected {
+		sb.WriteString(e.styled(">", (*ColorScheme).Bold, "\033[1m") + " ")
+	} else {
+		sb.WriteString("  ")
+	}
+
+	when := strings.Repeat(" ", 12)
+	if !m.Timestamp.IsZero() {
+		when = m.Timestamp.Format("Jan 02 15:04")
+	}
+	sb.WriteString(e.styled(when, (*ColorScheme).Dim, "\033[2m"))
+	status := "   "
+	if m.Detailed && m.ExitCode != 0 {
+		status = e.styled(fmt.Sprintf("%3d", m.ExitCode), (*ColorScheme).Error, "\033[31m")
+	}
+	sb.WriteString(" " + status + " ")
+
+	// Leave the last column free, so that the row does not wrap
+	command := []rune(m.Command)
+	cut := false
+	if room := width - 2 - 12 - 5 - 1; width > 0 && len(command) > room {
+		command = command[:max(0, room-1)]
+		cut = true
+	}
+	matched := make(map[int]bool, len(m.Positions))
+	for _, pos := range m.Positions {
+		matched[pos] = true
+	}
+	for i, r := range command {
+		if r == '\n' || r == '\t' {
+			r = ' '
+		}
+		if matched[i] {
+			sb.WriteString(e.styled(string(r), (*ColorScheme).Bold, "\033[1m"))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	if cut {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
 
-	// Write buffer content
-	e.terminal.WriteString(" " + string(e.buffer))
+// styled applies a style of the color scheme to text, or the escape code
+// of the style without a color scheme.
+func (e *LineEditor) styled(text string, style func(*ColorScheme, string) string, code string) string {
+	if e.colors != nil {
+		return style(e.colors, text)
+	}
+	return code + text + ResetCode
 }
 
 // ============================================================================
 // Line Rendering (T061)
 // ============================================================================
 
-// Render renders the current line to the terminal.
+// Render renders the prompt and buffer to the terminal. The lines of a
+// multi-line buffer after the first start with the continuation prompt,
+// and lines wider than the terminal wrap.
 func (e *LineEditor) Render() {
+	ghost := e.ghostText
+	if ghost != "" {
+		// Using color scheme or default dim
+		if e.colors != nil {
+			ghost = e.colors.GhostText(ghost)
+		} else {
+			ghost = "\033[2m" + ghost + "\033[0m"
+		}
+	}
+	e.draw(e.prompt, e.buffer, e.cursor, ghost, nil)
+}
+
+// draw redraws the rendered text in place: it goes back to the first row
+// of the last render, clears the screen from there, writes the prompt, the
+// text and the ghost text, then the rows of the panel below, and moves the
+// cursor to the given position of the text.
+func (e *LineEditor) draw(prompt string, text []rune, cursor int, ghost string, panel []string) {
 	if e.terminal == nil {
 		return
 	}
+	width, _, err := e.terminal.Size()
+	if err != nil {
+		width = 0 // Unknown width: no wrapping
+	}
 
-	// Move to start of line and clear it
-	e.terminal.WriteString("\r")     // Move to column 0
-	e.terminal.WriteString("\033[K") // Clear from cursor to end of line
+	// Back to the start of the last render, and clear it
+	e.terminal.MoveCursorUp(e.cursorRow)
+	e.terminal.WriteString("\r\033[J")
+
+	l := &screenLayout{width: width}
+	l.write(prompt)
+	l.write(e.continued(string(text[:cursor])))
+	cursorRow, cursorCol := l.position()
+	l.write(e.continued(string(text[cursor:])))
+	l.write(ghost)
+	for _, row := range panel {
+		l.write("\n" + row)
+	}
+	endRow, endCol := l.position()
+	if endRow > l.row {
+		// The text fills the last row: start the next one, so that the
+		// cursor can go there
+		l.sb.WriteString("\r\n")
+	}
+	e.terminal.WriteString(l.sb.String())
 
-	// Write prompt
-	e.terminal.WriteString(e.prompt)
+	// Move cursor to correct position
+	if cursorRow == endRow {
+		e.terminal.MoveCursorLeft(endCol - cursorCol)
+	} else {
+		e.terminal.MoveCursorUp(endRow - cursorRow)
+		e.terminal.WriteString("\r")
+		e.terminal.MoveCursorRight(cursorCol)
+	}
+	e.cursorRow, e.endRow = cursorRow, endRow
+}
 
-	// Write buffer content
-	e.terminal.WriteString(string(e.buffer))
+// continued returns text of the buffer with the continuation prompt after
+// each newline.
+func (e *LineEditor) continued(text string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+e.continuationPrompt)
+}
 
-	// Write ghost text if any (using color scheme or default dim)
-	if e.ghostText != "" {
-		if e.colors != nil {
-			e.terminal.WriteString(e.colors.GhostText(e.ghostText))
+// newRow moves the cursor below the rendered text, on a new row where the
+// next render starts.
+func (e *LineEditor) newRow() {
+	e.terminal.MoveCursorDown(e.endRow - e.cursorRow)
+	e.terminal.WriteString("\r\n")
+	e.cursorRow, e.endRow = 0, 0
+}
+
+// RenderNewLine renders a newline (after command submission). The buffer
+// is rendered again without ghost text and with the cursor at the end, so
+// that the new line starts below all of it.
+func (e *LineEditor) RenderNewLine() {
+	if e.terminal == nil {
+		return
+	}
+	if e.cursor != len(e.buffer) || e.ghostText != "" {
+		e.cursor = len(e.buffer)
+		e.ghostText = ""
+		e.Render()
+	}
+	e.newRow()
+}
+
+// interrupt abandons the buffer (Ctrl+C).
+func (e *LineEditor) interrupt() {
+	if e.terminal != nil {
+		e.cursor = len(e.buffer)
+		e.ghostText = ""
+		e.Render()
+		e.terminal.WriteString("^C")
+		e.newRow()
+	}
+	e.Clear()
+}
+
+// screenLayout follows the position of the cursor on the screen while
+// text is written, wrapping at the width of the terminal.
+type screenLayout struct {
+	sb       strings.Builder // Text to write to the terminal
+	width    int             // Terminal width, 0 if unknown
+	row, col int             // Position of the cursor
+}
+
+// write adds text, converting newlines to \r\n. Escape sequences, such as
+// colors, take no room on the screen.
+func (l *screenLayout) write(s string) {
+	for i := 0; i < len(s); {
+		if n := escapeLength(s[i:]); n > 0 {
+			l.sb.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		if r == '\n' {
+			l.sb.WriteString("\r\n")
+			l.row++
+			l.col = 0
+			continue
+		}
+		// A character after the last column goes to the next row
+		if l.width > 0 && l.col >= l.width {
+			l.row++
+			l.col = 0
+		}
+		l.sb.WriteRune(r)
+		if r == '\t' {
+			l.col += 8 - l.col%8
+			if l.width > 0 && l.col >= l.width {
+				l.col = l.width - 1 // Tabs do not wrap
+			}
 		} else {
-			e.terminal.WriteString("\033[2m") // Dim
-			e.terminal.WriteString(e.ghostText)
-			e.terminal.WriteString("\033[0m") // Reset
+			l.col++
 		}
 	}
+}
 
-	// Move cursor to correct position
-	// Calculate how far back we need to move from the end
-	moveBack := len(e.buffer) - e.cursor + len([]rune(e.ghostText))
-	if moveBack > 0 {
-		e.terminal.MoveCursorLeft(moveBack)
+// position returns the row and column where the next character goes.
+func (l *screenLayout) position() (row, col int) {
+	if l.width > 0 && l.col >= l.width {
+		return l.row + 1, 0
 	}
+	return l.row, l.col
 }
 
-// RenderNewLine renders a newline (after command submission).
-func (e *LineEditor) RenderNewLine() {
-	if e.terminal != nil {
-		e.terminal.WriteString("\r\n")
+// escapeLength returns the length of the CSI (ESC [ ... final) or OSC
+// (ESC ] ... BEL) escape sequence at the start of s, or 0 if there is none.
+func escapeLength(s string) int {
+	if len(s) < 2 || s[0] != '\033' {
+		return 0
 	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		if i := strings.IndexByte(s, '\a'); i > 0 {
+			return i + 1
+		}
+	}
+	return 0
 }
 
 // updateGhostText updates the ghost text from the completer.
@@ -566,7 +926,7 @@ func (e *LineEditor) showCompletionList() {
 	}
 
 	// Print newline, then all completions
-	e.terminal.WriteString("\r\n")
+	e.newRow()
 
 	// Display completions in columns
 	e.renderCompletions(completions)
@@ -632,8 +992,29 @@ func (e *LineEditor) renderCompletions(completions []string) {
 func (e *LineEditor) HandleKey(key Key) bool {
 	switch key.Special {
 	case KeyEnter:
+		if e.pasting {
+			e.Insert('\n')
+			return false
+		}
+		if e.incomplete != nil && e.incomplete(string(e.buffer)) {
+			// Unclosed quote or block, or trailing backslash: continue the
+			// command on a new line
+			e.cursor = len(e.buffer)
+			e.Insert('\n')
+			return false
+		}
 		return true
 
+	case KeyPasteStart:
+		e.pasting = true
+
+	case KeyPasteEnd:
+		e.pasting = false
+		// A trailing newline of the pasted text does not submit it
+		if e.cursor > 0 && e.buffer[e.cursor-1] == '\n' {
+			e.Backspace()
+		}
+
 	case KeyBackspace:
 		e.Backspace()
 
@@ -647,10 +1028,15 @@ func (e *LineEditor) HandleKey(key Key) bool {
 		e.MoveRight()
 
 	case KeyUp:
-		e.historyPrevious()
+		// Previous line of a multi-line buffer, then history
+		if !e.MoveUp() {
+			e.historyPrevious()
+		}
 
 	case KeyDown:
-		e.historyNext()
+		if !e.MoveDown() {
+			e.historyNext()
+		}
 
 	case KeyHome:
 		e.MoveToStart()
@@ -687,10 +1073,7 @@ func (e *LineEditor) HandleKey(key Key) bool {
 
 	case KeyCtrlC:
 		// Cancel - clear buffer and signal newline
-		e.Clear()
-		if e.terminal != nil {
-			e.terminal.WriteString("^C\r\n")
-		}
+		e.interrupt()
 		return false // Don't return the empty line, continue editing
 
 	case KeyCtrlD:
@@ -705,22 +1088,31 @@ func (e *LineEditor) HandleKey(key Key) bool {
 		// Clear screen and redraw
 		if e.terminal != nil {
 			e.terminal.Clear()
+			e.cursorRow, e.endRow = 0, 0
 			e.Render()
 		}
 
 	case KeyTab:
-		e.handleTab()
+		if e.pasting {
+			e.Insert('\t')
+		} else {
+			e.handleTab()
+		}
 
 	case KeyCtrlR:
 		e.startHistorySearch()
 
 	case KeyCtrlN:
 		// Same as Down arrow
-		e.historyNext()
+		if !e.MoveDown() {
+			e.historyNext()
+		}
 
 	case KeyCtrlP:
 		// Same as Up arrow
-		e.historyPrevious()
+		if !e.MoveUp() {
+			e.historyPrevious()
+		}
 
 	case KeyEscape:
 		// Ignore escape key alone
@@ -754,7 +1146,8 @@ func (e *LineEditor) HandleKey(key Key) bool {
 }
 
 // ReadLine reads a line of input interactively.
-// Returns the line content and any error.
+// Returns the line content and any error. Ctrl+C abandons the line and
+// returns errors.ErrInterrupted.
 func (e *LineEditor) ReadLine() (string, error) {
 	if e.terminal == nil {
 		return "", nil
@@ -767,8 +1160,21 @@ func (e *LineEditor) ReadLine() (string, error) {
 	}
 	defer restore()
 
+	// Pasted newlines are part of the input, not Enter
+	if e.terminal.IsTerminal() {
+		e.terminal.EnableBracketedPaste()
+		defer e.terminal.DisableBracketedPaste()
+	}
+
 	// Clear buffer for new input
 	e.Clear()
+	if e.initialText != "" {
+		e.SetBuffer(e.initialText)
+		e.MoveToEnd()
+		e.initialText = ""
+	}
+	e.pasting = false
+	e.cursorRow, e.endRow = 0, 0
 
 	// Reset history navigation
 	if e.history != nil {
@@ -793,20 +1199,35 @@ func (e *LineEditor) ReadLine() (string, error) {
 		if e.searchMode {
 			done = e.handleSearchKey(key)
 			if done {
+				// Replace the picker with the accepted line
+				e.Render()
 				e.RenderNewLine()
 				return e.String(), nil
 			}
-			e.renderSearch()
+			if e.searchMode {
+				e.renderSearch()
+			} else {
+				e.Render()
+			}
 			continue
 		}
 
 		done = e.HandleKey(key)
 
+		if key.Special == KeyCtrlC {
+			return "", errors.ErrInterrupted
+		}
+
 		if done {
 			e.RenderNewLine()
 			return e.String(), nil
 		}
 
+		// Pasted text is rendered once complete
+		if e.pasting {
+			continue
+		}
+
 		// Update ghost text after each key (unless it was Tab which handles it)
 		if key.Special != KeyTab {
 			e.updateGhostText()
@@ -3,7 +3,9 @@ package terminal
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"time"
 )
 
 // T052: Write tests for cursor movement
@@ -798,3 +800,296 @@ func TestInputLatencyMeetsTarget(t *testing.T) {
 	}
 	t.Logf("Input latency: %.4fms (target: <10ms)", insertMs)
 }
+
+// Test multi-line editing: Enter on an incomplete command opens a continuation line
+func TestLineEditorContinuation(t *testing.T) {
+	e := NewLineEditor(nil)
+	e.SetIncompleteFunc(func(s string) bool {
+		return strings.Count(s, "'")%2 == 1
+	})
+
+	e.InsertString("echo 'a")
+	if e.HandleKey(Key{Special: KeyEnter}) {
+		t.Fatal("Enter on an unclosed quote should not submit the buffer")
+	}
+	if got := e.String(); got != "echo 'a\n" {
+		t.Errorf("buffer = %q, want %q", got, "echo 'a\n")
+	}
+
+	e.InsertString("b'")
+	if !e.HandleKey(Key{Special: KeyEnter}) {
+		t.Fatal("Enter on a complete command should submit the buffer")
+	}
+	if got := e.String(); got != "echo 'a\nb'" {
+		t.Errorf("buffer = %q, want %q", got, "echo 'a\nb'")
+	}
+}
+
+// Test bracketed paste: pasted newlines do not submit the buffer
+func TestLineEditorPaste(t *testing.T) {
+	e := NewLineEditor(nil)
+	keys := []Key{
+		{Special: KeyPasteStart},
+		{Rune: 'l'}, {Rune: 's'}, {Special: KeyEnter},
+		{Special: KeyTab}, {Rune: 'p'}, {Rune: 'w'}, {Rune: 'd'}, {Special: KeyEnter},
+		{Special: KeyPasteEnd},
+	}
+	for _, key := range keys {
+		if e.HandleKey(key) {
+			t.Fatalf("key %+v submitted the buffer during a paste", key)
+		}
+	}
+	if got, want := e.String(), "ls\n\tpwd"; got != want {
+		t.Errorf("buffer = %q, want %q", got, want)
+	}
+	if !e.HandleKey(Key{Special: KeyEnter}) {
+		t.Error("Enter after the paste should submit the buffer")
+	}
+}
+
+// Test cursor movement and editing across the lines of a multi-line buffer
+func TestLineEditorMultiLine(t *testing.T) {
+	const buffer = "for x in a b\ndo\n  echo $x\ndone"
+	tests := []struct {
+		name       string
+		cursor     int
+		action     func(e *LineEditor) bool
+		wantCursor int
+		wantBuffer string
+		wantMoved  bool
+	}{
+		{"up keeps column", 18, (*LineEditor).MoveUp, 15, buffer, true},
+		{"up to shorter line", 21, (*LineEditor).MoveUp, 15, buffer, true},
+		{"up on first line", 5, (*LineEditor).MoveUp, 5, buffer, false},
+		{"down keeps column", 2, (*LineEditor).MoveDown, 15, buffer, true},
+		{"down on last line", 27, (*LineEditor).MoveDown, 27, buffer, false},
+		{"home", 18, func(e *LineEditor) bool { e.MoveToStart(); return true }, 16, buffer, true},
+		{"end", 18, func(e *LineEditor) bool { e.MoveToEnd(); return true }, 25, buffer, true},
+		{"ctrl+k", 18, func(e *LineEditor) bool { e.DeleteToEnd(); return true }, 18, "for x in a b\ndo\n  \ndone", true},
+		{"ctrl+u", 18, func(e *LineEditor) bool { e.DeleteToStart(); return true }, 16, "for x in a b\ndo\necho $x\ndone", true},
+		{"backspace joins lines", 16, func(e *LineEditor) bool { e.Backspace(); return true }, 15, "for x in a b\ndo  echo $x\ndone", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewLineEditor(nil)
+			e.SetBuffer(buffer)
+			e.SetCursor(tt.cursor)
+
+			if moved := tt.action(e); moved != tt.wantMoved {
+				t.Errorf("moved = %v, want %v", moved, tt.wantMoved)
+			}
+			if e.Cursor() != tt.wantCursor {
+				t.Errorf("cursor = %d, want %d", e.Cursor(), tt.wantCursor)
+			}
+			if e.String() != tt.wantBuffer {
+				t.Errorf("buffer = %q, want %q", e.String(), tt.wantBuffer)
+			}
+		})
+	}
+}
+
+// Test Render of a multi-line buffer
+func TestLineEditorRenderMultiLine(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	term := NewWithIO(nil, stdout, nil, -1)
+	e := NewLineEditor(term)
+	e.SetPrompt("$ ")
+	e.SetContinuationPrompt("> ")
+	e.SetBuffer("if true\nthen")
+	e.SetCursor(3)
+
+	e.Render()
+	if got, want := stdout.String(), "\r\033[J$ if true\r\n> then\033[1A\r\033[5C"; got != want {
+		t.Errorf("first render = %q, want %q", got, want)
+	}
+
+	// The next render starts from the first row
+	stdout.Reset()
+	e.Render()
+	if got := stdout.String(); !strings.HasPrefix(got, "\r\033[J") {
+		t.Errorf("second render = %q, should not move up from the first row", got)
+	}
+
+	stdout.Reset()
+	e.SetCursor(e.Len())
+	e.Render()
+	e.RenderNewLine()
+	if got, want := stdout.String(), "\r\033[J$ if true\r\n> then\r\n"; got != want {
+		t.Errorf("render and newline = %q, want %q", got, want)
+	}
+}
+
+// Test the screen layout used by Render for wrapping
+func TestScreenLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		width   int
+		text    string
+		wantRow int
+		wantCol int
+	}{
+		{"short", 10, "$ ls", 0, 4},
+		{"no width", 0, strings.Repeat("x", 100), 0, 100},
+		{"wraps", 10, "$ " + strings.Repeat("x", 12), 1, 4},
+		{"fills the row", 10, strings.Repeat("x", 10), 1, 0},
+		{"newline", 10, "$ a\n> b", 1, 3},
+		{"newline after a full row", 4, "xxxx\nab", 1, 2},
+		{"colors take no room", 10, "\033[32muser\033[0m$ ", 0, 6},
+		{"tab", 20, "a\tb", 0, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &screenLayout{width: tt.width}
+			l.write(tt.text)
+			row, col := l.position()
+			if row != tt.wantRow || col != tt.wantCol {
+				t.Errorf("position = (%d, %d), want (%d, %d)", row, col, tt.wantRow, tt.wantCol)
+			}
+		})
+	}
+}
+
+// searchHistory is a history whose search returns the commands, newest
+// first, that contain the runes of the query in order.
+type searchHistory struct {
+	matches []SearchMatch // Oldest first
+	dirs    []string      // Directory of each command
+}
+
+func (h *searchHistory) SetCurrentLine(line string) {}
+func (h *searchHistory) CurrentLine() string        { return "" }
+func (h *searchHistory) ResetNavigation()           {}
+func (h *searchHistory) Previous() (string, bool)   { return "", false }
+func (h *searchHistory) Next() (string, bool)       { return "", false }
+
+func (h *searchHistory) FuzzySearch(query string, filter SearchFilter, limit int) []SearchMatch {
+	var result []SearchMatch
+	for i := len(h.matches) - 1; i >= 0 && len(result) < limit; i-- {
+		m := h.matches[i]
+		if filter.Dir != "" && h.dirs[i] != filter.Dir ||
+			filter.Status == Succeeded && m.ExitCode != 0 ||
+			filter.Status == Failed && m.ExitCode == 0 {
+			continue
+		}
+		pattern := []rune(query)
+		m.Positions = nil
+		for pos, r := range []rune(m.Command) {
+			if len(m.Positions) < len(pattern) && r == pattern[len(m.Positions)] {
+				m.Positions = append(m.Positions, pos)
+			}
+		}
+		if len(m.Positions) == len(pattern) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// newSearchHistory returns a history with commands run in two directories.
+func newSearchHistory() *searchHistory {
+	h := &searchHistory{}
+	for _, run := range []struct {
+		cmd    string
+		dir    string
+		status int
+	}{
+		{"go test ./...", "/src/app", 1},
+		{"git status", "/src/app", 0},
+		{"make build", "/src/lib", 0},
+	} {
+		h.matches = append(h.matches, SearchMatch{Command: run.cmd, Timestamp: time.Now(), ExitCode: run.status, Detailed: true})
+		h.dirs = append(h.dirs, run.dir)
+	}
+	return h
+}
+
+// Test fuzzy history search (Ctrl+R) and its picker
+func TestLineEditorHistorySearch(t *testing.T) {
+	ctrlR := Key{Special: KeyCtrlR}
+	ctrlF := Key{Special: KeyCtrlF}
+	enter := Key{Special: KeyEnter}
+	tests := []struct {
+		name       string
+		initial    string
+		keys       []Key
+		wantBuffer string
+		wantDone   bool
+		wantSearch bool
+	}{
+		{"best match", "", []Key{ctrlR, {Rune: 'g'}, {Rune: 'o'}, enter}, "go test ./...", true, false},
+		{"move down", "", []Key{ctrlR, {Rune: 'g'}, {Special: KeyDown}, enter}, "go test ./...", true, false},
+		{"ctrl+r moves down", "", []Key{ctrlR, ctrlR, ctrlR, enter}, "go test ./...", true, false},
+		{"up stops at the top", "", []Key{ctrlR, {Special: KeyUp}, enter}, "make build", true, false},
+		{"buffer is the query", "stat", []Key{ctrlR, enter}, "git status", true, false},
+		{"backspace", "", []Key{ctrlR, {Rune: 'm'}, {Special: KeyBackspace}, enter}, "make build", true, false},
+		{"directory filter", "", []Key{ctrlR, ctrlF, enter}, "git status", true, false},
+		{"failed filter", "", []Key{ctrlR, ctrlF, ctrlF, ctrlF, enter}, "go test ./...", true, false},
+		{"escape restores the buffer", "echo", []Key{ctrlR, {Rune: 'g'}, {Special: KeyEscape}}, "echo", false, false},
+		{"right edits the match", "", []Key{ctrlR, {Rune: 'g'}, {Special: KeyRight}}, "git status", false, false},
+		{"enter without match", "", []Key{ctrlR, {Rune: 'z'}, enter}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewLineEditor(nil)
+			e.SetHistory(newSearchHistory())
+			e.SetWorkDirFunc(func() string { return "/src/app" })
+			e.SetBuffer(tt.initial)
+			e.SetCursor(e.Len())
+
+			done := false
+			for _, key := range tt.keys {
+				if e.searchMode {
+					done = e.handleSearchKey(key)
+				} else {
+					done = e.HandleKey(key)
+				}
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if e.searchMode != tt.wantSearch {
+				t.Errorf("search mode = %v, want %v", e.searchMode, tt.wantSearch)
+			}
+			if e.String() != tt.wantBuffer {
+				t.Errorf("buffer = %q, want %q", e.String(), tt.wantBuffer)
+			}
+		})
+	}
+}
+
+// Test the rendering of the history search picker below the query
+func TestLineEditorRenderSearch(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	e := NewLineEditor(NewWithIO(nil, stdout, nil, -1))
+	e.SetHistory(newSearchHistory())
+	e.HandleKey(Key{Special: KeyCtrlR})
+	e.handleSearchKey(Key{Rune: 'g'})
+
+	e.renderSearch()
+	got := stdout.String()
+	for _, want := range []string{
+		"(history: all) g",
+		"\r\n\033[1m>\033[0m ",
+		"\033[1mg\033[0mit status",
+		"\033[31m  1\033[0m \033[1mg\033[0mo test ./...",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("render = %q, should contain %q", got, want)
+		}
+	}
+	// Back up from the two rows of the picker to the query
+	if !strings.HasSuffix(got, "\033[2A\r\033[16C") {
+		t.Errorf("render = %q, should end on the query", got)
+	}
+
+	// Accepting the match clears the picker below the query
+	stdout.Reset()
+	e.handleSearchKey(Key{Special: KeyEnter})
+	e.Render()
+	if got, want := stdout.String(), "\r\033[Jgit status"; got != want {
+		t.Errorf("render after accepting = %q, want %q", got, want)
+	}
+}
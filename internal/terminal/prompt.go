@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +16,7 @@ type PromptExpander struct {
 	homeDir      string       // User's home directory
 	colorScheme  *ColorScheme // Color scheme for prompt colors
 	colorsActive bool         // Whether colors should be applied
+	exitCode     int          // Exit status of the last command
 }
 
 // NewPromptExpander creates a new PromptExpander.
@@ -44,6 +46,11 @@ func (p *PromptExpander) SetWorkDir(dir string) {
 	p.workDir = dir
 }
 
+// SetExitCode updates the exit status of the last command.
+func (p *PromptExpander) SetExitCode(code int) {
+	p.exitCode = code
+}
+
 // Expand expands all prompt variables in the given format string.
 //
 // Supported variables:
@@ -57,6 +64,7 @@ func (p *PromptExpander) SetWorkDir(dir string) {
 //   - %T  - Time with seconds (HH:MM:SS)
 //   - %n  - Newline
 //   - %$  - Shell indicator ($ for user, # for root)
+//   - %?  - Exit status of the last command
 //   - %%  - Literal %
 //
 // Color codes (use %{color} and %{reset} or %{/}):
@@ -115,6 +123,9 @@ func (p *PromptExpander) Expand(format string) string {
 			case '$': // Shell indicator ($ for user, # for root)
 				result.WriteString(p.shellIndicator())
 				i += 2
+			case '?': // Exit status of the last command
+				result.WriteString(strconv.Itoa(p.exitCode))
+				i += 2
 			case '%': // Literal %
 				result.WriteByte('%')
 				i += 2
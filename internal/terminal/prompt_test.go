@@ -408,3 +408,14 @@ func TestPromptExpanderShellIndicator(t *testing.T) {
 		}
 	}
 }
+
+func TestPromptExpanderExitCode(t *testing.T) {
+	p := NewPromptExpander()
+	if got := p.Expand("[%?]"); got != "[0]" {
+		t.Errorf("Expand([%%?]) = %q, want [0]", got)
+	}
+	p.SetExitCode(127)
+	if got := p.Expand("%? %%?"); got != "127 %?" {
+		t.Errorf("Expand(%%? %%%%?) = %q, want %q", got, "127 %?")
+	}
+}
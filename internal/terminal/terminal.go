@@ -37,9 +37,11 @@ const (
 	KeyCtrlP
 	KeyCtrlU
 	KeyCtrlW
-	KeyCtrlR     // Reverse search
-	KeyCtrlLeft  // Word navigation
-	KeyCtrlRight // Word navigation
+	KeyCtrlR      // Reverse search
+	KeyCtrlLeft   // Word navigation
+	KeyCtrlRight  // Word navigation
+	KeyPasteStart // Start of bracketed paste
+	KeyPasteEnd   // End of bracketed paste
 )
 
 // Key represents a keyboard input.
@@ -275,6 +277,21 @@ func (t *Terminal) handleEscapeSequence(buf []byte) (Key, error) {
 					}
 				}
 			}
+		case '2':
+			// Bracketed paste (ESC [ 200 ~ and ESC [ 201 ~)
+			n, _ = t.stdin.Read(buf[3:4])
+			if n > 0 && buf[3] == '0' {
+				t.stdin.Read(buf[4:5])
+				t.stdin.Read(buf[5:6])
+				if buf[5] == '~' {
+					switch buf[4] {
+					case '0':
+						return Key{Special: KeyPasteStart}, nil
+					case '1':
+						return Key{Special: KeyPasteEnd}, nil
+					}
+				}
+			}
 		case '3':
 			// Delete (ESC [ 3 ~)
 			n, _ = t.stdin.Read(buf[3:4])
@@ -332,6 +349,11 @@ func (t *Terminal) Size() (width, height int, err error) {
 	return term.GetSize(t.fd)
 }
 
+// Fd returns the file descriptor of the terminal input.
+func (t *Terminal) Fd() int {
+	return t.fd
+}
+
 // IsTerminal returns true if stdin is connected to a terminal.
 func (t *Terminal) IsTerminal() bool {
 	return term.IsTerminal(t.fd)
@@ -379,6 +401,38 @@ func (t *Terminal) MoveCursorRight(n int) error {
 	return err
 }
 
+// MoveCursorUp moves the cursor up by n lines.
+func (t *Terminal) MoveCursorUp(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := t.WriteString("\033[" + itoa(n) + "A")
+	return err
+}
+
+// MoveCursorDown moves the cursor down by n lines.
+func (t *Terminal) MoveCursorDown(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := t.WriteString("\033[" + itoa(n) + "B")
+	return err
+}
+
+// EnableBracketedPaste asks the terminal to mark pasted text with
+// KeyPasteStart and KeyPasteEnd, so that pasted newlines are not taken
+// for Enter.
+func (t *Terminal) EnableBracketedPaste() error {
+	_, err := t.WriteString("\033[?2004h")
+	return err
+}
+
+// DisableBracketedPaste turns bracketed paste off.
+func (t *Terminal) DisableBracketedPaste() error {
+	_, err := t.WriteString("\033[?2004l")
+	return err
+}
+
 // SaveCursor saves the current cursor position.
 func (t *Terminal) SaveCursor() error {
 	_, err := t.WriteString("\033[s")
@@ -261,6 +261,23 @@ func TestReadKeyCtrlArrows(t *testing.T) {
 	}
 }
 
+func TestReadKeyBracketedPaste(t *testing.T) {
+	var stdout bytes.Buffer
+	stdin := &mockReader{data: []byte("\033[200~ls\r\033[201~")}
+	term := NewWithIO(stdin, &stdout, &stdout, -1)
+
+	want := []Key{{Special: KeyPasteStart}, {Rune: 'l'}, {Rune: 's'}, {Special: KeyEnter}, {Special: KeyPasteEnd}}
+	for i, w := range want {
+		key, err := term.ReadKey()
+		if err != nil {
+			t.Fatalf("ReadKey error: %v", err)
+		}
+		if key != w {
+			t.Errorf("key %d = %+v, want %+v", i, key, w)
+		}
+	}
+}
+
 func TestClear(t *testing.T) {
 	var stdout bytes.Buffer
 	term := NewWithIO(&mockReader{}, &stdout, &stdout, -1)
@@ -328,6 +345,9 @@ func TestMoveCursorLeftRight(t *testing.T) {
 	}{
 		{"left 3", (*Terminal).MoveCursorLeft, 3, "\033[3D"},
 		{"right 5", (*Terminal).MoveCursorRight, 5, "\033[5C"},
+		{"up 2", (*Terminal).MoveCursorUp, 2, "\033[2A"},
+		{"down 1", (*Terminal).MoveCursorDown, 1, "\033[1B"},
+		{"up 0", (*Terminal).MoveCursorUp, 0, ""},
 		{"left 0", (*Terminal).MoveCursorLeft, 0, ""},
 		{"right 0", (*Terminal).MoveCursorRight, 0, ""},
 	}